@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// AggregateIndex is the sidecar of an AggregateFile, mapping session IDs to
+// the byte range of their events
+type AggregateIndex struct {
+	Sessions map[string]AggregateSession `json:"sessions"`
+}
+
+// AggregateSession is the byte range [Start, End) holding a session's events.
+// Interleaved is set when other sessions wrote inside the range.
+type AggregateSession struct {
+	Start       int64 `json:"start"`
+	End         int64 `json:"end"`
+	Interleaved bool  `json:"interleaved,omitempty"`
+}
+
+// aggregateRange tracks the range this logger's session occupies
+type aggregateRange struct {
+	enabled bool
+
+	mu      sync.Mutex
+	written bool
+	session AggregateSession
+}
+
+// aggregateIndexPath returns the index sidecar of an aggregate file:
+// all-sessions.ndjson is indexed in all-sessions.index.json
+func aggregateIndexPath(aggregateFile string) string {
+	return strings.TrimSuffix(aggregateFile, filepath.Ext(aggregateFile)) + ".index.json"
+}
+
+// recordAggregateWrite extends the session's range over a line written at
+// offset. The index is updated with every line, so it covers all the events
+// written even when the logger is never closed.
+func (l *Logger) recordAggregateWrite(aggregateFile string, offset, n int64) {
+	l.aggregate.mu.Lock()
+	if !l.aggregate.written {
+		l.aggregate.written = true
+		l.aggregate.session.Start = offset
+	} else if offset != l.aggregate.session.End {
+		l.aggregate.session.Interleaved = true
+	}
+	l.aggregate.session.End = offset + n
+	session := l.aggregate.session
+	l.aggregate.mu.Unlock()
+
+	// Outside the lock, since logging the error writes another event
+	if err := updateAggregateIndex(aggregateFile, l.sessionID, session); err != nil {
+		l.LogError(err, "failed to update aggregate index")
+	}
+}
+
+// closeAggregate records the session's final range in the index
+func (l *Logger) closeAggregate() error {
+	if !l.aggregate.enabled {
+		return nil
+	}
+
+	l.aggregate.mu.Lock()
+	defer l.aggregate.mu.Unlock()
+
+	if !l.aggregate.written {
+		return nil
+	}
+	return updateAggregateIndex(l.sessionFile, l.sessionID, l.aggregate.session)
+}
+
+// updateAggregateIndex merges one session's range into the index, holding
+// the index file's lock so concurrent sessions do not lose each other's
+// entries. A range only ever grows, so an update that lands late cannot
+// undo a newer one.
+func updateAggregateIndex(aggregateFile, sessionID string, session AggregateSession) error {
+	file, err := os.OpenFile(aggregateIndexPath(aggregateFile), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open aggregate index: %w", err)
+	}
+	defer file.Close()
+
+	if err := lockFile(file); err != nil {
+		return fmt.Errorf("failed to lock aggregate index: %w", err)
+	}
+	defer unlockFile(file)
+
+	index, err := decodeAggregateIndex(file)
+	if err != nil {
+		return err
+	}
+	if existing, ok := index.Sessions[sessionID]; ok {
+		// A gap between the ranges holds another session's events
+		gap := existing.End < session.Start || session.End < existing.Start
+		session.Interleaved = session.Interleaved || existing.Interleaved || gap
+
+		if existing.Start < session.Start {
+			session.Start = existing.Start
+		}
+		if existing.End > session.End {
+			session.End = existing.End
+		}
+	}
+	index.Sessions[sessionID] = session
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to rewrite aggregate index: %w", err)
+	}
+	if _, err := file.WriteAt(data, 0); err != nil {
+		return fmt.Errorf("failed to rewrite aggregate index: %w", err)
+	}
+	return nil
+}
+
+// decodeAggregateIndex reads an index, treating an empty file as an empty index
+func decodeAggregateIndex(r io.Reader) (*AggregateIndex, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read aggregate index: %w", err)
+	}
+
+	index := &AggregateIndex{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, index); err != nil {
+			return nil, fmt.Errorf("failed to parse aggregate index: %w", err)
+		}
+	}
+	if index.Sessions == nil {
+		index.Sessions = make(map[string]AggregateSession)
+	}
+	return index, nil
+}
+
+// SeekSession returns a reader over one session's events in an aggregate
+// file, looked up in its index. The range is read up front, so the reader
+// holds no open file. Lines other sessions wrote inside the range are
+// left out.
+func SeekSession(aggregateFile, sessionID string) (*io.SectionReader, error) {
+	indexFile, err := os.Open(aggregateIndexPath(aggregateFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open aggregate index: %w", err)
+	}
+	index, err := decodeAggregateIndex(indexFile)
+	indexFile.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	session, ok := index.Sessions[sessionID]
+	if !ok {
+		return nil, fmt.Errorf("session %s not found in aggregate index", sessionID)
+	}
+
+	file, err := os.Open(aggregateFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open aggregate file: %w", err)
+	}
+	defer file.Close()
+
+	data := make([]byte, session.End-session.Start)
+	if _, err := file.ReadAt(data, session.Start); err != nil {
+		return nil, fmt.Errorf("failed to read session %s: %w", sessionID, err)
+	}
+	data = sessionLines(data, sessionID)
+	return io.NewSectionReader(bytes.NewReader(data), 0, int64(len(data))), nil
+}
+
+// sessionLines keeps the lines of data that belong to sessionID, looking
+// inside CloudEvents envelopes for the session_id
+func sessionLines(data []byte, sessionID string) []byte {
+	kept := make([]byte, 0, len(data))
+	for len(data) > 0 {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i+1], data[i+1:]
+		} else {
+			data = nil
+		}
+
+		var event struct {
+			SessionID string `json:"session_id"`
+		}
+		if json.Unmarshal(cloudEventData(bytes.TrimSpace(line)), &event) == nil && event.SessionID == sessionID {
+			kept = append(kept, line...)
+		}
+	}
+	return kept
+}
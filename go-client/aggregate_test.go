@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAggregateFileSeekSession(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-aggregate-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	aggregateFile := filepath.Join(tempDir, "all-sessions.ndjson")
+	requests := map[string]int{"session-a": 2, "session-b": 3}
+
+	for _, sessionID := range []string{"session-a", "session-b"} {
+		config := newTestConfig(tempDir)
+		config.AggregateFile = aggregateFile
+		client := NewTracingHTTPClientWithConfig(sessionID, config)
+		for i := 0; i < requests[sessionID]; i++ {
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+		}
+		if err := client.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}
+
+	// No per-session files are written
+	if files, _ := filepath.Glob(filepath.Join(tempDir, "sessions", "*.jsonl")); len(files) != 0 {
+		t.Errorf("Expected no per-session files, got %v", files)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "all-sessions.index.json"))
+	if err != nil {
+		t.Fatalf("Expected an index sidecar: %v", err)
+	}
+	var index AggregateIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatal(err)
+	}
+	a, b := index.Sessions["session-a"], index.Sessions["session-b"]
+	if a.Start != 0 || a.End != b.Start || a.Interleaved || b.Interleaved {
+		t.Errorf("Expected adjacent, non-interleaved ranges, got %+v and %+v", a, b)
+	}
+	info, _ := os.Stat(aggregateFile)
+	if b.End != info.Size() {
+		t.Errorf("Expected session-b to end at %d, got %d", info.Size(), b.End)
+	}
+
+	for sessionID, count := range requests {
+		section, err := SeekSession(aggregateFile, sessionID)
+		if err != nil {
+			t.Fatalf("SeekSession(%s) failed: %v", sessionID, err)
+		}
+
+		types := make(map[string]int)
+		scanner := bufio.NewScanner(section)
+		for scanner.Scan() {
+			var event map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				t.Fatalf("Invalid line in %s: %v", sessionID, err)
+			}
+			if event["session_id"] != sessionID {
+				t.Errorf("Expected only %s events, got one from %v", sessionID, event["session_id"])
+			}
+			types[event["type"].(string)]++
+		}
+		if types["http_request"] != count || types["session_summary"] != 1 {
+			t.Errorf("%s: expected %d requests and a summary, got %v", sessionID, count, types)
+		}
+	}
+
+	if _, err := SeekSession(aggregateFile, "missing"); err == nil {
+		t.Error("Expected an error for a session not in the index")
+	}
+}
+
+func TestAggregateIndexInterleavedSessions(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-aggregate-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	aggregateFile := filepath.Join(tempDir, "all-sessions.ndjson")
+	loggers := make(map[string]*Logger)
+	for _, sessionID := range []string{"session-a", "session-b"} {
+		config := newTestConfig(tempDir)
+		config.AggregateFile = aggregateFile
+		loggers[sessionID] = NewLogger(config, sessionID)
+	}
+
+	// Neither logger is closed, as after a crash
+	for i := 0; i < 3; i++ {
+		for _, sessionID := range []string{"session-a", "session-b"} {
+			if err := loggers[sessionID].LogError(errors.New(sessionID), "interleaved"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "all-sessions.index.json"))
+	if err != nil {
+		t.Fatalf("Expected an index sidecar: %v", err)
+	}
+	var index AggregateIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(aggregateFile)
+	if b := index.Sessions["session-b"]; b.End != info.Size() || !b.Interleaved {
+		t.Errorf("Expected session-b to cover the file up to %d and be interleaved, got %+v", info.Size(), b)
+	}
+
+	for _, sessionID := range []string{"session-a", "session-b"} {
+		section, err := SeekSession(aggregateFile, sessionID)
+		if err != nil {
+			t.Fatalf("SeekSession(%s) failed: %v", sessionID, err)
+		}
+
+		events := 0
+		scanner := bufio.NewScanner(section)
+		for scanner.Scan() {
+			var event map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				t.Fatalf("Invalid line in %s: %v", sessionID, err)
+			}
+			if event["session_id"] != sessionID {
+				t.Errorf("Expected only %s events, got one from %v", sessionID, event["session_id"])
+			}
+			events++
+		}
+		if events != 3 {
+			t.Errorf("%s: expected 3 events, got %d", sessionID, events)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Overflow policies applied when the async write queue is full
+const (
+	OverflowBlock      = "block"
+	OverflowDropOldest = "drop_oldest"
+	OverflowDropNewest = "drop_newest"
+)
+
+// defaultAsyncQueueSize is used when AsyncQueueSize is not set
+const defaultAsyncQueueSize = 1024
+
+// asyncWriter moves event writes off the request path onto a dedicated
+// goroutine fed by a bounded queue
+type asyncWriter struct {
+	queue   chan interface{}
+	policy  string
+	write   func(event interface{})
+	dropped atomic.Int64
+	done    chan struct{}
+
+	// onDrop, when set, is called for every dropped event
+	onDrop func()
+
+	// closeMu guards sends against a concurrent close of the queue
+	closeMu sync.RWMutex
+	closed  bool
+
+	// pending counts events enqueued but not yet written or dropped
+	pendingMu sync.Mutex
+	pending   int
+	drained   *sync.Cond
+}
+
+// newAsyncWriter creates a writer; call start to begin consuming the queue
+func newAsyncWriter(size int, policy string, write func(event interface{})) *asyncWriter {
+	if size <= 0 {
+		size = defaultAsyncQueueSize
+	}
+
+	w := &asyncWriter{
+		queue:  make(chan interface{}, size),
+		policy: policy,
+		write:  write,
+		done:   make(chan struct{}),
+	}
+	w.drained = sync.NewCond(&w.pendingMu)
+	return w
+}
+
+// start launches the writer goroutine
+func (w *asyncWriter) start() {
+	go func() {
+		defer close(w.done)
+		for event := range w.queue {
+			w.write(event)
+			w.release(1)
+		}
+	}()
+}
+
+// enqueue hands an event to the writer goroutine, applying the overflow
+// policy when the queue is full. It returns false once the writer is closed.
+func (w *asyncWriter) enqueue(event interface{}) bool {
+	w.closeMu.RLock()
+	defer w.closeMu.RUnlock()
+
+	if w.closed {
+		return false
+	}
+
+	switch w.policy {
+	case OverflowDropNewest:
+		w.acquire()
+		select {
+		case w.queue <- event:
+		default:
+			w.drop()
+			w.release(1)
+		}
+
+	case OverflowDropOldest:
+		w.acquire()
+		for {
+			select {
+			case w.queue <- event:
+				return true
+			default:
+			}
+
+			// Make room by discarding the oldest queued event
+			select {
+			case <-w.queue:
+				w.drop()
+				w.release(1)
+			default:
+			}
+		}
+
+	default:
+		w.acquire()
+		w.queue <- event
+	}
+
+	return true
+}
+
+// drop records an event discarded by the overflow policy
+func (w *asyncWriter) drop() {
+	w.dropped.Add(1)
+	if w.onDrop != nil {
+		w.onDrop()
+	}
+}
+
+// acquire records a newly enqueued event
+func (w *asyncWriter) acquire() {
+	w.pendingMu.Lock()
+	w.pending++
+	w.pendingMu.Unlock()
+}
+
+// release records events that were written or dropped
+func (w *asyncWriter) release(n int) {
+	w.pendingMu.Lock()
+	w.pending -= n
+	if w.pending == 0 {
+		w.drained.Broadcast()
+	}
+	w.pendingMu.Unlock()
+}
+
+// flush blocks until every enqueued event has been written or dropped
+func (w *asyncWriter) flush() {
+	w.pendingMu.Lock()
+	for w.pending > 0 {
+		w.drained.Wait()
+	}
+	w.pendingMu.Unlock()
+}
+
+// close stops accepting events and waits for the queue to drain
+func (w *asyncWriter) close() {
+	w.closeMu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.closeMu.Unlock()
+
+	<-w.done
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingWriter collects events written by an asyncWriter
+type recordingWriter struct {
+	mu     sync.Mutex
+	events []interface{}
+}
+
+func (r *recordingWriter) write(event interface{}) {
+	r.mu.Lock()
+	r.events = append(r.events, event)
+	r.mu.Unlock()
+}
+
+func (r *recordingWriter) written() []interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]interface{}(nil), r.events...)
+}
+
+func TestAsyncWriterDropNewest(t *testing.T) {
+	recorder := &recordingWriter{}
+	writer := newAsyncWriter(2, OverflowDropNewest, recorder.write)
+
+	// Writer goroutine not started yet, so the queue fills up
+	for i := 0; i < 5; i++ {
+		if !writer.enqueue(i) {
+			t.Fatalf("Enqueue %d rejected", i)
+		}
+	}
+
+	writer.start()
+	writer.close()
+
+	if got := recorder.written(); !reflect.DeepEqual(got, []interface{}{0, 1}) {
+		t.Errorf("Expected oldest events to be kept, got %v", got)
+	}
+	if dropped := writer.dropped.Load(); dropped != 3 {
+		t.Errorf("Expected 3 dropped events, got %d", dropped)
+	}
+}
+
+func TestAsyncWriterDropOldest(t *testing.T) {
+	recorder := &recordingWriter{}
+	writer := newAsyncWriter(2, OverflowDropOldest, recorder.write)
+
+	for i := 0; i < 5; i++ {
+		if !writer.enqueue(i) {
+			t.Fatalf("Enqueue %d rejected", i)
+		}
+	}
+
+	writer.start()
+	writer.close()
+
+	if got := recorder.written(); !reflect.DeepEqual(got, []interface{}{3, 4}) {
+		t.Errorf("Expected newest events to be kept, got %v", got)
+	}
+	if dropped := writer.dropped.Load(); dropped != 3 {
+		t.Errorf("Expected 3 dropped events, got %d", dropped)
+	}
+}
+
+func TestAsyncWriterBlock(t *testing.T) {
+	recorder := &recordingWriter{}
+	writer := newAsyncWriter(2, OverflowBlock, recorder.write)
+
+	enqueued := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			writer.enqueue(i)
+		}
+		close(enqueued)
+	}()
+
+	// The producer must block on the full queue until the writer starts
+	select {
+	case <-enqueued:
+		t.Fatal("Expected enqueue to block while the queue is full")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	writer.start()
+	<-enqueued
+	writer.close()
+
+	if got := recorder.written(); !reflect.DeepEqual(got, []interface{}{0, 1, 2, 3, 4}) {
+		t.Errorf("Expected all events in order, got %v", got)
+	}
+	if dropped := writer.dropped.Load(); dropped != 0 {
+		t.Errorf("Expected no dropped events, got %d", dropped)
+	}
+}
+
+func TestAsyncWriterRejectsAfterClose(t *testing.T) {
+	writer := newAsyncWriter(1, OverflowBlock, func(interface{}) {})
+	writer.start()
+	writer.close()
+
+	if writer.enqueue("late") {
+		t.Error("Expected enqueue after close to be rejected")
+	}
+}
+
+func TestAsyncLoggerDrainsOnClose(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-async-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.AsyncWrite = true
+	config.AsyncQueueSize = 4
+	config.AsyncOverflowPolicy = OverflowBlock
+
+	client := NewTracingHTTPClientWithConfig("test-async", config)
+	for i := 0; i < 10; i++ {
+		resp, err := client.Get(server.URL + "/test")
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	events := readSessionEvents(t, tempDir)
+	if got := len(eventsOfType(events, "http_request")); got != 10 {
+		t.Errorf("Expected 10 request events after drain, got %d", got)
+	}
+	if got := len(eventsOfType(events, "http_response")); got != 10 {
+		t.Errorf("Expected 10 response events after drain, got %d", got)
+	}
+
+	summaries := eventsOfType(events, "session_summary")
+	if len(summaries) != 1 {
+		t.Fatalf("Expected 1 session_summary event, got %d", len(summaries))
+	}
+	if summaries[0]["events_written"] != float64(20) || summaries[0]["events_dropped"] != float64(0) {
+		t.Errorf("Unexpected summary counts: %v", summaries[0])
+	}
+}
+
+func TestAsyncLoggerReportsDroppedEvents(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-async-drop-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	logger := NewLogger(newTestConfig(tempDir), "test-async-drop")
+
+	// Hold the writer goroutine on the first event so the queue overflows
+	release := make(chan struct{})
+	logger.async = newAsyncWriter(1, OverflowDropNewest, func(event interface{}) {
+		<-release
+		logger.persistEvent(event)
+	})
+	logger.async.start()
+
+	capture := &RequestCapture{StartTime: time.Now(), Method: "GET", URL: "http://example.com/"}
+	for i := 0; i < 5; i++ {
+		logger.LogHTTPRequest(capture)
+	}
+	close(release)
+	logger.Close()
+
+	summary := logger.Summary()
+	if summary.EventsDropped == 0 {
+		t.Error("Expected dropped events to be counted")
+	}
+	if summary.EventsWritten+summary.EventsDropped != 6 {
+		t.Errorf("Expected written+dropped to cover all 5 events plus summary, got %+v", summary)
+	}
+
+	summaries := eventsOfType(readSessionEvents(t, tempDir), "session_summary")
+	if len(summaries) != 1 || summaries[0]["events_dropped"] != float64(summary.EventsDropped) {
+		t.Errorf("Expected dropped count in session_summary, got %v", summaries)
+	}
+}
+
+func TestUnknownOverflowPolicyReported(t *testing.T) {
+	config := &TracingConfig{Enabled: true, OutputDir: t.TempDir(), AsyncWrite: true, AsyncOverflowPolicy: "drop-newest"}
+
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "async_overflow_policy") {
+		t.Errorf("Expected Validate to report async_overflow_policy, got %v", err)
+	}
+
+	logger := NewLogger(config, "overflow-policy")
+	defer logger.Close()
+	select {
+	case warning := <-logger.Warnings():
+		if warning.Type != WarningInvalidConfig || !strings.Contains(warning.Message, `"drop-newest"`) {
+			t.Errorf("Expected an invalid_config warning naming the policy, got %+v", warning)
+		}
+	default:
+		t.Error("Expected a warning for the unknown overflow policy")
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// baselineIgnoredHeaders vary between otherwise identical runs and are not compared
+var baselineIgnoredHeaders = map[string]bool{
+	"Content-Length": true,
+	"Date":           true,
+	"Traceparent":    true,
+	"X-Request-Id":   true,
+}
+
+// baselineIDSegment matches path segments that identify a resource, such as
+// numeric IDs, UUIDs and long hex strings
+var baselineIDSegment = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F-]{16,})$`)
+
+// baselineRequest is a request recorded in the baseline session
+type baselineRequest struct {
+	headers map[string]string
+	body    string
+}
+
+// baselineMatcher compares live requests with a recorded baseline session.
+// The n-th request to an endpoint is compared with the n-th baseline request
+// to the same endpoint, so concurrent requests to one endpoint only match
+// reliably when they are issued in a deterministic order.
+type baselineMatcher struct {
+	mu       sync.Mutex
+	expected map[string][]baselineRequest
+	seen     map[string]int
+}
+
+// loadBaseline reads the requests of a baseline session file, or of all
+// session files below a session directory
+func loadBaseline(path string) (*baselineMatcher, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open baseline session: %w", err)
+	}
+
+	var events []map[string]interface{}
+	if info.IsDir() {
+		paths, findErr := findSessionFiles(path)
+		if findErr != nil {
+			return nil, findErr
+		}
+		events, err = mergeSessionFiles(paths)
+	} else {
+		events, err = ReadSessionFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline session: %w", err)
+	}
+
+	matcher := &baselineMatcher{
+		expected: make(map[string][]baselineRequest),
+		seen:     make(map[string]int),
+	}
+	for _, event := range events {
+		if eventString(event, "type") != "http_request" {
+			continue
+		}
+
+		headers := make(map[string]string)
+		if values, ok := event["headers"].(map[string]interface{}); ok {
+			for name, value := range values {
+				headers[http.CanonicalHeaderKey(name)], _ = value.(string)
+			}
+		}
+
+		key := baselineEndpoint(eventString(event, "method"), eventString(event, "url"))
+		matcher.expected[key] = append(matcher.expected[key], baselineRequest{
+			headers: headers,
+			body:    eventString(event, "body"),
+		})
+	}
+
+	return matcher, nil
+}
+
+// baselineEndpoint normalizes a request to its method, host and path, with
+// resource IDs in the path replaced by {id} and the query dropped
+func baselineEndpoint(method, rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return method + " " + rawURL
+	}
+
+	segments := strings.Split(parsed.Path, "/")
+	for i, segment := range segments {
+		if baselineIDSegment.MatchString(segment) {
+			segments[i] = "{id}"
+		}
+	}
+
+	return method + " " + parsed.Host + strings.Join(segments, "/")
+}
+
+// match compares a request event with its baseline counterpart and returns
+// whether they match along with a description of each difference
+func (m *baselineMatcher) match(event *HTTPRequestEvent, body string) (bool, []string) {
+	key := baselineEndpoint(event.Method, event.URL)
+
+	m.mu.Lock()
+	index := m.seen[key]
+	m.seen[key]++
+	m.mu.Unlock()
+
+	if index >= len(m.expected[key]) {
+		return false, []string{fmt.Sprintf("no baseline request #%d for %s", index+1, key)}
+	}
+	expected := m.expected[key][index]
+
+	var diffs []string
+	if !sameBody(expected.body, body) {
+		diffs = append(diffs, "body differs")
+	}
+
+	names := make(map[string]bool)
+	for name := range expected.headers {
+		names[name] = true
+	}
+	for name := range event.Headers {
+		names[http.CanonicalHeaderKey(name)] = true
+	}
+	live := make(map[string]string, len(event.Headers))
+	for name, value := range event.Headers {
+		live[http.CanonicalHeaderKey(name)] = value
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		if !baselineIgnoredHeaders[name] {
+			sorted = append(sorted, name)
+		}
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		want, inBaseline := expected.headers[name]
+		got, inLive := live[name]
+		switch {
+		case !inBaseline:
+			diffs = append(diffs, fmt.Sprintf("header %s not in baseline", name))
+		case !inLive:
+			diffs = append(diffs, fmt.Sprintf("header %s missing", name))
+		case want != got:
+			diffs = append(diffs, fmt.Sprintf("header %s: baseline %q, got %q", name, want, got))
+		}
+	}
+
+	return len(diffs) == 0, diffs
+}
+
+// sameBody compares two bodies, semantically when both are JSON
+func sameBody(expected, actual string) bool {
+	if expected == actual {
+		return true
+	}
+
+	var expectedJSON, actualJSON interface{}
+	if json.Unmarshal([]byte(expected), &expectedJSON) != nil || json.Unmarshal([]byte(actual), &actualJSON) != nil {
+		return false
+	}
+	return reflect.DeepEqual(expectedJSON, actualJSON)
+}
+
+// annotateBaseline marks a request event with whether it matches the baseline
+func (l *Logger) annotateBaseline(event *HTTPRequestEvent) {
+	if l.baseline == nil {
+		return
+	}
+
+	// Compare the stored body as a reader of the session would see it
+	body := event.Body
+	if event.BodyEncoding != "" {
+		decoded := map[string]interface{}{"body": event.Body, "body_encoding": event.BodyEncoding}
+		if decodeEventBody(decoded) == nil {
+			body = eventString(decoded, "body")
+		}
+	}
+
+	matches, diffs := l.baseline.match(event, body)
+	event.MatchesBaseline = &matches
+	event.BaselineDiff = diffs
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBaselineMismatchAnnotations(t *testing.T) {
+	baselineDir, err := os.MkdirTemp("", "trace-baseline-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baselineDir)
+
+	runDir, err := os.MkdirTemp("", "trace-baseline-run-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(runDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	post := func(client *TracingHTTPClient, path, body, model string) {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Model", model)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// Record the baseline session
+	baseline := NewTracingHTTPClientWithConfig("baseline", newTestConfig(baselineDir))
+	post(baseline, "/v1/messages", `{"prompt": "hi", "max_tokens": 10}`, "small")
+	post(baseline, "/v1/sessions/123/messages", `{"text": "a"}`, "small")
+	post(baseline, "/v1/messages", `{"prompt": "bye", "max_tokens": 10}`, "small")
+
+	config := newTestConfig(runDir)
+	config.BaselineSession = findSessionFile(t, baselineDir)
+	run := NewTracingHTTPClientWithConfig("run", config)
+
+	// Same request with reordered JSON keys, a different resource ID,
+	// a changed body, a changed header and an extra request
+	post(run, "/v1/messages", `{"max_tokens": 10, "prompt": "hi"}`, "small")
+	post(run, "/v1/sessions/456/messages", `{"text": "b"}`, "small")
+	post(run, "/v1/messages", `{"prompt": "bye", "max_tokens": 10}`, "large")
+	post(run, "/v1/messages", `{"prompt": "again"}`, "small")
+
+	requests := eventsOfType(readSessionEvents(t, runDir), "http_request")
+	if len(requests) != 4 {
+		t.Fatalf("Expected 4 request events, got %d", len(requests))
+	}
+
+	expected := []struct {
+		matches bool
+		diff    string
+	}{
+		{true, ""},
+		{false, "body differs"},
+		{false, `header X-Model: baseline "small", got "large"`},
+		{false, "no baseline request #3 for POST " + strings.TrimPrefix(server.URL, "http://") + "/v1/messages"},
+	}
+
+	for i, want := range expected {
+		if requests[i]["matches_baseline"] != want.matches {
+			t.Errorf("Request %d: expected matches_baseline %v, got %v", i, want.matches, requests[i]["matches_baseline"])
+		}
+
+		diffs, _ := requests[i]["baseline_diff"].([]interface{})
+		if want.diff == "" {
+			if len(diffs) != 0 {
+				t.Errorf("Request %d: expected no differences, got %v", i, diffs)
+			}
+			continue
+		}
+		if len(diffs) != 1 || diffs[0] != want.diff {
+			t.Errorf("Request %d: expected difference %q, got %v", i, want.diff, diffs)
+		}
+	}
+}
+
+func TestBaselineEndpoint(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://api.example.com/v1/messages?beta=1", "GET api.example.com/v1/messages"},
+		{"https://api.example.com/v1/files/12345", "GET api.example.com/v1/files/{id}"},
+		{"https://api.example.com/v1/runs/0f8c2a9e-4f5b-4c1d-9a57-3c2b1e0d4f6a/steps", "GET api.example.com/v1/runs/{id}/steps"},
+	}
+
+	for _, tt := range tests {
+		if got := baselineEndpoint(http.MethodGet, tt.url); got != tt.expected {
+			t.Errorf("baselineEndpoint(%q) = %q, expected %q", tt.url, got, tt.expected)
+		}
+	}
+}
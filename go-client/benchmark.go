@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// Benchmark defaults, used for zero BenchmarkOptions fields
+const (
+	defaultBenchmarkRequests = 500
+	defaultBenchmarkBodySize = 1024
+	benchmarkWarmupRequests  = 20
+)
+
+// BenchmarkOptions controls RunBenchmark. Each scenario sends Requests
+// requests, or sends requests for Duration when it is set.
+type BenchmarkOptions struct {
+	Requests int
+	Duration time.Duration
+
+	// Size of the request and response bodies exchanged
+	BodySize int
+
+	// Tracing settings the scenarios start from, getDefaultConfig when nil
+	Config *TracingConfig
+}
+
+// BenchmarkScenario is the measurement of one tracing setup
+type BenchmarkScenario struct {
+	Name              string        `json:"name"`
+	Requests          int           `json:"requests"`
+	RequestsPerSecond float64       `json:"requests_per_second"`
+	P50               time.Duration `json:"p50"`
+	P90               time.Duration `json:"p90"`
+	P99               time.Duration `json:"p99"`
+
+	// Median latency added over the untraced baseline, in absolute terms and
+	// as a percentage. Noise can make a traced run faster; that counts as 0.
+	AddedLatency    time.Duration `json:"added_latency"`
+	OverheadPercent float64       `json:"overhead_percent"`
+}
+
+// BenchmarkResult holds the untraced baseline followed by the traced scenarios
+type BenchmarkResult struct {
+	BodySize  int                 `json:"body_size"`
+	Scenarios []BenchmarkScenario `json:"scenarios"`
+}
+
+// benchmarkSetup is one combination of tracing settings
+type benchmarkSetup struct {
+	name   string
+	traced bool
+	bodies bool
+	async  bool
+}
+
+var benchmarkSetups = []benchmarkSetup{
+	{name: "tracing off"},
+	{name: "tracing on", traced: true, bodies: true},
+	{name: "tracing on, bodies off", traced: true},
+	{name: "tracing on, async", traced: true, bodies: true, async: true},
+	{name: "tracing on, bodies off, async", traced: true, async: true},
+}
+
+// RunBenchmark measures the tracer's overhead on this system against a local
+// server, with tracing off and then on with bodies and async writes on and off
+func RunBenchmark(options BenchmarkOptions) (*BenchmarkResult, error) {
+	if options.Requests <= 0 {
+		options.Requests = defaultBenchmarkRequests
+	}
+	if options.BodySize <= 0 {
+		options.BodySize = defaultBenchmarkBodySize
+	}
+	base := options.Config
+	if base == nil {
+		base = getDefaultConfig()
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start benchmark server: %v", err)
+	}
+	responseBody := bytes.Repeat([]byte("x"), options.BodySize)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write(responseBody)
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	url := "http://" + listener.Addr().String() + "/benchmark"
+	result := &BenchmarkResult{BodySize: options.BodySize}
+	for _, setup := range benchmarkSetups {
+		scenario, err := runBenchmarkScenario(setup, base, url, options)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", setup.name, err)
+		}
+		result.Scenarios = append(result.Scenarios, scenario)
+	}
+
+	baseline := result.Scenarios[0]
+	for i := range result.Scenarios[1:] {
+		scenario := &result.Scenarios[i+1]
+		if added := scenario.P50 - baseline.P50; added > 0 {
+			scenario.AddedLatency = added
+			if baseline.P50 > 0 {
+				scenario.OverheadPercent = float64(added) / float64(baseline.P50) * 100
+			}
+		}
+	}
+	return result, nil
+}
+
+// runBenchmarkScenario sends the benchmark requests through a client set up
+// for one scenario, writing any session into a temporary directory
+func runBenchmarkScenario(setup benchmarkSetup, base *TracingConfig, url string, options BenchmarkOptions) (BenchmarkScenario, error) {
+	dir, err := os.MkdirTemp("", "opencode-trace-benchmark-")
+	if err != nil {
+		return BenchmarkScenario{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	config := *base
+	config.Enabled = setup.traced
+	config.OutputDir = dir
+	config.OutputNamespace = ""
+	config.AggregateFile = ""
+	config.SessionStore = nil
+	config.SampleRate = 0
+	config.MaxRequests = 0
+	config.CaptureRequestBodies = setup.bodies
+	config.CaptureResponseBodies = setup.bodies
+	config.CaptureBodyStatusCodes = nil
+	config.BodyCaptureRules = nil
+	config.AsyncWrite = setup.async
+
+	client := NewTracingHTTPClientWithConfig("benchmark", &config)
+	defer client.Close()
+
+	requestBody := bytes.Repeat([]byte("y"), options.BodySize)
+	send := func() (time.Duration, error) {
+		start := time.Now()
+		resp, err := client.Post(url, "text/plain", bytes.NewReader(requestBody))
+		if err != nil {
+			return 0, err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return time.Since(start), nil
+	}
+
+	// Warm up the connection pool before measuring
+	for i := 0; i < benchmarkWarmupRequests; i++ {
+		if _, err := send(); err != nil {
+			return BenchmarkScenario{}, err
+		}
+	}
+
+	var latencies []time.Duration
+	start := time.Now()
+	for {
+		if options.Duration > 0 {
+			if time.Since(start) >= options.Duration {
+				break
+			}
+		} else if len(latencies) >= options.Requests {
+			break
+		}
+
+		latency, err := send()
+		if err != nil {
+			return BenchmarkScenario{}, err
+		}
+		latencies = append(latencies, latency)
+	}
+	elapsed := time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	scenario := BenchmarkScenario{
+		Name:     setup.name,
+		Requests: len(latencies),
+		P50:      latencyPercentile(latencies, 50),
+		P90:      latencyPercentile(latencies, 90),
+		P99:      latencyPercentile(latencies, 99),
+	}
+	if elapsed > 0 {
+		scenario.RequestsPerSecond = float64(len(latencies)) / elapsed.Seconds()
+	}
+	return scenario, nil
+}
+
+// latencyPercentile returns the nearest-rank percentile of sorted latencies
+func latencyPercentile(sorted []time.Duration, percentile int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (percentile*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// WriteTable prints the scenarios as an aligned table
+func (r *BenchmarkResult) WriteTable(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "scenario\trequests\treq/s\tp50\tp90\tp99\tadded p50\toverhead")
+	for _, scenario := range r.Scenarios {
+		fmt.Fprintf(table, "%s\t%d\t%.0f\t%v\t%v\t%v\t%v\t%.1f%%\n",
+			scenario.Name, scenario.Requests, scenario.RequestsPerSecond,
+			scenario.P50.Round(time.Microsecond), scenario.P90.Round(time.Microsecond), scenario.P99.Round(time.Microsecond),
+			scenario.AddedLatency.Round(time.Microsecond), scenario.OverheadPercent)
+	}
+	return table.Flush()
+}
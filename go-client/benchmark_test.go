@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunBenchmark(t *testing.T) {
+	result, err := RunBenchmark(BenchmarkOptions{Requests: 30, BodySize: 64})
+	if err != nil {
+		t.Fatalf("RunBenchmark failed: %v", err)
+	}
+
+	if len(result.Scenarios) != len(benchmarkSetups) {
+		t.Fatalf("Expected %d scenarios, got %d", len(benchmarkSetups), len(result.Scenarios))
+	}
+	if result.Scenarios[0].Name != "tracing off" {
+		t.Errorf("Expected the untraced baseline first, got %q", result.Scenarios[0].Name)
+	}
+
+	for _, scenario := range result.Scenarios {
+		if scenario.Requests != 30 {
+			t.Errorf("%s: expected 30 measured requests, got %d", scenario.Name, scenario.Requests)
+		}
+		if scenario.RequestsPerSecond <= 0 {
+			t.Errorf("%s: expected a positive request rate, got %v", scenario.Name, scenario.RequestsPerSecond)
+		}
+		if scenario.P50 <= 0 || scenario.P90 < scenario.P50 || scenario.P99 < scenario.P90 {
+			t.Errorf("%s: expected ordered percentiles, got p50 %v, p90 %v, p99 %v", scenario.Name, scenario.P50, scenario.P90, scenario.P99)
+		}
+		if scenario.AddedLatency < 0 || scenario.OverheadPercent < 0 {
+			t.Errorf("%s: expected non-negative overhead, got %v and %.1f%%", scenario.Name, scenario.AddedLatency, scenario.OverheadPercent)
+		}
+	}
+}
+
+func TestBenchmarkCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runCommand([]string{"benchmark", "--requests", "10", "--body-size", "16"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit 0, got %d: %s", code, stderr.String())
+	}
+
+	output := stdout.String()
+	for _, setup := range benchmarkSetups {
+		if !strings.Contains(output, setup.name) {
+			t.Errorf("Expected a row for %q, got %q", setup.name, output)
+		}
+	}
+	if !strings.Contains(output, "p99") {
+		t.Errorf("Expected a percentile header, got %q", output)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+)
+
+// BodyCaptureRule decides which sides of an exchange have their bodies
+// captured for URLs matching URLPattern, a regular expression matched
+// against the full request URL
+type BodyCaptureRule struct {
+	URLPattern      string `json:"url_pattern"`
+	CaptureRequest  bool   `json:"capture_request"`
+	CaptureResponse bool   `json:"capture_response"`
+}
+
+// bodyCaptureRule returns the first rule matching rawURL, or nil when none
+// does and the global flags apply
+func (c *TracingConfig) bodyCaptureRule(rawURL string) *BodyCaptureRule {
+	if rawURL == "" {
+		return nil
+	}
+	for i := range c.BodyCaptureRules {
+		// Invalid patterns are reported by Validate
+		if re, err := regexp.Compile(c.BodyCaptureRules[i].URLPattern); err == nil && re.MatchString(rawURL) {
+			return &c.BodyCaptureRules[i]
+		}
+	}
+	return nil
+}
+
+// captureRequestBody decides whether the body of a request to rawURL is
+// captured: a matching BodyCaptureRules entry, else CaptureRequestBodies
+func (c *TracingConfig) captureRequestBody(rawURL string) bool {
+	if rule := c.bodyCaptureRule(rawURL); rule != nil {
+		return rule.CaptureRequest
+	}
+	return c.CaptureRequestBodies
+}
+
+// requestURL returns the URL a response answers, empty when unknown
+func requestURL(resp *http.Response) string {
+	if resp.Request == nil || resp.Request.URL == nil {
+		return ""
+	}
+	return resp.Request.URL.String()
+}
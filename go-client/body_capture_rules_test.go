@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBodyCaptureRules(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-body-rules-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.BodyCaptureRules = []BodyCaptureRule{
+		{URLPattern: `/uploads$`, CaptureRequest: false, CaptureResponse: true},
+		{URLPattern: `/messages$`, CaptureRequest: true, CaptureResponse: false},
+	}
+	client := NewTracingHTTPClientWithConfig("test-body-rules", config)
+
+	for _, path := range []string{"/uploads", "/messages", "/other"} {
+		resp, err := client.PostJSON(server.URL+path, []byte(`{"sent":"`+path+`"}`))
+		if err != nil {
+			t.Fatalf("POST %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+	client.Close()
+
+	events := readSessionEvents(t, tempDir)
+	paths := make(map[string]string)
+	requestBodies := make(map[string]interface{})
+	for _, event := range eventsOfType(events, "http_request") {
+		url, _ := event["url"].(string)
+		requestID, _ := event["request_id"].(string)
+		path := url[strings.LastIndex(url, "/"):]
+		paths[requestID] = path
+		requestBodies[path] = event["body"]
+	}
+	responseBodies := make(map[string]interface{})
+	for _, event := range eventsOfType(events, "http_response") {
+		requestID, _ := event["request_id"].(string)
+		responseBodies[paths[requestID]] = event["body"]
+	}
+
+	if body, ok := requestBodies["/uploads"]; ok && body != nil {
+		t.Errorf("Expected no request body for /uploads, got %v", body)
+	}
+	if body := responseBodies["/uploads"]; body != `{"path":"/uploads"}` {
+		t.Errorf("Expected the response body for /uploads, got %v", body)
+	}
+
+	if body := requestBodies["/messages"]; body != `{"sent":"/messages"}` {
+		t.Errorf("Expected the request body for /messages, got %v", body)
+	}
+	if body, ok := responseBodies["/messages"]; ok && body != nil {
+		t.Errorf("Expected no response body for /messages, got %v", body)
+	}
+
+	// No rule matches, so the global flags capture both sides
+	if body := requestBodies["/other"]; body != `{"sent":"/other"}` {
+		t.Errorf("Expected the request body for /other, got %v", body)
+	}
+	if body := responseBodies["/other"]; body != `{"path":"/other"}` {
+		t.Errorf("Expected the response body for /other, got %v", body)
+	}
+}
+
+func TestBodyCaptureRulesConfigFile(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-body-rules-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	valid := writeConfigFile(t, tempDir, "valid.json", `{"body_capture_rules": [{"url_pattern": "/uploads", "capture_request": false, "capture_response": true}]}`)
+	if errs := ValidateConfigFile(valid); len(errs) != 0 {
+		t.Errorf("Expected a valid config, got %v", errs)
+	}
+
+	invalid := writeConfigFile(t, tempDir, "invalid.json", `{"body_capture_rules": [{"url_pattern": "(uploads", "capture_request": true}]}`)
+	errs := ValidateConfigFile(invalid)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `body_capture_rules url_pattern "(uploads"`) {
+		t.Errorf("Expected the invalid url_pattern to be reported, got %v", errs)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// Body compression modes
+const (
+	BodyCompressionNone = "none"
+	BodyCompressionGzip = "gzip"
+)
+
+// bodyEncodingGzipBase64 marks a stored body as base64 of gzip data
+const bodyEncodingGzipBase64 = "gzip+base64"
+
+// defaultBodyCompressionThreshold is used when BodyCompressionThreshold is not set
+const defaultBodyCompressionThreshold = 4 * 1024
+
+// encodeBody returns the stored form of a captured body, its encoding and the
+// original size when the body was compressed
+func (l *Logger) encodeBody(body []byte) (string, string, int64) {
+	if int64(len(body)) > l.config.MaxBodySize {
+		l.warn(WarningBodyTruncated, "", "body of %d bytes exceeds the %d byte limit and was not stored", len(body), l.config.MaxBodySize)
+		return fmt.Sprintf("[TRUNCATED - Body size %d bytes exceeds limit %d bytes]",
+			len(body), l.config.MaxBodySize), "", 0
+	}
+
+	return l.compressBody(body)
+}
+
+// compressBody applies the configured body compression to a body that is
+// already within the size limit
+func (l *Logger) compressBody(body []byte) (string, string, int64) {
+	if l.config.BodyCompression != BodyCompressionGzip {
+		return string(body), "", 0
+	}
+
+	threshold := l.config.BodyCompressionThreshold
+	if threshold <= 0 {
+		threshold = defaultBodyCompressionThreshold
+	}
+	if int64(len(body)) <= threshold {
+		return string(body), "", 0
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(body); err != nil {
+		return string(body), "", 0
+	}
+	if err := writer.Close(); err != nil {
+		return string(body), "", 0
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(compressed.Bytes())
+	if len(encoded) >= len(body) {
+		// Not worth it for incompressible bodies
+		return string(body), "", 0
+	}
+
+	return encoded, bodyEncodingGzipBase64, int64(len(body))
+}
+
+// decodeEventBody restores a compressed body in place so readers see the original content
+func decodeEventBody(event map[string]interface{}) error {
+	if eventString(event, "body_encoding") != bodyEncodingGzipBase64 {
+		return nil
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(eventString(event, "body"))
+	if err != nil {
+		return fmt.Errorf("failed to decode body: %w", err)
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return fmt.Errorf("failed to decompress body: %w", err)
+	}
+	defer reader.Close()
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to decompress body: %w", err)
+	}
+
+	event["body"] = string(body)
+	delete(event, "body_encoding")
+	delete(event, "body_original_size")
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBodyCompressionRoundTrip(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-compression-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	responseBody := strings.Repeat(`{"role":"assistant","content":"hello world"},`, 500)
+	requestBody := strings.Repeat(`{"role":"user","content":"compress me"},`, 500)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(responseBody))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.MaxBodySize = 1024 * 1024
+	config.BodyCompression = BodyCompressionGzip
+	config.BodyCompressionThreshold = 1024
+
+	client := NewTracingHTTPClientWithConfig("test-compression", config)
+	resp, err := client.PostJSON(server.URL+"/chat", []byte(requestBody))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	client.Close()
+
+	if string(body) != responseBody {
+		t.Error("Expected caller to receive the uncompressed body")
+	}
+
+	// Raw file holds compressed bodies
+	raw := readSessionEvents(t, tempDir)
+	for _, event := range raw[:2] {
+		if event["body_encoding"] != "gzip+base64" {
+			t.Errorf("%v: expected gzip+base64 body encoding, got %v", event["type"], event["body_encoding"])
+		}
+		if len(eventString(event, "body")) >= int(eventInt64(event, "body_original_size")) {
+			t.Errorf("%v: expected stored body to be smaller than the original", event["type"])
+		}
+	}
+	if raw[0]["body_original_size"] != float64(len(requestBody)) {
+		t.Errorf("Expected body_original_size %d, got %v", len(requestBody), raw[0]["body_original_size"])
+	}
+
+	// SessionReader restores them transparently
+	events, err := ReadSessionFile(findSessionFile(t, tempDir))
+	if err != nil {
+		t.Fatalf("ReadSessionFile failed: %v", err)
+	}
+	if events[0]["body"] != requestBody {
+		t.Error("Expected request body to round-trip through compression")
+	}
+	if events[1]["body"] != responseBody {
+		t.Error("Expected response body to round-trip through compression")
+	}
+	if _, ok := events[1]["body_encoding"]; ok {
+		t.Error("Expected body_encoding to be removed after decoding")
+	}
+}
+
+func TestBodyCompressionBelowThreshold(t *testing.T) {
+	config := newTestConfig(os.TempDir())
+	config.BodyCompression = BodyCompressionGzip
+	config.BodyCompressionThreshold = 1024
+
+	logger := NewLogger(config, "test-compression-small")
+	body, encoding, originalSize := logger.encodeBody([]byte(`{"small": true}`))
+	if body != `{"small": true}` || encoding != "" || originalSize != 0 {
+		t.Errorf("Expected small body to be stored as-is, got %q %q %d", body, encoding, originalSize)
+	}
+}
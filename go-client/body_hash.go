@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// hashingReader computes the SHA-256 of a body as it is read
+type hashingReader struct {
+	body io.ReadCloser
+	hash hash.Hash
+	size int64
+
+	mu       sync.Mutex
+	complete bool
+	onDone   func(*hashingReader)
+	doneOnce sync.Once
+}
+
+// newHashingReader wraps body. onDone, if set, runs once when the body has
+// been read to EOF or closed.
+func newHashingReader(body io.ReadCloser, onDone func(*hashingReader)) *hashingReader {
+	return &hashingReader{body: body, hash: sha256.New(), onDone: onDone}
+}
+
+func (r *hashingReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+
+	r.mu.Lock()
+	r.hash.Write(p[:n])
+	r.size += int64(n)
+	if err == io.EOF {
+		r.complete = true
+	}
+	r.mu.Unlock()
+
+	if err == io.EOF {
+		r.done()
+	}
+	return n, err
+}
+
+func (r *hashingReader) Close() error {
+	err := r.body.Close()
+	r.done()
+	return err
+}
+
+// done runs the completion callback once
+func (r *hashingReader) done() {
+	r.doneOnce.Do(func() {
+		if r.onDone != nil {
+			r.onDone(r)
+		}
+	})
+}
+
+// sum returns the hex digest and size, and false if the body was not read to the end
+func (r *hashingReader) sum() (string, int64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.complete {
+		return "", 0, false
+	}
+	return hex.EncodeToString(r.hash.Sum(nil)), r.size, true
+}
+
+// hashBodies logs a body_hash event for a request. When there is a response
+// body the event is written once the caller has read it; a body that is
+// closed early has no response hash.
+func (t *TracingRoundTripper) hashBodies(requestID string, requestHash *hashingReader, resp *http.Response) {
+	logHashes := func(responseHash *hashingReader) {
+		event := BodyHashEvent{
+			Type:      "body_hash",
+			Timestamp: time.Now().UnixMilli(),
+			SessionID: t.sessionID,
+			RequestID: requestID,
+		}
+		if requestHash != nil {
+			event.RequestBodySHA256, event.RequestBodySize, _ = requestHash.sum()
+		}
+		if responseHash != nil {
+			event.ResponseBodySHA256, event.ResponseBodySize, _ = responseHash.sum()
+		}
+
+		if err := t.logger.writeEvent(event); err != nil {
+			t.logger.LogError(err, "failed to log body hash")
+		}
+	}
+
+	if resp == nil || resp.Body == nil || resp.Body == http.NoBody || t.config.isUncapturedStream(resp.Header.Get("Content-Type")) {
+		logHashes(nil)
+		return
+	}
+
+	resp.Body = newHashingReader(resp.Body, logHashes)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBodyHashesWithoutCapture(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-body-hash-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte("echo:" + string(body)))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.CaptureRequestBodies = false
+	config.CaptureResponseBodies = false
+	config.HashBodies = true
+
+	client := NewTracingHTTPClientWithConfig("test-body-hash", config)
+
+	payloads := []string{`{"prompt": "same"}`, `{"prompt": "same"}`, `{"prompt": "different"}`}
+	for _, payload := range payloads {
+		resp, err := client.Post(server.URL, "application/json", strings.NewReader(payload))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		if body, _ := io.ReadAll(resp.Body); string(body) != "echo:"+payload {
+			t.Errorf("Caller received unexpected body %q", body)
+		}
+		resp.Body.Close()
+	}
+	client.Close()
+
+	events := readSessionEvents(t, tempDir)
+	hashes := eventsOfType(events, "body_hash")
+	if len(hashes) != len(payloads) {
+		t.Fatalf("Expected %d body_hash events, got %d", len(payloads), len(hashes))
+	}
+
+	for i, payload := range payloads {
+		requestSum := sha256.Sum256([]byte(payload))
+		responseSum := sha256.Sum256([]byte("echo:" + payload))
+		if hashes[i]["request_body_sha256"] != hex.EncodeToString(requestSum[:]) {
+			t.Errorf("Request %d: unexpected request hash %v", i, hashes[i]["request_body_sha256"])
+		}
+		if hashes[i]["response_body_sha256"] != hex.EncodeToString(responseSum[:]) {
+			t.Errorf("Request %d: unexpected response hash %v", i, hashes[i]["response_body_sha256"])
+		}
+		if hashes[i]["request_body_size"] != float64(len(payload)) {
+			t.Errorf("Request %d: unexpected request size %v", i, hashes[i]["request_body_size"])
+		}
+	}
+
+	if hashes[0]["request_body_sha256"] != hashes[1]["request_body_sha256"] {
+		t.Error("Expected identical bodies to produce identical hashes")
+	}
+	if hashes[0]["request_body_sha256"] == hashes[2]["request_body_sha256"] {
+		t.Error("Expected different bodies to produce different hashes")
+	}
+
+	for _, event := range events {
+		if _, ok := event["body"]; ok {
+			t.Errorf("Expected no bodies to be stored, got %v", event)
+		}
+	}
+}
+
+func TestBodyHashIncompleteResponse(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-body-hash-partial-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 64*1024)))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.CaptureResponseBodies = false
+	config.HashBodies = true
+
+	client := NewTracingHTTPClientWithConfig("test-body-hash-partial", config)
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Read(make([]byte, 10))
+	resp.Body.Close()
+	client.Close()
+
+	hashes := eventsOfType(readSessionEvents(t, tempDir), "body_hash")
+	if len(hashes) != 1 {
+		t.Fatalf("Expected 1 body_hash event, got %d", len(hashes))
+	}
+	if _, ok := hashes[0]["response_body_sha256"]; ok {
+		t.Error("Expected no response hash for a body closed before EOF")
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// redactedValue replaces redacted header values and body fields
+const redactedValue = "[REDACTED]"
+
+// jsonPathSegment is one step of a parsed JSONPath expression. An empty key
+// with wildcard set matches every member or element.
+type jsonPathSegment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseJSONPath parses the JSONPath subset used for body redaction:
+// $.a.b, $.items[*].token, $.items[0], $.*, $['key']
+func parseJSONPath(path string) ([]jsonPathSegment, bool) {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "$") {
+		return nil, false
+	}
+	rest := path[1:]
+
+	var segments []jsonPathSegment
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			if key == "" {
+				return nil, false
+			}
+			if key == "*" {
+				segments = append(segments, jsonPathSegment{wildcard: true})
+			} else {
+				segments = append(segments, jsonPathSegment{key: key})
+			}
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, false
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+
+			switch {
+			case inner == "*":
+				segments = append(segments, jsonPathSegment{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				segments = append(segments, jsonPathSegment{key: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, false
+				}
+				segments = append(segments, jsonPathSegment{index: index, isIndex: true})
+			}
+		default:
+			return nil, false
+		}
+	}
+
+	return segments, len(segments) > 0
+}
+
+// redactJSONPaths replaces the values addressed by paths in a JSON body with
+// [REDACTED]. Bodies that are not valid JSON, or where no path matches, are
+// returned unchanged.
+func redactJSONPaths(body []byte, paths []string) []byte {
+	if len(paths) == 0 || len(body) == 0 {
+		return body
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return body
+	}
+
+	redacted := false
+	for _, path := range paths {
+		segments, ok := parseJSONPath(path)
+		if !ok {
+			continue
+		}
+		if redactJSONSegments(document, segments) {
+			redacted = true
+		}
+	}
+
+	if !redacted {
+		return body
+	}
+
+	out, err := json.Marshal(document)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// redactJSONSegments walks node along segments and redacts the matched values
+func redactJSONSegments(node interface{}, segments []jsonPathSegment) bool {
+	segment, last := segments[0], len(segments) == 1
+	redacted := false
+
+	visit := func(child interface{}, replace func()) {
+		if last {
+			replace()
+			redacted = true
+		} else if redactJSONSegments(child, segments[1:]) {
+			redacted = true
+		}
+	}
+
+	switch value := node.(type) {
+	case map[string]interface{}:
+		if segment.isIndex {
+			return false
+		}
+		for key, child := range value {
+			if segment.wildcard || key == segment.key {
+				key := key
+				visit(child, func() { value[key] = redactedValue })
+			}
+		}
+	case []interface{}:
+		if !segment.wildcard && !segment.isIndex {
+			return false
+		}
+		for i, child := range value {
+			if segment.wildcard || i == segment.index {
+				i := i
+				visit(child, func() { value[i] = redactedValue })
+			}
+		}
+	}
+
+	return redacted
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactJSONPaths(t *testing.T) {
+	body := []byte(`{"data":{"secret":"sk-live-123","name":"key-1"},"items":[{"token":"a","id":1},{"token":"b","id":2}],"count":2}`)
+
+	out := redactJSONPaths(body, []string{"$.data.secret", "$.items[*].token", "$.missing.path"})
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatalf("Redacted body is not valid JSON: %v", err)
+	}
+
+	data := doc["data"].(map[string]interface{})
+	if data["secret"] != "[REDACTED]" {
+		t.Errorf("Expected data.secret to be redacted, got %v", data["secret"])
+	}
+	if data["name"] != "key-1" {
+		t.Errorf("Expected data.name to be untouched, got %v", data["name"])
+	}
+
+	for i, raw := range doc["items"].([]interface{}) {
+		item := raw.(map[string]interface{})
+		if item["token"] != "[REDACTED]" {
+			t.Errorf("Item %d: expected token to be redacted, got %v", i, item["token"])
+		}
+		if item["id"] != float64(i+1) {
+			t.Errorf("Item %d: expected id to be untouched, got %v", i, item["id"])
+		}
+	}
+	if doc["count"] != float64(2) {
+		t.Errorf("Expected count to be untouched, got %v", doc["count"])
+	}
+}
+
+func TestRedactJSONPathsIndexAndQuotedKey(t *testing.T) {
+	body := []byte(`{"items":[{"token":"a"},{"token":"b"}],"api-key":"xyz"}`)
+
+	out := redactJSONPaths(body, []string{"$.items[1].token", "$['api-key']"})
+
+	var doc map[string]interface{}
+	json.Unmarshal(out, &doc)
+
+	items := doc["items"].([]interface{})
+	if items[0].(map[string]interface{})["token"] != "a" {
+		t.Errorf("Expected items[0].token to be untouched, got %v", items[0])
+	}
+	if items[1].(map[string]interface{})["token"] != "[REDACTED]" {
+		t.Errorf("Expected items[1].token to be redacted, got %v", items[1])
+	}
+	if doc["api-key"] != "[REDACTED]" {
+		t.Errorf("Expected api-key to be redacted, got %v", doc["api-key"])
+	}
+}
+
+func TestRedactJSONPathsLeavesUnmatchedBodies(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"non-JSON", "plain text secret"},
+		{"truncated JSON", `{"data":{"secret":"sk`},
+		{"no match", `{"other": 1,  "spacing": "kept"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := redactJSONPaths([]byte(tt.body), []string{"$.data.secret"})
+			if string(out) != tt.body {
+				t.Errorf("Expected body to be untouched, got %q", out)
+			}
+		})
+	}
+}
+
+func TestResponseJSONPathRedactionIsLogged(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-body-redaction-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"secret":"sk-live-123","id":"key_1"}}`))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.RedactResponseJSONPaths = []string{"$.data.secret"}
+
+	client := NewTracingHTTPClientWithConfig("test-body-redaction", config)
+	defer client.Close()
+
+	resp, err := client.Get(server.URL + "/keys")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 1 {
+		t.Fatalf("Expected 1 response event, got %d", len(responses))
+	}
+	if body := responses[0]["body"]; body != `{"data":{"id":"key_1","secret":"[REDACTED]"}}` {
+		t.Errorf("Unexpected logged body: %v", body)
+	}
+}
+
+func TestTruncatedJSONBodyNotStoredUnredacted(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-body-redaction-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"secret":"sk-live-123","notes":"` + strings.Repeat("x", 1000) + `"}}`))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.MaxBodySize = 500
+	config.RedactResponseJSONPaths = []string{"$.data.secret"}
+
+	client := NewTracingHTTPClientWithConfig("test-body-redaction-truncated", config)
+
+	resp, err := client.Get(server.URL + "/keys")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 1 {
+		t.Fatalf("Expected 1 response event, got %d", len(responses))
+	}
+	if body, ok := responses[0]["body"]; ok {
+		t.Errorf("Expected the truncated body not to be stored, got %v", body)
+	}
+	if responses[0]["body_redaction_failed"] != true {
+		t.Errorf("Expected body_redaction_failed to be set, got %v", responses[0])
+	}
+
+	files, _ := filepath.Glob(filepath.Join(tempDir, "sessions", "*.jsonl"))
+	for _, file := range files {
+		content, _ := os.ReadFile(file)
+		if strings.Contains(string(content), "sk-live-123") {
+			t.Errorf("Secret written unredacted to %s", file)
+		}
+	}
+}
+
+func TestJSONBodyOfExactlyMaxBodySizeIsRedacted(t *testing.T) {
+	tempDir := t.TempDir()
+
+	prefix, suffix := `{"data":{"secret":"sk-live-123","notes":"`, `"}}`
+	payload := prefix + strings.Repeat("x", 1024-len(prefix)-len(suffix)) + suffix
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(payload))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.MaxBodySize = int64(len(payload))
+	config.RedactResponseJSONPaths = []string{"$.data.secret"}
+
+	client := NewTracingHTTPClientWithConfig("test-body-redaction-exact", config)
+
+	resp, err := client.Get(server.URL + "/keys")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 1 {
+		t.Fatalf("Expected 1 response event, got %d", len(responses))
+	}
+	if _, ok := responses[0]["body_redaction_failed"]; ok {
+		t.Errorf("Expected a complete body not to be dropped, got %v", responses[0])
+	}
+	body, _ := responses[0]["body"].(string)
+	if !strings.Contains(body, `"secret":"[REDACTED]"`) || strings.Contains(body, "sk-live-123") {
+		t.Errorf("Expected the stored body with the path redacted, got %q", body)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Body sampling modes
+const (
+	// BodySamplePrefix keeps the leading MaxBodySize bytes of a body
+	BodySamplePrefix = "prefix"
+	// BodySampleHeadTail keeps the start and the end of a body around an omission marker
+	BodySampleHeadTail = "head_tail"
+)
+
+// bodySampleSizes returns how many leading and trailing bytes head/tail
+// sampling keeps. The tail keeps BodySampleTailBytes bytes (half of
+// MaxBodySize by default) and the head fills the rest of the limit.
+func (c *TracingConfig) bodySampleSizes() (int64, int64) {
+	tail := c.BodySampleTailBytes
+	if tail <= 0 || tail >= c.MaxBodySize {
+		tail = c.MaxBodySize / 2
+	}
+	return c.MaxBodySize - tail, tail
+}
+
+// sampleHeadTail reports whether the body of resp is sampled head and tail.
+// Bodies still compressed for the caller cannot be decoded from their end,
+// and retry attempts collected by DoWithRetry are logged before the caller
+// reads the final body, so both keep a prefix.
+func (t *TracingRoundTripper) sampleHeadTail(resp *http.Response) bool {
+	if t.config.BodySampleMode != BodySampleHeadTail {
+		return false
+	}
+	if resp.Header.Get("Content-Encoding") != "" && !resp.Uncompressed {
+		return false
+	}
+	return resp.Request == nil || retryCollectorFromContext(resp.Request.Context()) == nil
+}
+
+// headTailBody joins the head and tail of a sampled body around a marker
+// counting the bytes left out between them
+func headTailBody(head, tail []byte, omitted int64) []byte {
+	marker := fmt.Sprintf("[...MIDDLE OMITTED %d bytes...]", omitted)
+
+	sampled := make([]byte, 0, len(head)+len(tail)+len(marker))
+	sampled = append(sampled, head...)
+	sampled = append(sampled, marker...)
+	sampled = append(sampled, tail...)
+	return sampled
+}
+
+// tailRing is a ring buffer keeping the last bytes written to it and
+// counting all of them, so the end of a body of any size is kept in bounded
+// memory
+type tailRing struct {
+	mu    sync.Mutex
+	size  int64
+	buf   []byte
+	next  int
+	total int64
+}
+
+func newTailRing(size int64) *tailRing {
+	return &tailRing{size: size, buf: make([]byte, 0, size)}
+}
+
+func (r *tailRing) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := len(p)
+	r.total += int64(n)
+	if r.size <= 0 {
+		return n, nil
+	}
+
+	// Only the last size bytes of a large write can survive it
+	if int64(len(p)) >= r.size {
+		r.buf = append(r.buf[:0], p[int64(len(p))-r.size:]...)
+		r.next = 0
+		return n, nil
+	}
+
+	for len(p) > 0 {
+		if int64(len(r.buf)) < r.size {
+			room := int(r.size) - len(r.buf)
+			if room > len(p) {
+				room = len(p)
+			}
+			r.buf = append(r.buf, p[:room]...)
+			p = p[room:]
+			continue
+		}
+		copied := copy(r.buf[r.next:], p)
+		p = p[copied:]
+		r.next = (r.next + copied) % len(r.buf)
+	}
+	return n, nil
+}
+
+// tail returns the kept bytes, oldest first, and the count of all bytes written
+func (r *tailRing) tail() ([]byte, int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tail := make([]byte, 0, len(r.buf))
+	tail = append(tail, r.buf[r.next:]...)
+	tail = append(tail, r.buf[:r.next]...)
+	return tail, r.total
+}
+
+// sampledBody is a response body longer than MaxBodySize handed to the
+// caller while it is sampled. The capture already holds the head; once the
+// caller has read the body to EOF the tail is added, and onDone logs the
+// capture. A body closed early is logged with its prefix only.
+type sampledBody struct {
+	io.ReadCloser
+	ring          *tailRing
+	capture       *ResponseCapture
+	wireSizeKnown bool
+
+	mu       sync.Mutex
+	complete bool
+	onDone   func()
+	doneOnce sync.Once
+}
+
+func (b *sampledBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.mu.Lock()
+		b.complete = true
+		b.mu.Unlock()
+		b.done()
+	}
+	return n, err
+}
+
+func (b *sampledBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	return err
+}
+
+// done completes the capture and runs the completion callback once
+func (b *sampledBody) done() {
+	b.doneOnce.Do(func() {
+		b.mu.Lock()
+		complete := b.complete
+		b.mu.Unlock()
+
+		capture := b.capture
+		capture.BodyClosed = time.Now()
+		if complete {
+			tail, total := b.ring.tail()
+			head := int64(len(capture.Body)) - int64(len(tail))
+			if head < 0 {
+				head = 0
+			}
+			capture.Body = capture.Body[:head]
+			capture.BodyTail = tail
+			capture.BodyOmitted = total - head - int64(len(tail))
+			capture.DecodedSize = total
+			if !b.wireSizeKnown {
+				capture.ResponseSize = total
+			}
+		}
+
+		if b.onDone != nil {
+			b.onDone()
+		}
+	})
+}
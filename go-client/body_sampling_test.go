@@ -0,0 +1,193 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestHeadTailBodySampling(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-body-sampling-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	head := strings.Repeat("H", 600)
+	middle := strings.Repeat("M", 2000)
+	tail := strings.Repeat("T", 200) + `{"error": "quota exceeded"}`
+	fullBody := head + middle + tail
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(fullBody))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.MaxBodySize = 1024
+	config.BodySampleMode = BodySampleHeadTail
+	config.BodySampleTailBytes = 256
+
+	client := NewTracingHTTPClientWithConfig("test-body-sampling", config)
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	received, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	client.Close()
+
+	if string(received) != fullBody {
+		t.Errorf("Expected caller to receive the full body (%d bytes), got %d bytes", len(fullBody), len(received))
+	}
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 1 {
+		t.Fatalf("Expected 1 response event, got %d", len(responses))
+	}
+	body, _ := responses[0]["body"].(string)
+
+	headBytes := 1024 - 256
+	omitted := len(fullBody) - 1024
+	expected := fullBody[:headBytes] + "[...MIDDLE OMITTED " + strconv.Itoa(omitted) + " bytes...]" + fullBody[len(fullBody)-256:]
+	if body != expected {
+		t.Errorf("Unexpected sampled body:\n got %q\nwant %q", body, expected)
+	}
+	if !strings.HasSuffix(body, `{"error": "quota exceeded"}`) {
+		t.Error("Expected the trailing error message to be preserved")
+	}
+	if responses[0]["response_size"] != float64(len(fullBody)) {
+		t.Errorf("Expected response_size %d, got %v", len(fullBody), responses[0]["response_size"])
+	}
+}
+
+func TestHeadTailSamplingLeavesSmallBodies(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-body-sampling-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("short body"))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.BodySampleMode = BodySampleHeadTail
+
+	client := NewTracingHTTPClientWithConfig("test-body-sampling-small", config)
+
+	// Bodies within MaxBodySize are logged before the caller reads them
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	resp.Body.Close()
+	client.Close()
+
+	if len(responses) != 1 || responses[0]["body"] != "short body" {
+		t.Errorf("Expected bodies within MaxBodySize not to be sampled, got %v", responses)
+	}
+}
+
+func TestPrefixSamplingIsDefault(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-body-sampling-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 4096)))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.MaxBodySize = 1024
+	client := NewTracingHTTPClientWithConfig("test-body-sampling-prefix", config)
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 1 {
+		t.Fatalf("Expected 1 response event, got %d", len(responses))
+	}
+	if body, _ := responses[0]["body"].(string); body != strings.Repeat("x", 1024) {
+		t.Errorf("Expected prefix mode to keep the leading MaxBodySize bytes, got %d bytes", len(body))
+	}
+}
+
+func TestHeadTailSamplingReadsLazily(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-body-sampling-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	fullBody := strings.Repeat("a", 512) + strings.Repeat("b", 1<<20) + strings.Repeat("z", 512)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(fullBody))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.MaxBodySize = 1024
+	config.BodySampleMode = BodySampleHeadTail
+	logger := NewLogger(config, "test-body-sampling-lazy")
+	base := &countingTransport{}
+	client := &http.Client{Transport: NewTracingRoundTripper(base, logger, config, "test-body-sampling-lazy")}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	// Only the prefix is buffered; the rest streams to the caller
+	if read := base.read.Load(); read > config.MaxBodySize+1 {
+		t.Errorf("Expected at most %d bytes read before the caller reads, got %d", config.MaxBodySize+1, read)
+	}
+	if responses := eventsOfType(readSessionEvents(t, tempDir), "http_response"); len(responses) != 0 {
+		t.Fatal("Expected the response to be logged only once its tail has been read")
+	}
+
+	received, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	logger.Close()
+
+	if string(received) != fullBody {
+		t.Errorf("Expected the caller to receive the full body, got %d bytes", len(received))
+	}
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 1 {
+		t.Fatalf("Expected 1 response event, got %d", len(responses))
+	}
+	expected := strings.Repeat("a", 512) + "[...MIDDLE OMITTED " + strconv.Itoa(1<<20) + " bytes...]" + strings.Repeat("z", 512)
+	if responses[0]["body"] != expected {
+		t.Errorf("Unexpected sampled body of %d bytes", len(responses[0]["body"].(string)))
+	}
+}
+
+func TestTailRing(t *testing.T) {
+	ring := newTailRing(5)
+	for _, chunk := range []string{"ab", "cde", "fg", "h", "ijklmnop", "qr"} {
+		ring.Write([]byte(chunk))
+	}
+
+	tail, total := ring.tail()
+	if string(tail) != "nopqr" || total != 18 {
+		t.Errorf("Expected the last 5 of 18 bytes, got %q of %d", tail, total)
+	}
+}
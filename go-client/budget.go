@@ -0,0 +1,70 @@
+package main
+
+import (
+	"time"
+)
+
+// overBodyBudget reports whether the session has grown past MaxSessionBytes,
+// after which bodies are dropped and only headers and metadata are kept
+func (l *Logger) overBodyBudget() bool {
+	return l.config.MaxSessionBytes > 0 && l.sessionBytes.Load() >= l.config.MaxSessionBytes
+}
+
+// withinHardBudget reports whether a line of n bytes may still be written
+// and counts it in sessionBytes, so concurrent writers cannot together cross
+// the budget. The first line that would take the session past
+// MaxSessionBytesHard is discarded; a single budget_exhausted event is
+// written in its place and all further events are discarded too.
+func (l *Logger) withinHardBudget(n int64) bool {
+	if l.config.MaxSessionBytesHard <= 0 {
+		l.sessionBytes.Add(n)
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.exhausted {
+		return false
+	}
+
+	if l.sessionBytes.Load()+n > l.config.MaxSessionBytesHard {
+		l.exhausted = true
+		l.appendEvent(map[string]interface{}{
+			"type":              "budget_exhausted",
+			"timestamp":         time.Now().UnixMilli(),
+			"session_id":        l.sessionID,
+			"session_bytes":     l.sessionBytes.Load(),
+			"max_session_bytes": l.config.MaxSessionBytesHard,
+		})
+		return false
+	}
+
+	l.sessionBytes.Add(n)
+	return true
+}
+
+// reserveRequestSlot reports whether another request may be traced under
+// MaxRequests. The first request over the limit writes a single
+// capture_limit_reached event; it and all later requests pass through untraced.
+func (l *Logger) reserveRequestSlot() bool {
+	if l.config.MaxRequests <= 0 {
+		return true
+	}
+
+	slot := l.requestSlots.Add(1)
+	limit := int64(l.config.MaxRequests)
+	if slot <= limit {
+		return true
+	}
+
+	if slot == limit+1 {
+		l.writeEvent(map[string]interface{}{
+			"type":         "capture_limit_reached",
+			"timestamp":    time.Now().UnixMilli(),
+			"session_id":   l.sessionID,
+			"max_requests": l.config.MaxRequests,
+		})
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSessionBudgetSuppressesBodies(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-budget-soft-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.MaxSessionBytes = 1000
+
+	logger := NewLogger(config, "test-budget-soft")
+	capture := &RequestCapture{
+		StartTime: time.Now(),
+		Method:    "POST",
+		URL:       "http://example.com/",
+		Body:      []byte(strings.Repeat("x", 400)),
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := logger.LogHTTPRequest(capture); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events := readSessionEvents(t, tempDir)
+	if len(events) != 5 {
+		t.Fatalf("Expected all 5 events to be written, got %d", len(events))
+	}
+
+	suppressed := false
+	for i, event := range events {
+		if event["body_suppressed_budget"] == true {
+			suppressed = true
+			if _, ok := event["body"]; ok {
+				t.Errorf("Event %d: expected body to be dropped once over budget", i+1)
+			}
+			if event["method"] != "POST" {
+				t.Errorf("Event %d: expected metadata to be kept", i+1)
+			}
+		} else if suppressed {
+			t.Errorf("Event %d: body captured again after budget was reached", i+1)
+		} else if event["body"] != strings.Repeat("x", 400) {
+			t.Errorf("Event %d: expected body before budget was reached", i+1)
+		}
+	}
+
+	if events[0]["body_suppressed_budget"] == true {
+		t.Error("Expected the first event to keep its body")
+	}
+	if !suppressed {
+		t.Error("Expected later events to have bodies suppressed")
+	}
+}
+
+func TestSessionBudgetHardStop(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-budget-hard-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.MaxSessionBytes = 500
+	config.MaxSessionBytesHard = 1500
+
+	logger := NewLogger(config, "test-budget-hard")
+	capture := &RequestCapture{
+		StartTime: time.Now(),
+		Method:    "POST",
+		URL:       "http://example.com/",
+		Body:      []byte(strings.Repeat("x", 400)),
+	}
+
+	for i := 0; i < 50; i++ {
+		if err := logger.LogHTTPRequest(capture); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logger.Close()
+
+	events := readSessionEvents(t, tempDir)
+	last := events[len(events)-1]
+	if last["type"] != "budget_exhausted" {
+		t.Fatalf("Expected budget_exhausted as the final event, got %v", last["type"])
+	}
+	if got := len(eventsOfType(events, "budget_exhausted")); got != 1 {
+		t.Errorf("Expected exactly 1 budget_exhausted event, got %d", got)
+	}
+	if got := len(eventsOfType(events, "session_summary")); got != 0 {
+		t.Errorf("Expected no events after the budget was exhausted, got %d summaries", got)
+	}
+	if len(events) >= 50 {
+		t.Errorf("Expected writing to stop early, got %d events", len(events))
+	}
+
+	// Only the budget_exhausted marker is written past the hard limit
+	content, err := os.ReadFile(findSessionFile(t, tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(strings.TrimSuffix(string(content), "\n"), "\n")
+	withinBudget := int64(len(content) - len(lines[len(lines)-1]) - 1)
+	if withinBudget > config.MaxSessionBytesHard {
+		t.Errorf("Expected the events before budget_exhausted to fit in %d bytes, got %d", config.MaxSessionBytesHard, withinBudget)
+	}
+}
+
+func TestMaxRequestsLimit(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-max-requests-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	var mu sync.Mutex
+	served := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		served++
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.MaxRequests = 3
+
+	client := NewTracingHTTPClientWithConfig("test-max-requests", config)
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(server.URL + "/test")
+			if err != nil {
+				t.Errorf("Request failed: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if served != 10 {
+		t.Errorf("Expected all 10 requests to reach the server, got %d", served)
+	}
+
+	events := readSessionEvents(t, tempDir)
+	if got := len(eventsOfType(events, "http_request")); got != 3 {
+		t.Errorf("Expected 3 request events, got %d", got)
+	}
+	if got := len(eventsOfType(events, "http_response")); got != 3 {
+		t.Errorf("Expected 3 response events, got %d", got)
+	}
+	if got := len(eventsOfType(events, "capture_limit_reached")); got != 1 {
+		t.Errorf("Expected 1 capture_limit_reached event, got %d", got)
+	}
+	if len(events) != 7 {
+		t.Errorf("Expected 7 events in total, got %d", len(events))
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CacheInfo describes whether a response was served from a CDN or proxy cache
+type CacheInfo struct {
+	Hit        bool   `json:"hit"`
+	AgeSeconds *int64 `json:"age_seconds,omitempty"`
+	Status     string `json:"status,omitempty"`
+}
+
+// Cache statuses that mean the response body came from the cache
+var cacheHitStatuses = map[string]bool{
+	"HIT":         true,
+	"STALE":       true,
+	"UPDATING":    true,
+	"REVALIDATED": true,
+}
+
+// parseCacheInfo interprets the Age, X-Cache, CF-Cache-Status and
+// Cache-Control response headers. It returns nil when none of them carry
+// cache information.
+func parseCacheInfo(headers map[string]string) *CacheInfo {
+	var cfStatus, xCache, age, cacheControl string
+	for name, value := range headers {
+		switch strings.ToLower(name) {
+		case "cf-cache-status":
+			cfStatus = value
+		case "x-cache":
+			xCache = value
+		case "age":
+			age = value
+		case "cache-control":
+			cacheControl = value
+		}
+	}
+
+	info := &CacheInfo{}
+	if seconds, err := strconv.ParseInt(strings.TrimSpace(age), 10, 64); err == nil && seconds >= 0 {
+		info.AgeSeconds = &seconds
+	}
+
+	// An explicit cache status wins over what Age and Cache-Control imply
+	switch {
+	case cfStatus != "":
+		info.Status = strings.ToUpper(strings.TrimSpace(cfStatus))
+		info.Hit = cacheHitStatuses[info.Status]
+	case xCache != "":
+		info.Status = xCacheStatus(xCache)
+		info.Hit = strings.Contains(info.Status, "HIT")
+	case info.AgeSeconds != nil && *info.AgeSeconds > 0:
+		// A positive Age means the response was stored by some cache on the way
+		info.Status = "HIT"
+		info.Hit = true
+	case hasCacheDirective(cacheControl, "no-store"):
+		info.Status = "UNCACHEABLE"
+	case info.AgeSeconds == nil:
+		return nil
+	}
+
+	return info
+}
+
+// xCacheStatus returns the status of the cache closest to the client from an
+// X-Cache value such as "HIT", "Hit from cloudfront" or "MISS, HIT"
+func xCacheStatus(value string) string {
+	entries := strings.Split(value, ",")
+	fields := strings.Fields(entries[len(entries)-1])
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// hasCacheDirective reports whether a Cache-Control value contains directive
+func hasCacheDirective(cacheControl, directive string) bool {
+	for _, part := range strings.Split(cacheControl, ",") {
+		name := strings.SplitN(strings.TrimSpace(part), "=", 2)[0]
+		if strings.EqualFold(name, directive) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestParseCacheInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		hit     bool
+		status  string
+	}{
+		{"cloudflare hit", map[string]string{"Cf-Cache-Status": "HIT"}, true, "HIT"},
+		{"cloudflare dynamic", map[string]string{"Cf-Cache-Status": "DYNAMIC", "Age": "5"}, false, "DYNAMIC"},
+		{"cloudfront", map[string]string{"X-Cache": "Hit from cloudfront"}, true, "HIT"},
+		{"fastly shield miss edge hit", map[string]string{"X-Cache": "MISS, HIT"}, true, "HIT"},
+		{"age only", map[string]string{"Age": "12"}, true, "HIT"},
+		{"no-store", map[string]string{"Cache-Control": "private, no-store"}, false, "UNCACHEABLE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := parseCacheInfo(tt.headers)
+			if info == nil {
+				t.Fatal("Expected cache info")
+			}
+			if info.Hit != tt.hit || info.Status != tt.status {
+				t.Errorf("Expected hit=%v status=%q, got hit=%v status=%q", tt.hit, tt.status, info.Hit, info.Status)
+			}
+		})
+	}
+
+	if info := parseCacheInfo(map[string]string{"Content-Type": "application/json", "Cache-Control": "max-age=60"}); info != nil {
+		t.Errorf("Expected no cache info without cache status headers, got %+v", info)
+	}
+}
+
+func TestCacheInfoOnResponseEvent(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-cache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Cache", "HIT")
+		w.Header().Set("Age", "30")
+		w.Write([]byte("cached"))
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-cache", newTestConfig(tempDir))
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 1 {
+		t.Fatalf("Expected 1 response event, got %d", len(responses))
+	}
+
+	cache, ok := responses[0]["cache"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected cache object, got %v", responses[0]["cache"])
+	}
+	if cache["hit"] != true || cache["status"] != "HIT" || cache["age_seconds"] != float64(30) {
+		t.Errorf("Unexpected cache info: %v", cache)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// requestCanceled reports whether a request failed because its caller
+// canceled the context, as opposed to a deadline or a transport failure
+func requestCanceled(req *http.Request, err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(req.Context().Err(), context.Canceled)
+}
+
+// LogHTTPCanceled logs a request canceled by its caller, with how long it
+// ran before the cancellation ended it
+func (l *Logger) LogHTTPCanceled(requestID string, req *http.Request, duration time.Duration, err error) error {
+	if !l.config.Enabled {
+		return nil
+	}
+
+	event := HTTPCanceledEvent{
+		Type:      "http_canceled",
+		Timestamp: time.Now().UnixMilli(),
+		SessionID: l.sessionID,
+		RequestID: requestID,
+		Method:    req.Method,
+		URL:       l.redactURL(req.URL.String()),
+		Duration:  duration.Milliseconds(),
+		Error:     l.redactError(err),
+	}
+
+	return l.writeEvent(event)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCanceledRequestLogged(t *testing.T) {
+	const cancelAfter = 150 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Start the cancel timer once the request reaches the server, which is
+	// after the round tripper started timing it, so the logged duration can
+	// never be shorter than cancelAfter
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.AfterFunc(cancelAfter, cancel)
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	tempDir, err := os.MkdirTemp("", "trace-canceled-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	client := NewTracingHTTPClientWithConfig("test-canceled", newTestConfig(tempDir))
+
+	_, err = client.GetWithContext(ctx, server.URL+"/slow")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a canceled request, got %v", err)
+	}
+	client.Close()
+
+	events := readSessionEvents(t, tempDir)
+	canceled := eventsOfType(events, "http_canceled")
+	if len(canceled) != 1 {
+		t.Fatalf("Expected one http_canceled event, got %d", len(canceled))
+	}
+	if errorEvents := eventsOfType(events, "error"); len(errorEvents) != 0 {
+		t.Errorf("Expected no generic error event for a cancellation, got %v", errorEvents)
+	}
+
+	event := canceled[0]
+	duration, _ := event["duration_ms"].(float64)
+	if duration < float64(cancelAfter.Milliseconds()) || duration >= float64((5*time.Second).Milliseconds()) {
+		t.Errorf("Expected the partial duration of about %v, got %vms", cancelAfter, duration)
+	}
+	if event["url"] != server.URL+"/slow" || event["method"] != http.MethodGet {
+		t.Errorf("Unexpected request in http_canceled: %v %v", event["method"], event["url"])
+	}
+
+	requests := eventsOfType(events, "http_request")
+	if len(requests) != 1 || requests[0]["request_id"] != event["request_id"] {
+		t.Errorf("Expected the cancellation to share the request's request_id")
+	}
+}
+
+func TestDeadlineIsNotCancellation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-canceled-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	client := NewTracingHTTPClientWithConfig("test-deadline", newTestConfig(tempDir))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.GetWithContext(ctx, server.URL); err == nil {
+		t.Fatal("Expected the request to time out")
+	}
+	client.Close()
+
+	events := readSessionEvents(t, tempDir)
+	if canceled := eventsOfType(events, "http_canceled"); len(canceled) != 0 {
+		t.Errorf("Expected a deadline not to be logged as a cancellation, got %v", canceled)
+	}
+	if errorEvents := eventsOfType(events, "error"); len(errorEvents) != 1 {
+		t.Errorf("Expected one timeout error event, got %d", len(errorEvents))
+	}
+}
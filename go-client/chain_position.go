@@ -0,0 +1,39 @@
+package main
+
+import "net/http"
+
+// Positions of the tracer in a chain of round trippers, recorded as
+// chain_position on request events
+const (
+	// ChainInnermost traces requests as sent, after every other middleware
+	ChainInnermost = "innermost"
+	// ChainOutermost traces requests as made, before any other middleware
+	ChainOutermost = "outermost"
+)
+
+// WrapInnermost returns a tracer for the bottom of a round tripper chain:
+// base is the network transport, and other middleware such as auth
+// injection wraps the result. Requests are traced as they go on the wire,
+// including headers that middleware added, such as Authorization.
+func WrapInnermost(base http.RoundTripper, logger *Logger, config *TracingConfig, sessionID string) *TracingRoundTripper {
+	return wrapAt(base, ChainInnermost, logger, config, sessionID)
+}
+
+// WrapOutermost returns a tracer for the top of a round tripper chain: base
+// is the complete chain of other middleware. Requests are traced as the
+// caller made them, before middleware changed them.
+func WrapOutermost(base http.RoundTripper, logger *Logger, config *TracingConfig, sessionID string) *TracingRoundTripper {
+	return wrapAt(base, ChainOutermost, logger, config, sessionID)
+}
+
+// wrapAt creates a tracer at the given chain position. A tracer passed as
+// base is replaced rather than wrapped, so a request is never traced twice.
+func wrapAt(base http.RoundTripper, position string, logger *Logger, config *TracingConfig, sessionID string) *TracingRoundTripper {
+	if tracer, ok := base.(*TracingRoundTripper); ok {
+		base = tracer.wrapped
+	}
+
+	tracer := NewTracingRoundTripper(base, logger, config, sessionID)
+	tracer.chainPosition = position
+	return tracer
+}
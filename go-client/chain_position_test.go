@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// authRoundTripper is middleware that injects credentials
+type authRoundTripper struct {
+	next http.RoundTripper
+}
+
+func (a *authRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer injected")
+	return a.next.RoundTrip(req)
+}
+
+func TestChainPosition(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer injected" {
+			t.Error("Expected the auth middleware to run in either placement")
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		chain    func(logger *Logger, config *TracingConfig) http.RoundTripper
+		position string
+		sawAuth  bool
+	}{
+		{
+			name: "innermost",
+			chain: func(logger *Logger, config *TracingConfig) http.RoundTripper {
+				return &authRoundTripper{next: WrapInnermost(http.DefaultTransport, logger, config, "test-chain")}
+			},
+			position: ChainInnermost,
+			sawAuth:  true,
+		},
+		{
+			name: "outermost",
+			chain: func(logger *Logger, config *TracingConfig) http.RoundTripper {
+				return WrapOutermost(&authRoundTripper{next: http.DefaultTransport}, logger, config, "test-chain")
+			},
+			position: ChainOutermost,
+			sawAuth:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir, err := os.MkdirTemp("", "trace-chain-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tempDir)
+
+			config := newTestConfig(tempDir)
+			logger := NewLogger(config, "test-chain")
+			client := &http.Client{Transport: tt.chain(logger, config)}
+
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			logger.Close()
+
+			requests := eventsOfType(readSessionEvents(t, tempDir), "http_request")
+			if len(requests) != 1 {
+				t.Fatalf("Expected 1 request event, got %d", len(requests))
+			}
+			if requests[0]["chain_position"] != tt.position {
+				t.Errorf("Expected chain_position %q, got %v", tt.position, requests[0]["chain_position"])
+			}
+			headers, _ := requests[0]["headers"].(map[string]interface{})
+			if _, saw := headers["Authorization"]; saw != tt.sawAuth {
+				t.Errorf("Expected Authorization seen=%v, got headers %v", tt.sawAuth, headers)
+			}
+		})
+	}
+}
+
+func TestWrapReplacesExistingTracer(t *testing.T) {
+	config := newTestConfig(os.TempDir())
+	logger := NewLogger(config, "test-chain-rewrap")
+
+	inner := WrapInnermost(http.DefaultTransport, logger, config, "test-chain-rewrap")
+	outer := WrapOutermost(inner, logger, config, "test-chain-rewrap")
+
+	if outer.wrapped != http.DefaultTransport {
+		t.Errorf("Expected the existing tracer to be replaced, got %T", outer.wrapped)
+	}
+}
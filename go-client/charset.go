@@ -0,0 +1,56 @@
+package main
+
+import (
+	"mime"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// transcodeBody converts body to UTF-8 according to the charset parameter of
+// contentType and returns it with the original charset. Bodies without a
+// charset, already in UTF-8 or in a charset that is not recognised are
+// returned unchanged with an empty charset.
+func transcodeBody(contentType string, body []byte) ([]byte, string) {
+	if contentType == "" || len(body) == 0 {
+		return body, ""
+	}
+
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return body, ""
+	}
+	charset := strings.TrimSpace(params["charset"])
+	if charset == "" {
+		return body, ""
+	}
+
+	encoding, err := htmlindex.Get(charset)
+	if err != nil {
+		return body, ""
+	}
+	if name, _ := htmlindex.Name(encoding); name == "utf-8" {
+		return body, ""
+	}
+
+	decoded, err := encoding.NewDecoder().Bytes(body)
+	if err != nil {
+		return body, ""
+	}
+
+	return decoded, charset
+}
+
+// encodeTextBody is encodeBody for a body declared with contentType. The size
+// limit applies to the bytes as captured, and the stored body is transcoded to
+// UTF-8; the original charset is returned when transcoding took place.
+func (l *Logger) encodeTextBody(body []byte, contentType string) (string, string, int64, string) {
+	if int64(len(body)) > l.config.MaxBodySize {
+		stored, encoding, originalSize := l.encodeBody(body)
+		return stored, encoding, originalSize, ""
+	}
+
+	body, charset := transcodeBody(contentType, body)
+	stored, encoding, originalSize := l.compressBody(body)
+	return stored, encoding, originalSize, charset
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestLatin1BodiesTranscoded(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-charset-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// "<p>Café naïve</p>" encoded as ISO-8859-1
+	latin1 := []byte("<p>Caf\xe9 na\xefve</p>")
+	expected := "<p>Café naïve</p>"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/utf8" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(expected))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=ISO-8859-1")
+		w.Write(latin1)
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-charset", newTestConfig(tempDir))
+
+	resp, err := client.Post(server.URL+"/latin1", "text/plain; charset=iso-8859-1", bytes.NewReader(latin1))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if !bytes.Equal(body, latin1) {
+		t.Errorf("Expected caller to receive the original bytes, got %q", body)
+	}
+
+	resp, err = client.Get(server.URL + "/utf8")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	events := readSessionEvents(t, tempDir)
+	requests := eventsOfType(events, "http_request")
+	responses := eventsOfType(events, "http_response")
+	if len(requests) != 2 || len(responses) != 2 {
+		t.Fatalf("Expected 2 requests and 2 responses, got %d and %d", len(requests), len(responses))
+	}
+
+	if requests[0]["body"] != expected || requests[0]["charset"] != "iso-8859-1" {
+		t.Errorf("Expected transcoded request body with charset, got %q (%v)", requests[0]["body"], requests[0]["charset"])
+	}
+	if responses[0]["body"] != expected || responses[0]["charset"] != "ISO-8859-1" {
+		t.Errorf("Expected transcoded response body with charset, got %q (%v)", responses[0]["body"], responses[0]["charset"])
+	}
+
+	if responses[1]["body"] != expected {
+		t.Errorf("Expected UTF-8 body to be stored unchanged, got %q", responses[1]["body"])
+	}
+	if charset, ok := responses[1]["charset"]; ok {
+		t.Errorf("Expected no charset for a UTF-8 body, got %v", charset)
+	}
+}
+
+func TestTranscodeBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    string
+		charset     string
+	}{
+		{"no charset", "text/plain", "caf\xe9", "caf\xe9", ""},
+		{"utf-8", "text/plain; charset=UTF-8", "café", "café", ""},
+		{"windows-1252", "text/plain; charset=windows-1252", "\x93quoted\x94", "“quoted”", "windows-1252"},
+		{"unknown charset", "text/plain; charset=x-unknown", "caf\xe9", "caf\xe9", ""},
+		{"malformed content type", "text/plain; charset", "caf\xe9", "caf\xe9", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, charset := transcodeBody(tt.contentType, []byte(tt.body))
+			if string(body) != tt.expected || charset != tt.charset {
+				t.Errorf("Expected %q (%q), got %q (%q)", tt.expected, tt.charset, body, charset)
+			}
+		})
+	}
+}
@@ -18,6 +18,18 @@ type TracingHTTPClient struct {
 	logger    *Logger
 	config    *TracingConfig
 	sessionID string
+
+	// backoff overrides the wait before each retry attempt
+	backoff func(attempt int) time.Duration
+
+	// jitter randomizes the default backoff, defaultJitter when nil
+	jitter JitterFunc
+
+	// Retries left across the session, nil when RetryBudget is unset
+	retryBudget *retryBudget
+
+	// Sends http+unix requests over their Unix socket
+	unixSockets *unixSocketTransport
 }
 
 // NewTracingHTTPClient creates a new tracing HTTP client
@@ -30,19 +42,26 @@ func NewTracingHTTPClient(sessionID string) *TracingHTTPClient {
 	config := LoadConfig()
 	logger := NewLogger(config, sessionID)
 
+	unixSockets := &unixSocketTransport{}
 	baseClient := &http.Client{
-		Timeout: config.Timeout,
+		Transport: newClientTransport(unixSockets),
+		Timeout:   config.Timeout,
 	}
 
 	// Wrap the client with tracing middleware
 	tracingClient := WrapClient(baseClient, logger, config, sessionID)
 
-	return &TracingHTTPClient{
-		client:    tracingClient,
-		logger:    logger,
-		config:    config,
-		sessionID: sessionID,
+	client := &TracingHTTPClient{
+		client:      tracingClient,
+		logger:      logger,
+		config:      config,
+		sessionID:   sessionID,
+		retryBudget: newRetryBudget(config.RetryBudget, config.RetryBudgetWindow),
+		unixSockets: unixSockets,
 	}
+	client.probeHealth()
+
+	return client
 }
 
 // NewTracingHTTPClientWithConfig creates a new tracing HTTP client with custom config
@@ -53,19 +72,38 @@ func NewTracingHTTPClientWithConfig(sessionID string, config *TracingConfig) *Tr
 
 	logger := NewLogger(config, sessionID)
 
+	unixSockets := &unixSocketTransport{}
 	baseClient := &http.Client{
-		Timeout: config.Timeout,
+		Transport: newClientTransport(unixSockets),
+		Timeout:   config.Timeout,
 	}
 
 	// Wrap the client with tracing middleware
 	tracingClient := WrapClient(baseClient, logger, config, sessionID)
 
-	return &TracingHTTPClient{
-		client:    tracingClient,
-		logger:    logger,
-		config:    config,
-		sessionID: sessionID,
+	client := &TracingHTTPClient{
+		client:      tracingClient,
+		logger:      logger,
+		config:      config,
+		sessionID:   sessionID,
+		retryBudget: newRetryBudget(config.RetryBudget, config.RetryBudgetWindow),
+		unixSockets: unixSockets,
 	}
+	client.probeHealth()
+
+	return client
+}
+
+// NewCheckedTracingHTTPClient creates a tracing HTTP client with custom config,
+// failing fast when the output directory is missing and CreateOutputDir is off
+func NewCheckedTracingHTTPClient(sessionID string, config *TracingConfig) (*TracingHTTPClient, error) {
+	if config.Enabled {
+		if err := checkOutputDir(config); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewTracingHTTPClientWithConfig(sessionID, config), nil
 }
 
 // GetSessionID returns the session ID
@@ -73,6 +111,11 @@ func (t *TracingHTTPClient) GetSessionID() string {
 	return t.sessionID
 }
 
+// Stats returns a snapshot of the request statistics for this client
+func (t *TracingHTTPClient) Stats() Stats {
+	return t.logger.Stats()
+}
+
 // IsEnabled returns whether tracing is enabled
 func (t *TracingHTTPClient) IsEnabled() bool {
 	return t.config.Enabled
@@ -96,6 +139,25 @@ func (t *TracingHTTPClient) GetWithContext(ctx context.Context, url string) (*ht
 	return t.Do(req)
 }
 
+// GetWithBody performs a GET request with a body, for APIs such as
+// Elasticsearch's search that read one
+func (t *TracingHTTPClient) GetWithBody(url string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, body)
+	if err != nil {
+		return nil, err
+	}
+	return t.Do(req)
+}
+
+// GetWithBodyWithContext performs a GET request with a body and context
+func (t *TracingHTTPClient) GetWithBodyWithContext(ctx context.Context, url string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, body)
+	if err != nil {
+		return nil, err
+	}
+	return t.Do(req)
+}
+
 // POST performs a POST request
 func (t *TracingHTTPClient) Post(url, contentType string, body io.Reader) (*http.Response, error) {
 	req, err := http.NewRequest(http.MethodPost, url, body)
@@ -252,22 +314,101 @@ func (t *TracingHTTPClient) OptionsWithContext(ctx context.Context, url string)
 	return t.Do(req)
 }
 
+// User-Agent set on requests without one, and the token appended to an
+// existing User-Agent when AppendUserAgent is enabled
+const (
+	defaultUserAgent = "opencode-trace-go-client/1.0"
+	userAgentSuffix  = "opencode-trace/1.0"
+)
+
 // Do executes an HTTP request with tracing
 func (t *TracingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	req = t.withSampling(req)
+
 	// Add default headers if not present
-	if req.Header.Get("User-Agent") == "" {
-		req.Header.Set("User-Agent", "opencode-trace-go-client/1.0")
+	if userAgent := req.Header.Get("User-Agent"); userAgent == "" {
+		req.Header.Set("User-Agent", defaultUserAgent)
+	} else if t.config.AppendUserAgent && !strings.HasSuffix(userAgent, " "+userAgentSuffix) {
+		// Keep the caller's User-Agent but make the request identifiable upstream
+		req.Header.Set("User-Agent", userAgent+" "+userAgentSuffix)
+	}
+
+	// A per-request timeout replaces the client-wide Timeout for this call
+	if timeout, ok := requestTimeoutFromContext(req.Context()); ok {
+		return t.doWithDeadline(req, timeout)
 	}
 
 	// Execute the request through the tracing client
 	return t.client.Do(req)
 }
 
+// DoWithTimeout executes an HTTP request with a timeout that overrides the client Timeout
+func (t *TracingHTTPClient) DoWithTimeout(req *http.Request, timeout time.Duration) (*http.Response, error) {
+	return t.Do(req.WithContext(WithRequestTimeout(req.Context(), timeout)))
+}
+
+// doWithDeadline executes the request bounded only by the given timeout.
+// The client Timeout is lifted so that a longer override is not cut off early.
+func (t *TracingHTTPClient) doWithDeadline(req *http.Request, timeout time.Duration) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+
+	client := *t.client
+	client.Timeout = 0
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil || resp == nil || resp.Body == nil {
+		cancel()
+		return resp, err
+	}
+
+	// Keep the deadline alive until the caller is done reading the body
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// requestTimeoutKey is the context key for per-request timeout overrides
+type requestTimeoutKey struct{}
+
+// WithRequestTimeout returns a context that overrides the client Timeout for requests made with it
+func WithRequestTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, requestTimeoutKey{}, timeout)
+}
+
+// requestTimeoutFromContext returns the per-request timeout override, if any
+func requestTimeoutFromContext(ctx context.Context) (time.Duration, bool) {
+	timeout, ok := ctx.Value(requestTimeoutKey{}).(time.Duration)
+	return timeout, ok && timeout > 0
+}
+
+// cancelOnCloseBody releases a request context once the response body is closed
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the associated context
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
 // DoWithRetry executes an HTTP request with retry logic
 func (t *TracingHTTPClient) DoWithRetry(req *http.Request) (*http.Response, error) {
 	var lastErr error
 	var resp *http.Response
 
+	// Every attempt belongs to the same operation, traced or left out as a whole
+	req = t.withSampling(req)
+	traced := t.config.Enabled && operationSampled(req.Context())
+
+	// In nested mode the attempts are collected and logged as a single event
+	var collector *retryCollector
+	if traced && t.config.RetryLoggingMode == RetryLoggingNested {
+		collector = &retryCollector{}
+		req = req.WithContext(withRetryCollector(req.Context(), collector))
+	}
+
 	// Setup GetBody function if request has a body and GetBody is not set
 	if req.Body != nil && req.GetBody == nil {
 		if bodyBytes, err := io.ReadAll(req.Body); err == nil {
@@ -279,23 +420,47 @@ func (t *TracingHTTPClient) DoWithRetry(req *http.Request) (*http.Response, erro
 		}
 	}
 
+	// Every attempt's events carry retryID and the attempt number
+	retryID := uuid.New().String()
+	attempts, exhausted := 0, false
+
 	for attempt := 0; attempt <= t.config.MaxRetries; attempt++ {
+		var backoff time.Duration
 		if attempt > 0 {
 			// Wait before retry (exponential backoff)
-			backoff := time.Duration(attempt) * time.Second
+			backoff = t.retryBackoff(attempt)
 			time.Sleep(backoff)
 		}
 
+		if collector != nil {
+			collector.startAttempt(attempt, backoff)
+		}
+
 		// Clone request for retry (in case body was consumed)
 		clonedReq := t.cloneRequest(req)
+		clonedReq = clonedReq.WithContext(withRetryAttempt(clonedReq.Context(), retryID, attempt))
 		
 		resp, lastErr = t.Do(clonedReq)
+		attempts++
 		
 		// Check if we should continue retrying
 		shouldRetry := lastErr != nil || t.isRetryableError(resp, lastErr)
 		if !shouldRetry {
 			break
 		}
+		exhausted = attempt == t.config.MaxRetries
+
+		// A spent session budget ends retrying; the caller gets this attempt's result
+		if !exhausted {
+			if allowed, firstRefusal := t.retryBudget.take(); !allowed {
+				if firstRefusal && traced {
+					if err := t.logger.LogRetryBudgetExhausted(retryID, req, resp, lastErr); err != nil {
+						t.logger.LogError(err, "failed to log exhausted retry budget")
+					}
+				}
+				break
+			}
+		}
 
 		// Close response body if it exists (to prevent resource leaks)
 		if resp != nil && resp.Body != nil {
@@ -303,9 +468,34 @@ func (t *TracingHTTPClient) DoWithRetry(req *http.Request) (*http.Response, erro
 		}
 	}
 
+	if collector != nil {
+		if err := t.logger.LogHTTPRequestWithRetries(collector, lastErr); err != nil {
+			t.logger.LogError(err, "failed to log retried request")
+		}
+	}
+	if exhausted && traced {
+		if err := t.logger.LogRetriesExhausted(retryID, req, attempts, resp, lastErr); err != nil {
+			t.logger.LogError(err, "failed to log exhausted retries")
+		}
+	}
+
 	return resp, lastErr
 }
 
+// retryBackoff returns how long to wait before the given retry attempt: a
+// jittered linear backoff unless overridden
+func (t *TracingHTTPClient) retryBackoff(attempt int) time.Duration {
+	if t.backoff != nil {
+		return t.backoff(attempt)
+	}
+
+	jitter := t.jitter
+	if jitter == nil {
+		jitter = defaultJitter
+	}
+	return jitter(time.Duration(attempt) * time.Second)
+}
+
 // cloneRequest creates a copy of the request for retries
 func (t *TracingHTTPClient) cloneRequest(req *http.Request) *http.Request {
 	// Clone the request
@@ -340,6 +530,11 @@ func (t *TracingHTTPClient) isRetryableError(resp *http.Response, err error) boo
 			http.StatusGatewayTimeout:
 			return true
 		}
+
+		// Some APIs report errors such as rate limits in a successful response
+		if t.retryableBody(resp) {
+			return true
+		}
 	}
 
 	return false
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -388,4 +389,233 @@ func TestClientDisabled(t *testing.T) {
 	if resp.StatusCode != http.StatusOK {
 		t.Errorf("Expected status 200, got %d", resp.StatusCode)
 	}
-}
\ No newline at end of file
+}
+
+// readSessionEvents reads every event written under dir/sessions in file order
+func readSessionEvents(t *testing.T, dir string) []map[string]interface{} {
+	t.Helper()
+
+	sessionDir := filepath.Join(dir, "sessions")
+	files, err := os.ReadDir(sessionDir)
+	if err != nil {
+		t.Fatalf("Failed to read session directory: %v", err)
+	}
+
+	var events []map[string]interface{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".jsonl") {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(sessionDir, file.Name()))
+		if err != nil {
+			t.Fatalf("Failed to read session file: %v", err)
+		}
+
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+
+			var event map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &event); err != nil {
+				t.Fatalf("Invalid JSON line %q: %v", line, err)
+			}
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+// eventsOfType filters events by their type field
+func eventsOfType(events []map[string]interface{}, eventType string) []map[string]interface{} {
+	var filtered []map[string]interface{}
+	for _, event := range events {
+		if event["type"] == eventType {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
+
+// newTestConfig returns an enabled config writing to dir
+func newTestConfig(dir string) *TracingConfig {
+	return &TracingConfig{
+		Enabled:               true,
+		OutputDir:             dir,
+		MaxBodySize:           1024,
+		CaptureRequestBodies:  true,
+		CaptureResponseBodies: true,
+		Timeout:               5 * time.Second,
+		MaxRetries:            0,
+		SensitiveHeaders:      []string{"authorization", "x-api-key"},
+	}
+}
+
+func TestDoWithTimeoutShorterOverride(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-timeout-short-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-timeout-short", newTestConfig(tempDir))
+	defer client.Close()
+
+	req, err := http.NewRequest("GET", server.URL+"/slow", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	resp, err := client.DoWithTimeout(req, 50*time.Millisecond)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected per-request timeout to cut off the slow call")
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("Expected request to fail fast, took %v", elapsed)
+	}
+
+	requests := eventsOfType(readSessionEvents(t, tempDir), "http_request")
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request event, got %d", len(requests))
+	}
+	if requests[0]["timeout_ms"] != float64(50) {
+		t.Errorf("Expected timeout_ms 50, got %v", requests[0]["timeout_ms"])
+	}
+}
+
+func TestDoWithTimeoutLongerOverride(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-timeout-long-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"done": true}`))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.Timeout = 100 * time.Millisecond
+
+	client := NewTracingHTTPClientWithConfig("test-timeout-long", config)
+	defer client.Close()
+
+	// The client Timeout alone is too short for this endpoint
+	if resp, err := client.Get(server.URL + "/slow"); err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected client Timeout to cut off the slow call")
+	}
+
+	req, err := http.NewRequest("GET", server.URL+"/slow", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.DoWithTimeout(req, 2*time.Second)
+	if err != nil {
+		t.Fatalf("Expected longer per-request timeout to succeed, got %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	if string(body) != `{"done": true}` {
+		t.Errorf("Unexpected body %q", body)
+	}
+
+	// Context-based override behaves the same way
+	ctx := WithRequestTimeout(context.Background(), 2*time.Second)
+	resp, err = client.GetWithContext(ctx, server.URL+"/slow")
+	if err != nil {
+		t.Fatalf("Expected context timeout override to succeed, got %v", err)
+	}
+	resp.Body.Close()
+
+	requests := eventsOfType(readSessionEvents(t, tempDir), "http_request")
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 request events, got %d", len(requests))
+	}
+	if requests[0]["timeout_ms"] != float64(100) {
+		t.Errorf("Expected client timeout_ms 100, got %v", requests[0]["timeout_ms"])
+	}
+	for _, event := range requests[1:] {
+		if event["timeout_ms"] != float64(2000) {
+			t.Errorf("Expected override timeout_ms 2000, got %v", event["timeout_ms"])
+		}
+	}
+}
+
+func TestAppendUserAgent(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-user-agent-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("User-Agent")
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.AppendUserAgent = true
+	client := NewTracingHTTPClientWithConfig("test-user-agent", config)
+
+	tests := []struct {
+		name      string
+		userAgent string
+		expected  string
+	}{
+		{"caller user agent", "opencode/0.5.2", "opencode/0.5.2 opencode-trace/1.0"},
+		{"already suffixed", "opencode/0.5.2 opencode-trace/1.0", "opencode/0.5.2 opencode-trace/1.0"},
+		{"no user agent", "", "opencode-trace-go-client/1.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+			if tt.userAgent != "" {
+				req.Header.Set("User-Agent", tt.userAgent)
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if got := <-received; got != tt.expected {
+				t.Errorf("Expected User-Agent %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	// Without the option the caller's User-Agent is left alone
+	config.AppendUserAgent = false
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("User-Agent", "opencode/0.5.2")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := <-received; got != "opencode/0.5.2" {
+		t.Errorf("Expected the caller's User-Agent unchanged, got %q", got)
+	}
+}
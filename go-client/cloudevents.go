@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Formats events are written in
+const (
+	EventFormatRaw         = "raw"
+	EventFormatCloudEvents = "cloudevents"
+)
+
+// cloudEventTypePrefix is prepended to the event type, e.g. com.opencode.trace.http_request
+const cloudEventTypePrefix = "com.opencode.trace."
+
+// cloudEvent is a CloudEvents 1.0 envelope in the JSON event format
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	Type            string          `json:"type"`
+	Source          string          `json:"source"`
+	ID              string          `json:"id"`
+	Time            string          `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// wrapCloudEvent wraps a serialized event in a CloudEvents envelope. The
+// source names the session and the time is the event timestamp in TimeZone.
+func (l *Logger) wrapCloudEvent(data []byte) ([]byte, error) {
+	var event struct {
+		Type      string `json:"type"`
+		Timestamp int64  `json:"timestamp"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("invalid event: %w", err)
+	}
+
+	timestamp := event.Timestamp
+	if timestamp <= 0 {
+		timestamp = time.Now().UnixMilli()
+	}
+
+	return json.Marshal(cloudEvent{
+		SpecVersion:     "1.0",
+		Type:            cloudEventTypePrefix + event.Type,
+		Source:          "/opencode-trace/sessions/" + l.sessionID,
+		ID:              uuid.New().String(),
+		Time:            formatTimestamp(timestamp, l.location),
+		DataContentType: "application/json",
+		Data:            data,
+	})
+}
+
+// unwrapCloudEvent returns the event a CloudEvents envelope carries under
+// data, or event itself when it was written raw
+func unwrapCloudEvent(event map[string]interface{}) map[string]interface{} {
+	if data, ok := event["data"].(map[string]interface{}); ok && event["specversion"] != nil {
+		return data
+	}
+	return event
+}
+
+// cloudEventData is unwrapCloudEvent for a serialized event, returning the
+// data bytes exactly as they were written
+func cloudEventData(raw []byte) []byte {
+	var envelope struct {
+		SpecVersion string          `json:"specversion"`
+		Data        json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil || envelope.SpecVersion == "" {
+		return raw
+	}
+	if data := bytes.TrimSpace(envelope.Data); len(data) > 0 && data[0] == '{' {
+		return data
+	}
+	return raw
+}
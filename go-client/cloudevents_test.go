@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCloudEventsFormat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-cloudevents-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.EventFormat = EventFormatCloudEvents
+	client := NewTracingHTTPClientWithConfig("test-cloudevents", config)
+
+	resp, err := client.Get(server.URL + "/resource")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	events := readSessionEvents(t, tempDir)
+	if len(events) < 2 {
+		t.Fatalf("Expected at least 2 events, got %d", len(events))
+	}
+
+	ids := make(map[interface{}]bool)
+	for i, event := range events {
+		for _, attribute := range []string{"specversion", "type", "source", "id", "time", "data"} {
+			if event[attribute] == nil {
+				t.Errorf("Event %d: missing CloudEvents attribute %s", i, attribute)
+			}
+		}
+		if event["specversion"] != "1.0" {
+			t.Errorf("Event %d: expected specversion 1.0, got %v", i, event["specversion"])
+		}
+		if event["source"] != "/opencode-trace/sessions/test-cloudevents" {
+			t.Errorf("Event %d: unexpected source %v", i, event["source"])
+		}
+		if ids[event["id"]] {
+			t.Errorf("Event %d: duplicate id %v", i, event["id"])
+		}
+		ids[event["id"]] = true
+		if _, err := time.Parse(time.RFC3339, event["time"].(string)); err != nil {
+			t.Errorf("Event %d: time is not RFC 3339: %v", i, err)
+		}
+	}
+
+	request := events[0]
+	if request["type"] != "com.opencode.trace.http_request" {
+		t.Errorf("Expected type com.opencode.trace.http_request, got %v", request["type"])
+	}
+	data, ok := request["data"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected the event under data, got %v", request["data"])
+	}
+	if data["type"] != "http_request" || data["method"] != "GET" || data["url"] != server.URL+"/resource" {
+		t.Errorf("Unexpected original event: %v", data)
+	}
+	if data["session_id"] != "test-cloudevents" {
+		t.Errorf("Expected session_id in data, got %v", data["session_id"])
+	}
+}
+
+func TestCloudEventsTimeFromTimestamp(t *testing.T) {
+	logger := NewLogger(&TracingConfig{}, "session")
+	wrapped, err := logger.wrapCloudEvent([]byte(`{"type":"error","timestamp":1700000000123}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var envelope map[string]interface{}
+	if err := json.Unmarshal(wrapped, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope["time"] != "2023-11-14T22:13:20.123Z" {
+		t.Errorf("Expected time from the event timestamp, got %v", envelope["time"])
+	}
+	if envelope["type"] != "com.opencode.trace.error" {
+		t.Errorf("Expected type com.opencode.trace.error, got %v", envelope["type"])
+	}
+}
+
+func TestCloudEventsSessionReadsBack(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-cloudevents-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	key := []byte("integrity-key")
+	config := newTestConfig(tempDir)
+	config.EventFormat = EventFormatCloudEvents
+	config.IntegrityKey = base64.StdEncoding.EncodeToString(key)
+	client := NewTracingHTTPClientWithConfig("test-cloudevents", config)
+
+	resp, err := client.Get(server.URL + "/resource")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+	sessionFile := findSessionFile(t, tempDir)
+
+	events, err := ReadSessionFile(sessionFile)
+	if err != nil {
+		t.Fatalf("ReadSessionFile failed: %v", err)
+	}
+	exchanges := GroupExchanges(events)
+	if len(exchanges) != 1 || exchanges[0].Request == nil || exchanges[0].Response == nil {
+		t.Fatalf("Expected one complete exchange, got %+v", exchanges)
+	}
+
+	var har bytes.Buffer
+	if err := ExportHAR(sessionFile, &har); err != nil {
+		t.Fatalf("ExportHAR failed: %v", err)
+	}
+	var document harDocument
+	if err := json.Unmarshal(har.Bytes(), &document); err != nil {
+		t.Fatal(err)
+	}
+	if len(document.Log.Entries) != 1 || document.Log.Entries[0].Request.URL != server.URL+"/resource" {
+		t.Errorf("Expected one HAR entry for the request, got %+v", document.Log.Entries)
+	}
+
+	var otlp bytes.Buffer
+	if err := ExportOTLP(sessionFile, &otlp); err != nil {
+		t.Fatalf("ExportOTLP failed: %v", err)
+	}
+	var spans struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					Name string `json:"name"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(otlp.Bytes(), &spans); err != nil {
+		t.Fatal(err)
+	}
+	if len(spans.ResourceSpans) != 1 || len(spans.ResourceSpans[0].ScopeSpans) != 1 || len(spans.ResourceSpans[0].ScopeSpans[0].Spans) != 1 {
+		t.Errorf("Expected one OTLP span, got %s", otlp.String())
+	}
+
+	if rows := buildHTMLReport(events).Rows; len(rows) != 1 || rows[0].URL != server.URL+"/resource" {
+		t.Errorf("Expected one report row for the request, got %+v", rows)
+	}
+
+	file, err := os.Open(sessionFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	lines := 0
+	for scanner.Scan() {
+		lines++
+		if valid, err := VerifyEvent(scanner.Bytes(), key); err != nil || !valid {
+			t.Errorf("Line %d: expected a valid hmac, got %v, %v", lines, valid, err)
+		}
+	}
+	if lines == 0 {
+		t.Error("Expected events in the session file")
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/google/uuid"
+)
+
+// runCommand dispatches command-line subcommands and returns the process exit code
+func runCommand(args []string, stdout, stderr io.Writer) int {
+	switch args[0] {
+	case "export-otlp":
+		if len(args) != 2 {
+			fmt.Fprintln(stderr, "usage: opencode-trace export-otlp <session.jsonl>")
+			return 2
+		}
+		if err := ExportOTLP(args[1], stdout); err != nil {
+			fmt.Fprintf(stderr, "export failed: %v\n", err)
+			return 1
+		}
+		return 0
+
+	case "export-har":
+		if len(args) != 2 {
+			fmt.Fprintln(stderr, "usage: opencode-trace export-har <session.jsonl | session-dir>")
+			return 2
+		}
+		export := ExportHAR
+		if info, err := os.Stat(args[1]); err == nil && info.IsDir() {
+			export = ExportHARMerged
+		}
+		if err := export(args[1], stdout); err != nil {
+			fmt.Fprintf(stderr, "export failed: %v\n", err)
+			return 1
+		}
+		return 0
+
+	case "export-parquet":
+		if len(args) < 3 {
+			fmt.Fprintln(stderr, "usage: opencode-trace export-parquet <out.parquet> <session.jsonl>...")
+			return 2
+		}
+		if err := ExportParquet(args[2:], args[1]); err != nil {
+			fmt.Fprintf(stderr, "export failed: %v\n", err)
+			return 1
+		}
+		return 0
+
+	case "report":
+		if len(args) != 2 {
+			fmt.Fprintln(stderr, "usage: opencode-trace report <session.jsonl>")
+			return 2
+		}
+		if err := GenerateHTMLReport(args[1], stdout); err != nil {
+			fmt.Fprintf(stderr, "report failed: %v\n", err)
+			return 1
+		}
+		return 0
+
+	case "proxy":
+		return runProxy(args[1:], stdout, stderr)
+
+	case "benchmark":
+		return runBenchmark(args[1:], stdout, stderr)
+
+	case "validate":
+		if len(args) != 2 {
+			fmt.Fprintln(stderr, "usage: opencode-trace validate <trace-config.json>")
+			return 2
+		}
+		errs := ValidateConfigFile(args[1])
+		for _, err := range errs {
+			fmt.Fprintf(stderr, "%s: %v\n", args[1], err)
+		}
+		if len(errs) > 0 {
+			return 1
+		}
+		fmt.Fprintf(stdout, "%s: ok\n", args[1])
+		return 0
+
+	case "help", "-h", "--help":
+		printUsage(stdout)
+		return 0
+
+	default:
+		fmt.Fprintf(stderr, "unknown command: %s\n", args[0])
+		printUsage(stderr)
+		return 2
+	}
+}
+
+// printUsage lists the available subcommands
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: opencode-trace <command> [arguments]")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "commands:")
+	fmt.Fprintln(w, "  export-otlp <session.jsonl>   convert a session to OTLP/JSON on stdout")
+	fmt.Fprintln(w, "  export-har <file | dir>       convert a session file, or merge a session directory, to HAR on stdout")
+	fmt.Fprintln(w, "  export-parquet <out> <files>  flatten sessions into a Parquet file (requires -tags parquet)")
+	fmt.Fprintln(w, "  report <session.jsonl>        render a session as a self-contained HTML report on stdout")
+	fmt.Fprintln(w, "  proxy [--listen addr] [--session id] [--mitm]  trace traffic sent through an HTTP proxy")
+	fmt.Fprintln(w, "  validate <config.json>        check a trace config file for unknown keys and invalid values")
+	fmt.Fprintln(w, "  benchmark [--requests n] [--duration d] [--body-size bytes]  measure tracing overhead against a local server")
+}
+
+// runBenchmark measures tracing overhead and prints it as a table
+func runBenchmark(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("benchmark", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	requests := flags.Int("requests", defaultBenchmarkRequests, "requests per scenario")
+	duration := flags.Duration("duration", 0, "time per scenario, instead of a request count")
+	bodySize := flags.Int("body-size", defaultBenchmarkBodySize, "size of request and response bodies in bytes")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	result, err := RunBenchmark(BenchmarkOptions{
+		Requests: *requests,
+		Duration: *duration,
+		BodySize: *bodySize,
+		Config:   LoadConfig(),
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "benchmark failed: %v\n", err)
+		return 1
+	}
+	if err := result.WriteTable(stdout); err != nil {
+		fmt.Fprintf(stderr, "benchmark failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runProxy runs the tracing forward proxy until it fails
+func runProxy(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("proxy", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	listen := flags.String("listen", "127.0.0.1:8888", "address to listen on")
+	sessionID := flags.String("session", "", "session ID to record into (default: generated)")
+	mitm := flags.Bool("mitm", false, "decrypt HTTPS with a generated CA that clients must trust")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *sessionID == "" {
+		*sessionID = uuid.New().String()
+	}
+
+	config := LoadConfig()
+	config.Enabled = true
+
+	proxy := NewTraceProxy(*sessionID, config, nil)
+	defer proxy.Close()
+
+	if *mitm {
+		ca, err := LoadOrCreateProxyCA(config.OutputDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "proxy failed: %v\n", err)
+			return 1
+		}
+		if err := proxy.EnableMITM(ca); err != nil {
+			fmt.Fprintf(stderr, "proxy failed: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "HTTPS is decrypted; clients must trust %s\n", filepath.Join(config.OutputDir, proxyCACertFile))
+	}
+
+	// Stop on interrupt so the session summary is still written
+	server := &http.Server{Addr: *listen, Handler: proxy}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	fmt.Fprintf(stdout, "tracing proxy on %s recording session %s\n", *listen, *sessionID)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		fmt.Fprintf(stderr, "proxy failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// exitWithCommand runs the subcommand given on the command line and exits
+func exitWithCommand() {
+	os.Exit(runCommand(os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -0,0 +1,13 @@
+package main
+
+import "math"
+
+// compressionRatio returns compressed/uncompressed rounded to four decimal
+// places, or false when either size is unknown
+func compressionRatio(compressed, uncompressed int64) (float64, bool) {
+	if compressed <= 0 || uncompressed <= 0 {
+		return 0, false
+	}
+	ratio := float64(compressed) / float64(uncompressed)
+	return math.Round(ratio*10000) / 10000, true
+}
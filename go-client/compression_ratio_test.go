@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCompressionRatioRecorded(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-compression-ratio-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	plain := strings.Repeat("compressible ", 4000)
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(plain))
+	writer.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plain" {
+			w.Write([]byte("not compressed"))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.MaxBodySize = 1024 * 1024
+	client := NewTracingHTTPClientWithConfig("test-compression-ratio", config)
+
+	for _, path := range []string{"/gzip", "/plain"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 2 {
+		t.Fatalf("Expected 2 response events, got %d", len(responses))
+	}
+
+	gzipped := responses[0]
+	if gzipped["compressed_size"] != float64(compressed.Len()) {
+		t.Errorf("Expected compressed size %d, got %v", compressed.Len(), gzipped["compressed_size"])
+	}
+	if gzipped["uncompressed_size"] != float64(len(plain)) {
+		t.Errorf("Expected uncompressed size %d, got %v", len(plain), gzipped["uncompressed_size"])
+	}
+	ratio, ok := gzipped["compression_ratio"].(float64)
+	if !ok || ratio <= 0 || ratio >= 0.1 {
+		t.Errorf("Expected a compression ratio well below 1.0, got %v", gzipped["compression_ratio"])
+	}
+
+	if _, ok := responses[1]["compression_ratio"]; ok {
+		t.Errorf("Expected no compression ratio for an uncompressed body, got %v", responses[1]["compression_ratio"])
+	}
+}
+
+func TestCompressionRatioSkippedForTruncatedBody(t *testing.T) {
+	if _, ok := compressionRatio(100, 0); ok {
+		t.Error("Expected no ratio without an uncompressed size")
+	}
+	if ratio, ok := compressionRatio(1, 3); !ok || ratio != 0.3333 {
+		t.Errorf("Expected ratio 0.3333, got %v", ratio)
+	}
+}
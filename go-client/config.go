@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -21,6 +22,10 @@ func LoadConfig() *TracingConfig {
 		config.OutputDir = outputDir
 	}
 
+	if namespace := os.Getenv("OPENCODE_TRACE_NAMESPACE"); namespace != "" {
+		config.OutputNamespace = namespace
+	}
+
 	if maxBodySize := os.Getenv("OPENCODE_TRACE_MAX_BODY_SIZE"); maxBodySize != "" {
 		if size, err := strconv.ParseInt(maxBodySize, 10, 64); err == nil {
 			config.MaxBodySize = size
@@ -47,6 +52,188 @@ func LoadConfig() *TracingConfig {
 		}
 	}
 
+	if verbose := os.Getenv("OPENCODE_TRACE_VERBOSE"); verbose != "" {
+		config.Verbose = verbose == "true" || verbose == "1"
+	}
+
+	if slow := os.Getenv("OPENCODE_TRACE_SLOW_REQUEST_THRESHOLD"); slow != "" {
+		if threshold, err := time.ParseDuration(slow); err == nil {
+			config.SlowRequestThreshold = threshold
+		}
+	}
+
+	if httpTrace := os.Getenv("OPENCODE_TRACE_HTTPTRACE"); httpTrace != "" {
+		config.EnableHTTPTrace = httpTrace == "true" || httpTrace == "1"
+	}
+
+	if appendUA := os.Getenv("OPENCODE_TRACE_APPEND_USER_AGENT"); appendUA != "" {
+		config.AppendUserAgent = appendUA == "true" || appendUA == "1"
+	}
+
+	if rawLines := os.Getenv("OPENCODE_TRACE_RAW_LINES"); rawLines != "" {
+		config.CaptureRawLines = rawLines == "true" || rawLines == "1"
+	}
+
+	if graphQLVariables := os.Getenv("OPENCODE_TRACE_GRAPHQL_VARIABLES"); graphQLVariables != "" {
+		config.CaptureGraphQLVariables = graphQLVariables == "true" || graphQLVariables == "1"
+	}
+
+	if createDir := os.Getenv("OPENCODE_TRACE_CREATE_OUTPUT_DIR"); createDir != "" {
+		config.CreateOutputDir = createDir == "true" || createDir == "1"
+	}
+
+	if hashBodies := os.Getenv("OPENCODE_TRACE_HASH_BODIES"); hashBodies != "" {
+		config.HashBodies = hashBodies == "true" || hashBodies == "1"
+	}
+
+	if effective := os.Getenv("OPENCODE_TRACE_EFFECTIVE_REQUESTS"); effective != "" {
+		config.CaptureEffectiveRequests = effective == "true" || effective == "1"
+	}
+
+	if minFree := os.Getenv("OPENCODE_TRACE_MIN_FREE_DISK_BYTES"); minFree != "" {
+		if bytes, err := strconv.ParseInt(minFree, 10, 64); err == nil {
+			config.MinFreeDiskBytes = bytes
+		}
+	}
+
+	if requireTracing := os.Getenv("OPENCODE_TRACE_REQUIRE"); requireTracing != "" {
+		config.RequireTracing = requireTracing == "true" || requireTracing == "1"
+	}
+
+	if fsync := os.Getenv("OPENCODE_TRACE_FSYNC"); fsync != "" {
+		config.FsyncOnWrite = fsync == "true" || fsync == "1"
+	}
+
+	if asyncWrite := os.Getenv("OPENCODE_TRACE_ASYNC_WRITE"); asyncWrite != "" {
+		config.AsyncWrite = asyncWrite == "true" || asyncWrite == "1"
+	}
+
+	if maxSessionBytes := os.Getenv("OPENCODE_TRACE_MAX_SESSION_BYTES"); maxSessionBytes != "" {
+		if size, err := strconv.ParseInt(maxSessionBytes, 10, 64); err == nil {
+			config.MaxSessionBytes = size
+		}
+	}
+
+	if compression := os.Getenv("OPENCODE_TRACE_BODY_COMPRESSION"); compression != "" {
+		config.BodyCompression = compression
+	}
+
+	if maxRequests := os.Getenv("OPENCODE_TRACE_MAX_REQUESTS"); maxRequests != "" {
+		if limit, err := strconv.Atoi(maxRequests); err == nil {
+			config.MaxRequests = limit
+		}
+	}
+
+	if retryLoggingMode := os.Getenv("OPENCODE_TRACE_RETRY_LOGGING_MODE"); retryLoggingMode != "" {
+		config.RetryLoggingMode = retryLoggingMode
+	}
+
+	if sampleMode := os.Getenv("OPENCODE_TRACE_BODY_SAMPLE_MODE"); sampleMode != "" {
+		config.BodySampleMode = sampleMode
+	}
+
+	if healthCheckURL := os.Getenv("OPENCODE_TRACE_HEALTH_CHECK_URL"); healthCheckURL != "" {
+		config.HealthCheckURL = healthCheckURL
+	}
+
+	if baseline := os.Getenv("OPENCODE_TRACE_BASELINE_SESSION"); baseline != "" {
+		config.BaselineSession = baseline
+	}
+
+	if promote := os.Getenv("OPENCODE_TRACE_PROMOTE_HEADERS"); promote != "" {
+		config.PromoteHeaders = nil
+		for _, name := range strings.Split(promote, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				config.PromoteHeaders = append(config.PromoteHeaders, name)
+			}
+		}
+	}
+
+	if mode := os.Getenv("OPENCODE_TRACE_NON_UTF8_HEADERS"); mode != "" {
+		config.NonUTF8HeaderMode = mode
+	}
+
+	if positions := os.Getenv("OPENCODE_TRACE_MASK_PATH_SEGMENTS"); positions != "" {
+		config.MaskPathSegments = nil
+		for _, position := range strings.Split(positions, ",") {
+			if n, err := strconv.Atoi(strings.TrimSpace(position)); err == nil {
+				config.MaskPathSegments = append(config.MaskPathSegments, n)
+			}
+		}
+	}
+
+	if aggregate := os.Getenv("OPENCODE_TRACE_AGGREGATE_FILE"); aggregate != "" {
+		config.AggregateFile = aggregate
+	}
+
+	if key := os.Getenv("OPENCODE_TRACE_INTEGRITY_KEY"); key != "" {
+		config.IntegrityKey = key
+	}
+
+	if hostInfo := os.Getenv("OPENCODE_TRACE_INCLUDE_HOST_INFO"); hostInfo != "" {
+		config.IncludeHostInfo = hostInfo == "true" || hostInfo == "1"
+	}
+
+	if rate := os.Getenv("OPENCODE_TRACE_SAMPLE_RATE"); rate != "" {
+		if sampleRate, err := strconv.ParseFloat(rate, 64); err == nil {
+			config.SampleRate = sampleRate
+		}
+	}
+
+	if allowList := os.Getenv("OPENCODE_TRACE_RESPONSE_HEADER_ALLOWLIST"); allowList != "" {
+		config.ResponseHeaderAllowList = strings.Split(allowList, ",")
+	}
+
+	if origins := os.Getenv("OPENCODE_TRACE_LIVE_ALLOWED_ORIGINS"); origins != "" {
+		config.LiveAllowedOrigins = strings.Split(origins, ",")
+	}
+
+	if budget := os.Getenv("OPENCODE_TRACE_RETRY_BUDGET"); budget != "" {
+		if retries, err := strconv.Atoi(budget); err == nil {
+			config.RetryBudget = retries
+		}
+	}
+
+	if window := os.Getenv("OPENCODE_TRACE_RETRY_BUDGET_WINDOW"); window != "" {
+		if duration, err := time.ParseDuration(window); err == nil {
+			config.RetryBudgetWindow = duration
+		}
+	}
+
+	if streaming := os.Getenv("OPENCODE_TRACE_STREAMING_CONTENT_TYPES"); streaming != "" {
+		config.StreamingContentTypes = strings.Split(streaming, ",")
+	}
+
+	if zone := os.Getenv("OPENCODE_TRACE_TIMEZONE"); zone != "" {
+		config.TimeZone = zone
+	}
+
+	if format := os.Getenv("OPENCODE_TRACE_EVENT_FORMAT"); format != "" {
+		config.EventFormat = format
+	}
+
+	if tlsInfo := os.Getenv("OPENCODE_TRACE_CAPTURE_TLS_INFO"); tlsInfo != "" {
+		config.CaptureTLSInfo = tlsInfo == "true" || tlsInfo == "1"
+	}
+
+	if multi := os.Getenv("OPENCODE_TRACE_HEADERS_MULTI"); multi != "" {
+		config.PreserveHeaderMultiValues = multi == "true" || multi == "1"
+	}
+
+	if statuses := os.Getenv("OPENCODE_TRACE_CAPTURE_BODY_STATUS_CODES"); statuses != "" {
+		config.CaptureBodyStatusCodes = parseStatusPatterns(statuses)
+	}
+
+	if minTLS := os.Getenv("OPENCODE_TRACE_MIN_TLS_VERSION"); minTLS != "" {
+		config.MinTLSVersion = minTLS
+	}
+
+	if maxLine := os.Getenv("OPENCODE_TRACE_MAX_LINE_BYTES"); maxLine != "" {
+		if limit, err := strconv.Atoi(maxLine); err == nil {
+			config.MaxLineBytes = limit
+		}
+	}
+
 	// Try to load from config file
 	loadConfigFromFile(config)
 
@@ -58,6 +245,7 @@ func getDefaultConfig() *TracingConfig {
 	return &TracingConfig{
 		Enabled:               isTracingEnabled(),
 		OutputDir:             ".opencode-trace",
+		CreateOutputDir:       true,
 		MaxBodySize:           1024 * 1024, // 1MB
 		CaptureRequestBodies:  true,
 		CaptureResponseBodies: true,
@@ -65,8 +253,13 @@ func getDefaultConfig() *TracingConfig {
 			"authorization", "cookie", "x-api-key", "x-auth-token",
 			"access-token", "refresh-token", "bearer", "api-key",
 		},
-		Timeout:    30 * time.Second,
-		MaxRetries: 3,
+		Timeout:             30 * time.Second,
+		MaxRetries:          3,
+		RetryLoggingMode:    RetryLoggingFlat,
+		BodySampleMode:      BodySamplePrefix,
+		AsyncQueueSize:      defaultAsyncQueueSize,
+		AsyncOverflowPolicy: OverflowBlock,
+		BodyCompression:     BodyCompressionNone,
 	}
 }
 
@@ -91,7 +284,8 @@ func loadConfigFromFile(config *TracingConfig) {
 		}
 
 		if data, err := os.ReadFile(path); err == nil {
-			var fileConfig TracingConfig
+			// Seed defaults that a file may turn off
+			fileConfig := TracingConfig{CreateOutputDir: config.CreateOutputDir}
 			if err := json.Unmarshal(data, &fileConfig); err == nil {
 				// Merge file config with current config
 				mergeConfig(config, &fileConfig)
@@ -103,9 +297,13 @@ func loadConfigFromFile(config *TracingConfig) {
 
 // mergeConfig merges file configuration into the main config
 func mergeConfig(config, fileConfig *TracingConfig) {
+	config.CreateOutputDir = fileConfig.CreateOutputDir
 	if fileConfig.OutputDir != "" {
 		config.OutputDir = fileConfig.OutputDir
 	}
+	if fileConfig.OutputNamespace != "" {
+		config.OutputNamespace = fileConfig.OutputNamespace
+	}
 	if fileConfig.MaxBodySize != 0 {
 		config.MaxBodySize = fileConfig.MaxBodySize
 	}
@@ -115,9 +313,156 @@ func mergeConfig(config, fileConfig *TracingConfig) {
 	if fileConfig.MaxRetries != 0 {
 		config.MaxRetries = fileConfig.MaxRetries
 	}
+	if fileConfig.BodySampleMode != "" {
+		config.BodySampleMode = fileConfig.BodySampleMode
+	}
+	if fileConfig.BodySampleTailBytes > 0 {
+		config.BodySampleTailBytes = fileConfig.BodySampleTailBytes
+	}
+	if len(fileConfig.RedactResponseJSONPaths) > 0 {
+		config.RedactResponseJSONPaths = fileConfig.RedactResponseJSONPaths
+	}
+	if len(fileConfig.PromoteHeaders) > 0 {
+		config.PromoteHeaders = fileConfig.PromoteHeaders
+	}
+	if len(fileConfig.CaptureBodyStatusCodes) > 0 {
+		config.CaptureBodyStatusCodes = fileConfig.CaptureBodyStatusCodes
+	}
+	if len(fileConfig.DefaultHeadersByHost) > 0 {
+		config.DefaultHeadersByHost = fileConfig.DefaultHeadersByHost
+	}
+	if fileConfig.BaselineSession != "" {
+		config.BaselineSession = fileConfig.BaselineSession
+	}
+	if fileConfig.MinTLSVersion != "" {
+		config.MinTLSVersion = fileConfig.MinTLSVersion
+	}
+	if fileConfig.MaxLineBytes != 0 {
+		config.MaxLineBytes = fileConfig.MaxLineBytes
+	}
+	if fileConfig.HealthCheckURL != "" {
+		config.HealthCheckURL = fileConfig.HealthCheckURL
+	}
+	if fileConfig.HealthCheckTimeout > 0 {
+		config.HealthCheckTimeout = fileConfig.HealthCheckTimeout
+	}
+	if fileConfig.RetryLoggingMode != "" {
+		config.RetryLoggingMode = fileConfig.RetryLoggingMode
+	}
 	if len(fileConfig.SensitiveHeaders) > 0 {
 		config.SensitiveHeaders = fileConfig.SensitiveHeaders
 	}
+	if fileConfig.EnableHTTPTrace {
+		config.EnableHTTPTrace = true
+	}
+	if fileConfig.SlowRequestThreshold > 0 {
+		config.SlowRequestThreshold = fileConfig.SlowRequestThreshold
+	}
+	if fileConfig.Verbose {
+		config.Verbose = true
+	}
+	if fileConfig.CaptureTLSInfo {
+		config.CaptureTLSInfo = true
+	}
+	if fileConfig.EventFormat != "" {
+		config.EventFormat = fileConfig.EventFormat
+	}
+	if fileConfig.TimeZone != "" {
+		config.TimeZone = fileConfig.TimeZone
+	}
+	if len(fileConfig.StreamingContentTypes) > 0 {
+		config.StreamingContentTypes = fileConfig.StreamingContentTypes
+	}
+	if len(fileConfig.ResponseHeaderAllowList) > 0 {
+		config.ResponseHeaderAllowList = fileConfig.ResponseHeaderAllowList
+	}
+	if len(fileConfig.LiveAllowedOrigins) > 0 {
+		config.LiveAllowedOrigins = fileConfig.LiveAllowedOrigins
+	}
+	if len(fileConfig.BodyCaptureRules) > 0 {
+		config.BodyCaptureRules = fileConfig.BodyCaptureRules
+	}
+	if fileConfig.SampleRate != 0 {
+		config.SampleRate = fileConfig.SampleRate
+	}
+	if fileConfig.IncludeHostInfo {
+		config.IncludeHostInfo = true
+	}
+	if fileConfig.RetryBudget != 0 {
+		config.RetryBudget = fileConfig.RetryBudget
+	}
+	if fileConfig.RetryBudgetWindow != 0 {
+		config.RetryBudgetWindow = fileConfig.RetryBudgetWindow
+	}
+	if fileConfig.PreserveHeaderMultiValues {
+		config.PreserveHeaderMultiValues = true
+	}
+	if fileConfig.NonUTF8HeaderMode != "" {
+		config.NonUTF8HeaderMode = fileConfig.NonUTF8HeaderMode
+	}
+	if len(fileConfig.MaskPathSegments) > 0 {
+		config.MaskPathSegments = fileConfig.MaskPathSegments
+	}
+	if len(fileConfig.PathMaskPatterns) > 0 {
+		config.PathMaskPatterns = fileConfig.PathMaskPatterns
+	}
+	if len(fileConfig.RetryOnBodyPatterns) > 0 {
+		config.RetryOnBodyPatterns = fileConfig.RetryOnBodyPatterns
+	}
+	if fileConfig.AggregateFile != "" {
+		config.AggregateFile = fileConfig.AggregateFile
+	}
+	if fileConfig.IntegrityKey != "" {
+		config.IntegrityKey = fileConfig.IntegrityKey
+	}
+	if fileConfig.AppendUserAgent {
+		config.AppendUserAgent = true
+	}
+	if fileConfig.CaptureRawLines {
+		config.CaptureRawLines = true
+	}
+	if fileConfig.CaptureGraphQLVariables {
+		config.CaptureGraphQLVariables = true
+	}
+	if fileConfig.HashBodies {
+		config.HashBodies = true
+	}
+	if fileConfig.CaptureEffectiveRequests {
+		config.CaptureEffectiveRequests = true
+	}
+	if fileConfig.MinFreeDiskBytes != 0 {
+		config.MinFreeDiskBytes = fileConfig.MinFreeDiskBytes
+	}
+	if fileConfig.FsyncOnWrite {
+		config.FsyncOnWrite = true
+	}
+	if fileConfig.RequireTracing {
+		config.RequireTracing = true
+	}
+	if fileConfig.AsyncWrite {
+		config.AsyncWrite = true
+	}
+	if fileConfig.AsyncQueueSize != 0 {
+		config.AsyncQueueSize = fileConfig.AsyncQueueSize
+	}
+	if fileConfig.AsyncOverflowPolicy != "" {
+		config.AsyncOverflowPolicy = fileConfig.AsyncOverflowPolicy
+	}
+	if fileConfig.MaxSessionBytes != 0 {
+		config.MaxSessionBytes = fileConfig.MaxSessionBytes
+	}
+	if fileConfig.MaxSessionBytesHard != 0 {
+		config.MaxSessionBytesHard = fileConfig.MaxSessionBytesHard
+	}
+	if fileConfig.MaxRequests != 0 {
+		config.MaxRequests = fileConfig.MaxRequests
+	}
+	if fileConfig.BodyCompression != "" {
+		config.BodyCompression = fileConfig.BodyCompression
+	}
+	if fileConfig.BodyCompressionThreshold != 0 {
+		config.BodyCompressionThreshold = fileConfig.BodyCompressionThreshold
+	}
 }
 
 // SaveConfig saves the current configuration to a file
@@ -128,7 +473,7 @@ func SaveConfig(config *TracingConfig, path string) error {
 	}
 
 	// Ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(path), outputDirPerm); err != nil {
 		return err
 	}
 
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+)
+
+// connAttemptRecorder counts the connections the transport asked for while
+// sending one request. The transport retries an idempotent request on a new
+// connection when a reused keep-alive connection turns out to be dead, so a
+// count above one shows connection churn the caller never sees.
+type connAttemptRecorder struct {
+	attempts atomic.Int64
+}
+
+// withConnAttemptTrace attaches a hook counting connection attempts
+func withConnAttemptTrace(req *http.Request) (*http.Request, *connAttemptRecorder) {
+	recorder := &connAttemptRecorder{}
+
+	trace := &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			recorder.attempts.Add(1)
+		},
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), recorder
+}
+
+// count returns how many connections were requested
+func (r *connAttemptRecorder) count() int {
+	return int(r.attempts.Load())
+}
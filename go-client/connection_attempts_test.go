@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+)
+
+func TestConnectionAttemptsOnDeadKeepAlive(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-conn-attempts-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// The first connection answers one request, then dies on the next one
+	// without responding, as a server dropping an idle keep-alive would
+	go func() {
+		for accepted := 0; ; accepted++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn, first bool) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					req, err := http.ReadRequest(reader)
+					if err != nil {
+						return
+					}
+					io.Copy(io.Discard, req.Body)
+					conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok"))
+					if first {
+						// Read the next request and hang up on it
+						http.ReadRequest(reader)
+						return
+					}
+				}
+			}(conn, accepted == 0)
+		}
+	}()
+
+	client := NewTracingHTTPClientWithConfig("test-conn-attempts", newTestConfig(tempDir))
+	url := "http://" + listener.Addr().String() + "/"
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i+1, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 2 {
+		t.Fatalf("Expected 2 response events, got %d", len(responses))
+	}
+	if responses[0]["connection_attempts"] != float64(1) {
+		t.Errorf("Expected 1 connection attempt on a fresh connection, got %v", responses[0]["connection_attempts"])
+	}
+	if attempts, _ := responses[1]["connection_attempts"].(float64); attempts < 2 {
+		t.Errorf("Expected the transport retry to show more than one attempt, got %v", responses[1]["connection_attempts"])
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+)
+
+// connSharing counts the traced requests in flight on each connection. Over
+// HTTP/2 one connection carries many concurrent streams, so a count above one
+// shows requests multiplexed behind each other, where head-of-line blocking
+// at the TCP level can slow all of them. The standard transport does not
+// expose stream IDs or priorities, so this is the closest observable signal.
+type connSharing struct {
+	mu       sync.Mutex
+	inFlight map[net.Conn]int
+}
+
+// acquire records a request sent on conn and returns the requests now in flight on it
+func (s *connSharing) acquire(conn net.Conn) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.inFlight == nil {
+		s.inFlight = make(map[net.Conn]int)
+	}
+	s.inFlight[conn]++
+	return s.inFlight[conn]
+}
+
+// release records that a request on conn has finished
+func (s *connSharing) release(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.inFlight[conn] <= 1 {
+		delete(s.inFlight, conn)
+	} else {
+		s.inFlight[conn]--
+	}
+}
+
+// connShareRecorder holds the connection one request was sent on
+type connShareRecorder struct {
+	sharing *connSharing
+
+	mu       sync.Mutex
+	conn     net.Conn
+	reused   bool
+	inFlight int
+}
+
+// withConnShareTrace attaches a hook recording the connection a request gets
+func (s *connSharing) withConnShareTrace(req *http.Request) (*http.Request, *connShareRecorder) {
+	recorder := &connShareRecorder{sharing: s}
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			recorder.mu.Lock()
+			defer recorder.mu.Unlock()
+
+			// A retry on a fresh connection replaces the dead one
+			if recorder.conn != nil {
+				s.release(recorder.conn)
+			}
+			recorder.conn = info.Conn
+			recorder.reused = info.Reused
+			recorder.inFlight = s.acquire(info.Conn)
+		},
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), recorder
+}
+
+// result returns whether the connection was reused and the requests in
+// flight on it when this one was sent
+func (r *connShareRecorder) result() (bool, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reused, r.inFlight
+}
+
+// done releases the request's hold on its connection
+func (r *connShareRecorder) done() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn != nil {
+		r.sharing.release(r.conn)
+		r.conn = nil
+	}
+}
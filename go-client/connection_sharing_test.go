@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConnectionSharingHTTP2(t *testing.T) {
+	const concurrent = 4
+
+	var arrived sync.WaitGroup
+	arrived.Add(concurrent)
+	allArrived := make(chan struct{})
+	go func() {
+		arrived.Wait()
+		close(allArrived)
+	}()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			t.Errorf("Expected HTTP/2, got %s", r.Proto)
+		}
+		if r.URL.Path == "/warmup" {
+			return
+		}
+
+		// Hold every response until all requests are in flight together
+		arrived.Done()
+		select {
+		case <-allArrived:
+		case <-time.After(5 * time.Second):
+			t.Error("Timed out waiting for concurrent requests")
+		}
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-conn-sharing-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	logger := NewLogger(config, "test-conn-sharing")
+	defer logger.Close()
+	client := &http.Client{
+		Transport: NewTracingRoundTripper(server.Client().Transport, logger, config, "test-conn-sharing"),
+	}
+
+	// Establish the HTTP/2 connection so the concurrent requests share it
+	resp, err := client.Get(server.URL + "/warmup")
+	if err != nil {
+		t.Fatalf("Warmup request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrent; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(server.URL + "/concurrent")
+			if err != nil {
+				t.Errorf("Concurrent request failed: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+	logger.Close()
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != concurrent+1 {
+		t.Fatalf("Expected %d responses, got %d", concurrent+1, len(responses))
+	}
+
+	warmup := responses[0]
+	if warmup["connection_in_flight"] != float64(1) || warmup["connection_reused"] != nil {
+		t.Errorf("Expected the warmup request alone on a new connection, got in flight %v, reused %v",
+			warmup["connection_in_flight"], warmup["connection_reused"])
+	}
+
+	var inFlight []int
+	for _, response := range responses[1:] {
+		if response["connection_reused"] != true {
+			t.Errorf("Expected concurrent requests on the reused connection, got %v", response["connection_reused"])
+		}
+		count, _ := response["connection_in_flight"].(float64)
+		inFlight = append(inFlight, int(count))
+	}
+	sort.Ints(inFlight)
+	for i, count := range inFlight {
+		if count != i+1 {
+			t.Fatalf("Expected in-flight counts 1 through %d, got %v", concurrent, inFlight)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// contentDecoders decode response bodies whose Content-Encoding the transport
+// leaves in place, keyed by encoding. Optional decoders register themselves
+// from files behind build tags, such as brotli.
+var contentDecoders = map[string]func(io.Reader) io.Reader{}
+
+// decodeCapturedBody decodes a captured body for the trace only; the caller
+// still reads the encoded bytes. The decoded body is subject to the same
+// limit as any captured body, and whether it was cut at the limit is
+// reported. It returns false when the encoding has no decoder or the body
+// does not decode, in which case the body is stored as is.
+func (t *TracingRoundTripper) decodeCapturedBody(encoding string, body []byte, limit int64) ([]byte, bool, bool) {
+	decoder, ok := contentDecoders[strings.ToLower(strings.TrimSpace(encoding))]
+	if !ok || len(body) == 0 {
+		return nil, false, false
+	}
+
+	decoded, truncated, _, err := t.captureBody(io.NopCloser(decoder(bytes.NewReader(body))), limit, 0)
+	if err != nil {
+		t.logger.warn(WarningDecompression, "", "failed to decode %s response body, storing it encoded: %v", encoding, err)
+		return nil, false, false
+	}
+	return decoded, truncated, true
+}
@@ -0,0 +1,17 @@
+//go:build brotli
+
+package main
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Brotli decoding is only available in builds with the brotli tag, which
+// keeps the decoder out of the default binary
+func init() {
+	contentDecoders["br"] = func(r io.Reader) io.Reader {
+		return brotli.NewReader(r)
+	}
+}
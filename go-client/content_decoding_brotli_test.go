@@ -0,0 +1,96 @@
+//go:build brotli
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestBrotliBodyDecoded(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-brotli-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	payload := `{"id": 123, "status": "created"}`
+	var compressed bytes.Buffer
+	writer := brotli.NewWriter(&compressed)
+	writer.Write([]byte(payload))
+	writer.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "br")
+		w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-brotli", newTestConfig(tempDir))
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Accept-Encoding", "br")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if !bytes.Equal(body, compressed.Bytes()) {
+		t.Error("Expected the caller to receive the encoded body unchanged")
+	}
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 1 {
+		t.Fatalf("Expected 1 response event, got %d", len(responses))
+	}
+	if responses[0]["body"] != payload {
+		t.Errorf("Expected the decoded JSON to be captured, got %v", responses[0]["body"])
+	}
+	if responses[0]["compression"] != "br" || responses[0]["body_decoded"] != true {
+		t.Errorf("Expected compression br and body_decoded, got %v and %v", responses[0]["compression"], responses[0]["body_decoded"])
+	}
+}
+
+func TestInvalidBrotliStoredEncoded(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-brotli-invalid-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		w.Write([]byte("not-really-brotli"))
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-brotli-invalid", newTestConfig(tempDir))
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Accept-Encoding", "br")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 1 || responses[0]["body"] != "not-really-brotli" {
+		t.Fatalf("Expected the undecodable body to be stored as received, got %v", responses)
+	}
+	if _, ok := responses[0]["body_decoded"]; ok {
+		t.Error("Expected no body_decoded for a body that failed to decode")
+	}
+	if client.WarningCount() != 1 {
+		t.Errorf("Expected a decompression warning, got %d warnings", client.WarningCount())
+	}
+}
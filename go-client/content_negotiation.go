@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ContentNegotiation holds the parsed Accept, Accept-Encoding and
+// Accept-Language headers of a request, which decide the format, encoding and
+// language the server answers in
+type ContentNegotiation struct {
+	Accept         []QualityValue `json:"accept,omitempty"`
+	AcceptEncoding []QualityValue `json:"accept_encoding,omitempty"`
+	AcceptLanguage []QualityValue `json:"accept_language,omitempty"`
+}
+
+// QualityValue is one entry of a content negotiation header with its q-factor,
+// 1 when the entry gives none. Parameters other than q stay in Value, e.g.
+// "text/html;level=1".
+type QualityValue struct {
+	Value string  `json:"value"`
+	Q     float64 `json:"q"`
+}
+
+// parseContentNegotiation extracts the content negotiation headers from
+// sanitized request headers. Redacted headers are left out.
+func parseContentNegotiation(headers map[string]string) *ContentNegotiation {
+	negotiation := &ContentNegotiation{}
+	found := false
+	for name, value := range headers {
+		if value == redactedValue {
+			continue
+		}
+		switch strings.ToLower(name) {
+		case "accept":
+			negotiation.Accept = parseQualityList(value)
+		case "accept-encoding":
+			negotiation.AcceptEncoding = parseQualityList(value)
+		case "accept-language":
+			negotiation.AcceptLanguage = parseQualityList(value)
+		default:
+			continue
+		}
+		found = true
+	}
+
+	if !found {
+		return nil
+	}
+	return negotiation
+}
+
+// parseQualityList parses a comma-separated header with optional ;q= weights,
+// keeping the order the client sent. Entries with a malformed q keep q=1.
+func parseQualityList(header string) []QualityValue {
+	var values []QualityValue
+	for _, entry := range strings.Split(header, ",") {
+		params := strings.Split(entry, ";")
+		value := QualityValue{Value: strings.TrimSpace(params[0]), Q: 1}
+		if value.Value == "" {
+			continue
+		}
+
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			key, raw, _ := strings.Cut(param, "=")
+			if strings.EqualFold(strings.TrimSpace(key), "q") {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(raw), 64); err == nil && q >= 0 && q <= 1 {
+					value.Q = q
+				}
+				// Parameters after q are accept-extensions, not part of the media range
+				break
+			}
+			if param != "" {
+				value.Value += ";" + param
+			}
+		}
+		values = append(values, value)
+	}
+	return values
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestContentNegotiationCapture(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-negotiation-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	client := NewTracingHTTPClientWithConfig("test-negotiation", newTestConfig(tempDir))
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Accept", "application/json, text/html;level=1;q=0.8, */*;q=0.1")
+	req.Header.Set("Accept-Language", "en-US, fr;q=0.5")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	requests := eventsOfType(readSessionEvents(t, tempDir), "http_request")
+	if len(requests) != 1 {
+		t.Fatalf("Expected one request event, got %d", len(requests))
+	}
+	negotiation, ok := requests[0]["content_negotiation"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected content_negotiation, got %v", requests[0]["content_negotiation"])
+	}
+
+	expectedAccept := []interface{}{
+		map[string]interface{}{"value": "application/json", "q": float64(1)},
+		map[string]interface{}{"value": "text/html;level=1", "q": 0.8},
+		map[string]interface{}{"value": "*/*", "q": 0.1},
+	}
+	if !reflect.DeepEqual(negotiation["accept"], expectedAccept) {
+		t.Errorf("Expected accept %v, got %v", expectedAccept, negotiation["accept"])
+	}
+
+	expectedLanguage := []interface{}{
+		map[string]interface{}{"value": "en-US", "q": float64(1)},
+		map[string]interface{}{"value": "fr", "q": 0.5},
+	}
+	if !reflect.DeepEqual(negotiation["accept_language"], expectedLanguage) {
+		t.Errorf("Expected accept_language %v, got %v", expectedLanguage, negotiation["accept_language"])
+	}
+	if _, ok := negotiation["accept_encoding"]; ok {
+		t.Errorf("Expected no accept_encoding when the header is absent, got %v", negotiation["accept_encoding"])
+	}
+}
+
+func TestParseQualityList(t *testing.T) {
+	got := parseQualityList("gzip;q=1.0, br; q=0.9, identity;q=bogus, , deflate;q=0")
+	expected := []QualityValue{
+		{Value: "gzip", Q: 1},
+		{Value: "br", Q: 0.9},
+		{Value: "identity", Q: 1},
+		{Value: "deflate", Q: 0},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	if parseContentNegotiation(map[string]string{"Content-Type": "text/plain"}) != nil {
+		t.Error("Expected no content negotiation without Accept headers")
+	}
+}
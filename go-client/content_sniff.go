@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// sniffLen is the number of leading body bytes http.DetectContentType considers
+const sniffLen = 512
+
+// detectContentType sniffs the media type of body and returns it when it
+// disagrees with the declared Content-Type, or "" when the two agree
+func detectContentType(declared string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if len(body) > sniffLen {
+		body = body[:sniffLen]
+	}
+
+	// DetectContentType reports JSON as plain text; name it so it can be compared
+	sniffed := http.DetectContentType(body)
+	if mediaType(sniffed) == "text/plain" && looksLikeJSON(body) {
+		sniffed = "application/json"
+	}
+
+	if declared != "" && compatibleContentTypes(declared, sniffed) {
+		return ""
+	}
+	return sniffed
+}
+
+// looksLikeJSON reports whether body opens like a JSON object or array
+func looksLikeJSON(body []byte) bool {
+	body = bytes.TrimLeft(body, " \t\r\n")
+	return len(body) > 0 && (body[0] == '{' || body[0] == '[')
+}
+
+// compatibleContentTypes reports whether a sniffed type is consistent with the
+// declared one. Sniffing only recognises broad families, so a generic text
+// result does not contradict a more specific textual declaration.
+func compatibleContentTypes(declared, sniffed string) bool {
+	declaredFamily := mediaFamily(mediaType(declared))
+	sniffedFamily := mediaFamily(mediaType(sniffed))
+
+	if declaredFamily == sniffedFamily {
+		return true
+	}
+	if sniffedFamily == "text" {
+		return declaredFamily == "json" || declaredFamily == "xml" || declaredFamily == "html"
+	}
+	return false
+}
+
+// mediaType returns the lower-cased media type without parameters
+func mediaType(contentType string) string {
+	if parsed, _, err := mime.ParseMediaType(contentType); err == nil {
+		return parsed
+	}
+	base, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(base))
+}
+
+// mediaFamily groups media types that describe the same kind of content
+func mediaFamily(mediaType string) string {
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return "json"
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return "xml"
+	case mediaType == "text/html":
+		return "html"
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/javascript",
+		mediaType == "application/x-www-form-urlencoded":
+		return "text"
+	}
+	return mediaType
+}
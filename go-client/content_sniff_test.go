@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestDetectedContentTypeRecorded(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-sniff-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	payload := `{"result": "ok", "items": [1, 2, 3]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType := "application/octet-stream"
+		if r.URL.Path == "/labeled" {
+			contentType = "application/json; charset=utf-8"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write([]byte(payload))
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-sniff", newTestConfig(tempDir))
+
+	for _, path := range []string{"/mislabeled", "/labeled"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if string(body) != payload {
+			t.Errorf("%s: expected caller to receive the full body, got %q", path, body)
+		}
+	}
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 2 {
+		t.Fatalf("Expected 2 response events, got %d", len(responses))
+	}
+
+	if detected := responses[0]["detected_content_type"]; detected != "application/json" {
+		t.Errorf("Expected mislabeled response to record application/json, got %v", detected)
+	}
+	if responses[0]["content_type"] != "application/octet-stream" {
+		t.Errorf("Expected declared content type to be kept, got %v", responses[0]["content_type"])
+	}
+	if detected, ok := responses[1]["detected_content_type"]; ok {
+		t.Errorf("Expected no detected type when it matches the declared one, got %v", detected)
+	}
+}
+
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		declared string
+		body     string
+		expected string
+	}{
+		{"json as octet-stream", "application/octet-stream", `[{"a": 1}]`, "application/json"},
+		{"json declared", "application/json", `{"a": 1}`, ""},
+		{"vendor json", "application/vnd.api+json", `{"a": 1}`, ""},
+		{"event stream", "text/event-stream", "data: {\"a\": 1}\n\n", ""},
+		{"missing header", "", "hello", "text/plain; charset=utf-8"},
+		{"png as json", "application/json", "\x89PNG\r\n\x1a\n0000", "image/png"},
+		{"empty body", "application/octet-stream", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectContentType(tt.declared, []byte(tt.body)); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// wouldAutoDecompress reports whether the wrapped http.Transport would add
+// Accept-Encoding: gzip to req and transparently decompress the response,
+// which hides the compressed size from the tracer
+func (t *TracingRoundTripper) wouldAutoDecompress(req *http.Request) bool {
+	transport, ok := t.wrapped.(*http.Transport)
+	if !ok || transport.DisableCompression {
+		return false
+	}
+
+	return req.Method != "HEAD" &&
+		req.Header.Get("Accept-Encoding") == "" &&
+		req.Header.Get("Range") == ""
+}
+
+// withAcceptGzip returns a copy of req that requests gzip explicitly, so the
+// transport passes the compressed body through
+func withAcceptGzip(req *http.Request) *http.Request {
+	clone := req.Clone(req.Context())
+	clone.Header.Set("Accept-Encoding", "gzip")
+	return clone
+}
+
+// decompressResponse undoes gzip the way http.Transport would, presenting
+// the caller with the decoded body. It returns nil when the response is not gzip.
+func decompressResponse(resp *http.Response) *decompressedBody {
+	if resp.Body == nil || !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return nil
+	}
+
+	body := &decompressedBody{
+		raw:        resp.Body,
+		headerSize: resp.ContentLength,
+	}
+
+	resp.Body = body
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return body
+}
+
+// decompressedBody lazily gunzips a response body while counting the
+// compressed bytes read from the wire
+type decompressedBody struct {
+	raw        io.ReadCloser
+	headerSize int64
+
+	mu     sync.Mutex
+	reader *gzip.Reader
+	err    error
+	read   int64
+	eof    bool
+}
+
+func (b *decompressedBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.reader == nil {
+		b.reader, b.err = gzip.NewReader(&countingReader{body: b})
+		if b.err != nil {
+			return 0, b.err
+		}
+	}
+
+	n, err := b.reader.Read(p)
+	if err == io.EOF {
+		b.eof = true
+	}
+	return n, err
+}
+
+func (b *decompressedBody) Close() error {
+	return b.raw.Close()
+}
+
+// wireSize returns the compressed size, from Content-Length or from the bytes
+// read once the body has been consumed
+func (b *decompressedBody) wireSize() (int64, bool) {
+	if b.headerSize >= 0 {
+		return b.headerSize, true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.read, b.eof
+}
+
+// countingReader counts the compressed bytes read for a decompressedBody
+type countingReader struct {
+	body *decompressedBody
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.body.raw.Read(p)
+	r.body.read += int64(n)
+	return n, err
+}
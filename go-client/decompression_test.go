@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAutoDecompressedResponseSizes(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-decompress-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	payload := strings.Repeat(`{"token": "hello"}`, 200)
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(payload))
+	writer.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("Expected gzip to be negotiated, got Accept-Encoding %q", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/sized" {
+			w.Header().Set("Content-Length", strconv.Itoa(compressed.Len()))
+		} else if flusher, ok := w.(http.Flusher); ok {
+			// Force chunked encoding so no Content-Length is sent
+			flusher.Flush()
+		}
+		w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.MaxBodySize = int64(len(payload))
+
+	client := NewTracingHTTPClientWithConfig("test-decompress", config)
+
+	for _, path := range []string{"/sized", "/chunked"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if string(body) != payload {
+			t.Errorf("%s: expected caller to receive the decoded body", path)
+		}
+		if resp.Header.Get("Content-Encoding") != "" || !resp.Uncompressed {
+			t.Errorf("%s: expected the response to look transparently decompressed", path)
+		}
+	}
+	client.Close()
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 2 {
+		t.Fatalf("Expected 2 responses, got %d", len(responses))
+	}
+	for i, response := range responses {
+		if response["compression"] != "gzip" {
+			t.Errorf("Response %d: expected compression gzip, got %v", i, response["compression"])
+		}
+		if response["response_size"] != float64(compressed.Len()) {
+			t.Errorf("Response %d: expected wire size %d, got %v", i, compressed.Len(), response["response_size"])
+		}
+		if response["decoded_size"] != float64(len(payload)) {
+			t.Errorf("Response %d: expected decoded size %d, got %v", i, len(payload), response["decoded_size"])
+		}
+		if response["body"] != payload {
+			t.Errorf("Response %d: expected decoded body to be captured", i)
+		}
+	}
+}
+
+func TestManualAcceptEncodingIsPassedThrough(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-decompress-manual-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		w.Write([]byte("not-really-brotli"))
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-decompress-manual", newTestConfig(tempDir))
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("Accept-Encoding", "br")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	client.Close()
+
+	if string(body) != "not-really-brotli" {
+		t.Errorf("Expected the encoded body to be passed through, got %q", body)
+	}
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 1 || responses[0]["compression"] != "br" {
+		t.Fatalf("Expected compression br, got %v", responses)
+	}
+	if responses[0]["response_size"] != responses[0]["decoded_size"] {
+		t.Errorf("Expected equal sizes without decoding, got %v and %v", responses[0]["response_size"], responses[0]["decoded_size"])
+	}
+}
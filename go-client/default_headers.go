@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// withDefaultHeaders adds the configured default headers for the request's
+// host. Headers the caller already set are left alone. The request is cloned
+// before it is changed, so the caller's request is never modified.
+func (t *TracingRoundTripper) withDefaultHeaders(req *http.Request) *http.Request {
+	if len(t.config.DefaultHeadersByHost) == 0 || req.URL == nil {
+		return req
+	}
+
+	cloned := false
+	// A host:port entry is more specific than a bare hostname and wins on conflict
+	for _, host := range []string{req.URL.Host, req.URL.Hostname()} {
+		for name, value := range defaultHeadersFor(t.config.DefaultHeadersByHost, host) {
+			if len(req.Header.Values(name)) > 0 {
+				continue
+			}
+			if !cloned {
+				req = req.Clone(req.Context())
+				cloned = true
+			}
+			req.Header.Set(name, value)
+		}
+	}
+
+	return req
+}
+
+// defaultHeadersFor returns the default headers configured for host, matching
+// host names case-insensitively
+func defaultHeadersFor(byHost map[string]map[string]string, host string) map[string]string {
+	if host == "" {
+		return nil
+	}
+	if headers, ok := byHost[host]; ok {
+		return headers
+	}
+	for configured, headers := range byHost {
+		if strings.EqualFold(configured, host) {
+			return headers
+		}
+	}
+	return nil
+}
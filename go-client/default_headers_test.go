@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+)
+
+func TestDefaultHeadersByHost(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-default-headers-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	received := make(chan http.Header, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+
+	config := newTestConfig(tempDir)
+	config.DefaultHeadersByHost = map[string]map[string]string{
+		serverURL.Hostname(): {"X-Trace-Env": "staging", "X-Team": "core"},
+		"other.example.com":  {"X-Other": "nope"},
+	}
+
+	client := NewTracingHTTPClientWithConfig("test-default-headers", config)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("X-Team", "caller")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	headers := <-received
+	if headers.Get("X-Trace-Env") != "staging" {
+		t.Errorf("Expected host default to be added, got %q", headers.Get("X-Trace-Env"))
+	}
+	if headers.Get("X-Team") != "caller" {
+		t.Errorf("Expected caller value to win, got %q", headers.Get("X-Team"))
+	}
+	if headers.Get("X-Other") != "" {
+		t.Error("Expected defaults for other hosts not to be applied")
+	}
+	if req.Header.Get("X-Trace-Env") != "" {
+		t.Error("Expected the caller's request not to be modified")
+	}
+
+	requests := eventsOfType(readSessionEvents(t, tempDir), "http_request")
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request event, got %d", len(requests))
+	}
+	captured, _ := requests[0]["headers"].(map[string]interface{})
+	if captured["X-Trace-Env"] != "staging" || captured["X-Team"] != "caller" {
+		t.Errorf("Expected injected headers in the request event, got %v", captured)
+	}
+}
+
+func TestDefaultHeadersPortSpecificWins(t *testing.T) {
+	config := &TracingConfig{
+		DefaultHeadersByHost: map[string]map[string]string{
+			"API.example.com":      {"X-Env": "default", "X-Region": "us"},
+			"api.example.com:8443": {"X-Env": "canary"},
+		},
+	}
+	roundTripper := &TracingRoundTripper{config: config}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.example.com:8443/v1", nil)
+	got := roundTripper.withDefaultHeaders(req)
+
+	if got.Header.Get("X-Env") != "canary" || got.Header.Get("X-Region") != "us" {
+		t.Errorf("Unexpected headers %v", got.Header)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"time"
+)
+
+// diskSpaceCheckInterval is how often the logger re-checks free disk space
+const diskSpaceCheckInterval = 5 * time.Second
+
+// hasDiskSpace reports whether events may be written. When free space on the
+// output filesystem drops below MinFreeDiskBytes, writing is suspended until
+// space recovers so that trace files cannot fill the disk. Free space is
+// checked at most once per diskCheckInterval, by one caller at a time and
+// without holding l.mu, so a slow filesystem only delays that caller; the
+// others use the last result.
+func (l *Logger) hasDiskSpace() bool {
+	// A custom store keeps sessions off the local disk this checks
+	if l.config.MinFreeDiskBytes <= 0 || l.config.SessionStore != nil {
+		return true
+	}
+
+	l.mu.Lock()
+	due := !l.diskChecking && (l.lastDiskCheck.IsZero() || time.Since(l.lastDiskCheck) >= l.diskCheckInterval)
+	if !due {
+		suspended := l.suspended
+		l.mu.Unlock()
+		return !suspended
+	}
+	l.diskChecking = true
+	l.lastDiskCheck = time.Now()
+	l.mu.Unlock()
+
+	free, err := l.freeDiskSpace(l.config.OutputDir)
+
+	l.mu.Lock()
+	transition := ""
+	minFree := uint64(l.config.MinFreeDiskBytes)
+	switch {
+	case err != nil:
+		// Unknown free space, keep the current state
+	case free < minFree && !l.suspended:
+		l.suspended = true
+		transition = "tracing_suspended"
+	case free >= minFree && l.suspended:
+		l.suspended = false
+		transition = "tracing_resumed"
+	}
+	suspended := l.suspended
+	l.mu.Unlock()
+
+	// Written before the next check may start, so notices keep their order
+	if transition != "" {
+		l.appendEvent(l.diskGuardEvent(transition, free))
+	}
+	l.mu.Lock()
+	l.diskChecking = false
+	l.mu.Unlock()
+
+	return !suspended
+}
+
+// diskGuardEvent builds a tracing_suspended or tracing_resumed event
+func (l *Logger) diskGuardEvent(eventType string, free uint64) map[string]interface{} {
+	return map[string]interface{}{
+		"type":           eventType,
+		"timestamp":      time.Now().UnixMilli(),
+		"session_id":     l.sessionID,
+		"reason":         "low_disk_space",
+		"free_bytes":     free,
+		"min_free_bytes": l.config.MinFreeDiskBytes,
+	}
+}
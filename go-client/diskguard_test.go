@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDiskGuardSuspendsAndResumes(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-diskguard-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.MinFreeDiskBytes = 1024 * 1024
+
+	free := uint64(10 * 1024 * 1024)
+	logger := NewLogger(config, "test-diskguard")
+	logger.diskCheckInterval = 0
+	logger.freeDiskSpace = func(path string) (uint64, error) {
+		if path != tempDir {
+			t.Errorf("Expected space check on %s, got %s", tempDir, path)
+		}
+		return free, nil
+	}
+
+	capture := &RequestCapture{StartTime: time.Now(), Method: "GET", URL: "http://example.com/"}
+
+	// Plenty of space
+	if err := logger.LogHTTPRequest(capture); err != nil {
+		t.Fatal(err)
+	}
+
+	// Low disk: writes are suspended with a single notice
+	free = 512
+	for i := 0; i < 3; i++ {
+		if err := logger.LogHTTPRequest(capture); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Space recovers
+	free = 10 * 1024 * 1024
+	if err := logger.LogHTTPRequest(capture); err != nil {
+		t.Fatal(err)
+	}
+
+	events := readSessionEvents(t, tempDir)
+	expectedTypes := []string{"http_request", "tracing_suspended", "tracing_resumed", "http_request"}
+	if len(events) != len(expectedTypes) {
+		t.Fatalf("Expected %d events, got %d: %v", len(expectedTypes), len(events), events)
+	}
+	for i, eventType := range expectedTypes {
+		if events[i]["type"] != eventType {
+			t.Errorf("Event %d: expected type %s, got %v", i+1, eventType, events[i]["type"])
+		}
+	}
+
+	if events[1]["free_bytes"] != float64(512) {
+		t.Errorf("Expected free_bytes 512 on suspension, got %v", events[1]["free_bytes"])
+	}
+}
+
+func TestDiskGuardDisabledByDefault(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-diskguard-off-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	logger := NewLogger(newTestConfig(tempDir), "test-diskguard-off")
+	logger.freeDiskSpace = func(string) (uint64, error) {
+		t.Error("Disk space should not be checked when MinFreeDiskBytes is unset")
+		return 0, nil
+	}
+
+	if err := logger.LogHTTPRequest(&RequestCapture{StartTime: time.Now(), Method: "GET"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if events := readSessionEvents(t, tempDir); len(events) != 1 {
+		t.Errorf("Expected 1 event, got %d", len(events))
+	}
+}
+
+func TestAvailableDiskSpace(t *testing.T) {
+	free, err := availableDiskSpace(os.TempDir())
+	if err != nil {
+		t.Skipf("Disk space check unavailable: %v", err)
+	}
+	if free == 0 {
+		t.Error("Expected non-zero free space on temp dir")
+	}
+}
+
+func TestDiskGuardCheckRunsOutsideLock(t *testing.T) {
+	config := newTestConfig(t.TempDir())
+	config.MinFreeDiskBytes = 1024
+
+	logger := NewLogger(config, "test-diskguard-slow")
+	logger.diskCheckInterval = 0
+	checking, release := make(chan struct{}), make(chan struct{})
+	logger.freeDiskSpace = func(string) (uint64, error) {
+		close(checking)
+		<-release
+		return 1 << 30, nil
+	}
+
+	done := make(chan bool)
+	go func() { done <- logger.hasDiskSpace() }()
+	<-checking
+
+	// While one caller waits on a slow filesystem, others use the cached state
+	returned := make(chan bool)
+	go func() { returned <- logger.hasDiskSpace() && logger.withinHardBudget(1) }()
+	select {
+	case ok := <-returned:
+		if !ok {
+			t.Error("Expected writing to continue during the check")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected other callers not to wait for the disk check")
+	}
+
+	close(release)
+	if !<-done {
+		t.Error("Expected space to be available")
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package main
+
+import (
+	"errors"
+)
+
+// availableDiskSpace is not supported on this platform, so the disk guard never suspends tracing
+func availableDiskSpace(path string) (uint64, error) {
+	return 0, errors.New("disk space check not supported on this platform")
+}
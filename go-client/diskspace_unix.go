@@ -0,0 +1,16 @@
+//go:build linux || darwin || freebsd
+
+package main
+
+import (
+	"syscall"
+)
+
+// availableDiskSpace returns the bytes available to unprivileged users on the filesystem containing path
+func availableDiskSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+)
+
+// DNSInfo is the outcome of the lookup for a request's hostname. Requests sent
+// on a reused connection, or to an IP address, make no lookup and have none.
+type DNSInfo struct {
+	Host      string   `json:"host"`
+	Addresses []string `json:"addresses"`
+	Coalesced bool     `json:"coalesced,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// dnsRecorder keeps the latest lookup made while sending one request
+type dnsRecorder struct {
+	mu   sync.Mutex
+	host string
+	info *DNSInfo
+}
+
+// withDNSTrace attaches hooks recording the resolved addresses of the host
+func withDNSTrace(req *http.Request) (*http.Request, *dnsRecorder) {
+	recorder := &dnsRecorder{}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			recorder.mu.Lock()
+			recorder.host = info.Host
+			recorder.mu.Unlock()
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			recorder.mu.Lock()
+			defer recorder.mu.Unlock()
+
+			dns := &DNSInfo{
+				Host:      recorder.host,
+				Addresses: make([]string, 0, len(info.Addrs)),
+				Coalesced: info.Coalesced,
+			}
+			for _, addr := range info.Addrs {
+				dns.Addresses = append(dns.Addresses, addr.IP.String())
+			}
+			if info.Err != nil {
+				dns.Error = info.Err.Error()
+			}
+			recorder.info = dns
+		},
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), recorder
+}
+
+// result returns the recorded lookup, nil when none was made
+func (r *dnsRecorder) result() *DNSInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.info
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDNSAddressesRecorded(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-dns-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.EnableHTTPTrace = true
+	client := NewTracingHTTPClientWithConfig("test-dns", config)
+
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	resp, err := client.Get("http://localhost:" + port + "/")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 1 {
+		t.Fatalf("Expected one response event, got %d", len(responses))
+	}
+	dns, ok := responses[0]["dns"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected a dns field, got %v", responses[0]["dns"])
+	}
+	if dns["host"] != "localhost" {
+		t.Errorf("Expected host localhost, got %v", dns["host"])
+	}
+
+	addresses, _ := dns["addresses"].([]interface{})
+	if len(addresses) == 0 {
+		t.Fatal("Expected resolved addresses")
+	}
+	for _, address := range addresses {
+		ip := net.ParseIP(address.(string))
+		if ip == nil || !ip.IsLoopback() {
+			t.Errorf("Expected a loopback address, got %v", address)
+		}
+	}
+}
+
+func TestDNSNotRecordedWithoutHTTPTrace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-dns-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	client := NewTracingHTTPClientWithConfig("test-dns-off", newTestConfig(tempDir))
+
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	resp, err := client.Get("http://localhost:" + port + "/")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 1 || responses[0]["dns"] != nil {
+		t.Errorf("Expected no dns field without EnableHTTPTrace, got %v", responses)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// effectiveRequestRecorder collects the header fields written by the transport
+type effectiveRequestRecorder struct {
+	mu      sync.Mutex
+	headers http.Header
+	wrote   bool
+	wroteAt time.Time
+}
+
+// withEffectiveRequestTrace attaches hooks that record the request as it is
+// written to the wire
+func withEffectiveRequestTrace(req *http.Request) (*http.Request, *effectiveRequestRecorder) {
+	recorder := &effectiveRequestRecorder{headers: make(http.Header)}
+
+	trace := &httptrace.ClientTrace{
+		WroteHeaderField: func(key string, value []string) {
+			recorder.mu.Lock()
+			for _, v := range value {
+				recorder.headers.Add(key, v)
+			}
+			recorder.mu.Unlock()
+		},
+		WroteHeaders: func() {
+			recorder.mu.Lock()
+			recorder.wrote = true
+			recorder.wroteAt = time.Now()
+			recorder.mu.Unlock()
+		},
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), recorder
+}
+
+// requestHop returns how many redirects preceded req and the URL it was redirected from
+func requestHop(req *http.Request) (int, string) {
+	hop := 0
+	for resp := req.Response; resp != nil && resp.Request != nil; resp = resp.Request.Response {
+		hop++
+	}
+
+	if req.Response != nil && req.Response.Request != nil {
+		return hop, req.Response.Request.URL.String()
+	}
+	return hop, ""
+}
+
+// LogHTTPEffectiveRequest logs the request a hop actually sent. Nothing is
+// logged when the transport never wrote the request headers.
+func (l *Logger) LogHTTPEffectiveRequest(requestID string, req *http.Request, recorder *effectiveRequestRecorder) error {
+	if !l.config.Enabled {
+		return nil
+	}
+
+	recorder.mu.Lock()
+	wrote, wroteAt := recorder.wrote, recorder.wroteAt
+	headers := flattenHeaders(recorder.headers)
+	recorder.mu.Unlock()
+
+	if !wrote {
+		return nil
+	}
+
+	hop, redirectedFrom := requestHop(req)
+
+	event := HTTPEffectiveRequestEvent{
+		Type:           "http_request_effective",
+		Timestamp:      wroteAt.UnixMilli(),
+		SessionID:      l.sessionID,
+		RequestID:      requestID,
+		Hop:            hop,
+		Method:         req.Method,
+		URL:            l.redactURL(req.URL.String()),
+		Headers:        l.sanitizeHeaders(headers),
+		RedirectedFrom: l.redactURL(redirectedFrom),
+	}
+
+	return l.writeEvent(event)
+}
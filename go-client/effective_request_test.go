@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestEffectiveRequestAfterRedirectAndCookies(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-effective-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc123", Path: "/"})
+			http.Redirect(w, r, "/dashboard", http.StatusFound)
+		case "/dashboard":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.CaptureEffectiveRequests = true
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := NewLogger(config, "test-effective")
+	client := WrapClient(&http.Client{Jar: jar}, logger, config, "test-effective")
+
+	resp, err := client.Get(server.URL + "/login")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	logger.Close()
+
+	effective := eventsOfType(readSessionEvents(t, tempDir), "http_request_effective")
+	if len(effective) != 2 {
+		t.Fatalf("Expected 2 effective request events, got %d", len(effective))
+	}
+
+	first, final := effective[0], effective[1]
+	if first["hop"] != float64(0) || first["url"] != server.URL+"/login" {
+		t.Errorf("Unexpected first hop: %v", first)
+	}
+	if first["redirected_from"] != nil {
+		t.Errorf("Expected no redirected_from on first hop, got %v", first["redirected_from"])
+	}
+
+	if final["hop"] != float64(1) {
+		t.Errorf("Expected final hop 1, got %v", final["hop"])
+	}
+	if final["url"] != server.URL+"/dashboard" {
+		t.Errorf("Expected final URL %s/dashboard, got %v", server.URL, final["url"])
+	}
+	if final["redirected_from"] != server.URL+"/login" {
+		t.Errorf("Expected redirected_from %s/login, got %v", server.URL, final["redirected_from"])
+	}
+
+	headers, _ := final["headers"].(map[string]interface{})
+	if headers["Cookie"] != "session=abc123" {
+		t.Errorf("Expected jar cookie on final hop, got headers %v", headers)
+	}
+	if headers["Host"] == nil || headers["User-Agent"] == nil {
+		t.Errorf("Expected transport-written Host and User-Agent headers, got %v", headers)
+	}
+}
+
+func TestEffectiveRequestDisabledByDefault(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-effective-off-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-effective-off", newTestConfig(tempDir))
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	if got := len(eventsOfType(readSessionEvents(t, tempDir), "http_request_effective")); got != 0 {
+		t.Errorf("Expected no effective request events by default, got %d", got)
+	}
+}
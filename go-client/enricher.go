@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// EventEnricher computes custom fields for a request at request time. The
+// returned fields are added to the request event and to its response event.
+type EventEnricher func(req *http.Request) map[string]interface{}
+
+// Fields of the request and response events, which enrichment never overwrites
+var (
+	requestEventFields  = jsonFieldNames(reflect.TypeOf(HTTPRequestEvent{}))
+	responseEventFields = jsonFieldNames(reflect.TypeOf(HTTPResponseEvent{}))
+)
+
+// WithEventEnricher sets the enricher called for each traced request and
+// returns the round tripper
+func (t *TracingRoundTripper) WithEventEnricher(enricher EventEnricher) *TracingRoundTripper {
+	t.enricher = enricher
+	return t
+}
+
+// WithEventEnricher sets the enricher called for each traced request and
+// returns the client
+func (t *TracingHTTPClient) WithEventEnricher(enricher EventEnricher) *TracingHTTPClient {
+	if roundTripper, ok := t.client.Transport.(*TracingRoundTripper); ok {
+		roundTripper.WithEventEnricher(enricher)
+	}
+	return t
+}
+
+// jsonFieldNames returns the JSON names of the fields of a struct type
+func jsonFieldNames(structType reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < structType.NumField(); i++ {
+		tag := strings.Split(structType.Field(i).Tag.Get("json"), ",")[0]
+		if tag != "" && tag != "-" {
+			names[tag] = true
+		}
+	}
+	return names
+}
+
+// marshalWithExtra marshals event and adds the extra fields whose names are
+// not reserved. Extra values that cannot be marshaled are dropped.
+func marshalWithExtra(event interface{}, extra map[string]interface{}, reserved map[string]bool) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for key, value := range extra {
+		if reserved[key] {
+			continue
+		}
+		if raw, err := json.Marshal(value); err == nil {
+			fields[key] = raw
+		}
+	}
+
+	return json.Marshal(fields)
+}
+
+// MarshalJSON adds enrichment fields to the request event
+func (e HTTPRequestEvent) MarshalJSON() ([]byte, error) {
+	type plain HTTPRequestEvent
+	return marshalWithExtra(plain(e), e.Extra, requestEventFields)
+}
+
+// MarshalJSON adds enrichment fields to the response event
+func (e HTTPResponseEvent) MarshalJSON() ([]byte, error) {
+	type plain HTTPResponseEvent
+	return marshalWithExtra(plain(e), e.Extra, responseEventFields)
+}
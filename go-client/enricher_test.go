@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestEventEnricher(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-enricher-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-enricher", newTestConfig(tempDir))
+	client.WithEventEnricher(func(req *http.Request) map[string]interface{} {
+		return map[string]interface{}{
+			"tenant":     req.Header.Get("X-Tenant"),
+			"type":       "spoofed",
+			"timestamp":  0,
+			"session_id": "spoofed",
+		}
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("X-Tenant", "acme")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	events := readSessionEvents(t, tempDir)
+	requests := eventsOfType(events, "http_request")
+	responses := eventsOfType(events, "http_response")
+	if len(requests) != 1 || len(responses) != 1 {
+		t.Fatalf("Expected 1 request and 1 response event, got %d and %d", len(requests), len(responses))
+	}
+
+	for _, event := range []map[string]interface{}{requests[0], responses[0]} {
+		if event["tenant"] != "acme" {
+			t.Errorf("Expected tenant acme on %v event, got %v", event["type"], event["tenant"])
+		}
+		if event["session_id"] != "test-enricher" {
+			t.Errorf("Expected session_id not to be overwritten, got %v", event["session_id"])
+		}
+		if timestamp, _ := event["timestamp"].(float64); timestamp == 0 {
+			t.Error("Expected timestamp not to be overwritten")
+		}
+	}
+	if requests[0]["request_id"] != responses[0]["request_id"] {
+		t.Error("Expected request and response events to share a request ID")
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+// EventSink receives every event the logger writes, as the JSON line stored in
+// the session file. WriteEvent is called on the write path, which may be the
+// request path, so it must return quickly and must not keep line.
+type EventSink interface {
+	WriteEvent(line []byte)
+}
+
+// AddEventSink registers sink to receive events written from now on
+func (l *Logger) AddEventSink(sink EventSink) {
+	l.sinksMu.Lock()
+	defer l.sinksMu.Unlock()
+	l.sinks = append(l.sinks, sink)
+}
+
+// RemoveEventSink stops delivering events to sink
+func (l *Logger) RemoveEventSink(sink EventSink) {
+	l.sinksMu.Lock()
+	defer l.sinksMu.Unlock()
+
+	sinks := make([]EventSink, 0, len(l.sinks))
+	for _, registered := range l.sinks {
+		if registered != sink {
+			sinks = append(sinks, registered)
+		}
+	}
+	l.sinks = sinks
+}
+
+// notifySinks hands a written event to every registered sink
+func (l *Logger) notifySinks(line []byte) {
+	l.sinksMu.RLock()
+	defer l.sinksMu.RUnlock()
+
+	for _, sink := range l.sinks {
+		sink.WriteEvent(line)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"sync"
+	"time"
+)
+
+// expectContinueRecorder times the wait between sending the headers of an
+// Expect: 100-continue request and receiving 100 Continue
+type expectContinueRecorder struct {
+	mu      sync.Mutex
+	started time.Time
+	ended   time.Time
+}
+
+// withExpectContinueTrace attaches hooks that time the 100-continue handshake.
+// If the server answers without 100 Continue, the wait ends at the first
+// response byte.
+func withExpectContinueTrace(req *http.Request) (*http.Request, *expectContinueRecorder) {
+	recorder := &expectContinueRecorder{}
+
+	trace := &httptrace.ClientTrace{
+		Wait100Continue: func() {
+			recorder.mu.Lock()
+			recorder.started = time.Now()
+			recorder.mu.Unlock()
+		},
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			if code == http.StatusContinue {
+				recorder.finish()
+			}
+			return nil
+		},
+		GotFirstResponseByte: func() {
+			recorder.finish()
+		},
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), recorder
+}
+
+// finish marks the end of the wait unless it has already ended
+func (r *expectContinueRecorder) finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.started.IsZero() && r.ended.IsZero() {
+		r.ended = time.Now()
+	}
+}
+
+// wait returns the time spent waiting and whether the transport waited at all
+func (r *expectContinueRecorder) wait() (time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.started.IsZero() || r.ended.IsZero() {
+		return 0, false
+	}
+	return r.ended.Sub(r.started), true
+}
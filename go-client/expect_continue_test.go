@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpectContinueTiming(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-expect-continue-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	upload := strings.Repeat("payload-", 512)
+
+	// net/http sends 100 Continue when the handler first reads the body
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Expect") != "100-continue" {
+			t.Errorf("Expected Expect: 100-continue, got %q", r.Header.Get("Expect"))
+		}
+		time.Sleep(50 * time.Millisecond)
+
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != upload {
+			t.Errorf("Server received %d bytes, expected %d", len(body), len(upload))
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.MaxBodySize = int64(len(upload))
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ExpectContinueTimeout = 5 * time.Second
+
+	logger := NewLogger(config, "test-expect-continue")
+	client := WrapClient(&http.Client{Transport: transport}, logger, config, "test-expect-continue")
+
+	req, err := http.NewRequest("PUT", server.URL+"/upload", bytes.NewReader([]byte(upload)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Expect", "100-continue")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	logger.Close()
+
+	events := readSessionEvents(t, tempDir)
+
+	requests := eventsOfType(events, "http_request")
+	if len(requests) != 1 || requests[0]["body"] != upload {
+		t.Fatalf("Expected the uploaded body to be logged, got %v", requests)
+	}
+
+	responses := eventsOfType(events, "http_response")
+	if len(responses) != 1 {
+		t.Fatalf("Expected 1 response event, got %d", len(responses))
+	}
+	wait, ok := responses[0]["wait_100_continue_ms"].(float64)
+	if !ok {
+		t.Fatalf("Expected wait_100_continue_ms on response, got %v", responses[0])
+	}
+	if wait < 40 {
+		t.Errorf("Expected wait of at least the handler delay, got %vms", wait)
+	}
+}
+
+func TestNoExpectContinueTimingWithoutHeader(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-no-expect-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-no-expect", newTestConfig(tempDir))
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("data"))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 1 {
+		t.Fatalf("Expected 1 response event, got %d", len(responses))
+	}
+	if _, ok := responses[0]["wait_100_continue_ms"]; ok {
+		t.Error("Expected no wait_100_continue_ms without Expect: 100-continue")
+	}
+}
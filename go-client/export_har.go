@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HAR format constants
+const (
+	harVersion        = "1.2"
+	harCreatorName    = "opencode-trace-go-client"
+	harCreatorVersion = "1.0"
+	harHTTPVersion    = "HTTP/1.1"
+)
+
+// harDocument mirrors the HAR 1.2 top-level object
+type harDocument struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            int64       `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	RequestID       string      `json:"_requestId,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	Cookies     []harNameValue `json:"cookies"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int64          `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	Cookies     []harNameValue `json:"cookies"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+}
+
+type harTimings struct {
+	Send    int64 `json:"send"`
+	Wait    int64 `json:"wait"`
+	Receive int64 `json:"receive"`
+}
+
+// ExportHAR converts a session file into a HAR document
+func ExportHAR(sessionFile string, w io.Writer) error {
+	return ExportHARFromStore(&FileSystemStore{}, sessionFile, w)
+}
+
+// ExportHARFromStore converts a session held in store into a HAR document
+func ExportHARFromStore(store SessionStore, name string, w io.Writer) error {
+	events, err := ReadSession(store, name)
+	if err != nil {
+		return err
+	}
+	return writeHAR(events, w)
+}
+
+// ExportHARMerged converts every session file under a session directory into a
+// single HAR document. Files from the TUI wrapper's injector and from the
+// go-client are merged chronologically and their schemas reconciled.
+func ExportHARMerged(sessionDir string, w io.Writer) error {
+	paths, err := findSessionFiles(sessionDir)
+	if err != nil {
+		return err
+	}
+
+	events, err := mergeSessionFiles(paths)
+	if err != nil {
+		return err
+	}
+	return writeHAR(events, w)
+}
+
+// writeHAR encodes the exchanges of a session as a HAR document, with entries
+// in the order their requests were logged
+func writeHAR(events []map[string]interface{}, w io.Writer) error {
+	entries := []harEntry{}
+	for _, exchange := range GroupExchanges(events) {
+		entries = append(entries, exchangeToHAREntry(exchange, time.UTC))
+	}
+
+	document := harDocument{Log: harLog{
+		Version: harVersion,
+		Creator: harCreator{Name: harCreatorName, Version: harCreatorVersion},
+		Entries: entries,
+	}}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("failed to encode HAR: %w", err)
+	}
+	return nil
+}
+
+// exchangeToHAREntry converts a request exchange into a HAR entry, with its
+// start time rendered in location
+func exchangeToHAREntry(exchange *Exchange, location *time.Location) harEntry {
+	request := exchange.Request
+	rawURL := eventString(request, "url")
+
+	entry := harEntry{
+		StartedDateTime: formatTimestamp(eventInt64(request, "timestamp"), location),
+		RequestID:       exchange.RequestID,
+		Request: harRequest{
+			Method:      eventString(request, "method"),
+			URL:         rawURL,
+			HTTPVersion: harHTTPVersion,
+			Headers:     harHeaders(request),
+			QueryString: harQueryString(rawURL),
+			Cookies:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Response: harResponse{
+			HTTPVersion: harHTTPVersion,
+			Headers:     []harNameValue{},
+			Cookies:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+	}
+
+	if body := eventString(request, "body"); body != "" {
+		entry.Request.PostData = &harPostData{
+			MimeType: eventString(request, "content_type"),
+			Text:     body,
+		}
+		entry.Request.BodySize = int64(len(body))
+	}
+
+	if response := exchange.Response; response != nil {
+		status := eventString(response, "status")
+		statusCode := eventInt64(response, "status_code")
+
+		entry.Time = eventInt64(response, "duration_ms")
+		entry.Timings.Wait = entry.Time
+		entry.Response.Status = statusCode
+		entry.Response.StatusText = strings.TrimSpace(strings.TrimPrefix(status, fmt.Sprint(statusCode)))
+		entry.Response.Headers = harHeaders(response)
+		entry.Response.BodySize = eventInt64(response, "response_size")
+		entry.Response.Content = harContent{
+			Size:     eventInt64(response, "response_size"),
+			MimeType: eventString(response, "content_type"),
+			Text:     eventString(response, "body"),
+		}
+		entry.Response.RedirectURL = harHeaderValue(response, "Location")
+	}
+
+	for _, errorEvent := range exchange.Errors {
+		if details, ok := errorEvent["error"].(map[string]interface{}); ok {
+			entry.Error, _ = details["message"].(string)
+		}
+	}
+
+	return entry
+}
+
+// harHeaders converts an event's header map into sorted HAR name/value pairs
+func harHeaders(event map[string]interface{}) []harNameValue {
+	headers := []harNameValue{}
+	raw, _ := event["headers"].(map[string]interface{})
+	for name, value := range raw {
+		if text, ok := value.(string); ok {
+			headers = append(headers, harNameValue{Name: name, Value: text})
+		}
+	}
+
+	sort.Slice(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+	return headers
+}
+
+// harHeaderValue returns a header of an event, matched case-insensitively
+func harHeaderValue(event map[string]interface{}, name string) string {
+	raw, _ := event["headers"].(map[string]interface{})
+	for key, value := range raw {
+		if strings.EqualFold(key, name) {
+			text, _ := value.(string)
+			return text
+		}
+	}
+	return ""
+}
+
+// harQueryString extracts the query parameters of a URL
+func harQueryString(rawURL string) []harNameValue {
+	query := []harNameValue{}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return query
+	}
+
+	values := parsed.Query()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, value := range values[name] {
+			query = append(query, harNameValue{Name: name, Value: value})
+		}
+	}
+	return query
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeJSONL writes events as a JSONL file
+func writeJSONL(t *testing.T, path string, events ...string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(events, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportHARMerged(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-har-merged-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	sessionsDir := filepath.Join(tempDir, "sessions")
+	sessionDir := filepath.Join(sessionsDir, "tui-1")
+
+	// The TUI wrapper's injector: no request_id, errors as http_error
+	writeJSONL(t, filepath.Join(sessionDir, "session.jsonl"),
+		`{"type":"http_request","timestamp":1000,"session_id":"tui-1","method":"GET","url":"https://api.example.com/a","headers":{"Accept":"*/*"}}`,
+		`{"type":"http_response","timestamp":1100,"session_id":"tui-1","status_code":200,"status":"200 OK","headers":{"Content-Type":"application/json"},"body":"{\"a\":1}","content_type":"application/json","response_size":7,"duration_ms":100,"success":true}`,
+		`{"type":"http_request","timestamp":1300,"session_id":"tui-1","method":"POST","url":"https://api.example.com/c","headers":{},"body":"{\"c\":3}","content_type":"application/json"}`,
+		`{"type":"http_error","timestamp":1350,"session_id":"tui-1","method":"POST","url":"https://api.example.com/c","headers":{},"body":"Error: connection refused"}`,
+	)
+
+	// The go-client: timestamped file next to the session directory
+	writeJSONL(t, filepath.Join(sessionsDir, "2025-01-15_14-30-45_session-tui-1.jsonl"),
+		`{"type":"http_request","timestamp":1050,"session_id":"tui-1","request_id":"req-b","method":"GET","url":"https://api.example.com/b?x=1&y=2","headers":{"authorization":"[REDACTED]"}}`,
+		`{"type":"http_response","timestamp":1200,"session_id":"tui-1","request_id":"req-b","status_code":404,"status":"404 Not Found","headers":{},"response_size":0,"duration_ms":150,"success":false}`,
+	)
+
+	// Files from other sessions must not be picked up
+	writeJSONL(t, filepath.Join(sessionsDir, "2025-01-15_14-30-45_session-other.jsonl"),
+		`{"type":"http_request","timestamp":1010,"session_id":"other","request_id":"req-x","method":"GET","url":"https://api.example.com/x","headers":{}}`,
+	)
+
+	var buf bytes.Buffer
+	if err := ExportHARMerged(sessionDir, &buf); err != nil {
+		t.Fatalf("ExportHARMerged failed: %v", err)
+	}
+
+	var har harDocument
+	if err := json.Unmarshal(buf.Bytes(), &har); err != nil {
+		t.Fatalf("Output is not valid HAR JSON: %v", err)
+	}
+	if har.Log.Version != "1.2" {
+		t.Errorf("Expected HAR version 1.2, got %q", har.Log.Version)
+	}
+
+	entries := har.Log.Entries
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+
+	expectedURLs := []string{"https://api.example.com/a", "https://api.example.com/b?x=1&y=2", "https://api.example.com/c"}
+	for i, entry := range entries {
+		if entry.Request.URL != expectedURLs[i] {
+			t.Errorf("Entry %d: expected %s, got %s", i, expectedURLs[i], entry.Request.URL)
+		}
+	}
+
+	if entries[0].Response.Status != 200 || entries[0].Response.StatusText != "OK" || entries[0].Response.Content.Text != `{"a":1}` {
+		t.Errorf("Unexpected injector response: %+v", entries[0].Response)
+	}
+	if entries[0].Time != 100 {
+		t.Errorf("Expected entry time 100, got %d", entries[0].Time)
+	}
+
+	if entries[1].Response.Status != 404 || entries[1].RequestID != "req-b" {
+		t.Errorf("Unexpected go-client entry: %+v", entries[1])
+	}
+	if len(entries[1].Request.QueryString) != 2 || entries[1].Request.QueryString[0].Name != "x" {
+		t.Errorf("Expected query string x and y, got %v", entries[1].Request.QueryString)
+	}
+
+	if entries[2].Error != "connection refused" {
+		t.Errorf("Expected injector http_error to become the entry error, got %q", entries[2].Error)
+	}
+	if entries[2].Request.PostData == nil || entries[2].Request.PostData.Text != `{"c":3}` {
+		t.Errorf("Expected POST body on entry, got %+v", entries[2].Request.PostData)
+	}
+	if entries[2].Response.Status != 0 {
+		t.Errorf("Expected no response for failed request, got status %d", entries[2].Response.Status)
+	}
+}
+
+func TestMergeSessionFilesSortsUnorderedFiles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// A concurrent request logged after a later one started, as the tracer writes them
+	first := filepath.Join(tempDir, "a.jsonl")
+	writeJSONL(t, first,
+		`{"type":"http_request","timestamp":1200,"session_id":"s","request_id":"req-2"}`,
+		`{"type":"http_request","timestamp":1000,"session_id":"s","request_id":"req-1"}`,
+		`{"type":"http_response","timestamp":1500,"session_id":"s","request_id":"req-1"}`,
+		`{"type":"http_response","timestamp":1300,"session_id":"s","request_id":"req-2"}`,
+	)
+	second := filepath.Join(tempDir, "b.jsonl")
+	writeJSONL(t, second,
+		`{"type":"http_request","timestamp":1400,"session_id":"s","request_id":"req-4"}`,
+		`{"type":"http_request","timestamp":1100,"session_id":"s","request_id":"req-3"}`,
+		`{"type":"http_request","timestamp":1500,"session_id":"s","request_id":"req-5"}`,
+	)
+
+	events, err := mergeSessionFiles([]string{first, second})
+	if err != nil {
+		t.Fatalf("mergeSessionFiles failed: %v", err)
+	}
+
+	var order []string
+	for _, event := range events {
+		order = append(order, eventString(event, "request_id"))
+	}
+	want := "req-1 req-3 req-2 req-2 req-4 req-1 req-5"
+	if got := strings.Join(order, " "); got != want {
+		t.Errorf("Expected events in timestamp order %q, got %q", want, got)
+	}
+}
+
+func TestExportHARCommand(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-har-cmd-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	sessionFile := filepath.Join(tempDir, "session.jsonl")
+	writeJSONL(t, sessionFile,
+		`{"type":"http_request","timestamp":1000,"session_id":"s","request_id":"r1","method":"GET","url":"https://api.example.com/","headers":{}}`,
+		`{"type":"http_response","timestamp":1010,"session_id":"s","request_id":"r1","status_code":200,"status":"200 OK","headers":{},"response_size":0,"duration_ms":10,"success":true}`,
+	)
+
+	for _, target := range []string{sessionFile, tempDir} {
+		var stdout, stderr bytes.Buffer
+		if code := runCommand([]string{"export-har", target}, &stdout, &stderr); code != 0 {
+			t.Fatalf("export-har %s exited %d: %s", target, code, stderr.String())
+		}
+
+		var har harDocument
+		if err := json.Unmarshal(stdout.Bytes(), &har); err != nil {
+			t.Fatalf("Output is not valid HAR JSON: %v", err)
+		}
+		if len(har.Log.Entries) != 1 {
+			t.Errorf("export-har %s: expected 1 entry, got %d", target, len(har.Log.Entries))
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// OTLP span kind and status codes
+const (
+	otlpSpanKindClient  = 3
+	otlpStatusUnset     = 0
+	otlpStatusError     = 2
+	otlpScopeName       = "opencode-trace-go-client"
+	otlpScopeVersion    = "1.0"
+	otlpServiceName     = "opencode-trace"
+	otlpExceptionEvent  = "exception"
+	otlpDefaultSpanName = "HTTP"
+)
+
+// otlpTracesData mirrors the OTLP/JSON ExportTraceServiceRequest
+type otlpTracesData struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+// otlpString builds a string attribute
+func otlpString(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+// otlpInt builds an integer attribute (int64 values are strings in OTLP/JSON)
+func otlpInt(key string, value int64) otlpKeyValue {
+	encoded := strconv.FormatInt(value, 10)
+	return otlpKeyValue{Key: key, Value: otlpValue{IntValue: &encoded}}
+}
+
+// ExportOTLP converts a session file into OTLP/JSON ResourceSpans with one span per request
+func ExportOTLP(sessionFile string, w io.Writer) error {
+	return ExportOTLPFromStore(&FileSystemStore{}, sessionFile, w)
+}
+
+// ExportOTLPFromStore is ExportOTLP for a session held in store
+func ExportOTLPFromStore(store SessionStore, name string, w io.Writer) error {
+	events, err := ReadSession(store, name)
+	if err != nil {
+		return err
+	}
+
+	sessionID := ""
+	if len(events) > 0 {
+		sessionID = eventString(events[0], "session_id")
+	}
+
+	var spans []otlpSpan
+	for i, exchange := range GroupExchanges(events) {
+		spans = append(spans, exchangeToSpan(exchange, sessionID, i))
+	}
+	if spans == nil {
+		spans = []otlpSpan{}
+	}
+
+	data := otlpTracesData{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{Attributes: []otlpKeyValue{
+				otlpString("service.name", otlpServiceName),
+				otlpString("session.id", sessionID),
+			}},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: otlpScopeName, Version: otlpScopeVersion},
+				Spans: spans,
+			}},
+		}},
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		return fmt.Errorf("failed to encode OTLP data: %w", err)
+	}
+	return nil
+}
+
+// exchangeToSpan converts a request exchange into a client span
+func exchangeToSpan(exchange *Exchange, sessionID string, index int) otlpSpan {
+	request := exchange.Request
+	method := eventString(request, "method")
+
+	// Span ID from the request ID, trace ID from the operation (or the session)
+	spanSource := exchange.RequestID
+	if spanSource == "" {
+		spanSource = fmt.Sprintf("%s/%d", sessionID, index)
+	}
+	traceSource := eventString(request, "operation_id")
+	if traceSource == "" {
+		traceSource = sessionID
+	}
+
+	startMillis := eventInt64(request, "timestamp")
+	endMillis := startMillis
+
+	attributes := []otlpKeyValue{
+		otlpString("http.request.method", method),
+	}
+	if rawURL := eventString(request, "url"); rawURL != "" {
+		attributes = append(attributes, otlpString("url.full", rawURL))
+		if parsed, err := url.Parse(rawURL); err == nil {
+			if parsed.Scheme != "" {
+				attributes = append(attributes, otlpString("url.scheme", parsed.Scheme))
+			}
+			if parsed.Hostname() != "" {
+				attributes = append(attributes, otlpString("server.address", parsed.Hostname()))
+			}
+			if port := parsed.Port(); port != "" {
+				if portNum, err := strconv.ParseInt(port, 10, 64); err == nil {
+					attributes = append(attributes, otlpInt("server.port", portNum))
+				}
+			}
+			attributes = append(attributes, otlpString("url.path", parsed.Path))
+		}
+	}
+	if userAgent := eventString(request, "user_agent"); userAgent != "" {
+		attributes = append(attributes, otlpString("user_agent.original", userAgent))
+	}
+	if exchange.RequestID != "" {
+		attributes = append(attributes, otlpString("opencode.request_id", exchange.RequestID))
+	}
+
+	status := otlpStatus{Code: otlpStatusUnset}
+	if response := exchange.Response; response != nil {
+		statusCode := eventInt64(response, "status_code")
+		duration := eventInt64(response, "duration_ms")
+		endMillis = startMillis + duration
+
+		attributes = append(attributes,
+			otlpInt("http.response.status_code", statusCode),
+			otlpInt("http.response.body.size", eventInt64(response, "response_size")),
+			otlpInt("http.duration_ms", duration),
+		)
+		if statusCode >= 400 {
+			status = otlpStatus{Code: otlpStatusError}
+		}
+	}
+
+	var spanEvents []otlpEvent
+	for _, errorEvent := range exchange.Errors {
+		message, errContext := "", ""
+		if details, ok := errorEvent["error"].(map[string]interface{}); ok {
+			message, _ = details["message"].(string)
+			errContext, _ = details["context"].(string)
+		}
+
+		errorMillis := eventInt64(errorEvent, "timestamp")
+		if errorMillis > endMillis {
+			endMillis = errorMillis
+		}
+
+		spanEvents = append(spanEvents, otlpEvent{
+			TimeUnixNano: millisToNanos(errorMillis),
+			Name:         otlpExceptionEvent,
+			Attributes: []otlpKeyValue{
+				otlpString("exception.type", errContext),
+				otlpString("exception.message", message),
+			},
+		})
+		status = otlpStatus{Code: otlpStatusError, Message: message}
+	}
+
+	name := method
+	if name == "" {
+		name = otlpDefaultSpanName
+	}
+
+	return otlpSpan{
+		TraceID:           otlpID(traceSource, 16),
+		SpanID:            otlpID(spanSource, 8),
+		Name:              name,
+		Kind:              otlpSpanKindClient,
+		StartTimeUnixNano: millisToNanos(startMillis),
+		EndTimeUnixNano:   millisToNanos(endMillis),
+		Attributes:        attributes,
+		Events:            spanEvents,
+		Status:            status,
+	}
+}
+
+// otlpID derives a hex ID of the given byte length. IDs that are already hex
+// (such as UUIDs) are used directly; anything else is hashed.
+func otlpID(source string, size int) string {
+	compact := strings.ReplaceAll(strings.ToLower(source), "-", "")
+	if decoded, err := hex.DecodeString(compact); err == nil && len(decoded) >= size {
+		return hex.EncodeToString(decoded[:size])
+	}
+
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:size])
+}
+
+// millisToNanos formats epoch milliseconds as an OTLP nanosecond timestamp
+func millisToNanos(millis int64) string {
+	return strconv.FormatInt(millis*1_000_000, 10)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// findSessionFile returns the single session file written under dir
+func findSessionFile(t *testing.T, dir string) string {
+	t.Helper()
+
+	matches, err := filepath.Glob(filepath.Join(dir, "sessions", "*.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) == 0 {
+		t.Fatal("No session files found")
+	}
+	return matches[0]
+}
+
+func TestExportOTLP(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-otlp-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"ok": true}`))
+	}))
+
+	client := NewTracingHTTPClientWithConfig("test-otlp", newTestConfig(tempDir))
+	for _, path := range []string{"/ok", "/missing"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// A transport error becomes an exception event
+	deadURL := server.URL
+	server.Close()
+	if resp, err := client.Get(deadURL + "/down"); err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected request to a closed server to fail")
+	}
+	client.Close()
+
+	var out bytes.Buffer
+	if err := ExportOTLP(findSessionFile(t, tempDir), &out); err != nil {
+		t.Fatalf("ExportOTLP failed: %v", err)
+	}
+
+	var data struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []map[string]interface{} `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Scope struct {
+					Name    string `json:"name"`
+					Version string `json:"version"`
+				} `json:"scope"`
+				Spans []struct {
+					TraceID           string                   `json:"traceId"`
+					SpanID            string                   `json:"spanId"`
+					Name              string                   `json:"name"`
+					Kind              int                      `json:"kind"`
+					StartTimeUnixNano string                   `json:"startTimeUnixNano"`
+					EndTimeUnixNano   string                   `json:"endTimeUnixNano"`
+					Attributes        []map[string]interface{} `json:"attributes"`
+					Events            []struct {
+						TimeUnixNano string                   `json:"timeUnixNano"`
+						Name         string                   `json:"name"`
+						Attributes   []map[string]interface{} `json:"attributes"`
+					} `json:"events"`
+					Status struct {
+						Code    int    `json:"code"`
+						Message string `json:"message"`
+					} `json:"status"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	decoder := json.NewDecoder(&out)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&data); err != nil {
+		t.Fatalf("Output is not valid OTLP JSON: %v", err)
+	}
+
+	if len(data.ResourceSpans) != 1 || len(data.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("Expected one resource with one scope, got %+v", data)
+	}
+	scope := data.ResourceSpans[0].ScopeSpans[0]
+	if scope.Scope.Name == "" {
+		t.Error("Expected instrumentation scope name")
+	}
+
+	spans := scope.Spans
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+
+	seenSpanIDs := make(map[string]bool)
+	for i, span := range spans {
+		if traceID, err := hex.DecodeString(span.TraceID); err != nil || len(traceID) != 16 {
+			t.Errorf("Span %d: invalid traceId %q", i, span.TraceID)
+		}
+		if spanID, err := hex.DecodeString(span.SpanID); err != nil || len(spanID) != 8 {
+			t.Errorf("Span %d: invalid spanId %q", i, span.SpanID)
+		}
+		if seenSpanIDs[span.SpanID] {
+			t.Errorf("Span %d: duplicate spanId %s", i, span.SpanID)
+		}
+		seenSpanIDs[span.SpanID] = true
+
+		if span.TraceID != spans[0].TraceID {
+			t.Errorf("Span %d: expected spans of one session to share a trace", i)
+		}
+		if span.Kind != otlpSpanKindClient || span.Name != "GET" {
+			t.Errorf("Span %d: expected client GET span, got kind %d name %s", i, span.Kind, span.Name)
+		}
+
+		start, err := strconv.ParseInt(span.StartTimeUnixNano, 10, 64)
+		if err != nil {
+			t.Errorf("Span %d: invalid start time %q", i, span.StartTimeUnixNano)
+		}
+		end, err := strconv.ParseInt(span.EndTimeUnixNano, 10, 64)
+		if err != nil || end < start {
+			t.Errorf("Span %d: invalid end time %q", i, span.EndTimeUnixNano)
+		}
+	}
+
+	attr := func(attributes []map[string]interface{}, key string) map[string]interface{} {
+		for _, attribute := range attributes {
+			if attribute["key"] == key {
+				value, _ := attribute["value"].(map[string]interface{})
+				return value
+			}
+		}
+		return nil
+	}
+
+	if value := attr(spans[0].Attributes, "http.response.status_code"); value["intValue"] != "200" {
+		t.Errorf("Expected status code attribute 200, got %v", value)
+	}
+	if value := attr(spans[0].Attributes, "url.full"); value["stringValue"] != server.URL+"/ok" {
+		t.Errorf("Expected url.full attribute, got %v", value)
+	}
+	if spans[1].Status.Code != otlpStatusError {
+		t.Errorf("Expected 404 span to have error status, got %d", spans[1].Status.Code)
+	}
+	if spans[2].Status.Code != otlpStatusError || len(spans[2].Events) == 0 || spans[2].Events[0].Name != "exception" {
+		t.Errorf("Expected transport error as exception event, got %+v", spans[2])
+	}
+	if value := attr(spans[2].Events[0].Attributes, "exception.message"); value["stringValue"] == "" {
+		t.Error("Expected exception.message attribute")
+	}
+}
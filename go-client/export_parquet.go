@@ -0,0 +1,100 @@
+//go:build parquet
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetExchange is one row of the Parquet export: a request and its response
+type parquetExchange struct {
+	SessionID  string `parquet:"session_id"`
+	RequestID  string `parquet:"request_id"`
+	Method     string `parquet:"method"`
+	Host       string `parquet:"host"`
+	Path       string `parquet:"path"`
+	Status     int64  `parquet:"status"`
+	DurationMS int64  `parquet:"duration_ms"`
+	ReqSize    int64  `parquet:"req_size"`
+	RespSize   int64  `parquet:"resp_size"`
+	Timestamp  int64  `parquet:"timestamp,timestamp(millisecond)"`
+}
+
+// ExportParquet flattens the exchanges of the given session files into a
+// single Parquet file at out, one row per request. Bodies are not exported.
+// Files are read one at a time, so the export does not hold every session
+// in memory.
+func ExportParquet(sessionFiles []string, out string) error {
+	file, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("failed to create Parquet file: %w", err)
+	}
+	defer file.Close()
+
+	writer := parquet.NewGenericWriter[parquetExchange](file)
+	for _, sessionFile := range sessionFiles {
+		events, err := ReadSessionFile(sessionFile)
+		if err != nil {
+			return err
+		}
+
+		var rows []parquetExchange
+		for _, exchange := range GroupExchanges(events) {
+			rows = append(rows, exchangeToParquetRow(exchange))
+		}
+		if _, err := writer.Write(rows); err != nil {
+			return fmt.Errorf("failed to write %s: %w", sessionFile, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to finish Parquet file: %w", err)
+	}
+	return file.Close()
+}
+
+// exchangeToParquetRow converts a request exchange into a Parquet row
+func exchangeToParquetRow(exchange *Exchange) parquetExchange {
+	request := exchange.Request
+	row := parquetExchange{
+		SessionID: eventString(request, "session_id"),
+		RequestID: exchange.RequestID,
+		Method:    eventString(request, "method"),
+		ReqSize:   parquetRequestSize(request),
+		Timestamp: eventInt64(request, "timestamp"),
+	}
+
+	if parsed, err := url.Parse(eventString(request, "url")); err == nil {
+		row.Host = parsed.Host
+		row.Path = parsed.Path
+	}
+
+	if response := exchange.Response; response != nil {
+		row.Status = eventInt64(response, "status_code")
+		row.DurationMS = eventInt64(response, "duration_ms")
+		row.RespSize = eventInt64(response, "response_size")
+	}
+
+	return row
+}
+
+// parquetRequestSize returns the size of a request body. The stored body may
+// be cut at MaxBodySize, redacted or left out, so the declared Content-Length
+// comes first, then the size of the body before it was encoded.
+func parquetRequestSize(request map[string]interface{}) int64 {
+	if size := eventInt64(request, "request_size"); size > 0 {
+		return size
+	}
+	if length, err := strconv.ParseInt(harHeaderValue(request, "Content-Length"), 10, 64); err == nil && length > 0 {
+		return length
+	}
+	if size := eventInt64(request, "body_original_size"); size > 0 {
+		return size
+	}
+	return int64(len(eventString(request, "body")))
+}
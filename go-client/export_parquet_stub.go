@@ -0,0 +1,11 @@
+//go:build !parquet
+
+package main
+
+import "errors"
+
+// ExportParquet is only available in builds with the parquet tag, which
+// keeps the Parquet writer out of the default binary
+func ExportParquet(sessionFiles []string, out string) error {
+	return errors.New("Parquet export requires building with -tags parquet")
+}
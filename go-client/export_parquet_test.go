@@ -0,0 +1,104 @@
+//go:build parquet
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestExportParquet(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-parquet-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-parquet", newTestConfig(tempDir))
+	for _, path := range []string{"/ok", "/missing"} {
+		resp, err := client.Post(server.URL+path, "text/plain", strings.NewReader("ping"))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	out := filepath.Join(tempDir, "requests.parquet")
+	if err := ExportParquet([]string{findSessionFile(t, tempDir)}, out); err != nil {
+		t.Fatalf("ExportParquet failed: %v", err)
+	}
+
+	rows, err := parquet.ReadFile[parquetExchange](out)
+	if err != nil {
+		t.Fatalf("Failed to read Parquet file: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(rows))
+	}
+
+	serverHost := strings.TrimPrefix(server.URL, "http://")
+	for i, want := range []struct {
+		path   string
+		status int64
+	}{{"/ok", 200}, {"/missing", 404}} {
+		row := rows[i]
+		if row.SessionID != "test-parquet" || row.RequestID == "" || row.Method != http.MethodPost {
+			t.Errorf("Unexpected identifiers in row %d: %+v", i, row)
+		}
+		if row.Host != serverHost || row.Path != want.path || row.Status != want.status {
+			t.Errorf("Expected %s%s with status %d, got %+v", serverHost, want.path, want.status, row)
+		}
+		if row.ReqSize != 4 || row.RespSize == 0 || row.Timestamp == 0 {
+			t.Errorf("Expected sizes and timestamp to be set, got %+v", row)
+		}
+	}
+}
+
+func TestExportParquetRequestSizeOfTruncatedBody(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-parquet-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.MaxBodySize = 16
+	client := NewTracingHTTPClientWithConfig("test-parquet", config)
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader(strings.Repeat("x", 100)))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	out := filepath.Join(tempDir, "requests.parquet")
+	if err := ExportParquet([]string{findSessionFile(t, tempDir)}, out); err != nil {
+		t.Fatalf("ExportParquet failed: %v", err)
+	}
+	rows, err := parquet.ReadFile[parquetExchange](out)
+	if err != nil {
+		t.Fatalf("Failed to read Parquet file: %v", err)
+	}
+	if len(rows) != 1 || rows[0].ReqSize != 100 {
+		t.Errorf("Expected the full 100 byte request size, got %+v", rows)
+	}
+}
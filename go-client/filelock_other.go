@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package main
+
+import (
+	"os"
+)
+
+// lockFile is a no-op where advisory locks are unavailable; appends rely on O_APPEND alone
+func lockFile(file *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op where advisory locks are unavailable
+func unlockFile(file *os.File) error {
+	return nil
+}
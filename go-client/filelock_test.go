@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	appendHelperEnv    = "OPENCODE_TRACE_APPEND_HELPER_FILE"
+	appendHelperLines  = 200
+	appendHelperWriter = "OPENCODE_TRACE_APPEND_HELPER_WRITER"
+)
+
+// TestAppendHelperProcess is run as a child process by TestConcurrentProcessAppends
+func TestAppendHelperProcess(t *testing.T) {
+	path := os.Getenv(appendHelperEnv)
+	if path == "" {
+		t.Skip("helper process only")
+	}
+
+	writer := os.Getenv(appendHelperWriter)
+	padding := strings.Repeat(writer, 16*1024)
+	for i := 0; i < appendHelperLines; i++ {
+		data, _ := json.Marshal(map[string]interface{}{"writer": writer, "seq": i, "padding": padding})
+		if _, err := appendLine(path, append(data, '\n'), false); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+	}
+}
+
+func TestConcurrentProcessAppends(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-filelock-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "shared.jsonl")
+
+	var cmds []*exec.Cmd
+	for _, writer := range []string{"a", "b"} {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestAppendHelperProcess$")
+		cmd.Env = append(os.Environ(), appendHelperEnv+"="+path, appendHelperWriter+"="+writer)
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, cmd)
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("helper process failed: %v", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	counts := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var event struct {
+			Writer  string `json:"writer"`
+			Seq     int    `json:"seq"`
+			Padding string `json:"padding"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Line %d is corrupt: %v", line, err)
+		}
+		if event.Padding != strings.Repeat(event.Writer, 16*1024) {
+			t.Fatalf("Line %d has interleaved content", line)
+		}
+		if event.Seq != counts[event.Writer] {
+			t.Fatalf("Line %d: writer %s out of order, expected seq %d, got %d", line, event.Writer, counts[event.Writer], event.Seq)
+		}
+		counts[event.Writer]++
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, writer := range []string{"a", "b"} {
+		if counts[writer] != appendHelperLines {
+			t.Errorf("Writer %s: expected %d lines, got %d", writer, appendHelperLines, counts[writer])
+		}
+	}
+}
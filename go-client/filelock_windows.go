@@ -0,0 +1,49 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const lockfileExclusiveLock = 0x00000002
+
+var (
+	procLockFileEx   = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+	procUnlockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("UnlockFileEx")
+)
+
+// lockFile takes an exclusive lock on the whole file, blocking until it is available
+func lockFile(file *os.File) error {
+	var overlapped syscall.Overlapped
+	ret, _, callErr := procLockFileEx.Call(
+		file.Fd(),
+		lockfileExclusiveLock,
+		0,
+		0xFFFFFFFF,
+		0xFFFFFFFF,
+		uintptr(unsafe.Pointer(&overlapped)),
+	)
+	if ret == 0 {
+		return callErr
+	}
+	return nil
+}
+
+// unlockFile releases the lock taken by lockFile
+func unlockFile(file *os.File) error {
+	var overlapped syscall.Overlapped
+	ret, _, callErr := procUnlockFileEx.Call(
+		file.Fd(),
+		0,
+		0xFFFFFFFF,
+		0xFFFFFFFF,
+		uintptr(unsafe.Pointer(&overlapped)),
+	)
+	if ret == 0 {
+		return callErr
+	}
+	return nil
+}
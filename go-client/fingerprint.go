@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"mime"
+	"sort"
+	"strings"
+)
+
+// requestFingerprint hashes the structure of a request: its endpoint as
+// normalized by baselineEndpoint, the sorted names of its non-sensitive
+// headers, and the shape of its body. Requests that differ only in IDs,
+// header values or JSON values share a fingerprint.
+func (l *Logger) requestFingerprint(capture *RequestCapture) string {
+	names := make([]string, 0, len(capture.Headers))
+	for name := range capture.Headers {
+		if !l.isSensitiveHeader(name) {
+			names = append(names, strings.ToLower(name))
+		}
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, part := range []string{
+		baselineEndpoint(capture.Method, capture.URL),
+		strings.Join(names, ","),
+		bodyShape(capture.ContentType, capture.Body),
+	} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// bodyShape describes a body without its values: the sorted key paths of a
+// JSON document, with arrays as [], or else the media type
+func bodyShape(contentType string, body []byte) string {
+	var document interface{}
+	if len(body) > 0 && json.Unmarshal(body, &document) == nil {
+		var paths []string
+		collectKeyPaths(document, "", &paths)
+		sort.Strings(paths)
+		return "json:" + strings.Join(paths, ",")
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType
+}
+
+// collectKeyPaths adds the path of every object key in value to paths.
+// Array elements share the path prefix[], so lists of any length have one shape.
+func collectKeyPaths(value interface{}, prefix string, paths *[]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			*paths = append(*paths, path)
+			collectKeyPaths(child, path, paths)
+		}
+	case []interface{}:
+		seen := make(map[string]bool)
+		for _, child := range v {
+			var childPaths []string
+			collectKeyPaths(child, prefix+"[]", &childPaths)
+			for _, path := range childPaths {
+				if !seen[path] {
+					seen[path] = true
+					*paths = append(*paths, path)
+				}
+			}
+		}
+	}
+}
+
+// observeFingerprint counts a request under its fingerprint for the session summary
+func (l *Logger) observeFingerprint(fingerprint string) {
+	l.fingerprintMu.Lock()
+	defer l.fingerprintMu.Unlock()
+
+	if l.fingerprints == nil {
+		l.fingerprints = make(map[string]int64)
+	}
+	l.fingerprints[fingerprint]++
+}
+
+// fingerprintSummary returns a copy of the request counts per fingerprint
+func (l *Logger) fingerprintSummary() map[string]int64 {
+	l.fingerprintMu.Lock()
+	defer l.fingerprintMu.Unlock()
+
+	if len(l.fingerprints) == 0 {
+		return nil
+	}
+	counts := make(map[string]int64, len(l.fingerprints))
+	for fingerprint, count := range l.fingerprints {
+		counts[fingerprint] = count
+	}
+	return counts
+}
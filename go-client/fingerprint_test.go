@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestRequestFingerprint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-fingerprint-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	client := NewTracingHTTPClientWithConfig("test-fingerprint", newTestConfig(tempDir))
+
+	send := func(path, body, apiKey string) {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Api-Key", apiKey)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// Same shape, different IDs and values
+	send("/users/17/messages", `{"text": "hello", "tags": [{"name": "a"}]}`, "key-1")
+	send("/users/42/messages", `{"tags": [{"name": "b"}, {"name": "c"}], "text": "bye"}`, "key-2")
+	// A different JSON shape
+	send("/users/42/messages", `{"text": "hi", "priority": 1}`, "key-1")
+	client.Close()
+
+	events := readSessionEvents(t, tempDir)
+	requests := eventsOfType(events, "http_request")
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 request events, got %d", len(requests))
+	}
+
+	first, second, third := requests[0]["fingerprint"], requests[1]["fingerprint"], requests[2]["fingerprint"]
+	if first == nil || first == "" {
+		t.Fatal("Expected a fingerprint on request events")
+	}
+	if first != second {
+		t.Errorf("Expected requests differing only in values to share a fingerprint, got %v and %v", first, second)
+	}
+	if first == third {
+		t.Errorf("Expected a different JSON shape to change the fingerprint, both are %v", first)
+	}
+
+	summaries := eventsOfType(events, "session_summary")
+	if len(summaries) != 1 {
+		t.Fatalf("Expected one session summary, got %d", len(summaries))
+	}
+	counts, _ := summaries[0]["requests_by_fingerprint"].(map[string]interface{})
+	if counts[first.(string)] != float64(2) || counts[third.(string)] != float64(1) {
+		t.Errorf("Unexpected counts per fingerprint: %v", counts)
+	}
+}
+
+func TestRequestFingerprintIgnoresSensitiveHeaderNames(t *testing.T) {
+	logger := NewLogger(newTestConfig(""), "session")
+	capture := &RequestCapture{Method: "GET", URL: "https://api.example.com/v1/items", Headers: map[string]string{"Accept": "*/*"}}
+	withToken := &RequestCapture{Method: "GET", URL: "https://api.example.com/v1/items", Headers: map[string]string{"Accept": "*/*", "Authorization": "Bearer x"}}
+	withHeader := &RequestCapture{Method: "GET", URL: "https://api.example.com/v1/items", Headers: map[string]string{"Accept": "*/*", "X-Trace": "1"}}
+
+	if logger.requestFingerprint(capture) != logger.requestFingerprint(withToken) {
+		t.Error("Expected sensitive headers to be left out of the fingerprint")
+	}
+	if logger.requestFingerprint(capture) == logger.requestFingerprint(withHeader) {
+		t.Error("Expected an extra header to change the fingerprint")
+	}
+}
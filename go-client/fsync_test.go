@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestFsyncOnWriteSyncsEveryLine(t *testing.T) {
+	for _, fsync := range []bool{true, false} {
+		tempDir, err := os.MkdirTemp("", "trace-fsync-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(tempDir)
+
+		config := newTestConfig(tempDir)
+		config.FsyncOnWrite = fsync
+		logger := NewLogger(config, "fsync")
+
+		store, ok := logger.store.(*FileSystemStore)
+		if !ok {
+			t.Fatalf("Expected the default file store, got %T", logger.store)
+		}
+		synced := 0
+		store.syncFile = func(file *os.File) error {
+			synced++
+			return file.Sync()
+		}
+
+		const events = 5
+		for i := 0; i < events; i++ {
+			if err := logger.LogError(fmt.Errorf("event %d", i), "fsync test"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		want := 0
+		if fsync {
+			want = events
+		}
+		if synced != want {
+			t.Errorf("FsyncOnWrite=%v: expected %d syncs, got %d", fsync, want, synced)
+		}
+		if written := len(eventsOfType(readSessionEvents(t, tempDir), "error")); written != events {
+			t.Errorf("FsyncOnWrite=%v: expected %d events, got %d", fsync, events, written)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGapSincePreviousRequest(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-gap-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-gap", newTestConfig(tempDir))
+	pauses := []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond}
+	for _, pause := range pauses {
+		time.Sleep(pause)
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	requests := eventsOfType(readSessionEvents(t, tempDir), "http_request")
+	if len(requests) != len(pauses) {
+		t.Fatalf("Expected %d request events, got %d", len(pauses), len(requests))
+	}
+
+	for i, pause := range pauses {
+		gap, ok := requests[i]["gap_since_prev_ms"].(float64)
+		if !ok {
+			t.Fatalf("Expected gap_since_prev_ms on request %d", i)
+		}
+		want := float64(pause.Milliseconds())
+		if gap < want || gap > want+80 {
+			t.Errorf("Expected a gap of about %vms for request %d, got %vms", want, i, gap)
+		}
+	}
+}
+
+func TestGapSincePreviousRequestOutOfOrder(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-gap-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	logger := NewLogger(newTestConfig(tempDir), "test-gap-order")
+	start := time.Now()
+
+	if gap := logger.gapSincePreviousRequest(start); gap != 0 {
+		t.Errorf("Expected 0 for the first request, got %v", gap)
+	}
+	if gap := logger.gapSincePreviousRequest(start.Add(-time.Second)); gap != 0 {
+		t.Errorf("Expected 0 for a request logged out of order, got %v", gap)
+	}
+	if gap := logger.gapSincePreviousRequest(start.Add(time.Second)); gap != time.Second {
+		t.Errorf("Expected the gap to be measured from the latest start, got %v", gap)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGetWithBodyCaptured(t *testing.T) {
+	const query = `{"query":{"match":{"title":"trace"}}}`
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.Write([]byte(`{"hits":[]}`))
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-get-body-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	client := NewTracingHTTPClientWithConfig("test-get-body", newTestConfig(tempDir))
+	resp, err := client.GetWithBody(server.URL+"/_search", strings.NewReader(query))
+	if err != nil {
+		t.Fatalf("GetWithBody failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	if received != query {
+		t.Errorf("Expected the server to receive %q, got %q", query, received)
+	}
+
+	requests := eventsOfType(readSessionEvents(t, tempDir), "http_request")
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request event, got %d", len(requests))
+	}
+	if requests[0]["method"] != http.MethodGet {
+		t.Errorf("Expected method GET, got %v", requests[0]["method"])
+	}
+	if requests[0]["body"] != query {
+		t.Errorf("Expected the GET body to be captured, got %v", requests[0]["body"])
+	}
+}
+
+func TestGetWithBodyRestoredForRetries(t *testing.T) {
+	const query = `{"query":{"match_all":{}}}`
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, string(body))
+		attempt := len(received)
+		mu.Unlock()
+		if attempt == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"hits":[]}`))
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-get-body-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.MaxRetries = 2
+	client := NewTracingHTTPClientWithConfig("test-get-body-retry", config)
+	client.backoff = func(int) time.Duration { return 0 }
+
+	// A reader without a known length, so DoWithRetry has to buffer it itself
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/_search", io.NopCloser(strings.NewReader(query)))
+	resp, err := client.DoWithRetry(req)
+	if err != nil {
+		t.Fatalf("DoWithRetry failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 attempts, got %d", len(received))
+	}
+	for i, body := range received {
+		if body != query {
+			t.Errorf("Expected attempt %d to send %q, got %q", i+1, query, body)
+		}
+	}
+
+	requests := eventsOfType(readSessionEvents(t, tempDir), "http_request")
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 request events, got %d", len(requests))
+	}
+	for i, request := range requests {
+		if request["body"] != query {
+			t.Errorf("Expected attempt %d to capture the GET body, got %v", i+1, request["body"])
+		}
+	}
+}
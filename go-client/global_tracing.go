@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// globalTracingMu serializes swaps of http.DefaultTransport
+var globalTracingMu sync.Mutex
+
+// InstallGlobalTracing replaces http.DefaultTransport with a tracing transport
+// so that code using http.DefaultClient, http.Get and friends is traced too.
+// Configuration is loaded as for NewTracingHTTPClient. The returned function
+// restores the previous transport and closes the session; it is safe to call
+// more than once.
+//
+// This is process-wide. http.DefaultTransport is a plain variable, so a
+// request already in flight, or code reading the variable concurrently with
+// the swap, may use either transport. Install before starting other
+// goroutines that issue requests, and restore nested installations in
+// reverse order: a restore leaves the transport alone once something else
+// has replaced it.
+func InstallGlobalTracing(sessionID string) func() {
+	if sessionID == "" {
+		sessionID = uuid.New().String()
+	}
+
+	config := LoadConfig()
+	logger := NewLogger(config, sessionID)
+
+	globalTracingMu.Lock()
+	previous := http.DefaultTransport
+	tracing := NewTracingRoundTripper(previous, logger, config, sessionID)
+	http.DefaultTransport = tracing
+	globalTracingMu.Unlock()
+
+	var restoreOnce sync.Once
+	return func() {
+		restoreOnce.Do(func() {
+			globalTracingMu.Lock()
+			if http.DefaultTransport == http.RoundTripper(tracing) {
+				http.DefaultTransport = previous
+			}
+			globalTracingMu.Unlock()
+
+			logger.Close()
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestInstallGlobalTracing(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-global-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	t.Setenv("OPENCODE_TRACE", "true")
+	t.Setenv("OPENCODE_TRACE_DIR", tempDir)
+	t.Setenv("OPENCODE_TRACE_CONFIG", "")
+	t.Setenv("HOME", tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	get := func(path string) {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != path {
+			t.Errorf("Expected body %q, got %q", path, body)
+		}
+	}
+
+	original := http.DefaultTransport
+	restore := InstallGlobalTracing("test-global")
+	if http.DefaultTransport == original {
+		t.Fatal("Expected http.DefaultTransport to be replaced")
+	}
+
+	get("/traced")
+	restore()
+	restore()
+
+	if http.DefaultTransport != original {
+		t.Fatal("Expected restore to put back the original transport")
+	}
+	get("/untraced")
+
+	requests := eventsOfType(readSessionEvents(t, tempDir), "http_request")
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 traced request, got %d", len(requests))
+	}
+	if requests[0]["url"] != server.URL+"/traced" {
+		t.Errorf("Expected only the request made while installed, got %v", requests[0]["url"])
+	}
+}
+
+func TestGlobalTracingRestoreOrder(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-global-nested-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	t.Setenv("OPENCODE_TRACE", "true")
+	t.Setenv("OPENCODE_TRACE_DIR", tempDir)
+	t.Setenv("OPENCODE_TRACE_CONFIG", "")
+	t.Setenv("HOME", tempDir)
+
+	original := http.DefaultTransport
+	restoreOuter := InstallGlobalTracing("outer")
+	outer := http.DefaultTransport
+	restoreInner := InstallGlobalTracing("inner")
+
+	// Restoring the outer installation first must not drop the inner one
+	restoreOuter()
+	if http.DefaultTransport == outer || http.DefaultTransport == original {
+		t.Error("Expected an out-of-order restore to leave the transport alone")
+	}
+
+	restoreInner()
+	if http.DefaultTransport != outer {
+		t.Error("Expected the inner restore to put back the outer transport")
+	}
+
+	http.DefaultTransport = original
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+)
+
+// GraphQLInfo describes the operation carried by a GraphQL request
+type GraphQLInfo struct {
+	OperationType string   `json:"operation_type"`
+	OperationName string   `json:"operation_name,omitempty"`
+	VariableKeys  []string `json:"variable_keys,omitempty"`
+}
+
+// graphQLVariablesPath selects every variable value of a JSON GraphQL request
+const graphQLVariablesPath = "$.variables.*"
+
+var (
+	graphQLComment   = regexp.MustCompile(`#[^\n]*`)
+	graphQLOperation = regexp.MustCompile(`(?:^|\})\s*(query|mutation|subscription)\b\s*([_A-Za-z][_0-9A-Za-z]*)?`)
+)
+
+// parseGraphQL returns the GraphQL operation of a request, or nil when the
+// request is not GraphQL. A POST counts as GraphQL when its Content-Type is
+// application/graphql or its JSON body has a query string.
+func parseGraphQL(method, contentType string, body []byte) *GraphQLInfo {
+	if method != http.MethodPost || len(body) == 0 {
+		return nil
+	}
+
+	if mediaType(contentType) == "application/graphql" {
+		return parseGraphQLDocument(string(body), "")
+	}
+
+	var request struct {
+		Query         *string                    `json:"query"`
+		OperationName string                     `json:"operationName"`
+		Variables     map[string]json.RawMessage `json:"variables"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil || request.Query == nil {
+		return nil
+	}
+
+	info := parseGraphQLDocument(*request.Query, request.OperationName)
+	for key := range request.Variables {
+		info.VariableKeys = append(info.VariableKeys, key)
+	}
+	sort.Strings(info.VariableKeys)
+	return info
+}
+
+// mayHoldGraphQLVariables reports whether a request body cut at MaxBodySize,
+// which parseGraphQL no longer recognizes, may carry GraphQL variables
+func mayHoldGraphQLVariables(method string, body []byte) bool {
+	return method == http.MethodPost && looksLikeJSON(body) && bytes.Contains(body, []byte(`"variables"`))
+}
+
+// parseGraphQLDocument reads the type and name of the first operation in a
+// query document, which is a query when written in shorthand ({ ... }). An
+// explicit operation name, as sent in operationName, wins over the name in
+// the document.
+func parseGraphQLDocument(document, operationName string) *GraphQLInfo {
+	info := &GraphQLInfo{OperationType: "query", OperationName: operationName}
+
+	document = graphQLComment.ReplaceAllString(document, "")
+	if match := graphQLOperation.FindStringSubmatch(document); match != nil {
+		info.OperationType = match[1]
+		if info.OperationName == "" {
+			info.OperationName = match[2]
+		}
+	}
+	return info
+}
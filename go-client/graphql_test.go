@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseGraphQL(t *testing.T) {
+	tests := []struct {
+		name          string
+		contentType   string
+		body          string
+		operationType string
+		operationName string
+	}{
+		{"named query", "application/json", `{"query":"query GetUser($id: ID!) { user(id: $id) { name } }"}`, "query", "GetUser"},
+		{"operationName wins", "application/json", `{"query":"query A { a } query B { b }","operationName":"B"}`, "query", "B"},
+		{"shorthand", "application/json", `{"query":"{ viewer { login } }"}`, "query", ""},
+		{"fragment first", "application/json", `{"query":"# comment\nfragment F on User { id } mutation Rename { rename }"}`, "mutation", "Rename"},
+		{"graphql content type", "application/graphql", `subscription OnEvent { event { id } }`, "subscription", "OnEvent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := parseGraphQL(http.MethodPost, tt.contentType, []byte(tt.body))
+			if info == nil {
+				t.Fatal("Expected GraphQL info")
+			}
+			if info.OperationType != tt.operationType || info.OperationName != tt.operationName {
+				t.Errorf("Expected %s %q, got %s %q", tt.operationType, tt.operationName, info.OperationType, info.OperationName)
+			}
+		})
+	}
+
+	if info := parseGraphQL(http.MethodPost, "application/json", []byte(`{"name":"not graphql"}`)); info != nil {
+		t.Errorf("Expected no GraphQL info for a plain JSON body, got %+v", info)
+	}
+}
+
+func TestGraphQLRequestEvent(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-graphql-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"user":{"name":"Ada"}}}`))
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-graphql", newTestConfig(tempDir))
+	query := `{"query":"query GetUser($id: ID!, $token: String) { user(id: $id) { name } }","variables":{"id":"user-8812","token":"tok-secret"}}`
+	resp, err := client.Post(server.URL+"/graphql", "application/json", strings.NewReader(query))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	requests := eventsOfType(readSessionEvents(t, tempDir), "http_request")
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request event, got %d", len(requests))
+	}
+
+	graphql, ok := requests[0]["graphql"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected graphql object, got %v", requests[0]["graphql"])
+	}
+	if graphql["operation_type"] != "query" || graphql["operation_name"] != "GetUser" {
+		t.Errorf("Unexpected operation: %v", graphql)
+	}
+	if keys, _ := graphql["variable_keys"].([]interface{}); len(keys) != 2 || keys[0] != "id" || keys[1] != "token" {
+		t.Errorf("Expected variable keys [id token], got %v", graphql["variable_keys"])
+	}
+
+	body, _ := requests[0]["body"].(string)
+	if strings.Contains(body, "user-8812") || strings.Contains(body, "tok-secret") {
+		t.Errorf("Expected variable values to be redacted, got %s", body)
+	}
+	if !strings.Contains(body, redactedValue) || !strings.Contains(body, "GetUser") {
+		t.Errorf("Expected the query to be kept with redacted variables, got %s", body)
+	}
+}
+
+func TestTruncatedGraphQLRequestNotStored(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-graphql-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{}}`))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.MaxBodySize = 200
+	client := NewTracingHTTPClientWithConfig("test-graphql-truncated", config)
+
+	query := `{"variables":{"token":"tok-secret"},"query":"mutation Upload($token: String) { upload(token: $token, data: \"` + strings.Repeat("x", 500) + `\") { id } }"}`
+	resp, err := client.Post(server.URL+"/graphql", "application/json", strings.NewReader(query))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	requests := eventsOfType(readSessionEvents(t, tempDir), "http_request")
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request event, got %d", len(requests))
+	}
+	if body, ok := requests[0]["body"]; ok {
+		t.Errorf("Expected the truncated GraphQL body not to be stored, got %v", body)
+	}
+	if requests[0]["body_redaction_failed"] != true {
+		t.Errorf("Expected body_redaction_failed to be set, got %v", requests[0])
+	}
+}
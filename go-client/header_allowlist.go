@@ -0,0 +1,56 @@
+package main
+
+import "strings"
+
+// responseHeaderAllowed reports whether a response header is on
+// ResponseHeaderAllowList. Names match case-insensitively, and an entry
+// ending in * matches every header with that prefix, e.g. x-ratelimit-*.
+// An empty list allows every header.
+func (c *TracingConfig) responseHeaderAllowed(name string) bool {
+	if len(c.ResponseHeaderAllowList) == 0 {
+		return true
+	}
+
+	lower := strings.ToLower(name)
+	for _, allowed := range c.ResponseHeaderAllowList {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok {
+			if strings.HasPrefix(lower, prefix) {
+				return true
+			}
+		} else if lower == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedResponseHeaders drops the response headers not on ResponseHeaderAllowList
+func (c *TracingConfig) allowedResponseHeaders(headers map[string]string) map[string]string {
+	if len(c.ResponseHeaderAllowList) == 0 || headers == nil {
+		return headers
+	}
+
+	allowed := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if c.responseHeaderAllowed(name) {
+			allowed[name] = value
+		}
+	}
+	return allowed
+}
+
+// allowedResponseMultiHeaders is allowedResponseHeaders for every header value
+func (c *TracingConfig) allowedResponseMultiHeaders(headers map[string][]string) map[string][]string {
+	if len(c.ResponseHeaderAllowList) == 0 || headers == nil {
+		return headers
+	}
+
+	allowed := make(map[string][]string, len(headers))
+	for name, values := range headers {
+		if c.responseHeaderAllowed(name) {
+			allowed[name] = values
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestResponseHeaderAllowList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "req-123")
+		w.Header().Set("X-Ratelimit-Remaining-Requests", "99")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("Server", "internal-build-42")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-header-allowlist-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.ResponseHeaderAllowList = []string{"content-type", "X-Request-ID", "x-ratelimit-*"}
+	config.PreserveHeaderMultiValues = true
+	client := NewTracingHTTPClientWithConfig("test-header-allowlist", config)
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 1 {
+		t.Fatalf("Expected one response event, got %d", len(responses))
+	}
+
+	expected := "Content-Type,X-Ratelimit-Remaining-Requests,X-Request-Id"
+	for _, field := range []string{"headers", "headers_multi"} {
+		headers, _ := responses[0][field].(map[string]interface{})
+		var names []string
+		for name := range headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if strings.Join(names, ",") != expected {
+			t.Errorf("%s: expected only %s, got %v", field, expected, names)
+		}
+	}
+
+	// The caller still receives every header
+	if resp.Header.Get("Server") != "internal-build-42" {
+		t.Error("Expected the allowlist to leave the caller's response untouched")
+	}
+}
+
+func TestEmptyResponseHeaderAllowListAllowsAll(t *testing.T) {
+	config := &TracingConfig{}
+	headers := map[string]string{"Server": "x", "Content-Type": "text/plain"}
+	if got := config.allowedResponseHeaders(headers); len(got) != 2 {
+		t.Errorf("Expected every header without an allowlist, got %v", got)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestPreserveHeaderMultiValues(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-headers-multi-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Set-Cookie", "a=1")
+		w.Header().Add("Set-Cookie", "b=2")
+		w.Header().Add("Set-Cookie", "c=3")
+		w.Header().Add("Vary", "Accept")
+		w.Header().Add("Vary", "Accept-Encoding")
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.PreserveHeaderMultiValues = true
+	client := NewTracingHTTPClientWithConfig("test-headers-multi", config)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Add("Accept", "text/html")
+	req.Header.Add("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	events := readSessionEvents(t, tempDir)
+	responses := eventsOfType(events, "http_response")
+	if len(responses) != 1 {
+		t.Fatalf("Expected 1 response event, got %d", len(responses))
+	}
+
+	multi, ok := responses[0]["headers_multi"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected headers_multi on the response, got %v", responses[0]["headers_multi"])
+	}
+	cookies, _ := multi["Set-Cookie"].([]interface{})
+	if len(cookies) != 3 {
+		t.Fatalf("Expected 3 Set-Cookie values, got %v", multi["Set-Cookie"])
+	}
+	for i, expected := range []string{"a=1", "b=2", "c=3"} {
+		if cookies[i] != expected {
+			t.Errorf("Expected Set-Cookie value %d to be %q, got %v", i, expected, cookies[i])
+		}
+	}
+	vary, _ := multi["Vary"].([]interface{})
+	if len(vary) != 2 || vary[0] != "Accept" || vary[1] != "Accept-Encoding" {
+		t.Errorf("Expected both Vary values in order, got %v", multi["Vary"])
+	}
+
+	requests := eventsOfType(events, "http_request")
+	requestMulti, _ := requests[0]["headers_multi"].(map[string]interface{})
+	if accept, _ := requestMulti["Accept"].([]interface{}); len(accept) != 2 {
+		t.Errorf("Expected both Accept values on the request, got %v", requestMulti["Accept"])
+	}
+	if token, _ := requestMulti["Authorization"].([]interface{}); len(token) != 1 || token[0] != redactedValue {
+		t.Errorf("Expected sensitive headers to be redacted, got %v", requestMulti["Authorization"])
+	}
+}
+
+func TestHeadersMultiOffByDefault(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-headers-multi-off-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Set-Cookie", "a=1")
+		w.Header().Add("Set-Cookie", "b=2")
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-headers-multi-off", newTestConfig(tempDir))
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	for _, event := range readSessionEvents(t, tempDir) {
+		if _, ok := event["headers_multi"]; ok {
+			t.Errorf("Expected no headers_multi by default, got %v", event["headers_multi"])
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/base64"
+	"strings"
+	"unicode/utf8"
+)
+
+// Modes for header values that are not valid UTF-8
+const (
+	NonUTF8HeaderReplace = "replace"
+	NonUTF8HeaderBase64  = "base64"
+)
+
+// nonUTF8HeaderPrefix marks a header value stored as base64 of its raw bytes
+const nonUTF8HeaderPrefix = "base64:"
+
+// headerValueUTF8 makes a header value safe to store as a JSON string.
+// Invalid bytes are replaced with U+FFFD, or with NonUTF8HeaderMode base64
+// the raw value is kept as base64 behind a marker so it can be recovered.
+func (l *Logger) headerValueUTF8(value string) string {
+	if utf8.ValidString(value) {
+		return value
+	}
+
+	if l.config.NonUTF8HeaderMode == NonUTF8HeaderBase64 {
+		return nonUTF8HeaderPrefix + base64.StdEncoding.EncodeToString([]byte(value))
+	}
+	return strings.ToValidUTF8(value, "�")
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestNonUTF8HeaderValues(t *testing.T) {
+	raw := "caf\xe9"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Legacy", raw)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		mode     string
+		expected string
+	}{
+		{"", "caf�"},
+		{NonUTF8HeaderReplace, "caf�"},
+		{NonUTF8HeaderBase64, nonUTF8HeaderPrefix + base64.StdEncoding.EncodeToString([]byte(raw))},
+	}
+
+	for _, tt := range tests {
+		t.Run("mode "+tt.mode, func(t *testing.T) {
+			tempDir, err := os.MkdirTemp("", "trace-header-utf8-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tempDir)
+
+			config := newTestConfig(tempDir)
+			config.NonUTF8HeaderMode = tt.mode
+			client := NewTracingHTTPClientWithConfig("test-header-utf8", config)
+
+			req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+			req.Header.Set("X-Legacy", raw)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			if resp.Header.Get("X-Legacy") != raw {
+				t.Errorf("Expected the caller to see the raw header, got %q", resp.Header.Get("X-Legacy"))
+			}
+			resp.Body.Close()
+
+			data, err := os.ReadFile(findSessionFile(t, tempDir))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+				if !json.Valid(line) || !bytes.Equal(bytes.ToValidUTF8(line, nil), line) {
+					t.Fatalf("Expected valid UTF-8 JSON, got %q", line)
+				}
+			}
+
+			for _, event := range readSessionEvents(t, tempDir) {
+				headers, _ := event["headers"].(map[string]interface{})
+				if headers["X-Legacy"] != tt.expected {
+					t.Errorf("%s: expected X-Legacy %q, got %q", event["type"], tt.expected, headers["X-Legacy"])
+				}
+			}
+		})
+	}
+}
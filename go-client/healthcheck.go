@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// defaultHealthCheckTimeout bounds the startup probe when no timeout is configured
+const defaultHealthCheckTimeout = 2 * time.Second
+
+// probeHealth performs the one-time connectivity probe configured by
+// HealthCheckURL and records the result as a startup_health event. The probe
+// is bounded by HealthCheckTimeout so client creation never waits longer.
+func (t *TracingHTTPClient) probeHealth() {
+	if !t.config.Enabled || t.config.HealthCheckURL == "" {
+		return
+	}
+
+	timeout := t.config.HealthCheckTimeout
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	statusCode, err := checkHealth(ctx, t.config.HealthCheckURL, timeout)
+
+	event := StartupHealthEvent{
+		Type:       "startup_health",
+		Timestamp:  start.UnixMilli(),
+		SessionID:  t.sessionID,
+		URL:        t.logger.redactURL(t.config.HealthCheckURL),
+		Reachable:  err == nil,
+		StatusCode: statusCode,
+		Latency:    time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		event.Error = t.logger.redactError(err)
+	}
+
+	t.logger.writeEvent(event)
+}
+
+// checkHealth probes target and returns the HTTP status code for http(s)
+// URLs. tcp://host:port and unix:///path targets are probed with a plain dial.
+func checkHealth(ctx context.Context, target string, timeout time.Duration) (int, error) {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return 0, err
+	}
+
+	var dialer net.Dialer
+	switch parsed.Scheme {
+	case "tcp":
+		conn, err := dialer.DialContext(ctx, "tcp", parsed.Host)
+		if err != nil {
+			return 0, err
+		}
+		return 0, conn.Close()
+	case "unix":
+		conn, err := dialer.DialContext(ctx, "unix", parsed.Path)
+		if err != nil {
+			return 0, err
+		}
+		return 0, conn.Close()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// The probe uses a private transport, not http.DefaultClient, which
+	// InstallGlobalTracing traces, so it is never logged as an exchange
+	client := &http.Client{
+		Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, DisableKeepAlives: true},
+		Timeout:   timeout,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
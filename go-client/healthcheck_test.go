@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStartupHealthReachable(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-health-ok-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.HealthCheckURL = server.URL + "/healthz"
+
+	client := NewTracingHTTPClientWithConfig("test-health-ok", config)
+	client.Close()
+
+	events := readSessionEvents(t, tempDir)
+	health := eventsOfType(events, "startup_health")
+	if len(health) != 1 {
+		t.Fatalf("Expected 1 startup_health event, got %d", len(health))
+	}
+	if health[0]["reachable"] != true {
+		t.Errorf("Expected reachable probe, got %v", health[0])
+	}
+	if health[0]["status_code"] != float64(http.StatusNoContent) {
+		t.Errorf("Expected status 204, got %v", health[0]["status_code"])
+	}
+	if _, ok := health[0]["latency_ms"]; !ok {
+		t.Error("Expected latency_ms on startup_health event")
+	}
+	if got := len(eventsOfType(events, "http_request")); got != 0 {
+		t.Errorf("Expected the probe not to be traced as a request, got %d http_request events", got)
+	}
+}
+
+func TestStartupHealthUnreachable(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-health-down-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Reserve a port and release it so nothing is listening there
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	config := newTestConfig(tempDir)
+	config.HealthCheckURL = "tcp://" + addr
+	config.HealthCheckTimeout = 500 * time.Millisecond
+
+	start := time.Now()
+	client := NewTracingHTTPClientWithConfig("test-health-down", config)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Client creation blocked for %v", elapsed)
+	}
+	client.Close()
+
+	health := eventsOfType(readSessionEvents(t, tempDir), "startup_health")
+	if len(health) != 1 {
+		t.Fatalf("Expected 1 startup_health event, got %d", len(health))
+	}
+	if health[0]["reachable"] != false {
+		t.Errorf("Expected unreachable probe, got %v", health[0])
+	}
+	if health[0]["error"] == nil || health[0]["error"] == "" {
+		t.Error("Expected error on unreachable probe")
+	}
+}
+
+// watchingTransport counts the requests sent through it
+type watchingTransport struct {
+	base  http.RoundTripper
+	count atomic.Int64
+}
+
+func (w *watchingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	w.count.Add(1)
+	return w.base.RoundTrip(req)
+}
+
+func TestStartupHealthProbeIsPrivate(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-health-private-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// Stands in for InstallGlobalTracing, which replaces http.DefaultTransport
+	watching := &watchingTransport{base: http.DefaultTransport}
+	http.DefaultTransport = watching
+	defer func() { http.DefaultTransport = watching.base }()
+
+	config := newTestConfig(tempDir)
+	config.HealthCheckURL = server.URL + "/tenants/alice@example.com/healthz"
+	config.PathMaskPatterns = []string{"@"}
+
+	client := NewTracingHTTPClientWithConfig("test-health-private", config)
+	client.Close()
+
+	if count := watching.count.Load(); count != 0 {
+		t.Errorf("Expected the probe not to use http.DefaultTransport, got %d requests", count)
+	}
+
+	health := eventsOfType(readSessionEvents(t, tempDir), "startup_health")
+	if len(health) != 1 {
+		t.Fatalf("Expected 1 startup_health event, got %d", len(health))
+	}
+	if expected := server.URL + "/tenants/[MASKED]/healthz"; health[0]["url"] != expected || health[0]["reachable"] != true {
+		t.Errorf("Expected a reachable probe of %q, got %v", expected, health[0])
+	}
+}
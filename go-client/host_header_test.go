@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestHostHeaderRecorded(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-host-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	received := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Host
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-host", newTestConfig(tempDir))
+
+	virtual, _ := http.NewRequest(http.MethodGet, server.URL+"/virtual", nil)
+	virtual.Host = "tenant.example.com"
+	plain, _ := http.NewRequest(http.MethodGet, server.URL+"/plain", nil)
+
+	for _, req := range []*http.Request{virtual, plain} {
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if host := <-received; host != "tenant.example.com" {
+		t.Fatalf("Expected the server to see the overridden host, got %q", host)
+	}
+
+	requests := eventsOfType(readSessionEvents(t, tempDir), "http_request")
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 request events, got %d", len(requests))
+	}
+	if requests[0]["host"] != "tenant.example.com" || requests[0]["url"] != server.URL+"/virtual" {
+		t.Errorf("Expected host recorded apart from the URL, got host %v url %v", requests[0]["host"], requests[0]["url"])
+	}
+	if _, ok := requests[1]["host"]; ok {
+		t.Errorf("Expected no host field when it matches the URL, got %v", requests[1]["host"])
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+)
+
+// hostInfoFields returns the hostname and pid members added to every event
+// with IncludeHostInfo, so merged streams from several machines stay
+// attributable. The hostname is empty when it cannot be determined.
+func hostInfoFields() []byte {
+	hostname, _ := os.Hostname()
+	name, _ := json.Marshal(hostname)
+	return []byte(`"hostname":` + string(name) + `,"pid":` + strconv.Itoa(os.Getpid()))
+}
+
+// addEventFields appends members, such as "a":1,"b":2, to a marshaled event
+func addEventFields(data, members []byte) []byte {
+	if len(data) < 2 || data[len(data)-1] != '}' {
+		return data
+	}
+
+	combined := make([]byte, 0, len(data)+len(members)+1)
+	combined = append(combined, data[:len(data)-1]...)
+	if len(data) > 2 {
+		combined = append(combined, ',')
+	}
+	combined = append(combined, members...)
+	return append(combined, '}')
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestIncludeHostInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skipf("Hostname unavailable: %v", err)
+	}
+
+	for _, enabled := range []bool{true, false} {
+		tempDir, err := os.MkdirTemp("", "trace-host-info-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(tempDir)
+
+		config := newTestConfig(tempDir)
+		config.IncludeHostInfo = enabled
+		client := NewTracingHTTPClientWithConfig("test-host-info", config)
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		client.Close()
+
+		events := readSessionEvents(t, tempDir)
+		if len(events) < 3 {
+			t.Fatalf("Expected request, response and summary events, got %d", len(events))
+		}
+		for _, event := range events {
+			if !enabled {
+				if _, ok := event["hostname"]; ok {
+					t.Errorf("Expected no hostname without IncludeHostInfo, got %v", event)
+				}
+				if _, ok := event["pid"]; ok {
+					t.Errorf("Expected no pid without IncludeHostInfo, got %v", event)
+				}
+				continue
+			}
+			if event["hostname"] != hostname {
+				t.Errorf("%v event: expected hostname %q, got %v", event["type"], hostname, event["hostname"])
+			}
+			if event["pid"] != float64(os.Getpid()) {
+				t.Errorf("%v event: expected pid %d, got %v", event["type"], os.Getpid(), event["pid"])
+			}
+		}
+	}
+}
+
+func TestAddEventFields(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected string
+	}{
+		{`{"type":"x"}`, `{"type":"x","pid":1}`},
+		{`{}`, `{"pid":1}`},
+		{`[1]`, `[1]`},
+	}
+
+	for _, tt := range tests {
+		if got := string(addEventFields([]byte(tt.data), []byte(`"pid":1`))); got != tt.expected {
+			t.Errorf("addEventFields(%s) = %s; expected %s", tt.data, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+import "net/http"
+
+// captureCacheRevalidation records how HTTP caching shaped a response: whether
+// the request was conditional, whether the server answered 304 Not Modified,
+// and whether a caching RoundTripper beneath the tracer served it. Caches in
+// the style of httpcache mark the responses they serve with X-From-Cache: 1.
+func captureCacheRevalidation(capture *ResponseCapture, resp *http.Response) {
+	if req := resp.Request; req != nil {
+		capture.ConditionalRequest = req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != ""
+	}
+	capture.Revalidated = resp.StatusCode == http.StatusNotModified
+	capture.FromCache = resp.Header.Get("X-From-Cache") == "1"
+}
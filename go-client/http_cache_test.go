@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestRevalidationRecorded(t *testing.T) {
+	const etag = `"v1"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("fresh"))
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-revalidation-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	client := NewTracingHTTPClientWithConfig("test-revalidation", newTestConfig(tempDir))
+
+	// A plain request, then a conditional one the server answers with 304
+	for _, ifNoneMatch := range []string{"", etag} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+	client.Close()
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 2 {
+		t.Fatalf("Expected 2 response events, got %d", len(responses))
+	}
+
+	plain, revalidated := responses[0], responses[1]
+	if plain["conditional_request"] != nil || plain["revalidated"] != nil {
+		t.Errorf("Expected no cache flags on the plain request, got %v and %v", plain["conditional_request"], plain["revalidated"])
+	}
+	if revalidated["status_code"] != float64(http.StatusNotModified) {
+		t.Fatalf("Expected 304, got %v", revalidated["status_code"])
+	}
+	if revalidated["conditional_request"] != true {
+		t.Error("Expected conditional_request on the If-None-Match request")
+	}
+	if revalidated["revalidated"] != true {
+		t.Error("Expected revalidated on the 304 response")
+	}
+}
+
+func TestFromCacheRecorded(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"X-From-Cache": []string{"1"}},
+		Request:    &http.Request{Header: http.Header{"If-Modified-Since": []string{"Mon, 02 Jan 2006 15:04:05 GMT"}}},
+	}
+
+	capture := &ResponseCapture{}
+	captureCacheRevalidation(capture, resp)
+	if !capture.FromCache || !capture.ConditionalRequest || capture.Revalidated {
+		t.Errorf("Unexpected cache flags: from_cache %v, conditional %v, revalidated %v", capture.FromCache, capture.ConditionalRequest, capture.Revalidated)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"time"
+)
+
+// withClientTrace attaches an httptrace.ClientTrace to the request so that
+// connection-level events are reported to the logger under requestID
+func (t *TracingRoundTripper) withClientTrace(req *http.Request, requestID string) *http.Request {
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			// Runs on the transport's read loop, where a panic would crash the process
+			defer t.recoverCapturePanic(requestID, "httptrace")
+
+			// Log interim responses (100 Continue, 103 Early Hints) as they arrive
+			if err := t.logger.LogHTTPInformational(requestID, code, flattenHeaders(http.Header(header)), time.Now()); err != nil {
+				t.logger.LogError(err, "failed to log informational response")
+			}
+			return nil
+		},
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestInformationalResponseCapture(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-1xx-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(http.StatusEarlyHints)
+
+		w.Header().Del("Link")
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("final"))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.EnableHTTPTrace = true
+
+	client := NewTracingHTTPClientWithConfig("test-1xx", config)
+	defer client.Close()
+
+	resp, err := client.Get(server.URL + "/page")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected final status 200, got %d", resp.StatusCode)
+	}
+
+	events := readSessionEvents(t, tempDir)
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+
+	expectedTypes := []string{"http_request", "http_1xx", "http_response"}
+	for i, eventType := range expectedTypes {
+		if events[i]["type"] != eventType {
+			t.Errorf("Event %d: expected type %s, got %v", i+1, eventType, events[i]["type"])
+		}
+	}
+
+	hint := events[1]
+	if hint["request_id"] == nil || hint["request_id"] != events[0]["request_id"] {
+		t.Errorf("Expected the 103 event to carry request_id %v, got %v", events[0]["request_id"], hint["request_id"])
+	}
+	if hint["status_code"] != float64(http.StatusEarlyHints) {
+		t.Errorf("Expected status_code 103, got %v", hint["status_code"])
+	}
+	headers, _ := hint["headers"].(map[string]interface{})
+	if headers["Link"] != "</style.css>; rel=preload; as=style" {
+		t.Errorf("Expected Link header on 103 event, got %v", headers["Link"])
+	}
+}
+
+func TestInformationalResponseRequiresHTTPTrace(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-1xx-off-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-1xx-off", newTestConfig(tempDir))
+	defer client.Close()
+
+	resp, err := client.Get(server.URL + "/page")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if hints := eventsOfType(readSessionEvents(t, tempDir), "http_1xx"); len(hints) != 0 {
+		t.Errorf("Expected no http_1xx events without httptrace enabled, got %d", len(hints))
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// integrityField is the event field holding the HMAC of the rest of the event
+const integrityField = "hmac"
+
+// integrityKey decodes IntegrityKey, which is base64 wherever it is set, so
+// the same value gives the same key from the environment and a config file.
+// It returns nil when no key is set.
+func (c *TracingConfig) integrityKey() ([]byte, error) {
+	if c.IntegrityKey == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(c.IntegrityKey))
+	if err != nil {
+		return nil, fmt.Errorf("integrity_key is not valid base64: %v", err)
+	}
+	if len(key) == 0 {
+		return nil, errors.New("integrity_key decodes to an empty key")
+	}
+	return key, nil
+}
+
+// bodyDigest returns the hex SHA-256 and byte length of a stored body
+func bodyDigest(body string) (string, int) {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:]), len(body)
+}
+
+// canonicalEventJSON re-encodes an event without its hmac field, with keys
+// sorted and numbers kept as written, so that writer and verifier hash the
+// same bytes regardless of field order. It returns the hmac found, if any.
+func canonicalEventJSON(raw []byte) ([]byte, string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var event map[string]interface{}
+	if err := decoder.Decode(&event); err != nil {
+		return nil, "", fmt.Errorf("invalid event: %w", err)
+	}
+	if event == nil {
+		return nil, "", errors.New("invalid event: not a JSON object")
+	}
+
+	mac, _ := event[integrityField].(string)
+	delete(event, integrityField)
+
+	canonical, err := json.Marshal(event)
+	if err != nil {
+		return nil, "", err
+	}
+	return canonical, mac, nil
+}
+
+// eventHMAC returns the hex HMAC-SHA256 of canonical under key
+func eventHMAC(canonical, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(canonical)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sealEvent appends an hmac field to a serialized event, keeping the order
+// of the fields already written
+func sealEvent(data, key []byte) ([]byte, error) {
+	canonical, _, err := canonicalEventJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := bytes.TrimRight(data, " \n")
+	if len(trimmed) < 2 || trimmed[len(trimmed)-1] != '}' {
+		return nil, errors.New("invalid event: not a JSON object")
+	}
+
+	sealed := make([]byte, 0, len(trimmed)+80)
+	sealed = append(sealed, trimmed[:len(trimmed)-1]...)
+	if len(trimmed) > 2 {
+		sealed = append(sealed, ',')
+	}
+	sealed = append(sealed, `"`+integrityField+`":"`+eventHMAC(canonical, key)+`"}`...)
+	return sealed, nil
+}
+
+// VerifyEvent reports whether a JSONL event line carries a valid hmac for
+// key, that is, whether it is unchanged since the tracer wrote it. A line in
+// a CloudEvents envelope is checked on the event under data. It returns an
+// error when the line is not a JSON object or has no hmac field.
+func VerifyEvent(raw []byte, key []byte) (bool, error) {
+	canonical, mac, err := canonicalEventJSON(cloudEventData(raw))
+	if err != nil {
+		return false, err
+	}
+	if mac == "" {
+		return false, errors.New("event has no hmac field")
+	}
+
+	expected, err := hex.DecodeString(mac)
+	if err != nil {
+		return false, nil
+	}
+	actual, _ := hex.DecodeString(eventHMAC(canonical, key))
+	return hmac.Equal(expected, actual), nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIntegrityFields(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-integrity-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"amount": 100}`))
+	}))
+	defer server.Close()
+
+	key := []byte("audit-key")
+	config := newTestConfig(tempDir)
+	config.IntegrityKey = base64.StdEncoding.EncodeToString(key)
+	client := NewTracingHTTPClientWithConfig("test-integrity", config)
+
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"transfer": 100}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	data, err := os.ReadFile(findSessionFile(t, tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	if len(lines) < 2 {
+		t.Fatalf("Expected request and response events, got %d lines", len(lines))
+	}
+
+	for i, line := range lines {
+		valid, err := VerifyEvent(line, key)
+		if err != nil || !valid {
+			t.Errorf("Line %d: expected a valid hmac, got %v (%v)", i, valid, err)
+		}
+
+		var event map[string]interface{}
+		json.Unmarshal(line, &event)
+		if body, ok := event["body"].(string); ok {
+			sum := sha256.Sum256([]byte(body))
+			if event["body_sha256"] != hex.EncodeToString(sum[:]) || event["body_length"] != float64(len(body)) {
+				t.Errorf("Line %d: body digest does not match body %q: %v, %v", i, body, event["body_sha256"], event["body_length"])
+			}
+		}
+	}
+
+	// Any change to the event, or the wrong key, fails verification
+	tampered := bytes.Replace(lines[0], []byte(`\"transfer\": 100`), []byte(`\"transfer\": 900`), 1)
+	if bytes.Equal(tampered, lines[0]) {
+		t.Fatalf("Test setup: expected the request body in %s", lines[0])
+	}
+	if valid, err := VerifyEvent(tampered, key); err != nil || valid {
+		t.Errorf("Expected a tampered event to fail verification, got %v (%v)", valid, err)
+	}
+	if valid, _ := VerifyEvent(lines[0], []byte("other-key")); valid {
+		t.Error("Expected verification with the wrong key to fail")
+	}
+}
+
+func TestVerifyEventErrors(t *testing.T) {
+	if _, err := VerifyEvent([]byte(`{"type": "http_request"}`), []byte("key")); err == nil {
+		t.Error("Expected an error for an event without hmac")
+	}
+	if _, err := VerifyEvent([]byte(`not json`), []byte("key")); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+
+	// Sealing is independent of field order and number formatting
+	sealed, err := sealEvent([]byte(`{"b": 1.50, "a": "x"}`), []byte("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var event map[string]interface{}
+	json.Unmarshal(sealed, &event)
+	reordered := []byte(`{"a": "x", "hmac": "` + event["hmac"].(string) + `", "b": 1.50}`)
+	if valid, err := VerifyEvent(reordered, []byte("key")); err != nil || !valid {
+		t.Errorf("Expected a reordered event to verify, got %v (%v)", valid, err)
+	}
+}
+
+func TestNoIntegrityFieldsWithoutKey(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-integrity-off-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-integrity-off", newTestConfig(tempDir))
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	for _, event := range readSessionEvents(t, tempDir) {
+		for _, field := range []string{"hmac", "body_sha256", "body_length"} {
+			if _, ok := event[field]; ok {
+				t.Errorf("Expected no %s without an integrity key, got %v", field, event[field])
+			}
+		}
+	}
+}
+
+func TestIntegrityKeySameFromEnvAndFile(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	encoded := base64.StdEncoding.EncodeToString([]byte("shared-integrity-key"))
+
+	t.Setenv("OPENCODE_TRACE_CONFIG", "")
+	t.Setenv("OPENCODE_TRACE_INTEGRITY_KEY", encoded)
+	fromEnv, err := LoadConfig().integrityKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configPath := filepath.Join(tempDir, "trace-config.json")
+	writeConfig := func(key string) {
+		data := []byte(`{"integrity_key": "` + key + `", "max_body_size": 2048}`)
+		if err := os.WriteFile(configPath, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("OPENCODE_TRACE_CONFIG", configPath)
+	t.Setenv("OPENCODE_TRACE_INTEGRITY_KEY", "")
+
+	writeConfig(encoded)
+	fromFile, err := LoadConfig().integrityKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(fromEnv) != "shared-integrity-key" || !bytes.Equal(fromEnv, fromFile) {
+		t.Errorf("Expected the same key from both sources, got %q and %q", fromEnv, fromFile)
+	}
+
+	// An invalid key is reported, and the rest of the file still applies
+	writeConfig("not base64!")
+	config := LoadConfig()
+	if config.MaxBodySize != 2048 {
+		t.Errorf("Expected the config file to be loaded, got max_body_size %d", config.MaxBodySize)
+	}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "integrity_key") {
+		t.Errorf("Expected Validate to reject the key, got %v", err)
+	}
+
+	config.OutputDir = tempDir
+	logger := NewLogger(config, "integrity-invalid")
+	defer logger.Close()
+	warned := false
+	for len(logger.Warnings()) > 0 {
+		warned = warned || (<-logger.Warnings()).Type == WarningInvalidConfig
+	}
+	if !warned || logger.integrityKey != nil {
+		t.Errorf("Expected an invalid_config warning and no key, got %v, %q", warned, logger.integrityKey)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// JitterFunc randomizes the base wait before a retry attempt
+type JitterFunc func(base time.Duration) time.Duration
+
+// defaultJitter is shared by clients without their own jitter source
+var defaultJitter = NewRandJitter(rand.New(rand.NewSource(time.Now().UnixNano())))
+
+// NewRandJitter returns a JitterFunc drawing from source, which waits between
+// half and all of the base duration ("equal jitter"). A seeded source gives
+// reproducible backoffs. The returned function is safe for concurrent use.
+func NewRandJitter(source *rand.Rand) JitterFunc {
+	var mu sync.Mutex
+	return func(base time.Duration) time.Duration {
+		if base <= 0 {
+			return base
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		half := base / 2
+		return half + time.Duration(source.Int63n(int64(base-half)+1))
+	}
+}
+
+// WithJitter sets the jitter applied to the default retry backoff and
+// returns the client
+func (t *TracingHTTPClient) WithJitter(jitter JitterFunc) *TracingHTTPClient {
+	t.jitter = jitter
+	return t
+}
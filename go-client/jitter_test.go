@@ -0,0 +1,46 @@
+package main
+
+import (
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSeededJitterIsReproducible(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-jitter-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	backoffs := func() []time.Duration {
+		client := NewTracingHTTPClientWithConfig("test-jitter", newTestConfig(tempDir))
+		client.WithJitter(NewRandJitter(rand.New(rand.NewSource(42))))
+
+		var durations []time.Duration
+		for attempt := 1; attempt <= 3; attempt++ {
+			durations = append(durations, client.retryBackoff(attempt))
+		}
+		return durations
+	}
+
+	// The same seed drawn directly gives the expected equal-jitter waits
+	source := rand.New(rand.NewSource(42))
+	var expected []time.Duration
+	for attempt := 1; attempt <= 3; attempt++ {
+		base := time.Duration(attempt) * time.Second
+		expected = append(expected, base/2+time.Duration(source.Int63n(int64(base-base/2)+1)))
+	}
+
+	first, second := backoffs(), backoffs()
+	for i := range expected {
+		if first[i] != expected[i] || second[i] != expected[i] {
+			t.Errorf("Attempt %d: expected %v in both runs, got %v and %v", i+1, expected[i], first[i], second[i])
+		}
+		base := time.Duration(i+1) * time.Second
+		if first[i] < base/2 || first[i] > base {
+			t.Errorf("Attempt %d: expected a wait between %v and %v, got %v", i+1, base/2, base, first[i])
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// firstByteRecorder records when the first byte of the response arrived
+type firstByteRecorder struct {
+	mu sync.Mutex
+	at time.Time
+}
+
+// withFirstByteTrace attaches a hook that records the time to first byte
+func withFirstByteTrace(req *http.Request) (*http.Request, *firstByteRecorder) {
+	recorder := &firstByteRecorder{}
+
+	trace := &httptrace.ClientTrace{
+		GotFirstResponseByte: func() {
+			recorder.mu.Lock()
+			if recorder.at.IsZero() {
+				recorder.at = time.Now()
+			}
+			recorder.mu.Unlock()
+		},
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), recorder
+}
+
+// firstByte returns when the first response byte arrived, if it did
+func (r *firstByteRecorder) firstByte() (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.at, !r.at.IsZero()
+}
+
+// recordLatency fills in time to first byte and, for streaming responses whose
+// body was read, the time from the first byte until the body was closed
+func (r *firstByteRecorder) recordLatency(capture *ResponseCapture, startTime time.Time) {
+	at, ok := r.firstByte()
+	if !ok {
+		return
+	}
+
+	capture.TTFB, capture.HasTTFB = at.Sub(startTime), true
+
+	if !capture.BodyClosed.IsZero() && isStreamingContentType(capture.ContentType) {
+		capture.StreamDuration, capture.Streamed = capture.BodyClosed.Sub(at), true
+	}
+}
+
+// isStreamingContentType reports whether a content type is delivered as a stream
+func isStreamingContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch mediaType {
+	case "text/event-stream", "application/x-ndjson", "application/jsonl":
+		return true
+	}
+	return false
+}
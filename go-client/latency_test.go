@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStreamingLatencySplit(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-latency-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	const chunkDelay = 100 * time.Millisecond
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		if r.URL.Path == "/json" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ok": true}`))
+			return
+		}
+
+		// The first token arrives at once, the rest of the stream trickles in
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 4; i++ {
+			if i > 0 {
+				time.Sleep(chunkDelay)
+			}
+			fmt.Fprintf(w, "data: {\"token\": %d}\n\n", i)
+			flusher.Flush()
+		}
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-latency", newTestConfig(tempDir))
+
+	for _, path := range []string{"/stream", "/json"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 2 {
+		t.Fatalf("Expected 2 response events, got %d", len(responses))
+	}
+
+	stream := responses[0]
+	ttfb, ok := stream["ttfb_ms"].(float64)
+	if !ok {
+		t.Fatalf("Expected ttfb_ms on the streaming response, got %v", stream["ttfb_ms"])
+	}
+	streamDuration, ok := stream["stream_duration_ms"].(float64)
+	if !ok {
+		t.Fatalf("Expected stream_duration_ms on the streaming response, got %v", stream["stream_duration_ms"])
+	}
+
+	if streamDuration < float64((3 * chunkDelay).Milliseconds()) {
+		t.Errorf("Expected the stream to last at least %v, got %vms", 3*chunkDelay, streamDuration)
+	}
+	if total := ttfb + streamDuration; ttfb*4 > total {
+		t.Errorf("Expected ttfb (%vms) to be much less than the total (%vms)", ttfb, total)
+	}
+
+	plain := responses[1]
+	if _, ok := plain["ttfb_ms"]; !ok {
+		t.Error("Expected ttfb_ms on a non-streaming response")
+	}
+	if _, ok := plain["stream_duration_ms"]; ok {
+		t.Error("Expected no stream_duration_ms on a non-streaming response")
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// Fields kept when an event is still over the line cap without its body
+var lineCapStubFields = []string{"type", "timestamp", "session_id", "request_id"}
+
+// minLineBytes is the smallest MaxLineBytes honored. It leaves room for the
+// identifying stub of an event with its hmac and CloudEvents envelope, for
+// session IDs of up to 200 bytes.
+const minLineBytes = 1024
+
+// capLine shrinks a serialized event to at most maxBytes. The body is
+// truncated first, or dropped when it is encoded and cannot be cut, and its
+// body digest is recomputed or dropped with it. An event that is still too
+// long is reduced to its identifying fields. Shrunk events are marked with
+// line_truncated.
+func capLine(data []byte, maxBytes int) []byte {
+	if maxBytes <= 0 || len(data) <= maxBytes {
+		return data
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return data
+	}
+	fields["line_truncated"] = json.RawMessage("true")
+
+	var body string
+	if raw, ok := fields["body"]; ok && json.Unmarshal(raw, &body) == nil {
+		// Compressed bodies are base64 and cannot be cut; drop them whole
+		if _, encoded := fields["body_encoding"]; encoded {
+			body = ""
+			delete(fields, "body_encoding")
+		}
+
+		// A digest of the full body would no longer match the body written
+		_, digested := fields["body_sha256"]
+		delete(fields, "body_sha256")
+		delete(fields, "body_length")
+
+		for body != "" {
+			fields["body"], _ = json.Marshal(body)
+			if digested {
+				sum, length := bodyDigest(body)
+				fields["body_sha256"], _ = json.Marshal(sum)
+				fields["body_length"], _ = json.Marshal(length)
+			}
+			capped, err := json.Marshal(fields)
+			if err != nil {
+				return data
+			}
+			if len(capped) <= maxBytes {
+				return capped
+			}
+			body = truncateUTF8(body, len(body)-(len(capped)-maxBytes))
+		}
+		delete(fields, "body")
+		delete(fields, "body_sha256")
+		delete(fields, "body_length")
+
+		if capped, err := json.Marshal(fields); err == nil && len(capped) <= maxBytes {
+			return capped
+		}
+	}
+
+	stub := map[string]json.RawMessage{"line_truncated": json.RawMessage("true")}
+	for _, name := range lineCapStubFields {
+		if raw, ok := fields[name]; ok {
+			stub[name] = raw
+		}
+	}
+	if capped, err := json.Marshal(stub); err == nil {
+		return capped
+	}
+	return data
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if n >= len(s) {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
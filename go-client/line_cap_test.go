@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMaxLineBytes(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-line-cap-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(strings.Repeat("é", 20000)))
+	}))
+	defer server.Close()
+
+	const maxLine = 2048
+	config := newTestConfig(tempDir)
+	config.MaxBodySize = 1024 * 1024
+	config.MaxLineBytes = maxLine
+
+	client := NewTracingHTTPClientWithConfig("test-line-cap", config)
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	file, err := os.Open(findSessionFile(t, tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > maxLine {
+			t.Errorf("Expected lines of at most %d bytes, got %d", maxLine, len(scanner.Bytes()))
+		}
+	}
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 1 {
+		t.Fatalf("Expected 1 response event, got %d", len(responses))
+	}
+	body, _ := responses[0]["body"].(string)
+	if responses[0]["line_truncated"] != true || body == "" || !strings.HasPrefix(strings.Repeat("é", 20000), body) {
+		t.Errorf("Expected a truncated body prefix marked line_truncated, got %d bytes, marker %v", len(body), responses[0]["line_truncated"])
+	}
+}
+
+func TestCapLineFallsBackToStub(t *testing.T) {
+	data := []byte(`{"type":"http_request","timestamp":1,"session_id":"s","request_id":"r","headers":{"X-Big":"` + strings.Repeat("x", 500) + `"}}`)
+
+	capped := string(capLine(data, 200))
+	if len(capped) > 200 || !strings.Contains(capped, `"request_id":"r"`) || !strings.Contains(capped, `"line_truncated":true`) {
+		t.Errorf("Expected an identifying stub under the cap, got %s", capped)
+	}
+}
+
+func TestMaxLineBytesCoversSealAndEnvelope(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-line-cap-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(strings.Repeat("x", 20000)))
+	}))
+	defer server.Close()
+
+	const maxLine = 1024
+	key := []byte("integrity-key")
+	config := newTestConfig(tempDir)
+	config.MaxBodySize = 1024 * 1024
+	config.MaxLineBytes = maxLine
+	config.IntegrityKey = base64.StdEncoding.EncodeToString(key)
+	config.EventFormat = EventFormatCloudEvents
+
+	client := NewTracingHTTPClientWithConfig("test-line-cap", config)
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	file, err := os.Open(findSessionFile(t, tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	truncated := false
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) > maxLine {
+			t.Errorf("Expected lines of at most %d bytes, got %d", maxLine, len(line))
+		}
+		if valid, err := VerifyEvent(line, key); err != nil || !valid {
+			t.Errorf("Expected a valid hmac on a capped line, got %v, %v", valid, err)
+		}
+		truncated = truncated || strings.Contains(string(line), `"line_truncated":true`)
+	}
+	if !truncated {
+		t.Error("Expected the response event to be marked line_truncated")
+	}
+}
+
+func TestCapLineKeepsBodyDigestInStep(t *testing.T) {
+	body := strings.Repeat("x", 2000)
+	sum, length := bodyDigest(body)
+	data := []byte(`{"type":"http_response","timestamp":1,"session_id":"s","request_id":"r","body":"` + body +
+		`","body_sha256":"` + sum + `","body_length":` + strconv.Itoa(length) + `}`)
+
+	var cut struct {
+		Body       string `json:"body"`
+		BodySHA256 string `json:"body_sha256"`
+		BodyLength int    `json:"body_length"`
+	}
+	if err := json.Unmarshal(capLine(data, 500), &cut); err != nil {
+		t.Fatal(err)
+	}
+	if cut.Body == "" || len(cut.Body) == len(body) {
+		t.Fatalf("Expected the body to be cut, got %d bytes", len(cut.Body))
+	}
+	if wantSum, wantLength := bodyDigest(cut.Body); cut.BodySHA256 != wantSum || cut.BodyLength != wantLength {
+		t.Errorf("Expected the digest of the cut body, got %s/%d", cut.BodySHA256, cut.BodyLength)
+	}
+
+	encoded := []byte(`{"type":"http_response","timestamp":1,"session_id":"s","body":"` + body +
+		`","body_encoding":"gzip+base64","body_sha256":"` + sum + `","body_length":` + strconv.Itoa(length) + `}`)
+	dropped := string(capLine(encoded, 500))
+	if strings.Contains(dropped, "body_sha256") || strings.Contains(dropped, "body_length") {
+		t.Errorf("Expected no digest once the body is dropped, got %s", dropped)
+	}
+}
+
+func TestMaxLineBytesMinimum(t *testing.T) {
+	tempDir := t.TempDir()
+
+	config := newTestConfig(tempDir)
+	config.MaxLineBytes = 100
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "max_line_bytes") {
+		t.Errorf("Expected Validate to reject a max_line_bytes below the minimum, got %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Big", strings.Repeat("h", 4000))
+	}))
+	defer server.Close()
+
+	config.IntegrityKey = base64.StdEncoding.EncodeToString([]byte("integrity-key"))
+	config.EventFormat = EventFormatCloudEvents
+
+	// The longest session ID the minimum is documented to fit
+	client := NewTracingHTTPClientWithConfig(strings.Repeat("s", 200), config)
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	warned := false
+	for len(client.logger.Warnings()) > 0 {
+		warning := <-client.logger.Warnings()
+		warned = warned || warning.Type == WarningInvalidConfig
+	}
+	if !warned {
+		t.Error("Expected an invalid_config warning for the raised limit")
+	}
+	client.Close()
+
+	content, err := os.ReadFile(findSessionFile(t, tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		if len(line) > minLineBytes {
+			t.Errorf("Expected lines of at most %d bytes, got %d", minLineBytes, len(line))
+		}
+		if !strings.Contains(line, `"line_truncated":true`) && strings.Contains(line, "http_response") {
+			t.Errorf("Expected the oversized response event to be cut, got %s", line)
+		}
+	}
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// LivePath is where ServeLive accepts WebSocket connections
+const LivePath = "/events"
+
+// DefaultLiveAddr is where ServeLive listens when given an empty address
+const DefaultLiveAddr = "127.0.0.1:8765"
+
+// Live feed limits. A viewer that falls this many messages behind misses
+// events rather than slowing the tracer down.
+const (
+	liveQueueSize       = 256
+	liveViewerQueueSize = 64
+	liveMaxPending      = 1024
+	liveMaxFrameSize    = 1 << 16
+)
+
+// websocketGUID is appended to the client key to form Sec-WebSocket-Accept (RFC 6455)
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes
+const (
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xA
+)
+
+// liveMessage is one WebSocket text message of the live feed. Every event is
+// sent as an "event" message; when a response or error completes a request
+// seen on the feed, a "har_entry" message with the HAR 1.2 entry follows.
+type liveMessage struct {
+	Type  string          `json:"type"`
+	Event json.RawMessage `json:"event,omitempty"`
+	Entry *harEntry       `json:"entry,omitempty"`
+}
+
+// LiveServer streams the events of a session to WebSocket viewers as they
+// are written
+type LiveServer struct {
+	logger   *Logger
+	server   *http.Server
+	listener net.Listener
+
+	viewersMu   sync.Mutex
+	viewers     map[*liveViewer]struct{}
+	viewerCount atomic.Int64
+
+	incoming  chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// Requests waiting for their response, by request_id; used by run only
+	pending map[string]map[string]interface{}
+}
+
+// ServeLive starts a WebSocket endpoint at addr, on path LivePath, streaming
+// each event of the session as it is written. Events are dropped, never
+// queued on the request path, while no viewer is connected or a viewer is
+// slow. An address without a host listens on loopback only; the feed carries
+// request and response bodies, so other interfaces must be named explicitly.
+// Close stops the server.
+func (l *Logger) ServeLive(addr string) (*LiveServer, error) {
+	addr = liveListenAddr(addr)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	live := &LiveServer{
+		logger:   l,
+		listener: listener,
+		viewers:  make(map[*liveViewer]struct{}),
+		incoming: make(chan []byte, liveQueueSize),
+		done:     make(chan struct{}),
+		pending:  make(map[string]map[string]interface{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(LivePath, live.handleViewer)
+	live.server = &http.Server{Handler: mux}
+
+	l.AddEventSink(live)
+	go live.run()
+	go live.server.Serve(listener)
+	return live, nil
+}
+
+// liveListenAddr fills in the loopback host for an address that leaves it out
+func liveListenAddr(addr string) string {
+	if addr == "" {
+		return DefaultLiveAddr
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+// ServeLive streams the client's events to WebSocket viewers, see Logger.ServeLive
+func (t *TracingHTTPClient) ServeLive(addr string) (*LiveServer, error) {
+	return t.logger.ServeLive(addr)
+}
+
+// Addr returns the address the live feed listens on
+func (s *LiveServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the live feed and disconnects every viewer
+func (s *LiveServer) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.logger.RemoveEventSink(s)
+		close(s.done)
+		err = s.server.Close()
+
+		s.viewersMu.Lock()
+		for viewer := range s.viewers {
+			viewer.close()
+		}
+		s.viewersMu.Unlock()
+	})
+	return err
+}
+
+// WriteEvent queues an event for the viewers. It never blocks: with no
+// viewers the event is ignored, and when the queue is full it is dropped.
+func (s *LiveServer) WriteEvent(line []byte) {
+	if s.viewerCount.Load() == 0 {
+		return
+	}
+
+	select {
+	case s.incoming <- append([]byte(nil), line...):
+	default:
+	}
+}
+
+// run turns queued events into feed messages and fans them out to viewers
+func (s *LiveServer) run() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case line := <-s.incoming:
+			for _, message := range s.messagesFor(line) {
+				s.broadcast(message)
+			}
+		}
+	}
+}
+
+// messagesFor builds the messages for one event, pairing responses and
+// errors with the request they answer into HAR entries
+func (s *LiveServer) messagesFor(line []byte) [][]byte {
+	eventMessage, _ := json.Marshal(liveMessage{Type: "event", Event: line})
+	messages := [][]byte{eventMessage}
+
+	var event map[string]interface{}
+	if err := json.Unmarshal(line, &event); err != nil {
+		return messages
+	}
+	// CloudEvents envelopes carry the event under data
+	event = unwrapCloudEvent(event)
+
+	requestID := eventString(event, "request_id")
+	if requestID == "" {
+		return messages
+	}
+
+	exchange := &Exchange{RequestID: requestID, Request: s.pending[requestID]}
+	switch eventString(event, "type") {
+	case "http_request":
+		if len(s.pending) < liveMaxPending {
+			s.pending[requestID] = event
+		}
+		return messages
+	case "http_response":
+		exchange.Response = event
+	case "error":
+		exchange.Errors = []map[string]interface{}{event}
+	default:
+		return messages
+	}
+	if exchange.Request == nil {
+		return messages
+	}
+	delete(s.pending, requestID)
+
+	entry := exchangeToHAREntry(exchange, s.logger.location)
+	entryMessage, err := json.Marshal(liveMessage{Type: "har_entry", Entry: &entry})
+	if err != nil {
+		return messages
+	}
+	return append(messages, entryMessage)
+}
+
+// broadcast queues a message for every viewer, skipping viewers that are behind
+func (s *LiveServer) broadcast(message []byte) {
+	s.viewersMu.Lock()
+	defer s.viewersMu.Unlock()
+
+	for viewer := range s.viewers {
+		select {
+		case viewer.send <- message:
+		default:
+		}
+	}
+}
+
+// handleViewer upgrades a request to a WebSocket connection and feeds it
+// events until either side closes it
+func (s *LiveServer) handleViewer(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || !headerHasToken(r.Header, "Connection", "upgrade") || key == "" {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return
+	}
+	// Browsers let any page open a WebSocket, so a viewer from another site
+	// could otherwise read the session
+	if !s.originAllowed(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket upgrade not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+
+	viewer := &liveViewer{conn: conn, send: make(chan []byte, liveViewerQueueSize), closed: make(chan struct{})}
+	s.addViewer(viewer)
+	defer s.removeViewer(viewer)
+
+	// Registered before the handshake completes, so no event after it is missed
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", websocketAccept(key))
+	if err := rw.Flush(); err != nil {
+		viewer.close()
+		return
+	}
+
+	go viewer.writeLoop()
+	viewer.readLoop(rw.Reader)
+}
+
+// originAllowed reports whether a viewer's Origin may read the feed. Clients
+// that send no Origin are not browsers and are allowed. A browser page must
+// be listed in LiveAllowedOrigins, or be served from the feed itself: its
+// Origin matches the Host header and that Host names the feed.
+func (s *LiveServer) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range s.logger.config.LiveAllowedOrigins {
+		if strings.EqualFold(strings.TrimRight(strings.TrimSpace(allowed), "/"), origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host) && s.hostAllowed(r.Host)
+}
+
+// hostAllowed reports whether a Host header names the feed: a loopback name
+// or the address it listens on. The Host is chosen by whoever resolved the
+// name, so a DNS-rebinding page reaching the feed under its own domain sends
+// a matching Origin and Host and is only turned away here.
+func (s *LiveServer) hostAllowed(host string) bool {
+	name := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		name = h
+	}
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "["), "]")
+
+	if strings.EqualFold(name, "localhost") {
+		return true
+	}
+	if ip := net.ParseIP(name); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	listenHost, _, err := net.SplitHostPort(s.Addr())
+	return err == nil && name == listenHost
+}
+
+func (s *LiveServer) addViewer(viewer *liveViewer) {
+	s.viewersMu.Lock()
+	defer s.viewersMu.Unlock()
+	s.viewers[viewer] = struct{}{}
+	s.viewerCount.Add(1)
+}
+
+func (s *LiveServer) removeViewer(viewer *liveViewer) {
+	s.viewersMu.Lock()
+	defer s.viewersMu.Unlock()
+	if _, ok := s.viewers[viewer]; ok {
+		delete(s.viewers, viewer)
+		s.viewerCount.Add(-1)
+	}
+	viewer.close()
+}
+
+// liveViewer is one connected WebSocket client
+type liveViewer struct {
+	conn      net.Conn
+	send      chan []byte
+	writeMu   sync.Mutex
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// writeLoop sends queued messages as text frames until the viewer closes
+func (v *liveViewer) writeLoop() {
+	for {
+		select {
+		case <-v.closed:
+			return
+		case message := <-v.send:
+			if err := v.writeFrame(wsOpText, message); err != nil {
+				v.close()
+				return
+			}
+		}
+	}
+}
+
+// readLoop answers pings and returns once the client closes the connection.
+// The feed is one-way, so any data the client sends is discarded.
+func (v *liveViewer) readLoop(r *bufio.Reader) {
+	for {
+		opcode, payload, err := readClientFrame(r)
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case wsOpClose:
+			v.writeFrame(wsOpClose, payload)
+			return
+		case wsOpPing:
+			if err := v.writeFrame(wsOpPong, payload); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeFrame writes a single unmasked, unfragmented frame
+func (v *liveViewer) writeFrame(opcode byte, payload []byte) error {
+	v.writeMu.Lock()
+	defer v.writeMu.Unlock()
+
+	header := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		header = append(header, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(len(payload)))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(len(payload)))
+	}
+
+	if _, err := v.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (v *liveViewer) close() {
+	v.closeOnce.Do(func() {
+		close(v.closed)
+		v.conn.Close()
+	})
+}
+
+// readClientFrame reads one frame from a client, which must mask it
+func readClientFrame(r *bufio.Reader) (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := header[0] & 0x0F
+	if header[1]&0x80 == 0 {
+		return 0, nil, errors.New("websocket: client frame not masked")
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(r, extended[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(r, extended[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if length > liveMaxFrameSize {
+		return 0, nil, errors.New("websocket: client frame too large")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(r, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
+
+// websocketAccept derives the Sec-WebSocket-Accept value for a client key
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerHasToken reports whether a comma-separated header lists token
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
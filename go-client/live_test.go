@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dialLive performs a WebSocket handshake against a live feed
+func dialLive(t *testing.T, addr string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect to live feed: %v", err)
+	}
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", LivePath, addr, key)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Failed to read handshake: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101, got %d", resp.StatusCode)
+	}
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Unexpected Sec-WebSocket-Accept %q", accept)
+	}
+	return conn, reader
+}
+
+// readServerFrame reads one unmasked frame sent by the live feed
+func readServerFrame(r *bufio.Reader) (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(r, extended[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(r, extended[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	payload := make([]byte, length)
+	_, err := io.ReadFull(r, payload)
+	return header[0] & 0x0F, payload, err
+}
+
+func TestServeLiveStreamsEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("live"))
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-live-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	client := NewTracingHTTPClientWithConfig("test-live", newTestConfig(tempDir))
+	defer client.Close()
+
+	live, err := client.ServeLive("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ServeLive failed: %v", err)
+	}
+	defer live.Close()
+
+	conn, reader := dialLive(t, live.Addr())
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	resp, err := client.Get(server.URL + "/watched")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	var types []string
+	var entry map[string]interface{}
+	for entry == nil {
+		opcode, payload, err := readServerFrame(reader)
+		if err != nil {
+			t.Fatalf("Failed to read feed after %v: %v", types, err)
+		}
+		if opcode != wsOpText {
+			t.Fatalf("Expected a text frame, got opcode %d", opcode)
+		}
+
+		var message map[string]interface{}
+		if err := json.Unmarshal(payload, &message); err != nil {
+			t.Fatalf("Feed message is not JSON: %v", err)
+		}
+		switch message["type"] {
+		case "event":
+			event := message["event"].(map[string]interface{})
+			types = append(types, event["type"].(string))
+		case "har_entry":
+			entry = message["entry"].(map[string]interface{})
+		}
+	}
+
+	if strings.Join(types, ",") != "http_request,http_response" {
+		t.Errorf("Expected request and response events, got %v", types)
+	}
+	request := entry["request"].(map[string]interface{})
+	response := entry["response"].(map[string]interface{})
+	if request["url"] != server.URL+"/watched" || response["status"] != float64(200) {
+		t.Errorf("Unexpected HAR entry: %v", entry)
+	}
+}
+
+func TestServeLiveWithoutViewers(t *testing.T) {
+	logger := NewLogger(&TracingConfig{}, "session")
+	live, err := logger.ServeLive("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ServeLive failed: %v", err)
+	}
+	defer live.Close()
+
+	// With nobody watching events are ignored rather than queued
+	for i := 0; i < 2*liveQueueSize; i++ {
+		live.WriteEvent([]byte(`{"type":"error"}`))
+	}
+	if queued := len(live.incoming); queued != 0 {
+		t.Errorf("Expected no queued events without viewers, got %d", queued)
+	}
+}
+
+func TestServeLiveRejectsPlainHTTP(t *testing.T) {
+	logger := NewLogger(&TracingConfig{}, "session")
+	live, err := logger.ServeLive("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ServeLive failed: %v", err)
+	}
+	defer live.Close()
+
+	resp, err := http.Get("http://" + live.Addr() + LivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a request without an upgrade, got %d", resp.StatusCode)
+	}
+}
+
+// liveHandshakeStatus returns the status of a WebSocket handshake sent with origin
+func liveHandshakeStatus(t *testing.T, addr, host, origin string) int {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect to live feed: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: %s\r\nOrigin: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n", LivePath, host, origin)
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("Failed to read handshake: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestServeLiveChecksOrigin(t *testing.T) {
+	logger := NewLogger(&TracingConfig{LiveAllowedOrigins: []string{"https://viewer.example.com/"}}, "session")
+	live, err := logger.ServeLive("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ServeLive failed: %v", err)
+	}
+	defer live.Close()
+
+	_, port, _ := net.SplitHostPort(live.Addr())
+	rebound := "evil.example:" + port
+	tests := []struct {
+		host   string
+		origin string
+		status int
+	}{
+		{live.Addr(), "https://evil.example.com", http.StatusForbidden},
+		{live.Addr(), "null", http.StatusForbidden},
+		{live.Addr(), "http://" + live.Addr(), http.StatusSwitchingProtocols},
+		{"localhost:" + port, "http://localhost:" + port, http.StatusSwitchingProtocols},
+		{"[::1]:" + port, "http://[::1]:" + port, http.StatusSwitchingProtocols},
+		{live.Addr(), "https://viewer.example.com", http.StatusSwitchingProtocols},
+		// A DNS-rebinding page sends its own domain as both Origin and Host
+		{rebound, "http://" + rebound, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if status := liveHandshakeStatus(t, live.Addr(), tt.host, tt.origin); status != tt.status {
+			t.Errorf("Host %q, Origin %q: expected %d, got %d", tt.host, tt.origin, tt.status, status)
+		}
+	}
+}
+
+func TestLiveListenAddrDefaultsToLoopback(t *testing.T) {
+	tests := map[string]string{
+		"":             DefaultLiveAddr,
+		":8765":        "127.0.0.1:8765",
+		"0.0.0.0:8765": "0.0.0.0:8765",
+		"[::1]:0":      "[::1]:0",
+	}
+	for addr, want := range tests {
+		if got := liveListenAddr(addr); got != want {
+			t.Errorf("liveListenAddr(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
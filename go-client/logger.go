@@ -3,9 +3,13 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,14 +17,165 @@ import (
 type Logger struct {
 	config    *TracingConfig
 	sessionID string
+
+	// Disk space guard state
+	mu                sync.Mutex
+	freeDiskSpace     func(path string) (uint64, error)
+	diskCheckInterval time.Duration
+	lastDiskCheck     time.Time
+	diskChecking      bool
+	suspended         bool
+
+	// Async writer, nil when writing synchronously
+	async *asyncWriter
+
+	// Requests in flight per connection, for connection sharing accounting
+	connSharing connSharing
+
+	eventsWritten atomic.Int64
+	sessionBytes  atomic.Int64
+	requestSlots  atomic.Int64
+	exhausted     bool
+	closeOnce     sync.Once
+
+	// Request counters behind Stats
+	stats requestStats
+
+	// Lowest remaining rate-limit quota seen, by provider and limit kind
+	rateLimitMu        sync.Mutex
+	rateLimitRemaining map[string]map[string]int64
+
+	// Baseline session requests are compared against, nil when not configured
+	baseline *baselineMatcher
+
+	// Secrets seen in responses, redacted wherever they appear afterwards
+	secretsMu sync.RWMutex
+	secrets   []string
+
+	// Start of the latest request logged, for gap_since_prev_ms
+	lastRequestMu    sync.Mutex
+	lastRequestStart time.Time
+
+	// Slowest requests for the session summary
+	slowMu  sync.Mutex
+	slowest []SlowRequest
+
+	// Requests per fingerprint for the session summary
+	fingerprintMu sync.Mutex
+	fingerprints  map[string]int64
+
+	// Session file, named on first write unless given to NewLoggerWithFile
+	sessionFileMu sync.Mutex
+	sessionFile   string
+	fixedFile     bool
+
+	// Set once the sessions directory has been prepared for the first write
+	sessionsDirReady atomic.Bool
+
+	// Byte range of this session in the AggregateFile, see aggregate.go
+	aggregate aggregateRange
+
+	// Where events are appended, SessionStore or the local file system
+	store SessionStore
+
+	// Non-fatal issues for embedding applications, see Warnings
+	warnings     chan Warning
+	warningCount atomic.Int64
+
+	// Where RFC 3339 timestamps are rendered, from TimeZone
+	location *time.Location
+
+	// Receivers of every written event, see AddEventSink
+	sinksMu sync.RWMutex
+	sinks   []EventSink
+
+	// hostname and pid members added to every event, with IncludeHostInfo
+	hostFields []byte
+
+	// Decoded IntegrityKey, nil when integrity fields are off
+	integrityKey []byte
+
+	// PathMaskPatterns compiled once for maskPath
+	pathMaskPatterns []*regexp.Regexp
 }
 
 // NewLogger creates a new logger instance
 func NewLogger(config *TracingConfig, sessionID string) *Logger {
-	return &Logger{
-		config:    config,
-		sessionID: sessionID,
+	logger := &Logger{
+		config:            config,
+		sessionID:         sessionID,
+		freeDiskSpace:     availableDiskSpace,
+		diskCheckInterval: diskSpaceCheckInterval,
+		warnings:          make(chan Warning, warningBufferSize),
+		location:          config.timeLocation(),
+		store:             config.SessionStore,
+	}
+	if logger.store == nil {
+		logger.store = &FileSystemStore{Sync: config.FsyncOnWrite}
+	}
+	if config.IncludeHostInfo {
+		logger.hostFields = hostInfoFields()
+	}
+	logger.pathMaskPatterns = config.compilePathMaskPatterns()
+
+	// The aggregate index is a file beside the AggregateFile, so only local files aggregate
+	if config.AggregateFile != "" && config.SessionStore == nil {
+		logger.sessionFile = config.AggregateFile
+		logger.fixedFile = true
+		logger.aggregate.enabled = true
+	}
+	for _, err := range config.sessionStoreConflicts() {
+		logger.warn(WarningInvalidConfig, "", "%v; the setting is ignored", err)
 	}
+	if config.MaxLineBytes > 0 && config.MaxLineBytes < minLineBytes {
+		logger.warn(WarningInvalidConfig, "", "max_line_bytes %d is below the minimum of %d; the minimum is used", config.MaxLineBytes, minLineBytes)
+	}
+	if key, err := config.integrityKey(); err != nil {
+		logger.warn(WarningInvalidConfig, "", "%v; integrity fields are off", err)
+	} else {
+		logger.integrityKey = key
+	}
+	// An unknown async_overflow_policy would otherwise block without a word
+	for _, err := range config.choiceErrors() {
+		logger.warn(WarningInvalidConfig, "", "%v; the default is used", err)
+	}
+
+	if config.AsyncWrite {
+		logger.async = newAsyncWriter(config.AsyncQueueSize, config.AsyncOverflowPolicy, func(event interface{}) {
+			logger.persistEvent(event)
+		})
+		logger.async.onDrop = func() {
+			logger.warn(WarningEventDropped, "", "async write queue full, event dropped (policy %s)", config.AsyncOverflowPolicy)
+		}
+		logger.async.start()
+	}
+
+	for _, path := range config.RedactResponseJSONPaths {
+		if _, ok := parseJSONPath(path); !ok {
+			logger.warn(WarningInvalidRedaction, "", "redaction path %q is invalid and is ignored", path)
+		}
+	}
+
+	if config.BaselineSession != "" {
+		baseline, err := loadBaseline(config.BaselineSession)
+		if err != nil {
+			logger.LogError(err, "failed to load baseline session")
+		}
+		logger.baseline = baseline
+	}
+
+	return logger
+}
+
+// NewLoggerWithFile creates a logger that appends to path instead of a
+// timestamped file in the sessions directory, so that repeated runs can
+// continue the same session file
+func NewLoggerWithFile(config *TracingConfig, sessionID, path string) *Logger {
+	logger := NewLogger(config, sessionID)
+	logger.sessionFile = path
+	logger.fixedFile = true
+	logger.aggregate.enabled = false
+	return logger
 }
 
 // LogHTTPRequest logs an HTTP request event
@@ -29,28 +184,94 @@ func (l *Logger) LogHTTPRequest(capture *RequestCapture) error {
 		return nil
 	}
 
+	event := l.requestEvent(capture)
+	l.observeFingerprint(event.Fingerprint)
+	gap := l.gapSincePreviousRequest(capture.StartTime).Milliseconds()
+	event.GapSincePrev = &gap
+	l.annotateBaseline(&event)
+
+	// A required audit trail cannot be handed to the async queue unchecked
+	if l.config.RequireTracing {
+		return l.persistRequired(event)
+	}
+	return l.writeEvent(event)
+}
+
+// gapSincePreviousRequest returns the time between the start of the latest
+// request logged so far and start, and records start. Concurrent requests
+// logged out of order get a gap of 0.
+func (l *Logger) gapSincePreviousRequest(start time.Time) time.Duration {
+	l.lastRequestMu.Lock()
+	defer l.lastRequestMu.Unlock()
+
+	var gap time.Duration
+	if !l.lastRequestStart.IsZero() {
+		gap = start.Sub(l.lastRequestStart)
+	}
+	if gap < 0 {
+		return 0
+	}
+	l.lastRequestStart = start
+	return gap
+}
+
+// requestEvent builds a sanitized request event from a capture
+func (l *Logger) requestEvent(capture *RequestCapture) HTTPRequestEvent {
+	headers := l.sanitizeHeaders(capture.Headers)
 	event := HTTPRequestEvent{
 		Type:        "http_request",
 		Timestamp:   capture.StartTime.UnixMilli(),
 		SessionID:   l.sessionID,
+		RequestID:   capture.RequestID,
 		Method:      capture.Method,
-		URL:         capture.URL,
-		Headers:     l.sanitizeHeaders(capture.Headers),
+		URL:         l.redactURL(capture.URL),
+		Headers:     headers,
 		ContentType: capture.ContentType,
 		UserAgent:   capture.UserAgent,
+		Timeout:     capture.Timeout.Milliseconds(),
+		RequestSize: capture.RequestSize,
+		Host:        capture.Host,
+		UnixSocket:  capture.UnixSocket,
+
+		EffectiveMethod: capture.EffectiveMethod,
+		ChainPosition:   capture.ChainPosition,
+
+		RawRequestLine: l.redactRequestLine(capture.RawRequestLine),
+		GraphQL:        parseGraphQL(capture.Method, capture.ContentType, capture.Body),
+
+		ContentNegotiation: parseContentNegotiation(headers),
+		Fingerprint:        l.requestFingerprint(capture),
+
+		PromotedHeaders: l.promoteHeaders(headers),
+		HeadersMulti:    l.sanitizeMultiHeaders(capture.MultiHeaders),
+
+		Extra: capture.Extra,
 	}
+	event.RetryID, event.RetryAttempt = capture.Retry.eventFields()
 
 	// Add body if enabled and within size limits
-	if l.config.CaptureRequestBodies && len(capture.Body) > 0 {
-		if int64(len(capture.Body)) <= l.config.MaxBodySize {
-			event.Body = string(capture.Body)
+	if l.config.captureRequestBody(capture.URL) && len(capture.Body) > 0 {
+		if l.overBodyBudget() {
+			event.BodySuppressedBudget = true
+		} else if capture.BodyTruncated && !l.config.CaptureGraphQLVariables && mayHoldGraphQLVariables(capture.Method, capture.Body) {
+			// A cut-off GraphQL request is not recognized, so its variables cannot be redacted
+			event.BodyRedactionFailed = true
+			l.warn(WarningBodyRedaction, capture.RequestID, "truncated GraphQL request body could not be redacted and was not stored")
 		} else {
-			event.Body = fmt.Sprintf("[TRUNCATED - Body size %d bytes exceeds limit %d bytes]",
-				len(capture.Body), l.config.MaxBodySize)
+			body := l.redactTrackedSecretsInBody(capture.Body)
+			// GraphQL variables often carry IDs and secrets
+			if event.GraphQL != nil && !l.config.CaptureGraphQLVariables {
+				body = redactJSONPaths(body, []string{graphQLVariablesPath})
+			}
+			event.Body, event.BodyEncoding, event.BodyOriginalSize, event.Charset = l.encodeTextBody(body, capture.ContentType)
 		}
 	}
 
-	return l.writeEvent(event)
+	if len(l.integrityKey) > 0 && event.Body != "" {
+		event.BodySHA256, event.BodyLength = bodyDigest(event.Body)
+	}
+
+	return event
 }
 
 // LogHTTPResponse logs an HTTP response event
@@ -59,29 +280,170 @@ func (l *Logger) LogHTTPResponse(capture *ResponseCapture) error {
 		return nil
 	}
 
+	event := l.responseEvent(capture)
+	l.observeRateLimit(event.RateLimit)
+	l.observeSlowRequest(capture, &event)
+
+	if err := l.writeEvent(event); err != nil {
+		return err
+	}
+
+	// Streamed LLM output is only reconstructed from a body the response
+	// event stored, so it is never less redacted than that body
+	if event.Body == "" {
+		return nil
+	}
+	if stream := aggregateLLMStream(capture); stream != nil {
+		return l.LogLLMStreamComplete(capture, stream)
+	}
+	return nil
+}
+
+// responseEvent builds a sanitized response event from a capture
+func (l *Logger) responseEvent(capture *ResponseCapture) HTTPResponseEvent {
+	// Structured fields such as rate_limit still see every header
+	headers := l.sanitizeHeaders(l.config.allowedResponseHeaders(capture.Headers))
 	event := HTTPResponseEvent{
 		Type:         "http_response",
 		Timestamp:    capture.EndTime.UnixMilli(),
 		SessionID:    l.sessionID,
+		RequestID:    capture.RequestID,
 		StatusCode:   capture.StatusCode,
 		Status:       capture.Status,
-		Headers:      l.sanitizeHeaders(capture.Headers),
+		Headers:      headers,
 		ContentType:  capture.ContentType,
 		ResponseSize: capture.ResponseSize,
 		Duration:     capture.Duration.Milliseconds(),
 		Success:      capture.Success,
+		RateLimit:    parseRateLimit(capture.Headers),
+		Cache:        parseCacheInfo(capture.Headers),
+		DNS:          capture.DNS,
+		EstimatedRTT: capture.EstimatedRTT,
+		DecodedSize:  capture.DecodedSize,
+		Compression:  capture.Compression,
+
+		DetectedContentType: capture.DetectedContentType,
+		RawStatusLine:       capture.RawStatusLine,
+		ConnectionAttempts:  capture.ConnectionAttempts,
+		ConnectionReused:    capture.ConnectionReused,
+		ConnectionInFlight:  capture.ConnectionInFlight,
+		RequestProto:        capture.RequestProto,
+		Proto:               capture.Proto,
+		TLSVersion:          capture.TLSVersion,
+		NegotiatedProtocol:  capture.NegotiatedProtocol,
+		ConditionalRequest:  capture.ConditionalRequest,
+		Revalidated:         capture.Revalidated,
+		FromCache:           capture.FromCache,
+		BodyDecoded:         capture.BodyDecoded,
+		PromotedHeaders:     l.promoteHeaders(headers),
+		HeadersMulti:        l.sanitizeMultiHeaders(l.config.allowedResponseMultiHeaders(capture.MultiHeaders)),
+
+		Extra: capture.Extra,
+	}
+
+	if capture.Waited100Continue {
+		wait := capture.Wait100Continue.Milliseconds()
+		event.Wait100Continue = &wait
+	}
+	event.RetryID, event.RetryAttempt = capture.Retry.eventFields()
+	if capture.BodyNotCapturedReason != "" {
+		captured := false
+		event.BodyCaptured, event.Reason = &captured, capture.BodyNotCapturedReason
+	}
+	if ratio, ok := compressionRatio(capture.CompressedSize, capture.UncompressedSize); ok {
+		event.CompressedSize = capture.CompressedSize
+		event.UncompressedSize = capture.UncompressedSize
+		event.CompressionRatio = ratio
+	}
+	if capture.HasTTFB {
+		ttfb := capture.TTFB.Milliseconds()
+		event.TTFB = &ttfb
+	}
+	if capture.Streamed {
+		streamDuration := capture.StreamDuration.Milliseconds()
+		event.StreamDuration = &streamDuration
 	}
 
 	// Add body if enabled and within size limits
-	if l.config.CaptureResponseBodies && len(capture.Body) > 0 {
-		if int64(len(capture.Body)) <= l.config.MaxBodySize {
-			event.Body = string(capture.Body)
+	if l.config.captureResponseBody(capture.URL, capture.StatusCode) && len(capture.Body) > 0 {
+		// Credentials minted by the server are redacted here and wherever they appear later
+		body, secrets := redactSecretFields(capture.Body)
+
+		// A cut-off JSON body does not parse, so neither its secret fields nor
+		// its paths can be redacted; secrets it shows in full are still tracked
+		unredactable := false
+		if capture.BodyTruncated && (containsSecretField(body) || containsSecretField(capture.BodyTail)) {
+			secrets, unredactable = fragmentSecretFields(body), true
+		} else if capture.BodyTruncated && len(l.config.RedactResponseJSONPaths) > 0 && looksLikeJSON(body) {
+			unredactable = true
+		}
+		l.trackSecrets(secrets)
+
+		if l.overBodyBudget() {
+			event.BodySuppressedBudget = true
+		} else if unredactable {
+			event.BodyRedactionFailed = true
+			l.warn(WarningBodyRedaction, capture.RequestID, "truncated JSON body could not be redacted and was not stored")
 		} else {
-			event.Body = fmt.Sprintf("[TRUNCATED - Body size %d bytes exceeds limit %d bytes]",
-				len(capture.Body), l.config.MaxBodySize)
+			body = l.redactTrackedSecretsInBody(body)
+			body = redactJSONPaths(body, l.config.RedactResponseJSONPaths)
+			if capture.BodyTail != nil {
+				sampled := headTailBody(body, l.redactTrackedSecretsInBody(capture.BodyTail), capture.BodyOmitted)
+				sampled, event.Charset = transcodeBody(capture.ContentType, sampled)
+				event.Body, event.BodyEncoding, event.BodyOriginalSize = l.compressBody(sampled)
+			} else {
+				event.Body, event.BodyEncoding, event.BodyOriginalSize, event.Charset = l.encodeTextBody(body, capture.ContentType)
+			}
 		}
 	}
 
+	if len(l.integrityKey) > 0 && event.Body != "" {
+		event.BodySHA256, event.BodyLength = bodyDigest(event.Body)
+	}
+
+	return event
+}
+
+// LogHTTPInformational logs an interim 1xx response event
+func (l *Logger) LogHTTPInformational(requestID string, statusCode int, headers map[string]string, timestamp time.Time) error {
+	if !l.config.Enabled {
+		return nil
+	}
+
+	event := HTTPInformationalEvent{
+		Type:       "http_1xx",
+		Timestamp:  timestamp.UnixMilli(),
+		SessionID:  l.sessionID,
+		RequestID:  requestID,
+		StatusCode: statusCode,
+		Headers:    l.sanitizeHeaders(l.config.allowedResponseHeaders(headers)),
+	}
+
+	return l.writeEvent(event)
+}
+
+// LogLLMStreamComplete logs the aggregated result of a streamed LLM response.
+// A stream rebuilt from a truncated or head/tail sampled body is marked truncated.
+func (l *Logger) LogLLMStreamComplete(capture *ResponseCapture, stream *LLMStreamAggregate) error {
+	if !l.config.Enabled {
+		return nil
+	}
+
+	event := LLMStreamCompleteEvent{
+		Type:             "llm_stream_complete",
+		Timestamp:        capture.EndTime.UnixMilli(),
+		SessionID:        l.sessionID,
+		RequestID:        capture.RequestID,
+		Provider:         stream.Provider,
+		Model:            stream.Model,
+		Text:             l.redactTrackedSecrets(stream.Text.String()),
+		TokenCount:       stream.TokenCount(),
+		TokenCountSource: stream.TokenCountSource(),
+		FrameCount:       stream.FrameCount,
+		FinishReason:     stream.FinishReason,
+		Truncated:        capture.BodyTruncated || capture.BodyTail != nil,
+	}
+
 	return l.writeEvent(event)
 }
 
@@ -96,7 +458,27 @@ func (l *Logger) LogError(err error, context string) error {
 		"timestamp":  time.Now().UnixMilli(),
 		"session_id": l.sessionID,
 		"error": map[string]string{
-			"message": err.Error(),
+			"message": l.redactError(err),
+			"context": context,
+		},
+	}
+
+	return l.writeEvent(errorEvent)
+}
+
+// LogRequestError logs an error event tied to a specific request
+func (l *Logger) LogRequestError(requestID string, err error, context string) error {
+	if !l.config.Enabled {
+		return nil
+	}
+
+	errorEvent := map[string]interface{}{
+		"type":       "error",
+		"timestamp":  time.Now().UnixMilli(),
+		"session_id": l.sessionID,
+		"request_id": requestID,
+		"error": map[string]string{
+			"message": l.redactError(err),
 			"context": context,
 		},
 	}
@@ -104,51 +486,206 @@ func (l *Logger) LogError(err error, context string) error {
 	return l.writeEvent(errorEvent)
 }
 
-// writeEvent writes an event to the JSONL file
+// writeEvent writes an event to the JSONL file, handing it to the async writer when enabled
 func (l *Logger) writeEvent(event interface{}) error {
-	// Serialize event to JSON
+	if l.async != nil && l.async.enqueue(event) {
+		return nil
+	}
+
+	return l.persistEvent(event)
+}
+
+// persistEvent writes an event to the JSONL file unless tracing is suspended
+// or the line would not fit in the session's hard byte budget
+func (l *Logger) persistEvent(event interface{}) error {
+	if !l.hasDiskSpace() {
+		return nil
+	}
+
+	data, err := l.encodeEvent(event)
+	if err != nil {
+		return err
+	}
+	if !l.withinHardBudget(int64(len(data)) + 1) {
+		return nil
+	}
+	return l.writeLine(data)
+}
+
+// appendEvent serializes an event and appends it to the session file,
+// bypassing the budgets
+func (l *Logger) appendEvent(event interface{}) error {
+	data, err := l.encodeEvent(event)
+	if err != nil {
+		return err
+	}
+	l.sessionBytes.Add(int64(len(data)) + 1)
+	return l.writeLine(data)
+}
+
+// encodeEvent serializes an event into the line written for it, without the
+// trailing newline
+func (l *Logger) encodeEvent(event interface{}) ([]byte, error) {
 	data, err := json.Marshal(event)
 	if err != nil {
-		return fmt.Errorf("failed to marshal event: %w", err)
+		return nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+	if l.hostFields != nil {
+		data = addEventFields(data, l.hostFields)
 	}
+	return l.capFramedLine(data)
+}
+
+// writeLine appends an encoded event to the session file. The caller has
+// already counted the line in sessionBytes; a failed write gives it back.
+func (l *Logger) writeLine(data []byte) error {
+	n := len(data) + 1
 
 	// Get session file path
 	sessionFile, err := l.getSessionFilePath()
 	if err != nil {
+		l.sessionBytes.Add(-int64(n))
 		return fmt.Errorf("failed to get session file path: %w", err)
 	}
 
-	// Ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(sessionFile), 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	// The output directory rules apply to the default store; others need no
+	// preparation. The directory is prepared once, not on every event.
+	if l.config.SessionStore == nil && !l.fixedFile && !l.sessionsDirReady.Load() {
+		if err := ensureSessionsDir(l.config); err != nil {
+			l.sessionBytes.Add(-int64(n))
+			return err
+		}
+		l.sessionsDirReady.Store(true)
 	}
 
 	// Append to session file (JSONL format - one JSON object per line)
-	file, err := os.OpenFile(sessionFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	line := append(data, '\n')
+	offset, err := l.store.Append(sessionFile, line)
 	if err != nil {
-		return fmt.Errorf("failed to open session file: %w", err)
+		l.sessionBytes.Add(-int64(n))
+		return err
+	}
+	if l.aggregate.enabled {
+		l.recordAggregateWrite(sessionFile, offset, int64(n))
+	}
+	l.notifySinks(data)
+
+	l.eventsWritten.Add(1)
+	return nil
+}
+
+// capFramedLine caps, seals and wraps a serialized event. MaxLineBytes
+// applies to the line as written, so the room the hmac and the CloudEvents
+// envelope take is cut from the event before they are added.
+func (l *Logger) capFramedLine(data []byte) ([]byte, error) {
+	maxBytes := l.config.MaxLineBytes
+	if maxBytes > 0 && maxBytes < minLineBytes {
+		maxBytes = minLineBytes
+	}
+	limit := maxBytes
+	for {
+		capped := capLine(data, limit)
+		line, err := l.frameEvent(capped)
+		if err != nil {
+			return nil, err
+		}
+		if maxBytes <= 0 || len(line) <= maxBytes || limit == 1 {
+			if len(capped) != len(data) {
+				l.warn(WarningLineTruncated, "", "event of %d bytes cut to the %d byte line limit", len(data), maxBytes)
+			}
+			return line, nil
+		}
+
+		limit -= len(line) - maxBytes
+		if limit < 1 {
+			limit = 1
+		}
+	}
+}
+
+// frameEvent seals and wraps a capped event as configured
+func (l *Logger) frameEvent(data []byte) ([]byte, error) {
+	var err error
+
+	// Seal after capping, so the hmac covers exactly the event bytes written
+	if len(l.integrityKey) > 0 {
+		if data, err = sealEvent(data, l.integrityKey); err != nil {
+			return nil, fmt.Errorf("failed to seal event: %w", err)
+		}
+	}
+
+	// Wrap last, so data holds the event exactly as the raw format writes it
+	if l.config.EventFormat == EventFormatCloudEvents {
+		if data, err = l.wrapCloudEvent(data); err != nil {
+			return nil, fmt.Errorf("failed to wrap event: %w", err)
+		}
+	}
+	return data, nil
+}
+
+// appendLine appends a complete line to path with a single write while
+// holding an advisory lock, so that processes sharing a session file never
+// interleave partial lines. With sync set the line is flushed to stable
+// storage before appendLine returns.
+func appendLine(path string, line []byte, sync bool) (int, error) {
+	var syncFile func(*os.File) error
+	if sync {
+		syncFile = (*os.File).Sync
+	}
+	_, n, err := appendLineAt(path, line, syncFile)
+	return n, err
+}
+
+// appendLineAt is appendLine that also returns the offset the line was
+// written at, read under the lock so no other writer can move it. The line
+// is flushed with syncFile unless it is nil.
+func appendLineAt(path string, line []byte, syncFile func(*os.File) error) (int64, int, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open session file: %w", err)
 	}
 	defer file.Close()
 
-	// Write JSON line
-	if _, err := file.Write(append(data, '\n')); err != nil {
-		return fmt.Errorf("failed to write event: %w", err)
+	if err := lockFile(file); err != nil {
+		return 0, 0, fmt.Errorf("failed to lock session file: %w", err)
 	}
+	defer unlockFile(file)
 
-	return nil
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to find end of session file: %w", err)
+	}
+
+	n, err := file.Write(line)
+	if err != nil {
+		return offset, n, fmt.Errorf("failed to write event: %w", err)
+	}
+
+	if syncFile != nil {
+		if err := syncFile(file); err != nil {
+			return offset, n, fmt.Errorf("failed to sync session file: %w", err)
+		}
+	}
+	return offset, n, nil
 }
 
-// getSessionFilePath returns the path to the session JSONL file
+// getSessionFilePath returns the path to the session JSONL file. The name is
+// fixed on the first write, so a session never spans several files.
 func (l *Logger) getSessionFilePath() (string, error) {
 	if l.sessionID == "" {
 		return "", fmt.Errorf("session ID is empty")
 	}
 
-	// Create filename with timestamp pattern: YYYY-MM-DD_HH-mm-ss_session-{id}.jsonl
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	filename := fmt.Sprintf("%s_session-%s.jsonl", timestamp, l.sessionID)
-	
-	return filepath.Join(l.config.OutputDir, "sessions", filename), nil
+	l.sessionFileMu.Lock()
+	defer l.sessionFileMu.Unlock()
+
+	if l.sessionFile == "" {
+		// Create filename with timestamp pattern: YYYY-MM-DD_HH-mm-ss_session-{id}.jsonl
+		timestamp := time.Now().Format("2006-01-02_15-04-05")
+		filename := fmt.Sprintf("%s_session-%s.jsonl", timestamp, l.sessionID)
+		l.sessionFile = filepath.Join(sessionsDir(l.config), filename)
+	}
+	return l.sessionFile, nil
 }
 
 // sanitizeHeaders removes sensitive headers and returns a clean copy
@@ -160,19 +697,71 @@ func (l *Logger) sanitizeHeaders(headers map[string]string) map[string]string {
 	sanitized := make(map[string]string)
 	
 	for key, value := range headers {
-		if l.isSensitiveHeader(key) {
-			sanitized[key] = "[REDACTED]"
-		} else {
-			sanitized[key] = value
-		}
+		sanitized[key] = l.sanitizeHeaderValue(key, value)
+	}
+
+	return sanitized
+}
+
+// sanitizeHeaderValue redacts one header value for an event. Headers that
+// carry a URL have its path masked like the request URL.
+func (l *Logger) sanitizeHeaderValue(key, value string) string {
+	if l.isSensitiveHeader(key) {
+		return redactedValue
 	}
+	value = l.redactTrackedSecrets(value)
+	if isURLHeader(key) {
+		value = l.maskPath(value)
+	}
+	return l.headerValueUTF8(value)
+}
 
+// sanitizeMultiHeaders is sanitizeHeaders for headers keeping every value
+func (l *Logger) sanitizeMultiHeaders(headers map[string][]string) map[string][]string {
+	if headers == nil {
+		return nil
+	}
+
+	sanitized := make(map[string][]string, len(headers))
+	for key, values := range headers {
+		sanitizedValues := make([]string, len(values))
+		for i, value := range values {
+			sanitizedValues[i] = l.sanitizeHeaderValue(key, value)
+		}
+		sanitized[key] = sanitizedValues
+	}
 	return sanitized
 }
 
+// promoteHeaders copies the sanitized headers named in PromoteHeaders into a
+// map keyed by lower-cased name, so sensitive ones stay redacted
+func (l *Logger) promoteHeaders(headers map[string]string) map[string]string {
+	if len(l.config.PromoteHeaders) == 0 {
+		return nil
+	}
+
+	var promoted map[string]string
+	for key, value := range headers {
+		for _, name := range l.config.PromoteHeaders {
+			if strings.EqualFold(key, name) {
+				if promoted == nil {
+					promoted = make(map[string]string)
+				}
+				promoted[strings.ToLower(name)] = value
+			}
+		}
+	}
+	return promoted
+}
+
 // isSensitiveHeader checks if a header contains sensitive information
 func (l *Logger) isSensitiveHeader(headerName string) bool {
 	lowerHeader := strings.ToLower(headerName)
+
+	// Provider rate-limit headers are never secret
+	if isRateLimitHeader(lowerHeader) {
+		return false
+	}
 	
 	for _, sensitive := range l.config.SensitiveHeaders {
 		if strings.Contains(lowerHeader, strings.ToLower(sensitive)) {
@@ -183,14 +772,25 @@ func (l *Logger) isSensitiveHeader(headerName string) bool {
 	return false
 }
 
-// Flush ensures all pending writes are flushed (placeholder for future buffering)
+// Flush waits until all queued events have been written
 func (l *Logger) Flush() error {
-	// Currently writing synchronously, but this could be enhanced
-	// with buffering in the future
+	if l.async != nil {
+		l.async.flush()
+	}
 	return nil
 }
 
-// Close cleans up the logger (placeholder for future cleanup)
+// Close drains any queued events and writes the session summary
 func (l *Logger) Close() error {
-	return l.Flush()
+	var err error
+	l.closeOnce.Do(func() {
+		if l.async != nil {
+			l.async.close()
+		}
+		err = l.writeSummary()
+		if indexErr := l.closeAggregate(); err == nil {
+			err = indexErr
+		}
+	})
+	return err
 }
\ No newline at end of file
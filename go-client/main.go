@@ -5,10 +5,16 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"strings"
 )
 
 func main() {
+	// Subcommands (e.g. export-otlp) run instead of the demo
+	if len(os.Args) > 1 {
+		exitWithCommand()
+	}
+
 	fmt.Println("opencode-trace Go client v1.0.0")
 	
 	// Check if tracing is enabled
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// methodOverrideHeaders name the method a POST is tunneling, in the order
+// they are consulted
+var methodOverrideHeaders = []string{
+	"X-HTTP-Method-Override",
+	"X-HTTP-Method",
+	"X-Method-Override",
+}
+
+// effectiveMethod returns the method requested by an override header, or ""
+// when there is none or it matches the wire method
+func effectiveMethod(req *http.Request) string {
+	for _, name := range methodOverrideHeaders {
+		override := strings.ToUpper(strings.TrimSpace(req.Header.Get(name)))
+		if override == "" {
+			continue
+		}
+		if override == req.Method {
+			return ""
+		}
+		return override
+	}
+	return ""
+}
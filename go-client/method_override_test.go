@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMethodOverrideRecorded(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-method-override-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Method
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-method-override", newTestConfig(tempDir))
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/items/1", strings.NewReader("{}"))
+	req.Header.Set("X-HTTP-Method-Override", "DELETE")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if method := <-received; method != http.MethodPost {
+		t.Fatalf("Expected the request to be sent unchanged as POST, got %s", method)
+	}
+
+	requests := eventsOfType(readSessionEvents(t, tempDir), "http_request")
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request event, got %d", len(requests))
+	}
+	if requests[0]["method"] != "POST" || requests[0]["effective_method"] != "DELETE" {
+		t.Errorf("Expected method POST and effective_method DELETE, got %v and %v", requests[0]["method"], requests[0]["effective_method"])
+	}
+}
+
+func TestEffectiveMethod(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		header   string
+		value    string
+		expected string
+	}{
+		{"no override", http.MethodPost, "", "", ""},
+		{"method override", http.MethodPost, "X-HTTP-Method-Override", "delete", "DELETE"},
+		{"x-http-method", http.MethodPost, "X-HTTP-Method", "PUT", "PUT"},
+		{"x-method-override", http.MethodPost, "X-Method-Override", "PATCH", "PATCH"},
+		{"same as wire method", http.MethodPost, "X-HTTP-Method-Override", "POST", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, "http://example.com", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			if got := effectiveMethod(req); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -5,15 +5,38 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
+// TraceSkipHeader marks a request as non-traceable when set to "true".
+// The header is stripped before the request is sent.
+const TraceSkipHeader = "X-Opencode-Trace-Skip"
+
 // TracingRoundTripper wraps http.RoundTripper to capture requests and responses
 type TracingRoundTripper struct {
 	wrapped   http.RoundTripper
 	logger    *Logger
 	config    *TracingConfig
 	sessionID string
+
+	// Optional source of custom fields for request and response events
+	enricher EventEnricher
+
+	// Optional hook that sees each exchange before redaction
+	rawObserver RawObserver
+
+	// Place in a chain of round trippers, set by WrapInnermost and WrapOutermost
+	chainPosition string
+
+	// Sampling decisions of redirect chains in flight, with SampleRate
+	redirects redirectSampling
+
+	// Client built around the round tripper by WrapClient, whose Timeout
+	// applies to the requests; nil for a bare transport
+	client *http.Client
 }
 
 // NewTracingRoundTripper creates a new tracing round tripper
@@ -21,6 +44,7 @@ func NewTracingRoundTripper(wrapped http.RoundTripper, logger *Logger, config *T
 	if wrapped == nil {
 		wrapped = http.DefaultTransport
 	}
+	wrapped = enforceMinTLSVersion(wrapped, minTLSVersion(config))
 
 	return &TracingRoundTripper{
 		wrapped:   wrapped,
@@ -32,46 +56,258 @@ func NewTracingRoundTripper(wrapped http.RoundTripper, logger *Logger, config *T
 
 // RoundTrip implements http.RoundTripper interface with tracing
 func (t *TracingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = t.withDefaultHeaders(req)
+
+	// Honor the skip marker and make sure it never reaches the wire
+	if skip := req.Header.Get(TraceSkipHeader); skip != "" {
+		req = req.Clone(req.Context())
+		req.Header.Del(TraceSkipHeader)
+
+		if skip == "true" || skip == "1" {
+			return t.wrapped.RoundTrip(req)
+		}
+	}
+
 	if !t.config.Enabled {
 		return t.wrapped.RoundTrip(req)
 	}
 
-	// Capture request
-	requestCapture, err := t.captureRequest(req)
-	if err != nil {
-		// Log error but continue with request
-		t.logger.LogError(err, "request capture failed")
-	} else {
-		// Log request event
-		if err := t.logger.LogHTTPRequest(requestCapture); err != nil {
-			// Don't fail the request if logging fails
-			t.logger.LogError(err, "failed to log HTTP request")
+	// Operations are sampled whole, so every request of one shares a decision
+	if t.config.sampling() {
+		sampled, ok := samplingDecisionFromContext(req.Context())
+		if !ok {
+			return t.roundTripSampled(req)
+		}
+		if !sampled {
+			return t.wrapped.RoundTrip(req)
+		}
+	}
+
+	if !t.logger.reserveRequestSlot() {
+		if t.config.RequireTracing {
+			return nil, refuseUntraced(req, errRequestLimitReached)
+		}
+		return t.wrapped.RoundTrip(req)
+	}
+
+	// Retry attempts in nested mode are reported by DoWithRetry as one event
+	if collector := retryCollectorFromContext(req.Context()); collector != nil {
+		return t.roundTripCollected(req, collector)
+	}
+
+	// Correlates the request with its response and errors
+	requestID := uuid.New().String()
+
+	var (
+		expectContinue *expectContinueRecorder
+		proxyConnect   *proxyConnectRecorder
+		dnsLookup      *dnsRecorder
+		rtt            *rttRecorder
+		firstByte      *firstByteRecorder
+		connAttempts   *connAttemptRecorder
+		connShare      *connShareRecorder
+		effective      *effectiveRequestRecorder
+		requestHash    *hashingReader
+		takeOverGzip   bool
+		extra          map[string]interface{}
+		recorded       bool
+		recordErr      error
+	)
+
+	// Capture runs guarded so that a tracer bug cannot fail the caller's request
+	func() {
+		defer t.recoverCapturePanic(requestID, "request capture")
+
+		// Capture request
+		requestCapture, err := t.captureRequest(req)
+		if err != nil {
+			// Log error but continue with request
+			t.logger.LogRequestError(requestID, err, "request capture failed")
+			recordErr = err
+		} else {
+			requestCapture.RequestID = requestID
+			if t.enricher != nil {
+				extra = t.enricher(req)
+				requestCapture.Extra = extra
+			}
+
+			// Log request event
+			if err := t.logger.LogHTTPRequest(requestCapture); err != nil {
+				// Don't fail the request if logging fails, unless tracing is required
+				t.logger.LogError(err, "failed to log HTTP request")
+				recordErr = err
+			} else {
+				recorded = true
+			}
+		}
+
+		// A redirect back to a URL already in the chain is a loop
+		if cycle := redirectCycle(req); cycle != nil {
+			hop, _ := requestHop(req)
+			if err := t.logger.LogRedirectLoop(requestID, hop, cycle); err != nil {
+				t.logger.LogError(err, "failed to log redirect loop")
+			}
+		}
+
+		// Attach httptrace hooks for connection-level events
+		if t.config.EnableHTTPTrace {
+			req = t.withClientTrace(req, requestID)
+			req, proxyConnect = t.withProxyConnectTrace(req)
+			req, dnsLookup = withDNSTrace(req)
+			req, rtt = withRTTTrace(req)
 		}
+
+		// Time the 100 Continue handshake of Expect: 100-continue uploads
+		if strings.EqualFold(req.Header.Get("Expect"), "100-continue") {
+			req, expectContinue = withExpectContinueTrace(req)
+		}
+
+		// Time to first byte, which for streamed LLM output is time to first token
+		req, firstByte = withFirstByteTrace(req)
+
+		// Count connection attempts, which exceed one when the transport retries
+		req, connAttempts = withConnAttemptTrace(req)
+
+		// Note connection reuse and how many requests share the connection
+		req, connShare = t.logger.connSharing.withConnShareTrace(req)
+
+		// Record the header fields the transport actually writes for this hop
+		if t.config.CaptureEffectiveRequests {
+			req, effective = withEffectiveRequestTrace(req)
+		}
+
+		// Hash the request body as the transport streams it
+		if t.config.HashBodies && req.Body != nil && req.Body != http.NoBody {
+			requestHash = newHashingReader(req.Body, nil)
+			req.Body = requestHash
+		}
+
+		// Negotiate gzip on the transport's behalf so the on-wire size stays observable
+		if t.wouldAutoDecompress(req) {
+			req = withAcceptGzip(req)
+			takeOverGzip = true
+		}
+	}()
+
+	// Under RequireTracing no request is sent without its request event
+	if t.config.RequireTracing && !recorded {
+		if recordErr == nil {
+			recordErr = errCapturePanicked
+		}
+		return nil, refuseUntraced(req, recordErr)
 	}
 
 	// Execute the actual request
+	t.logger.requestStarted()
 	startTime := time.Now()
 	resp, err := t.wrapped.RoundTrip(req)
 	endTime := time.Now()
 	duration := endTime.Sub(startTime)
+	t.logger.requestFinished(responseStatus(resp), err, duration)
 
-	// Capture response (even if there was an error)
-	if resp != nil {
-		responseCapture, captureErr := t.captureResponse(resp, endTime, duration, err == nil)
-		if captureErr != nil {
-			t.logger.LogError(captureErr, "response capture failed")
-		} else {
-			// Log response event
-			if logErr := t.logger.LogHTTPResponse(responseCapture); logErr != nil {
-				t.logger.LogError(logErr, "failed to log HTTP response")
+	t.observeRaw(requestID, req, resp)
+
+	func() {
+		defer t.recoverCapturePanic(requestID, "response capture")
+
+		var gzipBody *decompressedBody
+		if takeOverGzip && resp != nil {
+			gzipBody = decompressResponse(resp)
+		}
+
+		if proxyConnect != nil {
+			if logErr := t.logger.LogProxyConnect(requestID, proxyConnect, err); logErr != nil {
+				t.logger.LogError(logErr, "failed to log proxy connect")
 			}
 		}
-	}
 
-	// Log error if request failed
-	if err != nil {
-		t.logger.LogError(err, "HTTP request failed")
-	}
+		if effective != nil {
+			if logErr := t.logger.LogHTTPEffectiveRequest(requestID, req, effective); logErr != nil {
+				t.logger.LogError(logErr, "failed to log effective request")
+			}
+		}
+
+		// Capture response (even if there was an error)
+		if resp != nil {
+			responseCapture, captureErr := t.captureResponse(resp, endTime, duration, err == nil)
+			if captureErr != nil {
+				t.logger.LogRequestError(requestID, captureErr, "response capture failed")
+			} else {
+				responseCapture.RequestID = requestID
+				responseCapture.URL = req.URL.String()
+				responseCapture.Extra = extra
+				responseCapture.Retry = retryAttemptFromContext(req.Context())
+				if gzipBody != nil {
+					responseCapture.Compression = "gzip"
+					if wireSize, ok := gzipBody.wireSize(); ok {
+						responseCapture.ResponseSize = wireSize
+						if responseCapture.UncompressedSize > 0 {
+							responseCapture.CompressedSize = wireSize
+						}
+					}
+				}
+				if expectContinue != nil {
+					responseCapture.Wait100Continue, responseCapture.Waited100Continue = expectContinue.wait()
+				}
+				if firstByte != nil {
+					firstByte.recordLatency(responseCapture, startTime)
+				}
+				if connAttempts != nil {
+					responseCapture.ConnectionAttempts = connAttempts.count()
+				}
+				if dnsLookup != nil {
+					responseCapture.DNS = dnsLookup.result()
+				}
+				if rtt != nil {
+					responseCapture.EstimatedRTT = rtt.estimate(resp.Header)
+				}
+				if connShare != nil {
+					responseCapture.ConnectionReused, responseCapture.ConnectionInFlight = connShare.result()
+				}
+
+				// Log response event
+				logResponse := func() {
+					if logErr := t.logger.LogHTTPResponse(responseCapture); logErr != nil {
+						t.logger.LogError(logErr, "failed to log HTTP response")
+					}
+				}
+
+				// A sampled body is logged once the caller has read its tail
+				if sampled := responseCapture.sampledBody; sampled != nil {
+					sampled.onDone = func() {
+						defer t.recoverCapturePanic(requestID, "response capture")
+						logResponse()
+					}
+				} else {
+					logResponse()
+				}
+			}
+		}
+
+		if t.config.HashBodies {
+			t.hashBodies(requestID, requestHash, resp)
+		}
+
+		t.checkTLSPolicy(requestID, req, resp)
+
+		// The response has been captured, so the request no longer occupies its connection
+		if connShare != nil {
+			connShare.done()
+		}
+
+		// Log error if request failed
+		if err != nil {
+			if requestCanceled(req, err) {
+				t.logger.LogHTTPCanceled(requestID, req, duration, err)
+			} else if class := classifyConnectionError(err); class != "" {
+				t.logger.LogConnectionError(requestID, err, class)
+			} else if deadline := t.effectiveDeadline(req, startTime); deadline != "" {
+				t.logger.LogTimeoutError(requestID, err, deadline)
+			} else {
+				t.logger.LogRequestError(requestID, err, "HTTP request failed")
+			}
+		}
+	}()
 
 	return resp, err
 }
@@ -82,31 +318,53 @@ func (t *TracingRoundTripper) captureRequest(req *http.Request) (*RequestCapture
 		StartTime: time.Now(),
 		Method:    req.Method,
 		URL:       req.URL.String(),
-		Headers:   make(map[string]string),
-	}
-
-	// Capture headers
-	for key, values := range req.Header {
-		if len(values) > 0 {
-			capture.Headers[key] = values[0] // Take first value
-		}
+		Headers:   flattenHeaders(req.Header),
 	}
 
 	// Extract common headers
 	capture.ContentType = req.Header.Get("Content-Type")
 	capture.UserAgent = req.Header.Get("User-Agent")
 
+	// The transport sends req.Host, not a Host entry in req.Header
+	if req.Host != "" && !strings.EqualFold(req.Host, req.URL.Host) {
+		capture.Host = req.Host
+	}
+	if socketPath, _, ok := splitUnixSocketURL(req.URL); ok {
+		capture.UnixSocket = socketPath
+	}
+	capture.EffectiveMethod = effectiveMethod(req)
+	capture.ChainPosition = t.chainPosition
+	capture.Retry = retryAttemptFromContext(req.Context())
+
+	if t.config.PreserveHeaderMultiValues {
+		capture.MultiHeaders = req.Header.Clone()
+	}
+
+	if t.config.CaptureRawLines {
+		capture.RawRequestLine = rawRequestLine(req)
+	}
+
+	// Record the timeout that applies to this call
+	capture.Timeout = t.clientTimeout()
+	if timeout, ok := requestTimeoutFromContext(req.Context()); ok {
+		capture.Timeout = timeout
+	}
+
+	if req.ContentLength > 0 {
+		capture.RequestSize = req.ContentLength
+	}
+
 	// Capture request body if enabled
-	if t.config.CaptureRequestBodies && req.Body != nil {
-		bodyBytes, err := t.readBody(req.Body, t.config.MaxBodySize)
+	if t.config.captureRequestBody(capture.URL) && req.Body != nil {
+		bodyBytes, truncated, body, err := t.captureBody(req.Body, t.config.MaxBodySize, req.ContentLength)
+		// Restore body for the actual request, which still sends every byte
+		req.Body = body
 		if err != nil {
 			return nil, err
 		}
 
 		capture.Body = bodyBytes
-
-		// Restore body for the actual request
-		req.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+		capture.BodyTruncated = truncated
 	}
 
 	return capture, nil
@@ -118,62 +376,202 @@ func (t *TracingRoundTripper) captureResponse(resp *http.Response, endTime time.
 		EndTime:    endTime,
 		StatusCode: resp.StatusCode,
 		Status:     resp.Status,
-		Headers:    make(map[string]string),
+		Headers:    flattenHeaders(resp.Header),
 		Duration:   duration,
 		Success:    success && resp.StatusCode < 400,
-	}
-
-	// Capture headers
-	for key, values := range resp.Header {
-		if len(values) > 0 {
-			capture.Headers[key] = values[0] // Take first value
-		}
+		URL:        requestURL(resp),
 	}
 
 	// Extract common headers
 	capture.ContentType = resp.Header.Get("Content-Type")
 
+	if t.config.PreserveHeaderMultiValues {
+		capture.MultiHeaders = resp.Header.Clone()
+	}
+
+	if t.config.CaptureRawLines {
+		capture.RawStatusLine = rawStatusLine(resp)
+	}
+
+	if t.config.CaptureTLSInfo {
+		captureProtocolInfo(capture, resp)
+	}
+
+	captureCacheRevalidation(capture, resp)
+
 	// Get response size from headers
+	wireSizeKnown := false
 	if contentLength := resp.Header.Get("Content-Length"); contentLength != "" {
 		if size, err := strconv.ParseInt(contentLength, 10, 64); err == nil {
 			capture.ResponseSize = size
+			wireSizeKnown = true
 		}
 	}
 
-	// Capture response body if enabled
-	if t.config.CaptureResponseBodies && resp.Body != nil {
-		bodyBytes, err := t.readBody(resp.Body, t.config.MaxBodySize)
+	// Note the encoding, whether still applied or removed by the transport
+	capture.Compression = resp.Header.Get("Content-Encoding")
+	if resp.Uncompressed {
+		capture.Compression = "gzip"
+	}
+
+	// Capture response body if enabled; configured streams are never read
+	if t.config.isUncapturedStream(capture.ContentType) {
+		capture.BodyNotCapturedReason = bodyNotCapturedStreaming
+	} else if t.config.captureResponseBody(capture.URL, resp.StatusCode) && resp.Body != nil {
+		limit := t.config.MaxBodySize
+
+		// Head/tail sampling keeps the end of the body in a ring as it is read
+		var ring *tailRing
+		if t.sampleHeadTail(resp) {
+			_, tailSize := t.config.bodySampleSizes()
+			ring = newTailRing(tailSize)
+			resp.Body = &restoredBody{Reader: io.TeeReader(resp.Body, ring), Closer: resp.Body}
+		}
+
+		bodyBytes, truncated, body, err := t.captureBody(resp.Body, limit, resp.ContentLength)
+		// Restore body for the caller before anything that could panic looks
+		// at it; the caller still reads the part past the captured prefix
+		resp.Body = body
 		if err != nil {
+			if capture.Compression != "" {
+				t.logger.warn(WarningDecompression, "", "failed to read %s response body: %v", capture.Compression, err)
+			}
 			return nil, err
 		}
+		capture.BodyClosed = time.Now()
 
 		capture.Body = bodyBytes
-		capture.ResponseSize = int64(len(bodyBytes))
+		capture.DecodedSize = int64(len(bodyBytes))
+		capture.BodyTruncated = truncated
+
+		// Store a still-encoded body decoded when a decoder is available
+		if capture.Compression != "" && !resp.Uncompressed {
+			if decoded, decodedTruncated, ok := t.decodeCapturedBody(capture.Compression, bodyBytes, limit); ok {
+				capture.Body = decoded
+				capture.BodyDecoded = true
+				capture.BodyTruncated = truncated || decodedTruncated
+				if !capture.BodyTruncated {
+					capture.CompressedSize = int64(len(bodyBytes))
+					capture.UncompressedSize = int64(len(decoded))
+				}
+			}
+		} else if capture.Compression != "" && !truncated {
+			// Decompressed on the way in; the compressed size is filled in
+			// by RoundTrip when it took over gzip and counted the wire bytes
+			capture.UncompressedSize = capture.DecodedSize
+		}
 
-		// Restore body for the caller
-		resp.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+		// Sniff the type of decoded bodies to spot mislabeled responses
+		if capture.Compression == "" || resp.Uncompressed || capture.BodyDecoded {
+			capture.DetectedContentType = detectContentType(capture.ContentType, capture.Body)
+		}
+		if !wireSizeKnown {
+			capture.ResponseSize = capture.DecodedSize
+		}
+
+		// The tail of a longer body is only known once the caller has read it
+		if ring != nil && capture.BodyTruncated {
+			sampled := &sampledBody{ReadCloser: resp.Body, ring: ring, capture: capture, wireSizeKnown: wireSizeKnown}
+			resp.Body = sampled
+			capture.sampledBody = sampled
+		}
 	}
 
 	return capture, nil
 }
 
-// readBody reads and returns body content up to maxSize
-func (t *TracingRoundTripper) readBody(body io.ReadCloser, maxSize int64) ([]byte, error) {
+// responseStatus returns the status code of resp, or 0 when there is no response
+func responseStatus(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
+
+// flattenHeaders converts headers to a map keeping the first value of each
+func flattenHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string)
+	for key, values := range header {
+		if len(values) > 0 {
+			headers[key] = values[0] // Take first value
+		}
+	}
+	return headers
+}
+
+// readBody reads and returns body content up to maxSize. A positive sizeHint,
+// such as a known Content-Length, pre-sizes the buffer to avoid regrowing it.
+func (t *TracingRoundTripper) readBody(body io.ReadCloser, maxSize, sizeHint int64) ([]byte, error) {
 	defer body.Close()
 
-	// Limit read size to prevent memory issues
-	limitedReader := io.LimitReader(body, maxSize+1) // +1 to detect if truncated
-	bodyBytes, err := io.ReadAll(limitedReader)
+	bodyBytes, _, _, err := t.captureBody(body, maxSize, sizeHint)
+	return bodyBytes, err
+}
+
+// captureBody reads up to maxSize bytes of body for the trace, or all of it
+// when maxSize is negative, and reports whether the body went on past
+// maxSize. It also returns a body that replays what was read followed by the
+// rest of the original, so whoever reads the body after the tracer still
+// gets every byte however much was captured.
+func (t *TracingRoundTripper) captureBody(body io.ReadCloser, maxSize, sizeHint int64) ([]byte, bool, io.ReadCloser, error) {
+	reader := io.Reader(body)
+	if maxSize >= 0 {
+		// Limit read size to prevent memory issues
+		reader = io.LimitReader(body, maxSize+1) // +1 to detect if truncated
+		if sizeHint > maxSize {
+			sizeHint = maxSize + 1
+		}
+	}
+
+	bodyBytes, err := readAllSized(reader, sizeHint)
+	restored := &restoredBody{
+		Reader: io.MultiReader(bytes.NewReader(bodyBytes), body),
+		Closer: body,
+	}
 	if err != nil {
-		return nil, err
+		return nil, false, restored, err
 	}
 
-	// Truncate if body exceeds max size
-	if int64(len(bodyBytes)) > maxSize {
+	// Truncate if body exceeds max size; a body of exactly maxSize bytes is complete
+	truncated := maxSize >= 0 && int64(len(bodyBytes)) > maxSize
+	if truncated {
 		bodyBytes = bodyBytes[:maxSize]
+		t.logger.warn(WarningBodyTruncated, "", "body truncated to the %d byte limit", maxSize)
+	}
+
+	return bodyBytes, truncated, restored, nil
+}
+
+// maxSizeHint bounds the up-front allocation a size hint can cause, so that a
+// bogus Content-Length cannot reserve an arbitrary amount of memory
+const maxSizeHint = 32 << 20
+
+// readAllSized is io.ReadAll with the buffer pre-sized for sizeHint bytes.
+// One spare byte lets the final read observe EOF without growing the buffer.
+func readAllSized(r io.Reader, sizeHint int64) ([]byte, error) {
+	if sizeHint <= 0 {
+		return io.ReadAll(r)
+	}
+	if sizeHint > maxSizeHint {
+		sizeHint = maxSizeHint
 	}
 
-	return bodyBytes, nil
+	buf := make([]byte, 0, sizeHint+1)
+	for {
+		n, err := r.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if err == io.EOF {
+			return buf, nil
+		}
+		if err != nil {
+			return buf, err
+		}
+
+		// The hint was too small; let append pick the next capacity
+		if len(buf) == cap(buf) {
+			buf = append(buf, 0)[:len(buf)]
+		}
+	}
 }
 
 // TracingTransport creates a new HTTP transport with tracing capabilities
@@ -188,8 +586,9 @@ func WrapClient(client *http.Client, logger *Logger, config *TracingConfig, sess
 	}
 
 	// Create a copy to avoid modifying the original
+	transport := NewTracingRoundTripper(client.Transport, logger, config, sessionID)
 	tracingClient := &http.Client{
-		Transport:     NewTracingTransport(client.Transport, logger, config, sessionID),
+		Transport:     transport,
 		CheckRedirect: client.CheckRedirect,
 		Jar:           client.Jar,
 		Timeout:       client.Timeout,
@@ -200,5 +599,6 @@ func WrapClient(client *http.Client, logger *Logger, config *TracingConfig, sess
 		tracingClient.Timeout = config.Timeout
 	}
 
+	transport.client = tracingClient
 	return tracingClient
 }
\ No newline at end of file
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTraceSkipHeader(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-skip-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	var receivedSkipHeader []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedSkipHeader = append(receivedSkipHeader, r.Header.Get(TraceSkipHeader))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-skip", newTestConfig(tempDir))
+	defer client.Close()
+
+	req, err := http.NewRequest("GET", server.URL+"/health", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(TraceSkipHeader, "true")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if _, err := os.Stat(filepath.Join(tempDir, "sessions")); !os.IsNotExist(err) {
+		t.Error("Expected no events to be written for a skipped request")
+	}
+
+	// The caller's request is left untouched
+	if req.Header.Get(TraceSkipHeader) != "true" {
+		t.Error("Expected caller's request headers not to be modified")
+	}
+
+	// A non-true value is still stripped but the request is traced
+	req, _ = http.NewRequest("GET", server.URL+"/traced", nil)
+	req.Header.Set(TraceSkipHeader, "false")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	for i, value := range receivedSkipHeader {
+		if value != "" {
+			t.Errorf("Request %d: expected skip header to be stripped, server saw %q", i+1, value)
+		}
+	}
+
+	events := readSessionEvents(t, tempDir)
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events for the traced request, got %d", len(events))
+	}
+	headers, _ := events[0]["headers"].(map[string]interface{})
+	if _, ok := headers[TraceSkipHeader]; ok {
+		t.Error("Expected skip header not to be recorded")
+	}
+}
+
+// sizedBody is a response-like body that only returns a few KB per Read, as
+// a network connection does
+type sizedBody struct {
+	reader *bytes.Reader
+}
+
+func (b *sizedBody) Read(p []byte) (int, error) {
+	if len(p) > 16*1024 {
+		p = p[:16*1024]
+	}
+	return b.reader.Read(p)
+}
+
+func (b *sizedBody) Close() error { return nil }
+
+func TestReadBodySizeHint(t *testing.T) {
+	roundTripper := &TracingRoundTripper{}
+	payload := bytes.Repeat([]byte("x"), 10000)
+
+	tests := []struct {
+		name     string
+		maxSize  int64
+		sizeHint int64
+		expected int
+	}{
+		{"exact hint", 20000, 10000, 10000},
+		{"no hint", 20000, -1, 10000},
+		{"hint too small", 20000, 100, 10000},
+		{"hint too large", 20000, 50000, 10000},
+		{"truncated", 4000, 10000, 4000},
+		{"unlimited", -1, 10000, 10000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &sizedBody{reader: bytes.NewReader(payload)}
+			got, err := roundTripper.readBody(body, tt.maxSize, tt.sizeHint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, payload[:tt.expected]) {
+				t.Errorf("Expected %d bytes, got %d", tt.expected, len(got))
+			}
+		})
+	}
+}
+
+func TestCaptureBodyTruncatedOnlyPastLimit(t *testing.T) {
+	roundTripper := &TracingRoundTripper{logger: &Logger{config: &TracingConfig{}}}
+
+	for _, tt := range []struct {
+		name      string
+		size      int
+		truncated bool
+	}{
+		{"under the limit", 1023, false},
+		{"exactly the limit", 1024, false},
+		{"one byte over", 1025, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := bytes.Repeat([]byte("x"), tt.size)
+			got, truncated, _, err := roundTripper.captureBody(io.NopCloser(bytes.NewReader(payload)), 1024, int64(tt.size))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if truncated != tt.truncated {
+				t.Errorf("Expected truncated %v, got %v", tt.truncated, truncated)
+			}
+			if len(got) > 1024 {
+				t.Errorf("Expected at most 1024 bytes, got %d", len(got))
+			}
+		})
+	}
+}
+
+// BenchmarkReadBody compares reading a 1MB body with a known Content-Length
+// against the plain io.ReadAll path used when the length is unknown
+func BenchmarkReadBody(b *testing.B) {
+	payload := bytes.Repeat([]byte("x"), 1024*1024)
+	roundTripper := &TracingRoundTripper{}
+	maxSize := int64(len(payload))
+
+	for _, bm := range []struct {
+		name     string
+		sizeHint int64
+	}{
+		{"ReadAll", -1},
+		{"ContentLength", int64(len(payload))},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(payload)))
+			for i := 0; i < b.N; i++ {
+				body := &sizedBody{reader: bytes.NewReader(payload)}
+				if _, err := roundTripper.readBody(body, maxSize, bm.sizeHint); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// outputDirPerm is the permission used for directories the tracer creates
+const outputDirPerm = 0755
+
+// sessionsDir returns the directory session files are written to,
+// OutputDir/<namespace>/sessions when an output namespace is set
+func sessionsDir(config *TracingConfig) string {
+	return filepath.Join(config.OutputDir, config.OutputNamespace, "sessions")
+}
+
+// ensureSessionsDir makes sure the sessions directory exists. With
+// CreateOutputDir the output directory and any parents are created; otherwise
+// the output directory must already exist and only the namespace and
+// sessions directories below it may be created.
+func ensureSessionsDir(config *TracingConfig) error {
+	dir := sessionsDir(config)
+
+	if config.CreateOutputDir {
+		if err := os.MkdirAll(dir, outputDirPerm); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		return nil
+	}
+
+	if err := checkOutputDir(config); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, outputDirPerm); err != nil {
+		return fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+	return nil
+}
+
+// checkOutputDir reports an error when the output directory is missing and
+// may not be created
+func checkOutputDir(config *TracingConfig) error {
+	if config.CreateOutputDir {
+		return nil
+	}
+
+	outputDir := config.OutputDir
+	if outputDir == "" {
+		outputDir = "."
+	}
+
+	info, err := os.Stat(outputDir)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("output directory %q does not exist and create_output_dir is disabled", outputDir)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to access output directory %q: %w", outputDir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("output directory %q is not a directory", outputDir)
+	}
+	return nil
+}
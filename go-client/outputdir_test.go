@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateOutputDirCreatesNestedDirs(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-outputdir-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := newTestConfig(filepath.Join(tempDir, "nested", "trace"))
+	config.CreateOutputDir = true
+
+	client, err := NewCheckedTracingHTTPClient("test-create-dir", config)
+	if err != nil {
+		t.Fatalf("Expected client creation to succeed, got %v", err)
+	}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	if got := len(eventsOfType(readSessionEvents(t, config.OutputDir), "http_request")); got != 1 {
+		t.Errorf("Expected 1 request event in the created directory, got %d", got)
+	}
+}
+
+func TestMissingOutputDirFailsFast(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-outputdir-missing-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	missing := filepath.Join(tempDir, "not-provisioned")
+	config := newTestConfig(missing)
+	config.CreateOutputDir = false
+
+	client, err := NewCheckedTracingHTTPClient("test-missing-dir", config)
+	if err == nil {
+		client.Close()
+		t.Fatal("Expected an error for a missing output directory")
+	}
+	if !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Expected a clear error, got %v", err)
+	}
+
+	// Writes through an unchecked client must not create the directory either
+	logger := NewLogger(config, "test-missing-dir")
+	if err := logger.LogError(os.ErrInvalid, "test"); err == nil {
+		t.Error("Expected writing to fail without an output directory")
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Errorf("Expected output directory not to be created, got %v", err)
+	}
+}
+
+func TestExistingOutputDirWithoutCreate(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-outputdir-existing-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.CreateOutputDir = false
+
+	client, err := NewCheckedTracingHTTPClient("test-existing-dir", config)
+	if err != nil {
+		t.Fatalf("Expected an existing directory to be accepted, got %v", err)
+	}
+	client.logger.LogError(os.ErrInvalid, "test")
+	client.Close()
+
+	if _, err := os.Stat(filepath.Join(tempDir, "sessions")); err != nil {
+		t.Errorf("Expected the sessions subdirectory to be created, got %v", err)
+	}
+}
+
+func TestOutputNamespace(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-outputdir-namespace-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.CreateOutputDir = false
+	config.OutputNamespace = "tool-x"
+
+	client := NewTracingHTTPClientWithConfig("test-namespace", config)
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	matches, _ := filepath.Glob(filepath.Join(tempDir, "tool-x", "sessions", "*_session-test-namespace.jsonl"))
+	if len(matches) != 1 {
+		t.Fatalf("Expected the session file under the namespaced path, got %v", matches)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "sessions")); !os.IsNotExist(err) {
+		t.Errorf("Expected no sessions directory outside the namespace, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// recoverCapturePanic recovers a panic raised while capturing or logging a
+// request and records it as a tracer_internal_error event. It must be
+// deferred directly so that recover stops the panic.
+func (t *TracingRoundTripper) recoverCapturePanic(requestID, stage string) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	stack := debug.Stack()
+
+	// Logging may be what panicked; a second panic must not escape either
+	defer func() { recover() }()
+	t.logger.LogTracerInternalError(requestID, stage, recovered, stack)
+}
+
+// LogTracerInternalError logs a panic recovered inside the tracer
+func (l *Logger) LogTracerInternalError(requestID, stage string, recovered interface{}, stack []byte) error {
+	if !l.config.Enabled {
+		return nil
+	}
+
+	event := TracerInternalErrorEvent{
+		Type:      "tracer_internal_error",
+		Timestamp: time.Now().UnixMilli(),
+		SessionID: l.sessionID,
+		RequestID: requestID,
+		Stage:     stage,
+		Panic:     fmt.Sprint(recovered),
+		Stack:     string(stack),
+	}
+
+	return l.writeEvent(event)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCapturePanicDoesNotFailRequest(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-panic-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.MinFreeDiskBytes = 1
+
+	client := NewTracingHTTPClientWithConfig("test-panic", config)
+
+	// The first event written while logging the request hits a tracer bug.
+	// freeDiskSpace stands in for one because it runs inside writeEvent, so
+	// the panic comes from the logging path itself rather than user code.
+	calls := 0
+	client.logger.freeDiskSpace = func(path string) (uint64, error) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		return 1 << 40, nil
+	}
+	client.logger.diskCheckInterval = 0
+
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"q": 1}`))
+	if err != nil {
+		t.Fatalf("Expected the request to succeed despite the panic, got %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || string(body) != `{"ok": true}` {
+		t.Errorf("Expected the original response, got %d %q", resp.StatusCode, body)
+	}
+
+	events := readSessionEvents(t, tempDir)
+	internal := eventsOfType(events, "tracer_internal_error")
+	if len(internal) != 1 {
+		t.Fatalf("Expected 1 tracer_internal_error event, got %d", len(internal))
+	}
+	if internal[0]["stage"] != "request capture" || internal[0]["panic"] != "boom" {
+		t.Errorf("Unexpected internal error event: %v", internal[0])
+	}
+	if stack, _ := internal[0]["stack"].(string); !strings.Contains(stack, "hasDiskSpace") {
+		t.Errorf("Expected the stack to show where the panic happened, got %q", stack)
+	}
+	if internal[0]["request_id"] == nil {
+		t.Error("Expected the internal error to reference the request")
+	}
+
+	// Capture resumes for the response once the panic is recovered
+	if responses := eventsOfType(events, "http_response"); len(responses) != 1 {
+		t.Errorf("Expected the response to still be logged, got %d events", len(responses))
+	}
+}
+
+func TestResponseBodyRestoredBeforeCapturePanic(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-panic-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Decoding the captured body for the trace hits a tracer bug
+	contentDecoders["x-panic"] = func(io.Reader) io.Reader { panic("decoder bug") }
+	defer delete(contentDecoders, "x-panic")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "x-panic")
+		w.Write([]byte("encoded payload"))
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-panic-body", newTestConfig(tempDir))
+	defer client.Close()
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the request to succeed despite the panic, got %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "encoded payload" {
+		t.Errorf("Expected the caller to receive the whole body, got %q", body)
+	}
+}
+
+func TestPanickingEnricherDoesNotFailRequest(t *testing.T) {
+	tempDir := t.TempDir()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	// The enricher is the user code the tracer runs on every request event
+	client := NewTracingHTTPClientWithConfig("test-panic-enricher", newTestConfig(tempDir)).
+		WithEventEnricher(func(req *http.Request) map[string]interface{} {
+			panic("enricher bug")
+		})
+	defer client.Close()
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the request to succeed despite the panic, got %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || string(body) != "ok" {
+		t.Errorf("Expected the original response, got %d %q", resp.StatusCode, body)
+	}
+
+	events := readSessionEvents(t, tempDir)
+	internal := eventsOfType(events, "tracer_internal_error")
+	if len(internal) != 1 || internal[0]["panic"] != "enricher bug" {
+		t.Fatalf("Expected the enricher panic to be logged, got %v", internal)
+	}
+	if responses := eventsOfType(events, "http_response"); len(responses) != 1 {
+		t.Errorf("Expected the response to still be logged, got %d events", len(responses))
+	}
+}
+
+func TestInformationalHookPanicRecovered(t *testing.T) {
+	tempDir := t.TempDir()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("final"))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.EnableHTTPTrace = true
+	config.MinFreeDiskBytes = 1
+
+	client := NewTracingHTTPClientWithConfig("test-panic-1xx", config)
+	defer client.Close()
+
+	// Logging the 103, on the transport's read loop, hits a tracer bug
+	calls := 0
+	client.logger.freeDiskSpace = func(path string) (uint64, error) {
+		calls++
+		if calls == 2 {
+			panic("1xx bug")
+		}
+		return 1 << 40, nil
+	}
+	client.logger.diskCheckInterval = 0
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the request to succeed despite the panic, got %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "final" {
+		t.Errorf("Expected the final response body, got %q", body)
+	}
+
+	internal := eventsOfType(readSessionEvents(t, tempDir), "tracer_internal_error")
+	if len(internal) != 1 || internal[0]["stage"] != "httptrace" || internal[0]["panic"] != "1xx bug" {
+		t.Fatalf("Expected the hook panic to be logged at stage httptrace, got %v", internal)
+	}
+	if internal[0]["request_id"] == nil {
+		t.Error("Expected the internal error to reference the request")
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maskedValue replaces masked URL path segments
+const maskedValue = "[MASKED]"
+
+// urlHeaders carry a URL whose path is masked like the request URL, so a
+// redirect or referrer does not give away what the request URL hides
+var urlHeaders = []string{"Location", "Content-Location", "Referer"}
+
+// isURLHeader reports whether the header named key carries a URL
+func isURLHeader(key string) bool {
+	for _, name := range urlHeaders {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePathMaskPatterns compiles PathMaskPatterns for the logger. Invalid
+// patterns are skipped here and reported by Validate.
+func (c *TracingConfig) compilePathMaskPatterns() []*regexp.Regexp {
+	var patterns []*regexp.Regexp
+	for _, pattern := range c.PathMaskPatterns {
+		if re, err := regexp.Compile(pattern); err == nil {
+			patterns = append(patterns, re)
+		}
+	}
+	return patterns
+}
+
+// errorURL matches the URLs an error message can quote, such as the request
+// URL in a *url.Error
+var errorURL = regexp.MustCompile(`[A-Za-z][A-Za-z0-9+.-]*://[^\s"'<>]+`)
+
+// redactError prepares an error message for an event: tracked secrets are
+// redacted and every URL in it is masked as redactURL masks a request URL.
+// Every error written to an event goes through it.
+func (l *Logger) redactError(err error) string {
+	message := l.redactTrackedSecrets(err.Error())
+	if len(l.config.MaskPathSegments) == 0 && len(l.pathMaskPatterns) == 0 {
+		return message
+	}
+	return errorURL.ReplaceAllStringFunc(message, l.maskPath)
+}
+
+// redactURL prepares a URL for an event: tracked secrets are redacted and
+// the path segments selected by MaskPathSegments and PathMaskPatterns masked
+func (l *Logger) redactURL(rawURL string) string {
+	return l.maskPath(l.redactTrackedSecrets(rawURL))
+}
+
+// redactRequestLine is redactURL for the target of a raw request line such
+// as "GET /users/42 HTTP/1.1"
+func (l *Logger) redactRequestLine(line string) string {
+	line = l.redactTrackedSecrets(line)
+
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) != 3 {
+		return line
+	}
+	parts[1] = l.maskPath(parts[1])
+	return strings.Join(parts, " ")
+}
+
+// maskPath masks path segments of an absolute URL or a request target. The
+// URL is returned unchanged when nothing is masked or it does not parse.
+func (l *Logger) maskPath(rawURL string) string {
+	if rawURL == "" || (len(l.config.MaskPathSegments) == 0 && len(l.pathMaskPatterns) == 0) {
+		return rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	// Segments are counted after the leading slash: /users/42 is users, 42
+	segments := strings.Split(strings.TrimPrefix(u.EscapedPath(), "/"), "/")
+	masked := false
+	for i, segment := range segments {
+		if segment == "" || !l.maskSegment(i, segment) {
+			continue
+		}
+		segments[i] = maskedValue
+		masked = true
+	}
+	if !masked {
+		return rawURL
+	}
+
+	escaped := strings.Join(segments, "/")
+	if strings.HasPrefix(u.EscapedPath(), "/") {
+		escaped = "/" + escaped
+	}
+	path, err := url.PathUnescape(escaped)
+	if err != nil {
+		return rawURL
+	}
+	u.Path, u.RawPath = path, escaped
+	return u.String()
+}
+
+// maskSegment reports whether the escaped segment at position i is masked.
+// Patterns are matched against the unescaped segment.
+func (l *Logger) maskSegment(i int, segment string) bool {
+	for _, position := range l.config.MaskPathSegments {
+		if position == i {
+			return true
+		}
+	}
+
+	if unescaped, err := url.PathUnescape(segment); err == nil {
+		segment = unescaped
+	}
+	for _, re := range l.pathMaskPatterns {
+		if re.MatchString(segment) {
+			return true
+		}
+	}
+	return false
+}
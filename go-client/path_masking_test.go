@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPathMaskingInEvents(t *testing.T) {
+	var receivedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedPath = r.URL.Path
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-path-mask-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.PathMaskPatterns = []string{`^[^@]+@[^@]+$`}
+	config.CaptureRawLines = true
+	client := NewTracingHTTPClientWithConfig("test-path-mask", config)
+
+	resp, err := client.Get(server.URL + "/users/jane@example.com/orders?page=2")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	if receivedPath != "/users/jane@example.com/orders" {
+		t.Errorf("Expected the real request to use the original path, got %q", receivedPath)
+	}
+
+	requests := eventsOfType(readSessionEvents(t, tempDir), "http_request")
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request event, got %d", len(requests))
+	}
+	if expected := server.URL + "/users/[MASKED]/orders?page=2"; requests[0]["url"] != expected {
+		t.Errorf("Expected url %q, got %v", expected, requests[0]["url"])
+	}
+	if expected := "GET /users/[MASKED]/orders?page=2 HTTP/1.1"; requests[0]["raw_request_line"] != expected {
+		t.Errorf("Expected raw request line %q, got %v", expected, requests[0]["raw_request_line"])
+	}
+}
+
+func TestMaskPath(t *testing.T) {
+	tests := []struct {
+		name      string
+		positions []int
+		patterns  []string
+		url       string
+		expected  string
+	}{
+		{"position", []int{1}, nil, "https://api.example.com/users/42/orders", "https://api.example.com/users/[MASKED]/orders"},
+		{"position beyond path", []int{5}, nil, "https://api.example.com/users/42", "https://api.example.com/users/42"},
+		{"escaped segment", nil, []string{`^[^@]+@[^@]+$`}, "https://api.example.com/users/jane%40example.com", "https://api.example.com/users/[MASKED]"},
+		{"request target", []int{0}, nil, "/tenant-7/items?q=1", "/[MASKED]/items?q=1"},
+		{"nothing configured", nil, nil, "https://api.example.com/users/42", "https://api.example.com/users/42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := NewLogger(&TracingConfig{MaskPathSegments: tt.positions, PathMaskPatterns: tt.patterns}, "test-mask-path")
+			if got := logger.maskPath(tt.url); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	logger := NewLogger(&TracingConfig{PathMaskPatterns: []string{"@"}}, "test-redact-error")
+
+	err := &url.Error{Op: "Get", URL: "https://api.example.com/users/alice@example.com/orders", Err: errors.New("connection refused")}
+	expected := `Get "https://api.example.com/users/[MASKED]/orders": connection refused`
+	if got := logger.redactError(err); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestPathMaskingInErrorEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	deadURL := server.URL
+	server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-path-mask-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.PathMaskPatterns = []string{"@"}
+	config.MaxRetries = 1
+	client := NewTracingHTTPClientWithConfig("test-path-mask-errors", config)
+	client.backoff = func(int) time.Duration { return 0 }
+
+	req, _ := http.NewRequest(http.MethodGet, deadURL+"/users/alice@example.com", nil)
+	if resp, err := client.DoWithRetry(req); err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected DoWithRetry to fail")
+	}
+	client.Close()
+
+	data, err := os.ReadFile(findSessionFile(t, tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "alice@example.com") {
+		t.Errorf("Expected the masked path segment never to appear in the session file:\n%s", data)
+	}
+	if exhausted := eventsOfType(readSessionEvents(t, tempDir), "retries_exhausted"); len(exhausted) != 1 || exhausted[0]["final_error"] == nil {
+		t.Errorf("Expected a retries_exhausted event with the final error, got %v", exhausted)
+	}
+}
+
+func TestPathMaskingInURLHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/users/jane@example.com/orders/7")
+		w.Header().Set("Content-Location", "https://api.example.com/users/jane@example.com/orders/7")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	tempDir := t.TempDir()
+	config := newTestConfig(tempDir)
+	config.PathMaskPatterns = []string{`^[^@]+@[^@]+$`}
+	client := NewTracingHTTPClientWithConfig("test-path-mask-headers", config)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/orders", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Referer", "https://app.example.com/users/jane@example.com/cart")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	events := readSessionEvents(t, tempDir)
+	requests, responses := eventsOfType(events, "http_request"), eventsOfType(events, "http_response")
+	if len(requests) != 1 || len(responses) != 1 {
+		t.Fatalf("Expected 1 request and 1 response event, got %d and %d", len(requests), len(responses))
+	}
+
+	requestHeaders, _ := requests[0]["headers"].(map[string]interface{})
+	if expected := "https://app.example.com/users/[MASKED]/cart"; requestHeaders["Referer"] != expected {
+		t.Errorf("Expected Referer %q, got %v", expected, requestHeaders["Referer"])
+	}
+	responseHeaders, _ := responses[0]["headers"].(map[string]interface{})
+	if expected := "/users/[MASKED]/orders/7"; responseHeaders["Location"] != expected {
+		t.Errorf("Expected Location %q, got %v", expected, responseHeaders["Location"])
+	}
+	if expected := "https://api.example.com/users/[MASKED]/orders/7"; responseHeaders["Content-Location"] != expected {
+		t.Errorf("Expected Content-Location %q, got %v", expected, responseHeaders["Content-Location"])
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestPromoteHeaders(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-promote-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Correlation-Id", "corr-7")
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.PromoteHeaders = []string{"x-request-id", "X-CORRELATION-ID", "authorization"}
+
+	client := NewTracingHTTPClientWithConfig("test-promote", config)
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("X-Request-Id", "req-42")
+	req.Header.Set("Authorization", "Bearer secret")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	events := readSessionEvents(t, tempDir)
+	requests := eventsOfType(events, "http_request")
+	responses := eventsOfType(events, "http_response")
+	if len(requests) != 1 || len(responses) != 1 {
+		t.Fatalf("Expected 1 request and 1 response event, got %d and %d", len(requests), len(responses))
+	}
+
+	promoted, _ := requests[0]["promoted_headers"].(map[string]interface{})
+	headers, _ := requests[0]["headers"].(map[string]interface{})
+	if promoted["x-request-id"] != "req-42" || headers["X-Request-Id"] != "req-42" {
+		t.Errorf("Expected x-request-id in both places, got promoted %v headers %v", promoted, headers)
+	}
+	if promoted["authorization"] != redactedValue {
+		t.Errorf("Expected a promoted sensitive header to stay redacted, got %v", promoted["authorization"])
+	}
+
+	promoted, _ = responses[0]["promoted_headers"].(map[string]interface{})
+	if promoted["x-correlation-id"] != "corr-7" {
+		t.Errorf("Expected x-correlation-id promoted on the response, got %v", promoted)
+	}
+}
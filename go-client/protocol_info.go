@@ -0,0 +1,21 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+// captureProtocolInfo records the HTTP versions of an exchange and, for TLS
+// connections, the TLS version and the protocol agreed through ALPN
+func captureProtocolInfo(capture *ResponseCapture, resp *http.Response) {
+	capture.Proto = resp.Proto
+	if resp.Request != nil {
+		capture.RequestProto = resp.Request.Proto
+	}
+
+	if resp.TLS == nil {
+		return
+	}
+	capture.TLSVersion = tls.VersionName(resp.TLS.Version)
+	capture.NegotiatedProtocol = resp.TLS.NegotiatedProtocol
+}
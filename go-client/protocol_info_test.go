@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestCaptureTLSInfo(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	h2Server := httptest.NewUnstartedServer(handler)
+	h2Server.EnableHTTP2 = true
+	h2Server.StartTLS()
+	defer h2Server.Close()
+
+	plainServer := httptest.NewServer(handler)
+	defer plainServer.Close()
+
+	tests := []struct {
+		name      string
+		server    *httptest.Server
+		enabled   bool
+		proto     string
+		alpn      string
+		tlsRecord bool
+	}{
+		{"http2 over tls", h2Server, true, "HTTP/2.0", "h2", true},
+		{"plain http", plainServer, true, "HTTP/1.1", "", false},
+		{"disabled", h2Server, false, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir, err := os.MkdirTemp("", "trace-tls-info-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tempDir)
+
+			config := newTestConfig(tempDir)
+			config.CaptureTLSInfo = tt.enabled
+			logger := NewLogger(config, "test-tls-info")
+			client := &http.Client{
+				Transport: NewTracingRoundTripper(tt.server.Client().Transport, logger, config, "test-tls-info"),
+			}
+
+			resp, err := client.Get(tt.server.URL)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			logger.Close()
+
+			responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+			if len(responses) != 1 {
+				t.Fatalf("Expected 1 response event, got %d", len(responses))
+			}
+			response := responses[0]
+
+			if got, _ := response["proto"].(string); got != tt.proto {
+				t.Errorf("Expected proto %q, got %q", tt.proto, got)
+			}
+			if got, _ := response["negotiated_protocol"].(string); got != tt.alpn {
+				t.Errorf("Expected negotiated_protocol %q, got %q", tt.alpn, got)
+			}
+			if _, ok := response["tls_version"]; ok != tt.tlsRecord {
+				t.Errorf("Expected tls_version recorded = %v, got %v", tt.tlsRecord, response["tls_version"])
+			}
+			if tt.enabled && response["request_proto"] != "HTTP/1.1" {
+				t.Errorf("Expected request_proto HTTP/1.1, got %v", response["request_proto"])
+			}
+		})
+	}
+}
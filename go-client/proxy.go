@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Files of the generated proxy CA, kept in the output directory
+const (
+	proxyCACertFile = "proxy-ca.pem"
+	proxyCAKeyFile  = "proxy-ca-key.pem"
+)
+
+// hopByHopHeaders apply to a single connection and are not forwarded
+var hopByHopHeaders = []string{
+	"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate",
+	"Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// TraceProxy is an HTTP forward proxy that traces the requests it forwards
+// through a TracingRoundTripper, so processes that cannot be instrumented
+// are traced by pointing HTTP_PROXY at it. HTTPS is tunneled untraced unless
+// MITM is enabled with a CA the clients trust.
+type TraceProxy struct {
+	transport *TracingRoundTripper
+	logger    *Logger
+
+	// CA that signs per-host certificates, nil when HTTPS is tunneled
+	ca      *tls.Certificate
+	caLeaf  *x509.Certificate
+	certsMu sync.Mutex
+	certs   map[string]*tls.Certificate
+}
+
+// NewTraceProxy creates a proxy that records into the given session. A nil
+// upstream uses a transport that never goes through another proxy and leaves
+// content encoding to the client.
+func NewTraceProxy(sessionID string, config *TracingConfig, upstream http.RoundTripper) *TraceProxy {
+	if upstream == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = nil
+		transport.DisableCompression = true
+		upstream = transport
+	}
+
+	logger := NewLogger(config, sessionID)
+	return &TraceProxy{
+		transport: NewTracingRoundTripper(upstream, logger, config, sessionID),
+		logger:    logger,
+		certs:     make(map[string]*tls.Certificate),
+	}
+}
+
+// EnableMITM makes the proxy terminate HTTPS with certificates signed by ca,
+// so HTTPS requests are traced like plain ones. Clients must trust ca.
+func (p *TraceProxy) EnableMITM(ca tls.Certificate) error {
+	leaf, err := x509.ParseCertificate(ca.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse proxy CA: %w", err)
+	}
+	p.ca = &ca
+	p.caLeaf = leaf
+	return nil
+}
+
+// Close flushes the proxy's session
+func (p *TraceProxy) Close() error {
+	return p.logger.Close()
+}
+
+// ServeHTTP forwards a proxied request, or sets up a CONNECT tunnel
+func (p *TraceProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.serveConnect(w, r)
+		return
+	}
+
+	if !r.URL.IsAbs() {
+		http.Error(w, "opencode-trace proxy: request URI must be absolute", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := p.forward(r)
+	if err != nil {
+		http.Error(w, "opencode-trace proxy: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	removeHopByHopHeaders(resp.Header)
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	copyFlushing(w, resp.Body)
+}
+
+// forward sends a request received by the proxy upstream through the tracer
+func (p *TraceProxy) forward(r *http.Request) (*http.Response, error) {
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	removeHopByHopHeaders(out.Header)
+	return p.transport.RoundTrip(out)
+}
+
+// serveConnect answers a CONNECT request by tunneling bytes to the target,
+// or with MITM enabled by serving the client's HTTPS requests itself
+func (p *TraceProxy) serveConnect(w http.ResponseWriter, r *http.Request) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "opencode-trace proxy: CONNECT not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var upstream net.Conn
+	if p.ca == nil {
+		var err error
+		upstream, err = net.DialTimeout("tcp", r.Host, 30*time.Second)
+		if err != nil {
+			http.Error(w, "opencode-trace proxy: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+
+	conn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		if upstream != nil {
+			upstream.Close()
+		}
+		return
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		if upstream != nil {
+			upstream.Close()
+		}
+		return
+	}
+
+	if upstream != nil {
+		tunnel(conn, buffered, upstream)
+		return
+	}
+	p.serveMITM(conn, r.Host)
+}
+
+// tunnel copies bytes both ways until either side closes
+func tunnel(client net.Conn, buffered *bufio.ReadWriter, upstream net.Conn) {
+	defer upstream.Close()
+
+	done := make(chan struct{})
+	go func() {
+		io.Copy(upstream, buffered)
+		upstream.Close()
+		close(done)
+	}()
+	io.Copy(client, upstream)
+	client.Close()
+	<-done
+}
+
+// serveMITM terminates TLS for host and forwards each request on the
+// connection through the tracer
+func (p *TraceProxy) serveMITM(conn net.Conn, host string) {
+	tlsConn := tls.Server(conn, &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			name := hello.ServerName
+			if name == "" {
+				name, _, _ = net.SplitHostPort(host)
+			}
+			return p.certificateFor(name)
+		},
+	})
+	defer tlsConn.Close()
+
+	reader := bufio.NewReader(tlsConn)
+	for {
+		req, err := http.ReadRequest(reader)
+		if err != nil {
+			return
+		}
+		req.URL.Scheme = "https"
+		req.URL.Host = host
+
+		resp, err := p.forward(req)
+		if err != nil {
+			resp = &http.Response{
+				StatusCode: http.StatusBadGateway,
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+				Header:     http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+				Body:       io.NopCloser(strings.NewReader("opencode-trace proxy: " + err.Error())),
+			}
+		}
+
+		removeHopByHopHeaders(resp.Header)
+		writeErr := resp.Write(tlsConn)
+		resp.Body.Close()
+		if writeErr != nil || req.Close || resp.Close {
+			return
+		}
+	}
+}
+
+// certificateFor returns a certificate for host signed by the proxy CA
+func (p *TraceProxy) certificateFor(host string) (*tls.Certificate, error) {
+	p.certsMu.Lock()
+	defer p.certsMu.Unlock()
+
+	if cert, ok := p.certs[host]; ok {
+		return cert, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(30 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, p.caLeaf, &key.PublicKey, p.ca.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign certificate for %s: %w", host, err)
+	}
+
+	cert := &tls.Certificate{Certificate: [][]byte{der, p.ca.Certificate[0]}, PrivateKey: key}
+	p.certs[host] = cert
+	return cert, nil
+}
+
+// LoadOrCreateProxyCA loads the proxy CA from dir, generating and saving a
+// new one on first use. The certificate must be added to the trust store of
+// clients whose HTTPS traffic is to be traced; the key never leaves dir.
+func LoadOrCreateProxyCA(dir string) (tls.Certificate, error) {
+	certPath := filepath.Join(dir, proxyCACertFile)
+	keyPath := filepath.Join(dir, proxyCAKeyFile)
+
+	ca, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err == nil {
+		return ca, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return tls.Certificate{}, fmt.Errorf("failed to load proxy CA: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "opencode-trace proxy CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(5 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create proxy CA: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	if err := os.MkdirAll(dir, outputDirPerm); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to save proxy CA key: %w", err)
+	}
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to save proxy CA: %w", err)
+	}
+
+	return tls.LoadX509KeyPair(certPath, keyPath)
+}
+
+// removeHopByHopHeaders deletes headers that apply only to one connection,
+// including those named in Connection
+func removeHopByHopHeaders(header http.Header) {
+	for _, value := range header.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			header.Del(strings.TrimSpace(name))
+		}
+	}
+	for _, name := range hopByHopHeaders {
+		header.Del(name)
+	}
+}
+
+// copyFlushing copies a response body to the client, flushing after each
+// read so streamed responses reach it as they arrive
+func copyFlushing(w http.ResponseWriter, body io.Reader) {
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
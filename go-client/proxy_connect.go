@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// proxyConnectRecorder times the establishment of a CONNECT tunnel through an
+// HTTP proxy. httptrace has no CONNECT hooks, so the tunnel is taken to be
+// up when the TLS handshake with the target starts.
+type proxyConnectRecorder struct {
+	mu        sync.Mutex
+	proxy     string
+	target    string
+	tlsStarts int
+	// TLS handshakes that precede the one with the target (1 for an https proxy)
+	proxyTLS int
+	started  time.Time
+	tunneled time.Time
+}
+
+// connectProxy returns the proxy the wrapped transport will CONNECT through
+// for req, or nil when the request does not use a tunnel
+func (t *TracingRoundTripper) connectProxy(req *http.Request) *url.URL {
+	transport, ok := t.wrapped.(*http.Transport)
+	if !ok || transport.Proxy == nil || req.URL.Scheme != "https" {
+		return nil
+	}
+
+	proxyURL, err := transport.Proxy(req)
+	if err != nil || proxyURL == nil {
+		return nil
+	}
+	if proxyURL.Scheme != "http" && proxyURL.Scheme != "https" {
+		// SOCKS proxies do not use CONNECT
+		return nil
+	}
+	return proxyURL
+}
+
+// withProxyConnectTrace attaches hooks that time the CONNECT tunnel when req
+// goes through a proxy. The recorder is nil when no tunnel is involved.
+func (t *TracingRoundTripper) withProxyConnectTrace(req *http.Request) (*http.Request, *proxyConnectRecorder) {
+	proxyURL := t.connectProxy(req)
+	if proxyURL == nil {
+		return req, nil
+	}
+
+	recorder := &proxyConnectRecorder{
+		proxy:  canonicalHostPort(proxyURL),
+		target: canonicalHostPort(req.URL),
+	}
+	if proxyURL.Scheme == "https" {
+		recorder.proxyTLS = 1
+	}
+
+	trace := &httptrace.ClientTrace{
+		ConnectStart: func(network, addr string) {
+			recorder.mu.Lock()
+			if recorder.started.IsZero() {
+				recorder.started = time.Now()
+			}
+			recorder.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			recorder.mu.Lock()
+			recorder.tlsStarts++
+			if recorder.tlsStarts > recorder.proxyTLS && recorder.tunneled.IsZero() {
+				recorder.tunneled = time.Now()
+			}
+			recorder.mu.Unlock()
+		},
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), recorder
+}
+
+// canonicalHostPort returns host:port for u, filling in the scheme's default port
+func canonicalHostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := "80"
+	if u.Scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// event builds the proxy_connect event, or returns nil when the request
+// reused an existing connection and no tunnel was set up
+func (r *proxyConnectRecorder) event(err error) *ProxyConnectEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.started.IsZero() {
+		return nil
+	}
+
+	event := &ProxyConnectEvent{
+		Type:    "proxy_connect",
+		Proxy:   r.proxy,
+		Target:  r.target,
+		Success: !r.tunneled.IsZero(),
+	}
+
+	end := r.tunneled
+	if end.IsZero() {
+		end = time.Now()
+		if err != nil {
+			event.Error = err.Error()
+		}
+	}
+	event.Timestamp = r.started.UnixMilli()
+	event.Duration = end.Sub(r.started).Milliseconds()
+
+	return event
+}
+
+// LogProxyConnect logs the establishment of a CONNECT tunnel for a request
+func (l *Logger) LogProxyConnect(requestID string, recorder *proxyConnectRecorder, err error) error {
+	if !l.config.Enabled {
+		return nil
+	}
+
+	event := recorder.event(err)
+	if event == nil {
+		return nil
+	}
+	event.SessionID = l.sessionID
+	event.RequestID = requestID
+	if event.Error != "" {
+		event.Error = l.redactError(err)
+	}
+
+	return l.writeEvent(event)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+// newConnectProxy starts an HTTP proxy that tunnels CONNECT requests. When
+// requireAuth is set it refuses every tunnel with 407.
+func newConnectProxy(requireAuth bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		if requireAuth {
+			w.Header().Set("Proxy-Authenticate", `Basic realm="proxy"`)
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+
+		go func() {
+			io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+}
+
+func TestProxyConnectEvent(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("through the tunnel"))
+	}))
+	defer target.Close()
+	targetURL, _ := url.Parse(target.URL)
+
+	for _, tt := range []struct {
+		name        string
+		requireAuth bool
+	}{
+		{"tunnel established", false},
+		{"tunnel refused", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir, err := os.MkdirTemp("", "trace-proxy-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tempDir)
+
+			proxy := newConnectProxy(tt.requireAuth)
+			defer proxy.Close()
+			proxyURL, _ := url.Parse(proxy.URL)
+
+			transport := target.Client().Transport.(*http.Transport).Clone()
+			transport.Proxy = http.ProxyURL(proxyURL)
+			defer transport.CloseIdleConnections()
+
+			config := newTestConfig(tempDir)
+			config.EnableHTTPTrace = true
+			client := WrapClient(&http.Client{Transport: transport}, NewLogger(config, "test-proxy"), config, "test-proxy")
+
+			resp, err := client.Get(target.URL)
+			if tt.requireAuth {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("Expected the request to fail when the proxy refuses the tunnel")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Request failed: %v", err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if string(body) != "through the tunnel" {
+					t.Errorf("Unexpected body %q", body)
+				}
+			}
+
+			events := eventsOfType(readSessionEvents(t, tempDir), "proxy_connect")
+			if len(events) != 1 {
+				t.Fatalf("Expected 1 proxy_connect event, got %d", len(events))
+			}
+			event := events[0]
+
+			if event["proxy"] != proxyURL.Host || event["target"] != targetURL.Host {
+				t.Errorf("Expected proxy %s and target %s, got %v and %v", proxyURL.Host, targetURL.Host, event["proxy"], event["target"])
+			}
+			if event["success"] != !tt.requireAuth {
+				t.Errorf("Expected success %v, got %v", !tt.requireAuth, event["success"])
+			}
+			if _, ok := event["duration_ms"].(float64); !ok {
+				t.Errorf("Expected duration_ms, got %v", event["duration_ms"])
+			}
+			if errMessage, _ := event["error"].(string); tt.requireAuth && !strings.Contains(errMessage, "Proxy Authentication Required") {
+				t.Errorf("Expected the proxy error to be recorded, got %q", errMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTraceProxyHTTP(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-proxy-mode-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Connection") != "" {
+			t.Error("Expected hop-by-hop headers not to be forwarded")
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("proxied"))
+	}))
+	defer target.Close()
+
+	proxy := NewTraceProxy("test-proxy-mode", newTestConfig(tempDir), nil)
+	proxyServer := httptest.NewServer(proxy)
+	defer proxyServer.Close()
+	proxyURL, _ := url.Parse(proxyServer.URL)
+
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	req, _ := http.NewRequest(http.MethodGet, target.URL+"/path?q=1", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Proxy-Connection", "keep-alive")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request through proxy failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "proxied" {
+		t.Errorf("Unexpected body %q", body)
+	}
+	proxy.Close()
+
+	events := readSessionEvents(t, tempDir)
+	requests := eventsOfType(events, "http_request")
+	responses := eventsOfType(events, "http_response")
+	if len(requests) != 1 || len(responses) != 1 {
+		t.Fatalf("Expected 1 request and 1 response event, got %d and %d", len(requests), len(responses))
+	}
+	if requests[0]["url"] != target.URL+"/path?q=1" {
+		t.Errorf("Expected the target URL, got %v", requests[0]["url"])
+	}
+	headers, _ := requests[0]["headers"].(map[string]interface{})
+	if headers["Authorization"] != redactedValue {
+		t.Errorf("Expected Authorization to be redacted, got %v", headers["Authorization"])
+	}
+	if responses[0]["body"] != "proxied" || responses[0]["request_id"] != requests[0]["request_id"] {
+		t.Errorf("Unexpected response event: %v", responses[0])
+	}
+}
+
+func TestTraceProxyForwardsBodiesPastMaxBodySize(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-proxy-mode-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	upload := strings.Repeat("u", 3000)
+	download := strings.Repeat("d", 3000)
+
+	var received string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.Write([]byte(download))
+	}))
+	defer target.Close()
+
+	config := newTestConfig(tempDir)
+	config.MaxBodySize = 1000
+	proxy := NewTraceProxy("test-proxy-large", config, nil)
+	proxyServer := httptest.NewServer(proxy)
+	defer proxyServer.Close()
+	proxyURL, _ := url.Parse(proxyServer.URL)
+
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	resp, err := client.Post(target.URL+"/upload", "text/plain", strings.NewReader(upload))
+	if err != nil {
+		t.Fatalf("Request through proxy failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	proxy.Close()
+
+	if received != upload {
+		t.Errorf("Expected the target to receive all %d uploaded bytes, got %d", len(upload), len(received))
+	}
+	if string(body) != download {
+		t.Errorf("Expected the client to receive all %d response bytes, got %d", len(download), len(body))
+	}
+
+	events := readSessionEvents(t, tempDir)
+	requests := eventsOfType(events, "http_request")
+	responses := eventsOfType(events, "http_response")
+	if len(requests) != 1 || len(responses) != 1 {
+		t.Fatalf("Expected 1 request and 1 response event, got %d and %d", len(requests), len(responses))
+	}
+	if body, _ := requests[0]["body"].(string); len(body) != 1000 {
+		t.Errorf("Expected the captured request body to stop at MaxBodySize, got %d bytes", len(body))
+	}
+	if body, _ := responses[0]["body"].(string); len(body) != 1000 {
+		t.Errorf("Expected the captured response body to stop at MaxBodySize, got %d bytes", len(body))
+	}
+}
+
+func TestTraceProxyMITM(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-proxy-mitm-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("decrypted"))
+	}))
+	defer target.Close()
+
+	ca, err := LoadOrCreateProxyCA(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create proxy CA: %v", err)
+	}
+	if reloaded, err := LoadOrCreateProxyCA(tempDir); err != nil || string(reloaded.Certificate[0]) != string(ca.Certificate[0]) {
+		t.Fatalf("Expected the saved CA to be reused, got %v", err)
+	}
+
+	// The proxy must trust the target's test certificate
+	upstream := target.Client().Transport.(*http.Transport).Clone()
+	proxy := NewTraceProxy("test-proxy-mitm", newTestConfig(tempDir), upstream)
+	if err := proxy.EnableMITM(ca); err != nil {
+		t.Fatal(err)
+	}
+	proxyServer := httptest.NewServer(proxy)
+	defer proxyServer.Close()
+	proxyURL, _ := url.Parse(proxyServer.URL)
+
+	caLeaf, _ := x509.ParseCertificate(ca.Certificate[0])
+	roots := x509.NewCertPool()
+	roots.AddCert(caLeaf)
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyURL(proxyURL),
+		TLSClientConfig: &tls.Config{RootCAs: roots},
+	}}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Get(target.URL + "/secure")
+	if err != nil {
+		t.Fatalf("HTTPS request through proxy failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "decrypted" {
+		t.Errorf("Unexpected body %q", body)
+	}
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 1 || responses[0]["body"] != "decrypted" {
+		t.Fatalf("Expected the decrypted response to be traced, got %v", responses)
+	}
+	requests := eventsOfType(readSessionEvents(t, tempDir), "http_request")
+	if len(requests) != 1 || requests[0]["url"] != target.URL+"/secure" {
+		t.Errorf("Expected the HTTPS URL to be traced, got %v", requests)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Rate-limit providers
+const (
+	rateLimitProviderAnthropic = "anthropic"
+	rateLimitProviderOpenAI    = "openai"
+	rateLimitProviderGeneric   = "generic"
+)
+
+// rateLimitSchemes are the rate-limit header families, in the order they are
+// tried. A response carrying several is attributed to the first one found, so
+// the provider does not depend on header order.
+var rateLimitSchemes = []struct {
+	prefix     string
+	fieldFirst bool
+}{
+	{"anthropic-ratelimit-", false},
+	{"x-ratelimit-", true},
+}
+
+// RateLimitInfo holds the provider rate-limit state reported on a response
+type RateLimitInfo struct {
+	Provider   string                      `json:"provider"`
+	Limits     map[string]*RateLimitWindow `json:"limits"`
+	RetryAfter string                      `json:"retry_after,omitempty"`
+}
+
+// RateLimitWindow describes one limit, such as requests or tokens
+type RateLimitWindow struct {
+	Limit     *int64 `json:"limit,omitempty"`
+	Remaining *int64 `json:"remaining,omitempty"`
+	Reset     string `json:"reset,omitempty"`
+}
+
+// isRateLimitHeader reports whether a lower-cased header name is a provider rate-limit header
+func isRateLimitHeader(lowerHeader string) bool {
+	return strings.HasPrefix(lowerHeader, "anthropic-ratelimit-") ||
+		strings.HasPrefix(lowerHeader, "x-ratelimit-")
+}
+
+// parseRateLimit extracts rate-limit headers into a structured form. Anthropic
+// sends anthropic-ratelimit-<kind>-<field>; OpenAI and others send
+// x-ratelimit-<field>-<kind>, which is only labeled openai when the response
+// also carries OpenAI's openai-* headers.
+func parseRateLimit(headers map[string]string) *RateLimitInfo {
+	lowered := make(map[string]string, len(headers))
+	openAI := false
+	for name, value := range headers {
+		lower := strings.ToLower(name)
+		lowered[lower] = value
+		openAI = openAI || strings.HasPrefix(lower, "openai-")
+	}
+
+	for _, scheme := range rateLimitSchemes {
+		info := parseRateLimitScheme(lowered, scheme.prefix, scheme.fieldFirst)
+		if info == nil {
+			continue
+		}
+		switch {
+		case scheme.prefix == "anthropic-ratelimit-":
+			info.Provider = rateLimitProviderAnthropic
+		case openAI:
+			info.Provider = rateLimitProviderOpenAI
+		default:
+			info.Provider = rateLimitProviderGeneric
+		}
+		info.RetryAfter = lowered["retry-after"]
+		return info
+	}
+	return nil
+}
+
+// parseRateLimitScheme collects the limits of one header family from
+// lower-cased headers, or returns nil when there are none
+func parseRateLimitScheme(headers map[string]string, prefix string, fieldFirst bool) *RateLimitInfo {
+	var info *RateLimitInfo
+	for name, value := range headers {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		kind, field := splitRateLimitName(strings.TrimPrefix(name, prefix), fieldFirst)
+		if kind == "" {
+			continue
+		}
+
+		if info == nil {
+			info = &RateLimitInfo{Limits: make(map[string]*RateLimitWindow)}
+		}
+		window := info.Limits[kind]
+		if window == nil {
+			window = &RateLimitWindow{}
+			info.Limits[kind] = window
+		}
+
+		switch field {
+		case "limit":
+			if n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+				window.Limit = &n
+			}
+		case "remaining":
+			if n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+				window.Remaining = &n
+			}
+		case "reset":
+			window.Reset = value
+		}
+	}
+	return info
+}
+
+// splitRateLimitName splits the rest of a rate-limit header name into the limit
+// kind (requests, tokens, input_tokens, ...) and the field (limit, remaining, reset)
+func splitRateLimitName(rest string, fieldFirst bool) (string, string) {
+	for _, field := range []string{"limit", "remaining", "reset"} {
+		if fieldFirst && strings.HasPrefix(rest, field+"-") {
+			return strings.ReplaceAll(strings.TrimPrefix(rest, field+"-"), "-", "_"), field
+		}
+		if !fieldFirst && strings.HasSuffix(rest, "-"+field) {
+			return strings.ReplaceAll(strings.TrimSuffix(rest, "-"+field), "-", "_"), field
+		}
+	}
+	return "", ""
+}
+
+// observeRateLimit records the remaining quota of a response for the session summary
+func (l *Logger) observeRateLimit(info *RateLimitInfo) {
+	if info == nil {
+		return
+	}
+
+	l.rateLimitMu.Lock()
+	defer l.rateLimitMu.Unlock()
+
+	for kind, window := range info.Limits {
+		if window.Remaining == nil {
+			continue
+		}
+		if l.rateLimitRemaining == nil {
+			l.rateLimitRemaining = make(map[string]map[string]int64)
+		}
+		byKind := l.rateLimitRemaining[info.Provider]
+		if byKind == nil {
+			byKind = make(map[string]int64)
+			l.rateLimitRemaining[info.Provider] = byKind
+		}
+		if current, ok := byKind[kind]; !ok || *window.Remaining < current {
+			byKind[kind] = *window.Remaining
+		}
+	}
+}
+
+// rateLimitSummary returns a copy of the lowest remaining quotas observed
+func (l *Logger) rateLimitSummary() map[string]map[string]int64 {
+	l.rateLimitMu.Lock()
+	defer l.rateLimitMu.Unlock()
+
+	if len(l.rateLimitRemaining) == 0 {
+		return nil
+	}
+
+	summary := make(map[string]map[string]int64, len(l.rateLimitRemaining))
+	for provider, byKind := range l.rateLimitRemaining {
+		summary[provider] = make(map[string]int64, len(byKind))
+		for kind, remaining := range byKind {
+			summary[provider][kind] = remaining
+		}
+	}
+	return summary
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestParseRateLimitOpenAI(t *testing.T) {
+	info := parseRateLimit(map[string]string{
+		"X-Ratelimit-Limit-Requests":     "500",
+		"X-Ratelimit-Remaining-Requests": "499",
+		"X-Ratelimit-Reset-Requests":     "120ms",
+		"X-Ratelimit-Limit-Tokens":       "30000",
+		"X-Ratelimit-Remaining-Tokens":   "0",
+		"X-Ratelimit-Reset-Tokens":       "6m0s",
+		"Retry-After":                    "2",
+		"Content-Type":                   "application/json",
+		"Openai-Version":                 "2020-10-01",
+	})
+	if info == nil {
+		t.Fatal("Expected rate limit info")
+	}
+	if info.Provider != "openai" || info.RetryAfter != "2" {
+		t.Errorf("Unexpected provider/retry-after: %+v", info)
+	}
+
+	requests, tokens := info.Limits["requests"], info.Limits["tokens"]
+	if requests == nil || tokens == nil {
+		t.Fatalf("Expected requests and tokens limits, got %v", info.Limits)
+	}
+	if *requests.Limit != 500 || *requests.Remaining != 499 || requests.Reset != "120ms" {
+		t.Errorf("Unexpected requests window: %+v", requests)
+	}
+	if tokens.Remaining == nil || *tokens.Remaining != 0 || tokens.Reset != "6m0s" {
+		t.Errorf("Expected tokens remaining 0 with reset 6m0s, got %+v", tokens)
+	}
+}
+
+func TestParseRateLimitProvider(t *testing.T) {
+	generic := parseRateLimit(map[string]string{
+		"X-Ratelimit-Limit-Requests":     "60",
+		"X-Ratelimit-Remaining-Requests": "59",
+	})
+	if generic == nil || generic.Provider != "generic" {
+		t.Errorf("Expected x-ratelimit-* without OpenAI headers to be generic, got %+v", generic)
+	}
+
+	// Anthropic headers win over x-ratelimit-* whatever the map order
+	for i := 0; i < 20; i++ {
+		mixed := parseRateLimit(map[string]string{
+			"X-Ratelimit-Remaining-Requests":         "7",
+			"Anthropic-Ratelimit-Requests-Remaining": "42",
+		})
+		if mixed == nil || mixed.Provider != "anthropic" || *mixed.Limits["requests"].Remaining != 42 {
+			t.Fatalf("Expected the anthropic limits, got %+v", mixed)
+		}
+	}
+}
+
+func TestParseRateLimitAbsent(t *testing.T) {
+	if info := parseRateLimit(map[string]string{"Content-Type": "text/plain"}); info != nil {
+		t.Errorf("Expected no rate limit info, got %+v", info)
+	}
+}
+
+func TestRateLimitCaptureAndSummary(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-ratelimit-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	remaining := []int{50, 10, 30}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("anthropic-ratelimit-requests-limit", "60")
+		w.Header().Set("anthropic-ratelimit-requests-remaining", strconv.Itoa(remaining[calls]))
+		w.Header().Set("anthropic-ratelimit-requests-reset", "2025-01-15T14:31:00Z")
+		w.Header().Set("anthropic-ratelimit-input-tokens-remaining", "40000")
+		calls++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.SensitiveHeaders = append(config.SensitiveHeaders, "limit")
+
+	client := NewTracingHTTPClientWithConfig("test-ratelimit", config)
+	for range remaining {
+		resp, err := client.Get(server.URL + "/v1/messages")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+	client.Close()
+
+	events := readSessionEvents(t, tempDir)
+	responses := eventsOfType(events, "http_response")
+	if len(responses) != 3 {
+		t.Fatalf("Expected 3 responses, got %d", len(responses))
+	}
+
+	rateLimit, _ := responses[0]["rate_limit"].(map[string]interface{})
+	if rateLimit["provider"] != "anthropic" {
+		t.Fatalf("Expected anthropic rate limit, got %v", responses[0]["rate_limit"])
+	}
+	limits := rateLimit["limits"].(map[string]interface{})
+	requests := limits["requests"].(map[string]interface{})
+	if requests["limit"] != float64(60) || requests["remaining"] != float64(50) || requests["reset"] != "2025-01-15T14:31:00Z" {
+		t.Errorf("Unexpected requests window: %v", requests)
+	}
+	if _, ok := limits["input_tokens"]; !ok {
+		t.Errorf("Expected input_tokens window, got %v", limits)
+	}
+
+	headers := responses[0]["headers"].(map[string]interface{})
+	if headers["Anthropic-Ratelimit-Requests-Remaining"] != "50" {
+		t.Errorf("Expected rate limit headers never to be redacted, got %v", headers)
+	}
+
+	summaries := eventsOfType(events, "session_summary")
+	if len(summaries) != 1 {
+		t.Fatalf("Expected 1 session summary, got %d", len(summaries))
+	}
+	minRemaining := summaries[0]["rate_limit_min_remaining"].(map[string]interface{})
+	anthropic := minRemaining["anthropic"].(map[string]interface{})
+	if anthropic["requests"] != float64(10) || anthropic["input_tokens"] != float64(40000) {
+		t.Errorf("Unexpected minimum remaining: %v", anthropic)
+	}
+}
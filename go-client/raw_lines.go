@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// rawRequestLine reconstructs the HTTP/1.1 request line for req, as in
+// "GET /path?x=1 HTTP/1.1". The transport serializes the request itself, so
+// this is a faithful reconstruction rather than the bytes on the wire.
+func rawRequestLine(req *http.Request) string {
+	target := req.URL.RequestURI()
+	if req.Method == http.MethodConnect && req.URL.Path == "" {
+		// CONNECT uses the authority form
+		target = req.URL.Host
+	}
+
+	proto := req.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+
+	return req.Method + " " + target + " " + proto
+}
+
+// rawStatusLine reconstructs the status line for resp, as in "HTTP/1.1 200 OK"
+func rawStatusLine(resp *http.Response) string {
+	status := resp.Status
+	if status == "" {
+		status = strconv.Itoa(resp.StatusCode) + " " + http.StatusText(resp.StatusCode)
+	}
+
+	proto := resp.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+
+	return proto + " " + status
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRawLinesCaptured(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-raw-lines-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.CaptureRawLines = true
+
+	client := NewTracingHTTPClientWithConfig("test-raw-lines", config)
+
+	resp, err := client.Post(server.URL+"/v1/messages?beta=true&x=1", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	events := readSessionEvents(t, tempDir)
+	requests := eventsOfType(events, "http_request")
+	responses := eventsOfType(events, "http_response")
+	if len(requests) != 1 || len(responses) != 1 {
+		t.Fatalf("Expected 1 request and 1 response, got %d and %d", len(requests), len(responses))
+	}
+
+	if line := requests[0]["raw_request_line"]; line != "POST /v1/messages?beta=true&x=1 HTTP/1.1" {
+		t.Errorf("Unexpected request line %q", line)
+	}
+	if line := responses[0]["raw_status_line"]; line != "HTTP/1.1 202 Accepted" {
+		t.Errorf("Unexpected status line %q", line)
+	}
+}
+
+func TestRawLinesDisabledByDefault(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-raw-lines-off-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-raw-lines-off", newTestConfig(tempDir))
+
+	resp, err := client.Get(server.URL + "/path")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	for _, event := range readSessionEvents(t, tempDir) {
+		if _, ok := event["raw_request_line"]; ok {
+			t.Errorf("Unexpected raw_request_line in %v", event["type"])
+		}
+		if _, ok := event["raw_status_line"]; ok {
+			t.Errorf("Unexpected raw_status_line in %v", event["type"])
+		}
+	}
+}
+
+func TestRawLineReconstruction(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/a%20b/c?q=1", nil)
+	if line := rawRequestLine(req); line != "GET /a%20b/c?q=1 HTTP/1.1" {
+		t.Errorf("Unexpected request line %q", line)
+	}
+
+	connect := &http.Request{Method: http.MethodConnect, URL: &url.URL{Host: "example.com:443"}}
+	if line := rawRequestLine(connect); line != "CONNECT example.com:443 HTTP/1.1" {
+		t.Errorf("Unexpected CONNECT line %q", line)
+	}
+
+	resp := &http.Response{StatusCode: http.StatusNotFound, Proto: "HTTP/2.0"}
+	if line := rawStatusLine(resp); line != "HTTP/2.0 404 Not Found" {
+		t.Errorf("Unexpected status line %q", line)
+	}
+}
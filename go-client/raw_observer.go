@@ -0,0 +1,41 @@
+package main
+
+import "net/http"
+
+// RawObserver is called with the real request and response of every traced
+// round trip, before the response is captured, redacted and logged. resp is
+// nil when the request failed.
+//
+// The observer sees secrets that are never written to the trace, such as
+// Authorization headers and minted credentials. It is meant for in-process
+// metrics only and must not persist what it sees. It runs synchronously in
+// the RoundTripper, so it should be fast, and it must not read or close
+// resp.Body, which still belongs to the caller.
+type RawObserver func(req *http.Request, resp *http.Response)
+
+// WithRawObserver sets the observer called for each traced round trip and
+// returns the round tripper. There is no observer by default.
+func (t *TracingRoundTripper) WithRawObserver(observer RawObserver) *TracingRoundTripper {
+	t.rawObserver = observer
+	return t
+}
+
+// WithRawObserver sets the observer called for each traced round trip and
+// returns the client. There is no observer by default.
+func (t *TracingHTTPClient) WithRawObserver(observer RawObserver) *TracingHTTPClient {
+	if roundTripper, ok := t.client.Transport.(*TracingRoundTripper); ok {
+		roundTripper.WithRawObserver(observer)
+	}
+	return t
+}
+
+// observeRaw passes the unredacted exchange to the raw observer, if any. A
+// panicking observer is logged like a tracer bug and does not fail the request.
+func (t *TracingRoundTripper) observeRaw(requestID string, req *http.Request, resp *http.Response) {
+	if t.rawObserver == nil {
+		return
+	}
+
+	defer t.recoverCapturePanic(requestID, "raw observer")
+	t.rawObserver(req, resp)
+}
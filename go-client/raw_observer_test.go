@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestRawObserverSeesUnredactedRequest(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-raw-observer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var validTokens, observedStatus int
+	client := NewTracingHTTPClientWithConfig("test-raw-observer", newTestConfig(tempDir))
+	client.WithRawObserver(func(req *http.Request, resp *http.Response) {
+		if req.Header.Get("Authorization") == "Bearer sk-real-token" {
+			validTokens++
+		}
+		if resp != nil {
+			observedStatus = resp.StatusCode
+		}
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Authorization", "Bearer sk-real-token")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if validTokens != 1 || observedStatus != http.StatusOK {
+		t.Errorf("Expected the observer to see the real token and a 200, got %d tokens and status %d", validTokens, observedStatus)
+	}
+
+	requests := eventsOfType(readSessionEvents(t, tempDir), "http_request")
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request event, got %d", len(requests))
+	}
+	headers, _ := requests[0]["headers"].(map[string]interface{})
+	if headers["Authorization"] != redactedValue {
+		t.Errorf("Expected the persisted Authorization header to be redacted, got %v", headers["Authorization"])
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// redirectChain returns the URLs of the requests that led to req through
+// redirects, oldest first, followed by req's own URL
+func redirectChain(req *http.Request) []string {
+	chain := []string{req.URL.String()}
+	for resp := req.Response; resp != nil && resp.Request != nil; resp = resp.Request.Response {
+		chain = append(chain, resp.Request.URL.String())
+	}
+
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// redirectCycle returns the cycle closed by req when its URL was already
+// visited in the redirect chain, or nil. Only the hop that first closes a
+// cycle reports it, so a loop followed several times is logged once.
+func redirectCycle(req *http.Request) []string {
+	if req.Response == nil {
+		return nil
+	}
+
+	chain := redirectChain(req)
+	seen := make(map[string]int, len(chain))
+	for i, url := range chain {
+		if first, ok := seen[url]; ok {
+			if i != len(chain)-1 {
+				return nil
+			}
+			return chain[first:]
+		}
+		seen[url] = i
+	}
+	return nil
+}
+
+// LogRedirectLoop logs a redirect chain that revisited a URL at the given hop
+func (l *Logger) LogRedirectLoop(requestID string, hop int, cycle []string) error {
+	if !l.config.Enabled {
+		return nil
+	}
+
+	redacted := make([]string, len(cycle))
+	for i, url := range cycle {
+		redacted[i] = l.redactURL(url)
+	}
+
+	event := RedirectLoopEvent{
+		Type:      "redirect_loop",
+		Timestamp: time.Now().UnixMilli(),
+		SessionID: l.sessionID,
+		RequestID: requestID,
+		Cycle:     redacted,
+		Hop:       hop,
+	}
+
+	return l.writeEvent(event)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestRedirectLoopDetected(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-redirect-loop-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/a", http.StatusFound)
+	})
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/b", http.StatusFound)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/a", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-redirect-loop", newTestConfig(tempDir))
+	if resp, err := client.Get(server.URL + "/start"); err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected the client to give up on the redirect loop")
+	}
+
+	loops := eventsOfType(readSessionEvents(t, tempDir), "redirect_loop")
+	if len(loops) != 1 {
+		t.Fatalf("Expected the loop to be logged once, got %d events", len(loops))
+	}
+
+	var cycle []string
+	for _, url := range loops[0]["cycle"].([]interface{}) {
+		cycle = append(cycle, url.(string))
+	}
+	expected := []string{server.URL + "/a", server.URL + "/b", server.URL + "/a"}
+	if !reflect.DeepEqual(cycle, expected) {
+		t.Errorf("Expected cycle %v, got %v", expected, cycle)
+	}
+	if loops[0]["hop"] != float64(3) {
+		t.Errorf("Expected the loop to close at hop 3, got %v", loops[0]["hop"])
+	}
+}
+
+func TestRedirectWithoutLoop(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-redirect-no-loop-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-redirect-no-loop", newTestConfig(tempDir))
+	resp, err := client.Get(server.URL + "/old")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if loops := eventsOfType(readSessionEvents(t, tempDir), "redirect_loop"); len(loops) != 0 {
+		t.Errorf("Expected no redirect_loop event, got %v", loops)
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// htmlReport is the data behind the session report template
+type htmlReport struct {
+	SessionID string
+	Stats     []htmlReportStat
+	Rows      []htmlReportRow
+}
+
+// htmlReportStat is one line of the report's summary
+type htmlReportStat struct {
+	Name  string
+	Value string
+}
+
+// htmlReportRow is one request in the report's table
+type htmlReportRow struct {
+	Index        int
+	Method       string
+	URL          string
+	Status       int64
+	StatusText   string
+	Duration     int64
+	Failed       bool
+	Error        string
+	RequestBody  template.HTML
+	ResponseBody template.HTML
+}
+
+// GenerateHTMLReport renders a session file as a self-contained HTML page:
+// summary statistics and a sortable table of requests with expandable bodies
+func GenerateHTMLReport(sessionFile string, w io.Writer) error {
+	return GenerateHTMLReportFromStore(&FileSystemStore{}, sessionFile, w)
+}
+
+// GenerateHTMLReportFromStore is GenerateHTMLReport for a session held in store
+func GenerateHTMLReportFromStore(store SessionStore, name string, w io.Writer) error {
+	events, err := ReadSession(store, name)
+	if err != nil {
+		return err
+	}
+
+	report := buildHTMLReport(events)
+	if err := htmlReportTemplate.Execute(w, report); err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+	return nil
+}
+
+// buildHTMLReport collects the rows and statistics of a session
+func buildHTMLReport(events []map[string]interface{}) htmlReport {
+	var report htmlReport
+	var summary map[string]interface{}
+	for _, event := range events {
+		if report.SessionID == "" {
+			report.SessionID = eventString(event, "session_id")
+		}
+		if eventString(event, "type") == "session_summary" {
+			summary = event
+		}
+	}
+
+	var failed, totalDuration int64
+	for i, exchange := range GroupExchanges(events) {
+		row := htmlReportRow{
+			Index:       i + 1,
+			Method:      eventString(exchange.Request, "method"),
+			URL:         eventString(exchange.Request, "url"),
+			StatusText:  "-",
+			RequestBody: highlightJSON(eventString(exchange.Request, "body")),
+		}
+
+		if response := exchange.Response; response != nil {
+			row.Status = eventInt64(response, "status_code")
+			row.StatusText = fmt.Sprint(row.Status)
+			row.Duration = eventInt64(response, "duration_ms")
+			row.Failed = row.Status >= 400
+			row.ResponseBody = highlightJSON(eventString(response, "body"))
+		}
+		for _, errorEvent := range exchange.Errors {
+			if details, ok := errorEvent["error"].(map[string]interface{}); ok {
+				row.Error, _ = details["message"].(string)
+			}
+		}
+		if row.Error != "" {
+			row.Failed = true
+			if exchange.Response == nil {
+				row.StatusText = "error"
+			}
+		}
+
+		if row.Failed {
+			failed++
+		}
+		totalDuration += row.Duration
+		report.Rows = append(report.Rows, row)
+	}
+
+	report.Stats = append(report.Stats,
+		htmlReportStat{"Requests", fmt.Sprint(len(report.Rows))},
+		htmlReportStat{"Failed", fmt.Sprint(failed)},
+		htmlReportStat{"Total duration", fmt.Sprintf("%d ms", totalDuration)},
+	)
+	if len(report.Rows) > 0 {
+		report.Stats = append(report.Stats, htmlReportStat{"Average duration", fmt.Sprintf("%d ms", totalDuration/int64(len(report.Rows)))})
+	}
+	if summary != nil {
+		report.Stats = append(report.Stats,
+			htmlReportStat{"Events written", fmt.Sprint(eventInt64(summary, "events_written"))},
+			htmlReportStat{"Events dropped", fmt.Sprint(eventInt64(summary, "events_dropped"))},
+			htmlReportStat{"Bytes written", fmt.Sprint(eventInt64(summary, "bytes_written"))},
+		)
+	}
+	return report
+}
+
+// highlightJSON escapes a body for display, indenting JSON and wrapping its
+// keys, strings, numbers and literals in spans for the report's colors
+func highlightJSON(body string) template.HTML {
+	var indented bytes.Buffer
+	if body == "" || json.Indent(&indented, []byte(body), "", "  ") != nil {
+		return template.HTML(html.EscapeString(body))
+	}
+
+	s := indented.String()
+	var out strings.Builder
+	span := func(class, token string) {
+		fmt.Fprintf(&out, `<span class="%s">%s</span>`, class, html.EscapeString(token))
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			token := s[i : j+1]
+			i = j + 1
+
+			class := "json-string"
+			if strings.HasPrefix(strings.TrimLeft(s[i:], " "), ":") {
+				class = "json-key"
+			}
+			span(class, token)
+
+		case c == '-' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(s) && strings.IndexByte("0123456789.eE+-", s[j]) >= 0 {
+				j++
+			}
+			span("json-number", s[i:j])
+			i = j
+
+		case strings.HasPrefix(s[i:], "true"), strings.HasPrefix(s[i:], "null"):
+			span("json-literal", s[i:i+4])
+			i += 4
+
+		case strings.HasPrefix(s[i:], "false"):
+			span("json-literal", s[i:i+5])
+			i += 5
+
+		default:
+			out.WriteString(html.EscapeString(string(c)))
+			i++
+		}
+	}
+	return template.HTML(out.String())
+}
+
+// htmlReportTemplate is the report page. It is kept well-formed XML so that
+// it can be checked by a strict parser, which is why the script avoids the
+// characters < and &.
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8"/>
+<title>opencode-trace session {{.SessionID}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+dl.stats { display: grid; grid-template-columns: max-content auto; gap: 0.2em 1em; }
+dl.stats dt { font-weight: bold; }
+table { border-collapse: collapse; width: 100%; margin-top: 1.5em; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.4em; text-align: left; vertical-align: top; }
+th[data-sort] { cursor: pointer; user-select: none; }
+th[data-order="asc"]::after { content: " \25B2"; }
+th[data-order="desc"]::after { content: " \25BC"; }
+tr.failed td.status { color: #b00020; font-weight: bold; }
+td.url { word-break: break-all; }
+pre { background: #f6f8fa; padding: 0.6em; overflow-x: auto; max-height: 30em; }
+.json-key { color: #0451a5; }
+.json-string { color: #a31515; }
+.json-number { color: #098658; }
+.json-literal { color: #0000ff; }
+</style>
+</head>
+<body>
+<h1>Session {{.SessionID}}</h1>
+<dl class="stats">
+{{- range .Stats}}
+<dt>{{.Name}}</dt><dd>{{.Value}}</dd>
+{{- end}}
+</dl>
+<table id="requests">
+<thead>
+<tr>
+<th data-sort="number">#</th>
+<th data-sort="text">Method</th>
+<th data-sort="text">URL</th>
+<th data-sort="number">Status</th>
+<th data-sort="number">Duration (ms)</th>
+<th>Bodies</th>
+</tr>
+</thead>
+<tbody>
+{{- range .Rows}}
+<tr class="exchange{{if .Failed}} failed{{end}}">
+<td data-value="{{.Index}}">{{.Index}}</td>
+<td data-value="{{.Method}}">{{.Method}}</td>
+<td class="url" data-value="{{.URL}}">{{.URL}}</td>
+<td class="status" data-value="{{.Status}}">{{.StatusText}}</td>
+<td data-value="{{.Duration}}">{{.Duration}}</td>
+<td>
+{{- if .Error}}<div class="error">{{.Error}}</div>{{end}}
+{{- if .RequestBody}}<details><summary>Request body</summary><pre>{{.RequestBody}}</pre></details>{{end}}
+{{- if .ResponseBody}}<details><summary>Response body</summary><pre>{{.ResponseBody}}</pre></details>{{end}}
+</td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+<script>
+document.querySelectorAll("th[data-sort]").forEach(function (th) {
+  th.addEventListener("click", function () {
+    var body = th.closest("table").tBodies[0];
+    var column = th.cellIndex;
+    var numeric = th.dataset.sort === "number";
+    var descending = th.dataset.order === "asc";
+    th.closest("tr").querySelectorAll("th").forEach(function (other) { delete other.dataset.order; });
+    th.dataset.order = descending ? "desc" : "asc";
+
+    var rows = Array.prototype.slice.call(body.rows);
+    rows.sort(function (a, b) {
+      var x = a.cells[column].dataset.value, y = b.cells[column].dataset.value;
+      var order = numeric ? Number(x) - Number(y) : x.localeCompare(y);
+      return descending ? -order : order;
+    });
+    rows.forEach(function (row) { body.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`))
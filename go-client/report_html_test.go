@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGenerateHTMLReport(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-report-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model": "gpt-<4>", "tokens": 42, "ok": true}`))
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-report", newTestConfig(tempDir))
+	for _, path := range []string{"/chat?a=1&b=2", "/missing"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+	client.Close()
+
+	var out bytes.Buffer
+	if err := GenerateHTMLReport(findSessionFile(t, tempDir), &out); err != nil {
+		t.Fatalf("GenerateHTMLReport failed: %v", err)
+	}
+	page := out.String()
+
+	// The page must be well-formed: every element closed and all text escaped
+	decoder := xml.NewDecoder(strings.NewReader(page))
+	decoder.Strict = true
+	decoder.Entity = xml.HTMLEntity
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Report is not well-formed: %v\n%s", err, page)
+		}
+	}
+
+	for _, expected := range []string{
+		"<title>opencode-trace session test-report</title>",
+		`<td class="url" data-value="` + server.URL + `/chat?a=1&amp;b=2">`,
+		`<td class="status" data-value="200">200</td>`,
+		`<tr class="exchange failed">`,
+		`<td class="status" data-value="404">404</td>`,
+		`<span class="json-key">&#34;model&#34;</span>: <span class="json-string">&#34;gpt-&lt;4&gt;&#34;</span>`,
+		`<span class="json-number">42</span>`,
+		`<span class="json-literal">true</span>`,
+		"<dt>Requests</dt><dd>2</dd>",
+		"<dt>Failed</dt><dd>1</dd>",
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected the report to contain %q", expected)
+		}
+	}
+}
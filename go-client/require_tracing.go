@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrTracingRequired is returned by the RoundTripper when RequireTracing is
+// set and a request could not be recorded, so it was not sent
+var ErrTracingRequired = errors.New("tracing required but the request could not be recorded")
+
+// Reasons a request is not recorded even though no write failed
+var (
+	errTracingSuspended    = errors.New("tracing suspended on low disk space")
+	errSessionBudgetSpent  = errors.New("session byte budget exhausted")
+	errRequestLimitReached = errors.New("request limit reached")
+	errCapturePanicked     = errors.New("request capture panicked")
+)
+
+// persistRequired writes an event synchronously, after anything still
+// queued, and reports every reason it was not written
+func (l *Logger) persistRequired(event interface{}) error {
+	l.Flush()
+
+	if !l.hasDiskSpace() {
+		return errTracingSuspended
+	}
+	data, err := l.encodeEvent(event)
+	if err != nil {
+		return err
+	}
+	if !l.withinHardBudget(int64(len(data)) + 1) {
+		return errSessionBudgetSpent
+	}
+	return l.writeLine(data)
+}
+
+// refuseUntraced closes the request body, as a RoundTripper must, and
+// returns the error for a request that is not sent because it was not recorded
+func refuseUntraced(req *http.Request, cause error) error {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return fmt.Errorf("%w: %v", ErrTracingRequired, cause)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRequireTracing(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-require-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// A regular file where the output directory should be cannot hold sessions
+	unwritable := filepath.Join(tempDir, "not-a-dir")
+	if err := os.WriteFile(unwritable, []byte("file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var served int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		name    string
+		require bool
+	}{
+		{"required", true},
+		{"best effort", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			served = 0
+			config := newTestConfig(unwritable)
+			config.RequireTracing = tt.require
+
+			client := NewTracingHTTPClientWithConfig("test-require", config)
+			resp, err := client.Get(server.URL)
+
+			if tt.require {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("Expected the request to fail without an audit trail")
+				}
+				if !errors.Is(err, ErrTracingRequired) {
+					t.Errorf("Expected ErrTracingRequired, got %v", err)
+				}
+				if served != 0 {
+					t.Error("Expected the request not to reach the server")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected the request to proceed untraced, got %v", err)
+			}
+			resp.Body.Close()
+			if served != 1 {
+				t.Errorf("Expected the request to reach the server once, got %d", served)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"regexp"
+)
+
+// retryableBody reports whether the response body matches one of
+// RetryOnBodyPatterns. The body is buffered to inspect it and put back for
+// the caller. Only bodies the tracer already captured, or that declare a
+// Content-Length within MaxBodySize, are inspected, so streamed and large
+// responses are never held back.
+func (t *TracingHTTPClient) retryableBody(resp *http.Response) bool {
+	if len(t.config.RetryOnBodyPatterns) == 0 || resp.Body == nil || resp.Body == http.NoBody {
+		return false
+	}
+	if t.config.isUncapturedStream(resp.Header.Get("Content-Type")) {
+		return false
+	}
+
+	captured := t.config.Enabled && t.config.captureResponseBody(requestURL(resp), resp.StatusCode)
+	if !captured && (resp.ContentLength < 0 || resp.ContentLength > t.config.MaxBodySize) {
+		return false
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body = &restoredBody{
+		Reader: io.MultiReader(bytes.NewReader(body), resp.Body),
+		Closer: resp.Body,
+	}
+	if err != nil {
+		return false
+	}
+
+	for _, pattern := range t.config.RetryOnBodyPatterns {
+		// Invalid patterns are reported by Validate
+		if re, err := regexp.Compile(pattern); err == nil && re.Match(body) {
+			return true
+		}
+	}
+	return false
+}
+
+// restoredBody replays buffered bytes while keeping the original body's
+// Close, which may release the request's context
+type restoredBody struct {
+	io.Reader
+	io.Closer
+}
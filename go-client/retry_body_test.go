@@ -0,0 +1,91 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryOnBodyPattern(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) == 1 {
+			w.Write([]byte(`{"error": "rate_limited"}`))
+			return
+		}
+		w.Write([]byte(`{"result": "ok"}`))
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-retry-body-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.MaxRetries = 2
+	config.RetryOnBodyPatterns = []string{`"error":\s*"rate_limited"`}
+	client := NewTracingHTTPClientWithConfig("test-retry-body", config)
+	client.backoff = func(int) time.Duration { return 0 }
+	defer client.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := client.DoWithRetry(req)
+	if err != nil {
+		t.Fatalf("DoWithRetry failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != `{"result": "ok"}` {
+		t.Errorf("Expected the successful body, got %q", body)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected one retry, got %d calls", calls.Load())
+	}
+}
+
+func TestRetryOnBodyPatternRestoresBody(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"result": "fine"}`))
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-retry-body-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Bodies are not captured, so only the Content-Length bound lets the body be inspected
+	config := newTestConfig(tempDir)
+	config.CaptureResponseBodies = false
+	config.MaxRetries = 2
+	config.RetryOnBodyPatterns = []string{`rate_limited`}
+	client := NewTracingHTTPClientWithConfig("test-retry-body-restore", config)
+	client.backoff = func(int) time.Duration { return 0 }
+	defer client.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := client.DoWithRetry(req)
+	if err != nil {
+		t.Fatalf("DoWithRetry failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != `{"result": "fine"}` {
+		t.Errorf("Expected the inspected body to be restored, got %q", body)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected no retry, got %d calls", calls.Load())
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// retryBudget is a token bucket capping the retries DoWithRetry makes across
+// a session. It holds up to capacity tokens, one spent per retry, and with a
+// window refills at capacity tokens per window; without one it never refills.
+type retryBudget struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	window   time.Duration
+	last     time.Time
+	now      func() time.Time
+
+	// Set once a retry has been refused, until a token is spent again
+	empty bool
+}
+
+// newRetryBudget returns a full budget, or nil, which allows every retry,
+// when capacity is not positive
+func newRetryBudget(capacity int, window time.Duration) *retryBudget {
+	if capacity <= 0 {
+		return nil
+	}
+	return &retryBudget{
+		capacity: float64(capacity),
+		tokens:   float64(capacity),
+		window:   window,
+		last:     time.Now(),
+		now:      time.Now,
+	}
+}
+
+// take spends a token for one retry. It reports whether the retry may go
+// ahead and, when it may not, whether this is the first refusal since the
+// budget ran out.
+func (b *retryBudget) take() (allowed, firstRefusal bool) {
+	if b == nil {
+		return true, false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if b.window > 0 {
+		elapsed := now.Sub(b.last)
+		b.tokens += b.capacity * float64(elapsed) / float64(b.window)
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		b.empty = false
+		return true, false
+	}
+
+	firstRefusal = !b.empty
+	b.empty = true
+	return false, firstRefusal
+}
+
+// LogRetryBudgetExhausted records that DoWithRetry stopped retrying a request
+// because the session's RetryBudget was spent. It is logged when the budget
+// first runs out, not for every retry refused afterwards.
+func (l *Logger) LogRetryBudgetExhausted(retryID string, req *http.Request, resp *http.Response, err error) error {
+	if !l.config.Enabled {
+		return nil
+	}
+
+	event := RetryBudgetExhaustedEvent{
+		Type:      "retry_budget_exhausted",
+		Timestamp: time.Now().UnixMilli(),
+		SessionID: l.sessionID,
+		RetryID:   retryID,
+		Method:    req.Method,
+		URL:       l.redactURL(req.URL.String()),
+		Budget:    l.config.RetryBudget,
+		WindowMs:  l.config.RetryBudgetWindow.Milliseconds(),
+	}
+	if resp != nil {
+		event.StatusCode = resp.StatusCode
+	}
+	if err != nil {
+		event.Error = l.redactError(err)
+	}
+
+	return l.writeEvent(event)
+}
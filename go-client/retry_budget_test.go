@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBudgetStopsRetries(t *testing.T) {
+	var hits atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-retry-budget-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.MaxRetries = 3
+	config.RetryBudget = 5
+	client := NewTracingHTTPClientWithConfig("test-retry-budget", config)
+	client.backoff = func(int) time.Duration { return 0 }
+
+	const requests = 10
+	for i := 0; i < requests; i++ {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/failing", nil)
+		resp, err := client.DoWithRetry(req)
+		if err != nil {
+			t.Fatalf("DoWithRetry failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+	client.Close()
+
+	// One attempt per request plus the 5 retries the budget allows
+	if got := hits.Load(); got != requests+int64(config.RetryBudget) {
+		t.Errorf("Expected %d attempts, got %d", requests+config.RetryBudget, got)
+	}
+
+	events := readSessionEvents(t, tempDir)
+	exhausted := eventsOfType(events, "retry_budget_exhausted")
+	if len(exhausted) != 1 {
+		t.Fatalf("Expected one retry_budget_exhausted event, got %d", len(exhausted))
+	}
+	if exhausted[0]["budget"] != float64(5) || exhausted[0]["status_code"] != float64(http.StatusServiceUnavailable) {
+		t.Errorf("Unexpected retry_budget_exhausted event: %v", exhausted[0])
+	}
+}
+
+func TestRetryBudgetRefills(t *testing.T) {
+	budget := newRetryBudget(2, time.Minute)
+	now := time.Now()
+	budget.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if allowed, _ := budget.take(); !allowed {
+			t.Fatalf("Retry %d: expected the budget to allow it", i)
+		}
+	}
+	if allowed, first := budget.take(); allowed || !first {
+		t.Errorf("Expected the first refusal once spent, got allowed %v, first %v", allowed, first)
+	}
+	if allowed, first := budget.take(); allowed || first {
+		t.Errorf("Expected a later refusal not to be reported as first, got allowed %v, first %v", allowed, first)
+	}
+
+	// Two retries per minute: one token after 30 seconds
+	now = now.Add(30 * time.Second)
+	if allowed, _ := budget.take(); !allowed {
+		t.Error("Expected a retry allowed after the budget refilled")
+	}
+	if allowed, first := budget.take(); allowed || !first {
+		t.Errorf("Expected the budget to run out again, got allowed %v, first %v", allowed, first)
+	}
+}
+
+func TestNoRetryBudgetAllowsEveryRetry(t *testing.T) {
+	var budget *retryBudget
+	if allowed, _ := budget.take(); !allowed {
+		t.Error("Expected a nil budget to allow retries")
+	}
+	if newRetryBudget(0, 0) != nil {
+		t.Error("Expected no budget when RetryBudget is 0")
+	}
+}
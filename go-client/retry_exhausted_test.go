@@ -0,0 +1,129 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRetriesExhaustedEvent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-retries-exhausted-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.MaxRetries = 2
+	client := NewTracingHTTPClientWithConfig("test-retries-exhausted", config)
+	client.backoff = func(int) time.Duration { return 0 }
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/flaky", nil)
+	resp, err := client.DoWithRetry(req)
+	if err != nil {
+		t.Fatalf("DoWithRetry failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	events := readSessionEvents(t, tempDir)
+	requests := eventsOfType(events, "http_request")
+	responses := eventsOfType(events, "http_response")
+	exhausted := eventsOfType(events, "retries_exhausted")
+
+	attempts := config.MaxRetries + 1
+	if len(requests) != attempts || len(responses) != attempts {
+		t.Fatalf("Expected %d request and response events, got %d and %d", attempts, len(requests), len(responses))
+	}
+	if len(exhausted) != 1 {
+		t.Fatalf("Expected one retries_exhausted event, got %d", len(exhausted))
+	}
+
+	retryID := exhausted[0]["retry_id"]
+	if retryID == nil || retryID == "" {
+		t.Fatal("Expected a retry_id on the exhaustion event")
+	}
+	for i := 0; i < attempts; i++ {
+		for _, event := range []map[string]interface{}{requests[i], responses[i]} {
+			if event["retry_id"] != retryID {
+				t.Errorf("%s %d: expected retry_id %v, got %v", event["type"], i, retryID, event["retry_id"])
+			}
+			if event["retry_attempt"] != float64(i) {
+				t.Errorf("%s %d: expected retry_attempt %d, got %v", event["type"], i, i, event["retry_attempt"])
+			}
+		}
+	}
+
+	if exhausted[0]["attempts"] != float64(attempts) {
+		t.Errorf("Expected %d attempts, got %v", attempts, exhausted[0]["attempts"])
+	}
+	if exhausted[0]["final_status_code"] != float64(http.StatusInternalServerError) {
+		t.Errorf("Expected final status 500, got %v", exhausted[0]["final_status_code"])
+	}
+	if exhausted[0]["url"] != server.URL+"/flaky" || exhausted[0]["method"] != http.MethodGet {
+		t.Errorf("Unexpected request in exhaustion event: %v %v", exhausted[0]["method"], exhausted[0]["url"])
+	}
+}
+
+func TestRetriesExhaustedOnTransportError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	deadURL := server.URL
+	server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-retries-exhausted-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.MaxRetries = 1
+	client := NewTracingHTTPClientWithConfig("test-retries-exhausted-error", config)
+	client.backoff = func(int) time.Duration { return 0 }
+
+	req, _ := http.NewRequest(http.MethodGet, deadURL, nil)
+	if resp, err := client.DoWithRetry(req); err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected DoWithRetry to fail")
+	}
+	client.Close()
+
+	exhausted := eventsOfType(readSessionEvents(t, tempDir), "retries_exhausted")
+	if len(exhausted) != 1 {
+		t.Fatalf("Expected one retries_exhausted event, got %d", len(exhausted))
+	}
+	if exhausted[0]["attempts"] != float64(2) || exhausted[0]["final_error"] == nil {
+		t.Errorf("Expected 2 attempts and the final error, got %v", exhausted[0])
+	}
+}
+
+func TestNoRetriesExhaustedOnSuccess(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-retries-exhausted-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	client := NewTracingHTTPClientWithConfig("test-retries-success", newTestConfig(tempDir))
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := client.DoWithRetry(req)
+	if err != nil {
+		t.Fatalf("DoWithRetry failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	if exhausted := eventsOfType(readSessionEvents(t, tempDir), "retries_exhausted"); len(exhausted) != 0 {
+		t.Errorf("Expected no retries_exhausted event, got %v", exhausted)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Retry logging modes
+const (
+	// RetryLoggingFlat logs every attempt as ordinary request/response events
+	RetryLoggingFlat = "flat"
+	// RetryLoggingNested logs one http_request_with_retries event per DoWithRetry call
+	RetryLoggingNested = "nested"
+)
+
+// retryCollectorKey is the context key carrying a retryCollector
+type retryCollectorKey struct{}
+
+// withRetryCollector returns a context whose requests report to the collector
+func withRetryCollector(ctx context.Context, collector *retryCollector) context.Context {
+	return context.WithValue(ctx, retryCollectorKey{}, collector)
+}
+
+// retryCollectorFromContext returns the collector for a request, if any
+func retryCollectorFromContext(ctx context.Context) *retryCollector {
+	collector, _ := ctx.Value(retryCollectorKey{}).(*retryCollector)
+	return collector
+}
+
+// retryAttemptKey is the context key carrying a retryAttemptInfo
+type retryAttemptKey struct{}
+
+// retryAttemptInfo identifies one attempt of a DoWithRetry call
+type retryAttemptInfo struct {
+	retryID string
+	attempt int
+}
+
+// withRetryAttempt returns a context whose request is the given attempt
+func withRetryAttempt(ctx context.Context, retryID string, attempt int) context.Context {
+	return context.WithValue(ctx, retryAttemptKey{}, &retryAttemptInfo{retryID: retryID, attempt: attempt})
+}
+
+// retryAttemptFromContext returns the attempt a request belongs to, if any
+func retryAttemptFromContext(ctx context.Context) *retryAttemptInfo {
+	info, _ := ctx.Value(retryAttemptKey{}).(*retryAttemptInfo)
+	return info
+}
+
+// eventFields returns the retry_id and retry_attempt of an event, empty
+// for requests not made by DoWithRetry
+func (i *retryAttemptInfo) eventFields() (string, *int) {
+	if i == nil {
+		return "", nil
+	}
+	attempt := i.attempt
+	return i.retryID, &attempt
+}
+
+// retryAttemptRecord holds the captures for one attempt
+type retryAttemptRecord struct {
+	attempt  int
+	backoff  time.Duration
+	request  *RequestCapture
+	response *ResponseCapture
+	err      error
+	duration time.Duration
+}
+
+// retryCollector gathers the attempts of a DoWithRetry call
+type retryCollector struct {
+	mu       sync.Mutex
+	attempt  int
+	backoff  time.Duration
+	attempts []retryAttemptRecord
+}
+
+// startAttempt marks the beginning of a new attempt
+func (c *retryCollector) startAttempt(attempt int, backoff time.Duration) {
+	c.mu.Lock()
+	c.attempt = attempt
+	c.backoff = backoff
+	c.mu.Unlock()
+}
+
+// record stores the outcome of a round trip for the current attempt
+func (c *retryCollector) record(request *RequestCapture, response *ResponseCapture, err error, duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.attempts = append(c.attempts, retryAttemptRecord{
+		attempt:  c.attempt,
+		backoff:  c.backoff,
+		request:  request,
+		response: response,
+		err:      err,
+		duration: duration,
+	})
+}
+
+// roundTripCollected executes a retry attempt and hands the captures to the
+// collector instead of logging them
+func (t *TracingRoundTripper) roundTripCollected(req *http.Request, collector *retryCollector) (*http.Response, error) {
+	var requestCapture *RequestCapture
+	func() {
+		defer t.recoverCapturePanic("", "request capture")
+		if capture, captureErr := t.captureRequest(req); captureErr == nil {
+			requestCapture = capture
+		}
+	}()
+
+	t.logger.requestStarted()
+	startTime := time.Now()
+	resp, err := t.wrapped.RoundTrip(req)
+	endTime := time.Now()
+	duration := endTime.Sub(startTime)
+	t.logger.requestFinished(responseStatus(resp), err, duration)
+
+	t.observeRaw("", req, resp)
+
+	var responseCapture *ResponseCapture
+	if resp != nil {
+		func() {
+			defer t.recoverCapturePanic("", "response capture")
+			if capture, captureErr := t.captureResponse(resp, endTime, duration, err == nil); captureErr == nil {
+				responseCapture = capture
+			}
+		}()
+	}
+
+	collector.record(requestCapture, responseCapture, err, duration)
+	return resp, err
+}
+
+// LogRetriesExhausted records that DoWithRetry gave up after attempts
+// failed attempts, with the status or error of the last one
+func (l *Logger) LogRetriesExhausted(retryID string, req *http.Request, attempts int, resp *http.Response, err error) error {
+	if !l.config.Enabled {
+		return nil
+	}
+
+	event := RetriesExhaustedEvent{
+		Type:      "retries_exhausted",
+		Timestamp: time.Now().UnixMilli(),
+		SessionID: l.sessionID,
+		RetryID:   retryID,
+		Method:    req.Method,
+		URL:       l.redactURL(req.URL.String()),
+		Attempts:  attempts,
+	}
+	if resp != nil {
+		event.FinalStatusCode = resp.StatusCode
+	}
+	if err != nil {
+		event.FinalError = l.redactError(err)
+	}
+
+	return l.writeEvent(event)
+}
+
+// LogHTTPRequestWithRetries logs the attempts gathered by a collector as one
+// event. Redaction and body rules apply to the final attempt's request and response.
+func (l *Logger) LogHTTPRequestWithRetries(collector *retryCollector, finalErr error) error {
+	if !l.config.Enabled {
+		return nil
+	}
+
+	collector.mu.Lock()
+	records := append([]retryAttemptRecord(nil), collector.attempts...)
+	collector.mu.Unlock()
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	event := HTTPRequestWithRetriesEvent{
+		Type:      "http_request_with_retries",
+		SessionID: l.sessionID,
+		RequestID: uuid.New().String(),
+		Attempts:  make([]RetryAttempt, 0, len(records)),
+		Outcome:   "failure",
+	}
+
+	var totalDuration time.Duration
+	for _, record := range records {
+		attempt := RetryAttempt{
+			Attempt:  record.attempt,
+			Duration: record.duration.Milliseconds(),
+			Backoff:  record.backoff.Milliseconds(),
+		}
+		if record.response != nil {
+			attempt.StatusCode = record.response.StatusCode
+		}
+		if record.err != nil {
+			attempt.Error = l.redactError(record.err)
+		}
+		event.Attempts = append(event.Attempts, attempt)
+		totalDuration += record.backoff + record.duration
+	}
+	event.TotalDuration = totalDuration.Milliseconds()
+
+	first, final := records[0], records[len(records)-1]
+	if first.request != nil {
+		event.Timestamp = first.request.StartTime.UnixMilli()
+		event.Method = first.request.Method
+		event.URL = l.redactURL(first.request.URL)
+	}
+
+	if final.request != nil {
+		finalRequest := l.requestEvent(final.request)
+		event.FinalRequest = &finalRequest
+		l.observeFingerprint(finalRequest.Fingerprint)
+	}
+	if final.response != nil {
+		finalResponse := l.responseEvent(final.response)
+		event.FinalResponse = &finalResponse
+		l.observeRateLimit(finalResponse.RateLimit)
+		if finalErr == nil && final.response.Success {
+			event.Outcome = "success"
+		}
+	}
+	if finalErr != nil {
+		event.FinalError = l.redactError(finalErr)
+	}
+
+	return l.writeEvent(event)
+}
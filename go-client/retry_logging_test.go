@@ -0,0 +1,186 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNestedRetryLogging(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-retry-nested-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"prompt": "hi"}` {
+			t.Errorf("Attempt %d: expected request body to be replayed, got %q", attempts+1, body)
+		}
+
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": "overloaded"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"answer": 42}`))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.MaxRetries = 3
+	config.RetryLoggingMode = RetryLoggingNested
+
+	client := NewTracingHTTPClientWithConfig("test-retry-nested", config)
+	client.backoff = func(attempt int) time.Duration {
+		return time.Duration(attempt) * 10 * time.Millisecond
+	}
+	defer client.Close()
+
+	req, err := http.NewRequest("POST", server.URL+"/v1/complete", strings.NewReader(`{"prompt": "hi"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret-token")
+
+	resp, err := client.DoWithRetry(req)
+	if err != nil {
+		t.Fatalf("DoWithRetry failed: %v", err)
+	}
+	resp.Body.Close()
+
+	events := readSessionEvents(t, tempDir)
+	if len(events) != 1 {
+		t.Fatalf("Expected a single consolidated event, got %d: %v", len(events), events)
+	}
+
+	event := events[0]
+	if event["type"] != "http_request_with_retries" {
+		t.Fatalf("Expected http_request_with_retries, got %v", event["type"])
+	}
+	if event["outcome"] != "success" || event["method"] != "POST" {
+		t.Errorf("Unexpected outcome/method: %v %v", event["outcome"], event["method"])
+	}
+
+	attemptList, _ := event["attempts"].([]interface{})
+	if len(attemptList) != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", len(attemptList))
+	}
+	expectedStatus := []float64{503, 503, 200}
+	expectedBackoff := []float64{0, 10, 20}
+	for i, raw := range attemptList {
+		attempt := raw.(map[string]interface{})
+		if attempt["attempt"] != float64(i) {
+			t.Errorf("Attempt %d: unexpected attempt number %v", i, attempt["attempt"])
+		}
+		if attempt["status_code"] != expectedStatus[i] {
+			t.Errorf("Attempt %d: expected status %v, got %v", i, expectedStatus[i], attempt["status_code"])
+		}
+		if attempt["backoff_ms"] != expectedBackoff[i] {
+			t.Errorf("Attempt %d: expected backoff %v, got %v", i, expectedBackoff[i], attempt["backoff_ms"])
+		}
+		if _, ok := attempt["duration_ms"]; !ok {
+			t.Errorf("Attempt %d: missing duration_ms", i)
+		}
+	}
+
+	finalRequest, _ := event["final_request"].(map[string]interface{})
+	headers, _ := finalRequest["headers"].(map[string]interface{})
+	if headers["Authorization"] != "[REDACTED]" {
+		t.Errorf("Expected final request Authorization to be redacted, got %v", headers["Authorization"])
+	}
+	if finalRequest["body"] != `{"prompt": "hi"}` {
+		t.Errorf("Expected final request body, got %v", finalRequest["body"])
+	}
+
+	finalResponse, _ := event["final_response"].(map[string]interface{})
+	if finalResponse["status_code"] != float64(200) || finalResponse["body"] != `{"answer": 42}` {
+		t.Errorf("Unexpected final response: %v", finalResponse)
+	}
+}
+
+func TestFlatRetryLoggingIsDefault(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-retry-flat-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.MaxRetries = 2
+
+	client := NewTracingHTTPClientWithConfig("test-retry-flat", config)
+	client.backoff = func(int) time.Duration { return 0 }
+	defer client.Close()
+
+	req, _ := http.NewRequest("GET", server.URL+"/test", nil)
+	resp, err := client.DoWithRetry(req)
+	if err != nil {
+		t.Fatalf("DoWithRetry failed: %v", err)
+	}
+	resp.Body.Close()
+
+	events := readSessionEvents(t, tempDir)
+	if got := len(eventsOfType(events, "http_request")); got != 2 {
+		t.Errorf("Expected 2 flat request events, got %d", got)
+	}
+	if got := len(eventsOfType(events, "http_request_with_retries")); got != 0 {
+		t.Errorf("Expected no nested events in flat mode, got %d", got)
+	}
+}
+
+func TestNestedRetryErrorsRedacted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	deadURL := server.URL
+	server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-retry-logging-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.RetryLoggingMode = RetryLoggingNested
+	config.PathMaskPatterns = []string{"@"}
+	config.MaxRetries = 1
+	client := NewTracingHTTPClientWithConfig("test-retry-errors", config)
+	client.backoff = func(int) time.Duration { return 0 }
+
+	req, _ := http.NewRequest(http.MethodGet, deadURL+"/users/alice@example.com", nil)
+	if resp, err := client.DoWithRetry(req); err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected DoWithRetry to fail")
+	}
+	client.Close()
+
+	data, err := os.ReadFile(findSessionFile(t, tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "alice@example.com") {
+		t.Errorf("Expected attempt and final errors to be redacted:\n%s", data)
+	}
+	if !strings.Contains(string(data), `"final_error"`) {
+		t.Error("Expected the nested event to record the final error")
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rttRecorder estimates the network round-trip time of a request that opened
+// a new connection. The TCP handshake takes one round trip, so the time from
+// ConnectStart to ConnectDone is the main estimate. When the server reports
+// its processing time in Server-Timing, the wait between writing the request
+// and the first response byte, less that processing, is a second estimate;
+// both can only overstate the RTT, so the smaller is kept.
+type rttRecorder struct {
+	mu          sync.Mutex
+	dialStarts  map[string]time.Time
+	connect     time.Duration
+	connected   bool
+	wroteAt     time.Time
+	firstByteAt time.Time
+}
+
+// withRTTTrace attaches hooks timing the connection handshake and the
+// request's wait for its first response byte
+func withRTTTrace(req *http.Request) (*http.Request, *rttRecorder) {
+	recorder := &rttRecorder{dialStarts: make(map[string]time.Time)}
+
+	trace := &httptrace.ClientTrace{
+		ConnectStart: func(network, addr string) {
+			recorder.mu.Lock()
+			recorder.dialStarts[addr] = time.Now()
+			recorder.mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			now := time.Now()
+			recorder.mu.Lock()
+			defer recorder.mu.Unlock()
+
+			// With several addresses dialed in parallel, the first to connect is used
+			start, ok := recorder.dialStarts[addr]
+			if err != nil || !ok || recorder.connected {
+				return
+			}
+			recorder.connect = now.Sub(start)
+			recorder.connected = true
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			recorder.mu.Lock()
+			recorder.wroteAt = time.Now()
+			recorder.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			recorder.mu.Lock()
+			recorder.firstByteAt = time.Now()
+			recorder.mu.Unlock()
+		},
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), recorder
+}
+
+// estimate returns the RTT estimate in milliseconds, nil when the request
+// reused a connection
+func (r *rttRecorder) estimate(header http.Header) *int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.connected {
+		return nil
+	}
+
+	rtt := r.connect
+	if processing, ok := serverProcessingTime(header); ok && !r.wroteAt.IsZero() && r.firstByteAt.After(r.wroteAt) {
+		if wait := r.firstByteAt.Sub(r.wroteAt) - processing; wait > 0 && wait < rtt {
+			rtt = wait
+		}
+	}
+
+	ms := rtt.Milliseconds()
+	return &ms
+}
+
+// serverProcessingTime returns the longest dur in a Server-Timing header.
+// Metrics may overlap, such as a total and its parts, so they are not summed.
+func serverProcessingTime(header http.Header) (time.Duration, bool) {
+	var longest float64
+	found := false
+	for _, value := range header.Values("Server-Timing") {
+		for _, metric := range strings.Split(value, ",") {
+			for _, param := range strings.Split(metric, ";")[1:] {
+				name, raw, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(name), "dur") {
+					continue
+				}
+				dur, err := strconv.ParseFloat(strings.Trim(strings.TrimSpace(raw), `"`), 64)
+				if err != nil || dur < 0 {
+					continue
+				}
+				if dur > longest {
+					longest = dur
+				}
+				found = true
+			}
+		}
+	}
+	return time.Duration(longest * float64(time.Millisecond)), found
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestEstimatedRTTRecorded(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-rtt-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.EnableHTTPTrace = true
+	client := NewTracingHTTPClientWithConfig("test-rtt", config)
+
+	// The second request reuses the keep-alive connection
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+	client.Close()
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 2 {
+		t.Fatalf("Expected two response events, got %d", len(responses))
+	}
+
+	rtt, ok := responses[0]["estimated_rtt_ms"].(float64)
+	if !ok {
+		t.Fatalf("Expected estimated_rtt_ms on a new connection, got %v", responses[0]["estimated_rtt_ms"])
+	}
+	if rtt < 0 || rtt > 100 {
+		t.Errorf("Expected a small loopback RTT, got %vms", rtt)
+	}
+	if _, ok := responses[1]["estimated_rtt_ms"]; ok {
+		t.Errorf("Expected no estimated_rtt_ms on a reused connection, got %v", responses[1]["estimated_rtt_ms"])
+	}
+}
+
+func TestEstimatedRTTNotRecordedWithoutHTTPTrace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-rtt-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	client := NewTracingHTTPClientWithConfig("test-rtt-off", newTestConfig(tempDir))
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 1 {
+		t.Fatalf("Expected one response event, got %d", len(responses))
+	}
+	if _, ok := responses[0]["estimated_rtt_ms"]; ok {
+		t.Error("Expected no estimated_rtt_ms without EnableHTTPTrace")
+	}
+}
+
+func TestServerProcessingTime(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected time.Duration
+		found    bool
+	}{
+		{`db;dur=53.2, app;dur=47.2`, 53200 * time.Microsecond, true},
+		{`total;dur=120;desc="Total", cache;desc="Cache Read"`, 120 * time.Millisecond, true},
+		{`miss, edge;desc=hit`, 0, false},
+		{`bad;dur=abc`, 0, false},
+	}
+
+	for _, tt := range tests {
+		header := http.Header{}
+		header.Set("Server-Timing", tt.header)
+		got, found := serverProcessingTime(header)
+		if got != tt.expected || found != tt.found {
+			t.Errorf("serverProcessingTime(%q) = %v, %v; expected %v, %v", tt.header, got, found, tt.expected, tt.found)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"sync"
+)
+
+// maxPendingRedirects bounds the sampling decisions kept for redirect chains
+// of requests not sent by TracingHTTPClient; redirects that are never followed
+// would otherwise keep theirs forever
+const maxPendingRedirects = 1024
+
+// samplingKey is the context key carrying an operation's sampling decision
+type samplingKey struct{}
+
+// withSamplingDecision returns a context whose requests share the decision
+func withSamplingDecision(ctx context.Context, sampled bool) context.Context {
+	return context.WithValue(ctx, samplingKey{}, sampled)
+}
+
+// samplingDecisionFromContext returns the decision made for the operation a
+// request belongs to, if one was made
+func samplingDecisionFromContext(ctx context.Context) (sampled bool, ok bool) {
+	sampled, ok = ctx.Value(samplingKey{}).(bool)
+	return sampled, ok
+}
+
+// operationSampled reports whether the operation of a request is traced.
+// Requests of no sampled operation are traced.
+func operationSampled(ctx context.Context) bool {
+	sampled, ok := samplingDecisionFromContext(ctx)
+	return !ok || sampled
+}
+
+// sampling reports whether SampleRate leaves some operations untraced
+func (c *TracingConfig) sampling() bool {
+	return c.SampleRate > 0 && c.SampleRate < 1
+}
+
+// drawSample decides whether a new operation is traced
+func (c *TracingConfig) drawSample() bool {
+	return !c.sampling() || rand.Float64() < c.SampleRate
+}
+
+// withSampling makes the sampling decision for the operation a request starts.
+// Its retries and redirects reuse the request's context, and so the decision.
+func (t *TracingHTTPClient) withSampling(req *http.Request) *http.Request {
+	if !t.config.Enabled || !t.config.sampling() {
+		return req
+	}
+	if _, ok := samplingDecisionFromContext(req.Context()); ok {
+		return req
+	}
+	return req.WithContext(withSamplingDecision(req.Context(), t.config.drawSample()))
+}
+
+// redirectSampling keeps the decision of redirect chains followed by clients
+// other than TracingHTTPClient, keyed by the redirect response. The client
+// sends the next hop with that response as its Response.
+type redirectSampling struct {
+	mu      sync.Mutex
+	pending map[*http.Response]bool
+}
+
+// take returns and forgets the decision of the chain resp redirected
+func (r *redirectSampling) take(resp *http.Response) (sampled bool, ok bool) {
+	if resp == nil {
+		return false, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sampled, ok = r.pending[resp]
+	delete(r.pending, resp)
+	return sampled, ok
+}
+
+// remember keeps the decision for the hop that follows resp, if it redirects
+func (r *redirectSampling) remember(resp *http.Response, sampled bool) {
+	if !isRedirect(resp) {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending == nil || len(r.pending) >= maxPendingRedirects {
+		r.pending = make(map[*http.Response]bool)
+	}
+	r.pending[resp] = sampled
+}
+
+// isRedirect reports whether an http.Client would follow resp
+func isRedirect(resp *http.Response) bool {
+	if resp == nil || resp.Header.Get("Location") == "" {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// roundTripSampled decides on a request that carries no sampling decision,
+// one sent by a client other than TracingHTTPClient. A redirect inherits the
+// decision of the request that started its chain.
+func (t *TracingRoundTripper) roundTripSampled(req *http.Request) (*http.Response, error) {
+	sampled, ok := t.redirects.take(req.Response)
+	if !ok {
+		sampled = t.config.drawSample()
+	}
+
+	resp, err := t.RoundTrip(req.WithContext(withSamplingDecision(req.Context(), sampled)))
+	if err == nil {
+		t.redirects.remember(resp, sampled)
+	}
+	return resp, err
+}
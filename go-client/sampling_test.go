@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newChainServer redirects /start to /target, which fails the first time
+// each operation, named by the op query parameter, reaches it
+func newChainServer() *httptest.Server {
+	var mu sync.Mutex
+	failed := make(map[string]bool)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op := r.URL.Query().Get("op")
+		if r.URL.Path == "/start" {
+			http.Redirect(w, r, "/target?op="+op, http.StatusFound)
+			return
+		}
+
+		mu.Lock()
+		first := !failed[op]
+		failed[op] = true
+		mu.Unlock()
+		if first {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+}
+
+// operationEventCounts counts request and response events by op parameter
+func operationEventCounts(t *testing.T, dir string) (map[string]int, map[string]int) {
+	t.Helper()
+
+	events := readSessionEvents(t, dir)
+	requests := make(map[string]int)
+	ops := make(map[string]string)
+	for _, event := range eventsOfType(events, "http_request") {
+		rawURL, _ := event["url"].(string)
+		parsed, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		op := parsed.Query().Get("op")
+		requestID, _ := event["request_id"].(string)
+		ops[requestID] = op
+		requests[op]++
+	}
+
+	responses := make(map[string]int)
+	for _, event := range eventsOfType(events, "http_response") {
+		requestID, _ := event["request_id"].(string)
+		op, ok := ops[requestID]
+		if !ok {
+			t.Errorf("Response %s has no request event", requestID)
+		}
+		responses[op]++
+	}
+	return requests, responses
+}
+
+func TestSamplingKeepsWholeOperations(t *testing.T) {
+	server := newChainServer()
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-sampling-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.SampleRate = 0.5
+	config.MaxRetries = 1
+	client := NewTracingHTTPClientWithConfig("test-sampling", config)
+	client.backoff = func(int) time.Duration { return 0 }
+
+	const operations = 40
+	for i := 0; i < operations; i++ {
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/start?op=%d", server.URL, i), nil)
+		resp, err := client.DoWithRetry(req)
+		if err != nil {
+			t.Fatalf("Operation %d failed: %v", i, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Operation %d: expected 200 after a retry, got %d", i, resp.StatusCode)
+		}
+	}
+	client.Close()
+
+	// Each operation is two attempts, each a redirect and its target
+	requests, responses := operationEventCounts(t, tempDir)
+	for op, count := range requests {
+		if count != 4 || responses[op] != 4 {
+			t.Errorf("Operation %s: expected all 4 requests and responses, got %d and %d", op, count, responses[op])
+		}
+	}
+	if len(requests) == 0 || len(requests) == operations {
+		t.Errorf("Expected some of %d operations to be sampled out, %d traced", operations, len(requests))
+	}
+}
+
+func TestSamplingFollowsRedirectsOfOtherClients(t *testing.T) {
+	server := newChainServer()
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-sampling-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.SampleRate = 0.5
+	logger := NewLogger(config, "test-sampling-transport")
+	httpClient := &http.Client{Transport: NewTracingTransport(http.DefaultTransport, logger, config, "test-sampling-transport")}
+
+	const operations = 40
+	for i := 0; i < operations; i++ {
+		resp, err := httpClient.Get(fmt.Sprintf("%s/start?op=%d", server.URL, i))
+		if err != nil {
+			t.Fatalf("Operation %d failed: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+	logger.Close()
+
+	// Without retries each operation is the redirect and its target
+	requests, responses := operationEventCounts(t, tempDir)
+	for op, count := range requests {
+		if count != 2 || responses[op] != 2 {
+			t.Errorf("Operation %s: expected both requests and responses, got %d and %d", op, count, responses[op])
+		}
+	}
+	if len(requests) == 0 || len(requests) == operations {
+		t.Errorf("Expected some of %d operations to be sampled out, %d traced", operations, len(requests))
+	}
+}
+
+func TestSampleRateValidation(t *testing.T) {
+	config := getDefaultConfig()
+	config.SampleRate = 1.5
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "sample_rate") {
+		t.Errorf("Expected an out-of-range sample_rate to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// secretResponseFields are JSON keys whose values are credentials minted by
+// the server, as returned by OAuth token endpoints
+var secretResponseFields = []string{"access_token", "refresh_token", "id_token", "client_secret"}
+
+// minTrackedSecretLength keeps short values from being treated as secrets,
+// which would redact unrelated text that happens to contain them
+const minTrackedSecretLength = 8
+
+// redactSecretFields redacts the values of secretResponseFields anywhere in a
+// JSON body and returns the body with the secret values it removed. Bodies
+// that are not JSON or carry no such field are returned unchanged.
+func redactSecretFields(body []byte) ([]byte, []string) {
+	if !containsSecretField(body) {
+		return body, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return body, nil
+	}
+
+	secrets := collectSecretFields(document, nil)
+	if len(secrets) == 0 {
+		return body, nil
+	}
+
+	out, err := json.Marshal(document)
+	if err != nil {
+		return body, nil
+	}
+	return out, secrets
+}
+
+// fragmentSecretFields returns the string values of secret fields in a JSON
+// body that was cut short and cannot be decoded as a whole. Tokens are read
+// until the body ends; a value cut off at the end is not returned.
+func fragmentSecretFields(body []byte) []string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+
+	// One entry per open container: whether it is an object and the next
+	// token in it is a key
+	type container struct{ object, key bool }
+	var stack []container
+	var secrets []string
+	secretValue := false
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return secrets
+		}
+
+		if delim, ok := token.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				stack = append(stack, container{object: delim == '{', key: delim == '{'})
+				secretValue = false
+			case '}', ']':
+				if len(stack) > 0 {
+					stack = stack[:len(stack)-1]
+				}
+				if len(stack) > 0 && stack[len(stack)-1].object {
+					stack[len(stack)-1].key = true
+				}
+			}
+			continue
+		}
+
+		if len(stack) > 0 && stack[len(stack)-1].object && stack[len(stack)-1].key {
+			key, _ := token.(string)
+			secretValue = isSecretField(key)
+			stack[len(stack)-1].key = false
+			continue
+		}
+
+		if secret, ok := token.(string); ok && secretValue && secret != "" && secret != redactedValue {
+			secrets = append(secrets, secret)
+		}
+		secretValue = false
+		if len(stack) > 0 && stack[len(stack)-1].object {
+			stack[len(stack)-1].key = true
+		}
+	}
+}
+
+// containsSecretField is a cheap check that spares parsing bodies which
+// cannot contain any secret field
+func containsSecretField(body []byte) bool {
+	for _, field := range secretResponseFields {
+		if bytes.Contains(body, []byte(`"`+field+`"`)) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectSecretFields redacts secret fields below node in place and appends
+// their string values to secrets
+func collectSecretFields(node interface{}, secrets []string) []string {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if secret, ok := child.(string); ok && isSecretField(key) {
+				if secret != "" && secret != redactedValue {
+					secrets = append(secrets, secret)
+				}
+				value[key] = redactedValue
+				continue
+			}
+			secrets = collectSecretFields(child, secrets)
+		}
+	case []interface{}:
+		for _, child := range value {
+			secrets = collectSecretFields(child, secrets)
+		}
+	}
+	return secrets
+}
+
+// isSecretField reports whether a JSON key names a minted credential
+func isSecretField(key string) bool {
+	for _, field := range secretResponseFields {
+		if strings.EqualFold(key, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// trackSecrets adds values seen in responses to the runtime sensitive set
+func (l *Logger) trackSecrets(secrets []string) {
+	if len(secrets) == 0 {
+		return
+	}
+
+	l.secretsMu.Lock()
+	defer l.secretsMu.Unlock()
+
+	for _, secret := range secrets {
+		if len(secret) < minTrackedSecretLength || containsString(l.secrets, secret) {
+			continue
+		}
+		l.secrets = append(l.secrets, secret)
+	}
+}
+
+// redactTrackedSecrets replaces every occurrence of a tracked secret in s
+func (l *Logger) redactTrackedSecrets(s string) string {
+	l.secretsMu.RLock()
+	defer l.secretsMu.RUnlock()
+
+	for _, secret := range l.secrets {
+		if strings.Contains(s, secret) {
+			s = strings.ReplaceAll(s, secret, redactedValue)
+		}
+	}
+	return s
+}
+
+// redactTrackedSecretsInBody is redactTrackedSecrets for a captured body
+func (l *Logger) redactTrackedSecretsInBody(body []byte) []byte {
+	l.secretsMu.RLock()
+	defer l.secretsMu.RUnlock()
+
+	for _, secret := range l.secrets {
+		if bytes.Contains(body, []byte(secret)) {
+			body = bytes.ReplaceAll(body, []byte(secret), []byte(redactedValue))
+		}
+	}
+	return body
+}
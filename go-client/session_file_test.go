@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewLoggerWithFileResumesSession(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-session-file-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "named", "nightly.jsonl")
+	config := newTestConfig(tempDir)
+
+	for _, run := range []string{"first run", "second run"} {
+		logger := NewLoggerWithFile(config, "test-session-file", path)
+		if err := logger.LogError(errors.New(run), "resume"); err != nil {
+			t.Fatalf("Failed to log in %s: %v", run, err)
+		}
+		logger.Close()
+	}
+
+	events, err := ReadSessionFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read the named session file: %v", err)
+	}
+
+	var messages []string
+	for _, event := range eventsOfType(events, "error") {
+		details, _ := event["error"].(map[string]interface{})
+		messages = append(messages, details["message"].(string))
+	}
+	if len(messages) != 2 || messages[0] != "first run" || messages[1] != "second run" {
+		t.Errorf("Expected events from both runs in order, got %v", messages)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "sessions")); !os.IsNotExist(err) {
+		t.Errorf("Expected no timestamped session file, got %v", err)
+	}
+}
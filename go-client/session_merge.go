@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// mergeSource is one session stream taking part in a merge
+type mergeSource struct {
+	index   int
+	pending []string
+	counter int
+}
+
+// normalize reconciles events written by the TUI wrapper's injector with the
+// go-client schema. Injector events carry no request_id, so requests are
+// paired with their response or http_error in order within the source, and
+// http_error events become go-client error events.
+func (s *mergeSource) normalize(event map[string]interface{}) map[string]interface{} {
+	if eventString(event, "request_id") != "" {
+		return event
+	}
+
+	switch eventString(event, "type") {
+	case "http_request":
+		s.counter++
+		requestID := fmt.Sprintf("source-%d-%d", s.index, s.counter)
+		s.pending = append(s.pending, requestID)
+		event["request_id"] = requestID
+
+	case "http_response":
+		if len(s.pending) > 0 {
+			event["request_id"] = s.pending[0]
+			s.pending = s.pending[1:]
+		}
+
+	case "http_error":
+		requestID := ""
+		if len(s.pending) > 0 {
+			requestID = s.pending[0]
+			s.pending = s.pending[1:]
+		}
+		return map[string]interface{}{
+			"type":       "error",
+			"timestamp":  event["timestamp"],
+			"session_id": event["session_id"],
+			"request_id": requestID,
+			"error": map[string]interface{}{
+				"message": strings.TrimPrefix(eventString(event, "body"), "Error: "),
+				"context": "HTTP request failed",
+			},
+		}
+	}
+
+	return event
+}
+
+// mergeSessionFiles reads several session files and merges their events in
+// chronological order. Files are not strictly in timestamp order, since an
+// event is stamped when its request started or ended but written when it was
+// logged, so every event is buffered and sorted by timestamp. Events with
+// equal timestamps keep their file order, files taken in the order given.
+func mergeSessionFiles(paths []string) ([]map[string]interface{}, error) {
+	var events []map[string]interface{}
+	for i, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open session file: %w", err)
+		}
+
+		source := &mergeSource{index: i}
+		reader := NewSessionReader(file)
+		for {
+			event, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				file.Close()
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			// Injector events are paired in file order, before sorting
+			events = append(events, source.normalize(event))
+		}
+		file.Close()
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventInt64(events[i], "timestamp") < eventInt64(events[j], "timestamp")
+	})
+	return events, nil
+}
+
+// findSessionFiles returns the JSONL files that belong to a session directory:
+// every *.jsonl below it (such as the injector's session.jsonl) plus go-client
+// files named <timestamp>_session-<id>.jsonl next to it.
+func findSessionFiles(sessionDir string) ([]string, error) {
+	var paths []string
+
+	err := filepath.WalkDir(sessionDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".jsonl") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan session directory: %w", err)
+	}
+
+	sessionID := filepath.Base(filepath.Clean(sessionDir))
+	siblings, _ := filepath.Glob(filepath.Join(filepath.Dir(filepath.Clean(sessionDir)), "*_session-"+sessionID+".jsonl"))
+	paths = append(paths, siblings...)
+
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// SessionReader reads events from a session JSONL stream
+type SessionReader struct {
+	reader *bufio.Reader
+	line   int
+}
+
+// NewSessionReader creates a reader over a JSONL stream
+func NewSessionReader(r io.Reader) *SessionReader {
+	return &SessionReader{reader: bufio.NewReader(r)}
+}
+
+// Next returns the next event, or io.EOF when the stream is exhausted
+func (r *SessionReader) Next() (map[string]interface{}, error) {
+	for {
+		data, err := r.reader.ReadBytes('\n')
+		if len(data) == 0 && err != nil {
+			return nil, err
+		}
+		r.line++
+
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		var event map[string]interface{}
+		if jsonErr := json.Unmarshal(data, &event); jsonErr != nil {
+			return nil, fmt.Errorf("invalid event on line %d: %w", r.line, jsonErr)
+		}
+		// CloudEvents envelopes carry the event under data
+		event = unwrapCloudEvent(event)
+
+		// Compressed bodies are expanded transparently
+		if decodeErr := decodeEventBody(event); decodeErr != nil {
+			return nil, fmt.Errorf("invalid event on line %d: %w", r.line, decodeErr)
+		}
+		return event, nil
+	}
+}
+
+// ReadAll returns every remaining event
+func (r *SessionReader) ReadAll() ([]map[string]interface{}, error) {
+	var events []map[string]interface{}
+	for {
+		event, err := r.Next()
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+}
+
+// ReadSessionFile reads all events from a session file
+func ReadSessionFile(path string) ([]map[string]interface{}, error) {
+	return ReadSession(&FileSystemStore{}, path)
+}
+
+// Exchange groups the events that belong to a single request
+type Exchange struct {
+	RequestID string
+	Request   map[string]interface{}
+	Response  map[string]interface{}
+	Errors    []map[string]interface{}
+}
+
+// GroupExchanges pairs request, response and error events by request_id.
+// Events from older sessions without a request_id are paired in order of appearance.
+func GroupExchanges(events []map[string]interface{}) []*Exchange {
+	var exchanges []*Exchange
+	byID := make(map[string]*Exchange)
+
+	// oldestPending returns the first exchange still waiting for a response
+	oldestPending := func() *Exchange {
+		for _, exchange := range exchanges {
+			if exchange.RequestID == "" && exchange.Response == nil && len(exchange.Errors) == 0 {
+				return exchange
+			}
+		}
+		return nil
+	}
+
+	for _, event := range events {
+		requestID := eventString(event, "request_id")
+
+		switch eventString(event, "type") {
+		case "http_request":
+			exchange := &Exchange{RequestID: requestID, Request: event}
+			exchanges = append(exchanges, exchange)
+			if requestID != "" {
+				byID[requestID] = exchange
+			}
+
+		case "http_response":
+			if exchange := byID[requestID]; exchange != nil {
+				exchange.Response = event
+			} else if exchange := oldestPending(); requestID == "" && exchange != nil {
+				exchange.Response = event
+			}
+
+		case "error":
+			if exchange := byID[requestID]; exchange != nil {
+				exchange.Errors = append(exchange.Errors, event)
+			}
+		}
+	}
+
+	return exchanges
+}
+
+// eventString returns a string field of an event, or "" when absent
+func eventString(event map[string]interface{}, key string) string {
+	value, _ := event[key].(string)
+	return value
+}
+
+// eventInt64 returns a numeric field of an event, or 0 when absent
+func eventInt64(event map[string]interface{}, key string) int64 {
+	value, _ := event[key].(float64)
+	return int64(value)
+}
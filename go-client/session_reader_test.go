@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGroupExchangesPairsByRequestID(t *testing.T) {
+	input := `{"type":"http_request","request_id":"a","method":"GET"}
+{"type":"http_request","request_id":"b","method":"POST"}
+{"type":"http_response","request_id":"b","status_code":201}
+{"type":"error","request_id":"a","error":{"message":"boom"}}
+
+{"type":"http_response","request_id":"a","status_code":200}
+`
+	events, err := NewSessionReader(strings.NewReader(input)).ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	if len(events) != 5 {
+		t.Fatalf("Expected 5 events, got %d", len(events))
+	}
+
+	exchanges := GroupExchanges(events)
+	if len(exchanges) != 2 {
+		t.Fatalf("Expected 2 exchanges, got %d", len(exchanges))
+	}
+	if eventInt64(exchanges[0].Response, "status_code") != 200 || len(exchanges[0].Errors) != 1 {
+		t.Errorf("Unexpected first exchange: %+v", exchanges[0])
+	}
+	if eventInt64(exchanges[1].Response, "status_code") != 201 {
+		t.Errorf("Unexpected second exchange: %+v", exchanges[1])
+	}
+}
+
+func TestGroupExchangesLegacyOrder(t *testing.T) {
+	input := `{"type":"http_request","method":"GET"}
+{"type":"http_response","status_code":200}
+{"type":"http_request","method":"PUT"}
+{"type":"http_response","status_code":204}`
+
+	events, err := NewSessionReader(strings.NewReader(input)).ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+
+	exchanges := GroupExchanges(events)
+	if len(exchanges) != 2 {
+		t.Fatalf("Expected 2 exchanges, got %d", len(exchanges))
+	}
+	if eventString(exchanges[1].Request, "method") != "PUT" || eventInt64(exchanges[1].Response, "status_code") != 204 {
+		t.Errorf("Expected legacy events to pair in order, got %+v", exchanges[1])
+	}
+}
+
+func TestSessionReaderInvalidLine(t *testing.T) {
+	_, err := NewSessionReader(strings.NewReader("{\"type\":\"x\"}\nnot json\n")).ReadAll()
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected error pointing at line 2, got %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SessionStore is where a Logger writes sessions and where the export tools
+// read them from, so traces can be kept somewhere other than local disk.
+// A session is a named stream of JSONL lines, its summary included; the
+// Logger names sessions by the file path it would use on disk.
+// Implementations must be safe for concurrent use.
+type SessionStore interface {
+	// Append adds a complete line to the named session, creating it, and
+	// returns the offset the line was written at
+	Append(name string, line []byte) (int64, error)
+
+	// Open returns a reader over the named session
+	Open(name string) (io.ReadCloser, error)
+
+	// List returns the names of the stored sessions
+	List() ([]string, error)
+}
+
+// FileSystemStore keeps each session in a file, the default store. Relative
+// names are resolved against Dir, or the working directory when Dir is empty.
+type FileSystemStore struct {
+	Dir string
+
+	// Flush every line to stable storage before Append returns
+	Sync bool
+
+	// Flushes a file when Sync is set, os.File.Sync unless replaced in tests
+	syncFile func(*os.File) error
+
+	// Session paths whose directory has been created, so it is created once
+	createdDirs sync.Map
+}
+
+func (s *FileSystemStore) path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(s.Dir, name)
+}
+
+// Append appends line to the session file under an advisory lock, creating
+// missing directories on the first line of each session
+func (s *FileSystemStore) Append(name string, line []byte) (int64, error) {
+	path := s.path(name)
+	if _, created := s.createdDirs.Load(path); !created {
+		if err := os.MkdirAll(filepath.Dir(path), outputDirPerm); err != nil {
+			return 0, fmt.Errorf("failed to create session file directory: %w", err)
+		}
+		s.createdDirs.Store(path, struct{}{})
+	}
+	var syncFile func(*os.File) error
+	if s.Sync {
+		syncFile = s.syncFile
+		if syncFile == nil {
+			syncFile = (*os.File).Sync
+		}
+	}
+	offset, _, err := appendLineAt(path, line, syncFile)
+	return offset, err
+}
+
+// Open opens the session file
+func (s *FileSystemStore) Open(name string) (io.ReadCloser, error) {
+	return os.Open(s.path(name))
+}
+
+// List returns the .jsonl files below Dir, relative to it
+func (s *FileSystemStore) List() ([]string, error) {
+	root := s.Dir
+	if root == "" {
+		root = "."
+	}
+
+	var names []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && strings.HasSuffix(path, ".jsonl") {
+			name, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// MemoryStore keeps sessions in memory, for tests and for embedding
+// applications that ship traces elsewhere themselves
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]*bytes.Buffer
+}
+
+// NewMemoryStore creates an empty in-memory session store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]*bytes.Buffer)}
+}
+
+// Append appends a copy of line to the named session
+func (s *MemoryStore) Append(name string, line []byte) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[name]
+	if !ok {
+		session = &bytes.Buffer{}
+		s.sessions[name] = session
+	}
+	offset := int64(session.Len())
+	session.Write(line)
+	return offset, nil
+}
+
+// Open returns a reader over a snapshot of the named session
+func (s *MemoryStore) Open(name string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[name]
+	if !ok {
+		return nil, fmt.Errorf("session %q: %w", name, fs.ErrNotExist)
+	}
+	return io.NopCloser(bytes.NewReader(bytes.Clone(session.Bytes()))), nil
+}
+
+// List returns the names of the stored sessions in sorted order
+func (s *MemoryStore) List() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.sessions))
+	for name := range s.sessions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ReadSession reads all events of a session from store
+func ReadSession(store SessionStore, name string) ([]map[string]interface{}, error) {
+	session, err := store.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session file: %w", err)
+	}
+	defer session.Close()
+
+	return NewSessionReader(session).ReadAll()
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMemoryStoreSession(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	// OutputDir does not exist; nothing may be written to disk
+	outputDir := filepath.Join(os.TempDir(), "trace-memory-store-never-created")
+	store := NewMemoryStore()
+	config := newTestConfig(outputDir)
+	config.SessionStore = store
+
+	client := NewTracingHTTPClientWithConfig("test-memory-store", config)
+	resp, err := client.Get(server.URL + "/items")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	if _, err := os.Stat(outputDir); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected nothing written to disk, stat returned %v", err)
+	}
+
+	names, err := store.List()
+	if err != nil || len(names) != 1 {
+		t.Fatalf("Expected one stored session, got %v (%v)", names, err)
+	}
+
+	events, err := ReadSession(store, names[0])
+	if err != nil {
+		t.Fatalf("ReadSession failed: %v", err)
+	}
+	var types []string
+	for _, event := range events {
+		types = append(types, event["type"].(string))
+	}
+	expected := []string{"http_request", "http_response", "session_summary"}
+	if len(types) != len(expected) {
+		t.Fatalf("Expected events %v, got %v", expected, types)
+	}
+	for i := range expected {
+		if types[i] != expected[i] {
+			t.Errorf("Event %d: expected %s, got %s", i, expected[i], types[i])
+		}
+	}
+	if events[1]["body"] != `{"ok":true}` {
+		t.Errorf("Expected the response body, got %v", events[1]["body"])
+	}
+
+	// Export tools read from the store too
+	var har bytes.Buffer
+	if err := ExportHARFromStore(store, names[0], &har); err != nil {
+		t.Fatalf("ExportHARFromStore failed: %v", err)
+	}
+	var document harDocument
+	if err := json.Unmarshal(har.Bytes(), &document); err != nil {
+		t.Fatalf("Invalid HAR: %v", err)
+	}
+	if len(document.Log.Entries) != 1 || document.Log.Entries[0].Request.URL != server.URL+"/items" {
+		t.Errorf("Unexpected HAR entries: %+v", document.Log.Entries)
+	}
+}
+
+func TestMemoryStoreOffsets(t *testing.T) {
+	store := NewMemoryStore()
+	if offset, _ := store.Append("a", []byte("one\n")); offset != 0 {
+		t.Errorf("Expected offset 0, got %d", offset)
+	}
+	if offset, _ := store.Append("a", []byte("two\n")); offset != 4 {
+		t.Errorf("Expected offset 4, got %d", offset)
+	}
+	if _, err := store.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist for a missing session, got %v", err)
+	}
+}
+
+func TestFileSystemStoreList(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-fs-store-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	store := &FileSystemStore{Dir: tempDir}
+	for _, name := range []string{"sessions/b.jsonl", "sessions/a.jsonl"} {
+		if _, err := store.Append(name, []byte("{}\n")); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	os.WriteFile(filepath.Join(tempDir, "notes.txt"), []byte("x"), 0644)
+
+	names, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{filepath.Join("sessions", "a.jsonl"), filepath.Join("sessions", "b.jsonl")}
+	if len(names) != 2 || names[0] != expected[0] || names[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+}
+
+func TestFileSystemStoreCreatesDirectoryOnce(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-fs-store-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	store := &FileSystemStore{Dir: tempDir}
+	if _, err := store.Append("sessions/a.jsonl", []byte("{}\n")); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	// The directory of a known session is not checked again, so removing it
+	// makes later lines fail instead of quietly creating it per line
+	os.RemoveAll(filepath.Join(tempDir, "sessions"))
+	if _, err := store.Append("sessions/a.jsonl", []byte("{}\n")); err == nil {
+		t.Error("Expected Append to fail once the session directory was removed")
+	}
+
+	// A new session still gets its directory
+	if _, err := store.Append("sessions/b.jsonl", []byte("{}\n")); err != nil {
+		t.Fatalf("Append for a new session failed: %v", err)
+	}
+}
+
+func TestSessionStoreRejectsLocalOnlySettings(t *testing.T) {
+	config := &TracingConfig{
+		Enabled:          true,
+		SessionStore:     NewMemoryStore(),
+		AggregateFile:    filepath.Join(t.TempDir(), "all-sessions.ndjson"),
+		MinFreeDiskBytes: 1 << 30,
+	}
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Expected Validate to reject local-only settings with a custom store")
+	}
+	for _, name := range []string{"aggregate_file", "min_free_disk_bytes"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("Expected an error naming %s, got %v", name, err)
+		}
+	}
+
+	logger := NewLogger(config, "store-conflicts")
+	logger.freeDiskSpace = func(string) (uint64, error) {
+		t.Error("Expected no local disk check with a custom store")
+		return 0, nil
+	}
+	if !logger.hasDiskSpace() {
+		t.Error("Expected writing to continue with a custom store")
+	}
+	if logger.aggregate.enabled {
+		t.Error("Expected the aggregate file to be ignored with a custom store")
+	}
+
+	warnings := 0
+	for len(logger.Warnings()) > 0 {
+		if warning := <-logger.Warnings(); warning.Type == WarningInvalidConfig {
+			warnings++
+		}
+	}
+	if warnings != 2 {
+		t.Errorf("Expected 2 invalid_config warnings, got %d", warnings)
+	}
+}
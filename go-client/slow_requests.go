@@ -0,0 +1,54 @@
+package main
+
+import "sort"
+
+// slowestRequestsKept is how many of the slowest requests the session summary lists
+const slowestRequestsKept = 10
+
+// SlowRequest identifies one of the slowest requests of a session
+type SlowRequest struct {
+	RequestID string `json:"request_id"`
+	URL       string `json:"url"`
+	Duration  int64  `json:"duration_ms"`
+}
+
+// observeSlowRequest flags a response slower than SlowRequestThreshold and
+// keeps the slowest requests for the session summary
+func (l *Logger) observeSlowRequest(capture *ResponseCapture, event *HTTPResponseEvent) {
+	threshold := l.config.SlowRequestThreshold
+	if threshold <= 0 {
+		return
+	}
+
+	url := l.redactURL(capture.URL)
+	if capture.Duration > threshold {
+		event.Slow = true
+		l.warn(WarningSlowRequest, capture.RequestID, "slow request %s took %v (threshold %v)", url, capture.Duration, threshold)
+	}
+
+	l.slowMu.Lock()
+	defer l.slowMu.Unlock()
+
+	l.slowest = append(l.slowest, SlowRequest{
+		RequestID: capture.RequestID,
+		URL:       url,
+		Duration:  capture.Duration.Milliseconds(),
+	})
+	sort.SliceStable(l.slowest, func(i, j int) bool {
+		return l.slowest[i].Duration > l.slowest[j].Duration
+	})
+	if len(l.slowest) > slowestRequestsKept {
+		l.slowest = l.slowest[:slowestRequestsKept]
+	}
+}
+
+// slowestRequests returns the slowest requests seen so far, slowest first
+func (l *Logger) slowestRequests() []SlowRequest {
+	l.slowMu.Lock()
+	defer l.slowMu.Unlock()
+
+	if len(l.slowest) == 0 {
+		return nil
+	}
+	return append([]SlowRequest(nil), l.slowest...)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSlowRequests(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-slow-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(150 * time.Millisecond)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.SlowRequestThreshold = 100 * time.Millisecond
+
+	client := NewTracingHTTPClientWithConfig("test-slow", config)
+	for _, path := range []string{"/fast", "/slow"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+	client.Close()
+
+	events := readSessionEvents(t, tempDir)
+	responses := eventsOfType(events, "http_response")
+	if len(responses) != 2 {
+		t.Fatalf("Expected 2 response events, got %d", len(responses))
+	}
+	if _, ok := responses[0]["slow"]; ok {
+		t.Error("Expected the fast response not to be flagged")
+	}
+	if responses[1]["slow"] != true {
+		t.Error("Expected the slow response to be flagged")
+	}
+
+	summaries := eventsOfType(events, "session_summary")
+	if len(summaries) != 1 {
+		t.Fatalf("Expected 1 session_summary event, got %d", len(summaries))
+	}
+	slowest, _ := summaries[0]["slowest_requests"].([]interface{})
+	if len(slowest) != 2 {
+		t.Fatalf("Expected 2 entries in slowest_requests, got %v", summaries[0]["slowest_requests"])
+	}
+	first, _ := slowest[0].(map[string]interface{})
+	if first["url"] != server.URL+"/slow" || first["request_id"] != responses[1]["request_id"] {
+		t.Errorf("Expected the slow request first, got %v", first)
+	}
+	if duration, _ := first["duration_ms"].(float64); duration < 150 {
+		t.Errorf("Expected a duration of at least 150ms, got %v", first["duration_ms"])
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// sseFrame is a single Server-Sent Events frame
+type sseFrame struct {
+	Event string
+	Data  string
+}
+
+// parseSSEFrames splits an event-stream body into frames
+func parseSSEFrames(body []byte) []sseFrame {
+	var frames []sseFrame
+	var current sseFrame
+	var data []string
+
+	flush := func() {
+		if current.Event != "" || len(data) > 0 {
+			current.Data = strings.Join(data, "\n")
+			frames = append(frames, current)
+		}
+		current = sseFrame{}
+		data = nil
+	}
+
+	body = bytes.ReplaceAll(body, []byte("\r\n"), []byte("\n"))
+	for _, line := range strings.Split(string(body), "\n") {
+		if line == "" {
+			flush()
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // comment
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			current.Event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	flush()
+
+	return frames
+}
+
+// LLMStreamAggregate holds the completion reconstructed from streaming deltas
+type LLMStreamAggregate struct {
+	Provider     string
+	Model        string
+	Text         strings.Builder
+	DeltaCount   int
+	UsageTokens  int
+	FrameCount   int
+	FinishReason string
+}
+
+// TokenCount returns the provider-reported output tokens, or the number of deltas when usage is absent
+func (a *LLMStreamAggregate) TokenCount() int {
+	if a.UsageTokens > 0 {
+		return a.UsageTokens
+	}
+	return a.DeltaCount
+}
+
+// TokenCountSource reports where TokenCount came from
+func (a *LLMStreamAggregate) TokenCountSource() string {
+	if a.UsageTokens > 0 {
+		return "usage"
+	}
+	return "deltas"
+}
+
+// openAIStreamChunk is the subset of an OpenAI chat completion chunk we aggregate
+type openAIStreamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// anthropicStreamEvent is the subset of an Anthropic messages stream event we aggregate
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Model string `json:"model"`
+	} `json:"message"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// aggregateLLMStream reconstructs the completion text from a captured SSE response.
+// It returns nil for non-streaming responses and streams that are not from a known provider.
+func aggregateLLMStream(capture *ResponseCapture) *LLMStreamAggregate {
+	if len(capture.Body) == 0 || !strings.HasPrefix(strings.ToLower(capture.ContentType), "text/event-stream") {
+		return nil
+	}
+
+	aggregate := &LLMStreamAggregate{}
+	for _, frame := range parseSSEFrames(capture.Body) {
+		aggregate.FrameCount++
+		if frame.Data == "" || frame.Data == "[DONE]" {
+			continue
+		}
+
+		var probe struct {
+			Type    string          `json:"type"`
+			Choices json.RawMessage `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(frame.Data), &probe); err != nil {
+			continue
+		}
+
+		switch {
+		case probe.Choices != nil:
+			aggregate.addOpenAIChunk(frame.Data)
+		case probe.Type != "":
+			aggregate.addAnthropicEvent(frame.Data)
+		}
+	}
+
+	if aggregate.Provider == "" {
+		return nil
+	}
+	return aggregate
+}
+
+// addOpenAIChunk folds a chat completion chunk (choices[].delta.content) into the aggregate
+func (a *LLMStreamAggregate) addOpenAIChunk(data string) {
+	var chunk openAIStreamChunk
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return
+	}
+
+	a.Provider = "openai"
+	if chunk.Model != "" {
+		a.Model = chunk.Model
+	}
+	for _, choice := range chunk.Choices {
+		if choice.Delta.Content != "" {
+			a.Text.WriteString(choice.Delta.Content)
+			a.DeltaCount++
+		}
+		if choice.FinishReason != "" {
+			a.FinishReason = choice.FinishReason
+		}
+	}
+	if chunk.Usage != nil {
+		a.UsageTokens = chunk.Usage.CompletionTokens
+	}
+}
+
+// addAnthropicEvent folds a messages stream event (content_block_delta) into the aggregate
+func (a *LLMStreamAggregate) addAnthropicEvent(data string) {
+	var event anthropicStreamEvent
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return
+	}
+
+	switch event.Type {
+	case "message_start":
+		a.Provider = "anthropic"
+		a.Model = event.Message.Model
+	case "content_block_delta":
+		a.Provider = "anthropic"
+		if event.Delta.Type == "text_delta" || event.Delta.Text != "" {
+			a.Text.WriteString(event.Delta.Text)
+			a.DeltaCount++
+		}
+	case "message_delta":
+		a.Provider = "anthropic"
+		if event.Delta.StopReason != "" {
+			a.FinishReason = event.Delta.StopReason
+		}
+		if event.Usage != nil {
+			a.UsageTokens = event.Usage.OutputTokens
+		}
+	}
+}
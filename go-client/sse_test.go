@@ -0,0 +1,235 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// Recorded from an OpenAI chat completions stream
+const openAIStreamPayload = `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","model":"gpt-4o-mini","choices":[{"index":0,"delta":{"role":"assistant","content":""},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","model":"gpt-4o-mini","choices":[{"index":0,"delta":{"content":"Hello"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","model":"gpt-4o-mini","choices":[{"index":0,"delta":{"content":", world"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","model":"gpt-4o-mini","choices":[{"index":0,"delta":{"content":"!"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","model":"gpt-4o-mini","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}
+
+data: [DONE]
+
+`
+
+// Recorded from an Anthropic messages stream
+const anthropicStreamPayload = "event: message_start\r\n" +
+	`data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-5-sonnet-20241022","content":[],"usage":{"input_tokens":12,"output_tokens":1}}}` + "\r\n\r\n" +
+	"event: content_block_start\r\n" +
+	`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}` + "\r\n\r\n" +
+	"event: ping\r\n" +
+	`data: {"type": "ping"}` + "\r\n\r\n" +
+	"event: content_block_delta\r\n" +
+	`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}` + "\r\n\r\n" +
+	"event: content_block_delta\r\n" +
+	`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" there"}}` + "\r\n\r\n" +
+	"event: content_block_stop\r\n" +
+	`data: {"type":"content_block_stop","index":0}` + "\r\n\r\n" +
+	"event: message_delta\r\n" +
+	`data: {"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":3}}` + "\r\n\r\n" +
+	"event: message_stop\r\n" +
+	`data: {"type":"message_stop"}` + "\r\n\r\n"
+
+func TestAggregateOpenAIStream(t *testing.T) {
+	stream := aggregateLLMStream(&ResponseCapture{
+		ContentType: "text/event-stream; charset=utf-8",
+		Body:        []byte(openAIStreamPayload),
+	})
+	if stream == nil {
+		t.Fatal("Expected OpenAI stream to be aggregated")
+	}
+
+	if stream.Provider != "openai" {
+		t.Errorf("Expected provider openai, got %s", stream.Provider)
+	}
+	if stream.Model != "gpt-4o-mini" {
+		t.Errorf("Expected model gpt-4o-mini, got %s", stream.Model)
+	}
+	if got := stream.Text.String(); got != "Hello, world!" {
+		t.Errorf("Expected reconstructed text %q, got %q", "Hello, world!", got)
+	}
+	if stream.TokenCount() != 3 || stream.TokenCountSource() != "deltas" {
+		t.Errorf("Expected 3 tokens from deltas, got %d from %s", stream.TokenCount(), stream.TokenCountSource())
+	}
+	if stream.FinishReason != "stop" {
+		t.Errorf("Expected finish reason stop, got %s", stream.FinishReason)
+	}
+	if stream.FrameCount != 6 {
+		t.Errorf("Expected 6 frames, got %d", stream.FrameCount)
+	}
+}
+
+func TestAggregateAnthropicStream(t *testing.T) {
+	stream := aggregateLLMStream(&ResponseCapture{
+		ContentType: "text/event-stream",
+		Body:        []byte(anthropicStreamPayload),
+	})
+	if stream == nil {
+		t.Fatal("Expected Anthropic stream to be aggregated")
+	}
+
+	if stream.Provider != "anthropic" {
+		t.Errorf("Expected provider anthropic, got %s", stream.Provider)
+	}
+	if stream.Model != "claude-3-5-sonnet-20241022" {
+		t.Errorf("Expected model claude-3-5-sonnet-20241022, got %s", stream.Model)
+	}
+	if got := stream.Text.String(); got != "Hi there" {
+		t.Errorf("Expected reconstructed text %q, got %q", "Hi there", got)
+	}
+	if stream.TokenCount() != 3 || stream.TokenCountSource() != "usage" {
+		t.Errorf("Expected 3 tokens from usage, got %d from %s", stream.TokenCount(), stream.TokenCountSource())
+	}
+	if stream.FinishReason != "end_turn" {
+		t.Errorf("Expected finish reason end_turn, got %s", stream.FinishReason)
+	}
+}
+
+func TestAggregateIgnoresNonStreamingResponses(t *testing.T) {
+	if stream := aggregateLLMStream(&ResponseCapture{ContentType: "application/json", Body: []byte(`{"choices":[]}`)}); stream != nil {
+		t.Error("Expected JSON response not to be aggregated")
+	}
+	if stream := aggregateLLMStream(&ResponseCapture{ContentType: "text/event-stream", Body: []byte("data: hello\n\n")}); stream != nil {
+		t.Error("Expected unknown stream shape not to be aggregated")
+	}
+}
+
+func TestLLMStreamCompleteEvent(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-sse-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(anthropicStreamPayload))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.MaxBodySize = 64 * 1024
+
+	client := NewTracingHTTPClientWithConfig("test-sse", config)
+	defer client.Close()
+
+	resp, err := client.PostJSON(server.URL+"/v1/messages", []byte(`{"stream": true}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != anthropicStreamPayload {
+		t.Error("Expected caller to receive the full stream")
+	}
+
+	events := readSessionEvents(t, tempDir)
+	if len(events) != 3 || events[2]["type"] != "llm_stream_complete" {
+		t.Fatalf("Expected llm_stream_complete after the response, got %v", events)
+	}
+
+	complete := events[2]
+	if complete["text"] != "Hi there" {
+		t.Errorf("Expected text %q, got %v", "Hi there", complete["text"])
+	}
+	if complete["provider"] != "anthropic" {
+		t.Errorf("Expected provider anthropic, got %v", complete["provider"])
+	}
+	if complete["token_count"] != float64(3) {
+		t.Errorf("Expected token_count 3, got %v", complete["token_count"])
+	}
+}
+
+func TestLLMStreamCompleteFollowsStoredBody(t *testing.T) {
+	const token = "sk-live-minted-0123456789"
+	streamPayload := `data: {"choices":[{"index":0,"delta":{"content":"your key is ` + token + `"},"finish_reason":"stop"}]}` + "\n\n" +
+		"data: [DONE]\n\n"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"` + token + `"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(streamPayload))
+	}))
+	defer server.Close()
+
+	run := func(t *testing.T, config *TracingConfig) []map[string]interface{} {
+		client := NewTracingHTTPClientWithConfig("test-sse-redaction", config)
+		defer client.Close()
+
+		for _, path := range []string{"/token", "/v1/chat/completions"} {
+			resp, err := client.PostJSON(server.URL+path, []byte(`{"stream": true}`))
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		return readSessionEvents(t, config.OutputDir)
+	}
+
+	findStream := func(events []map[string]interface{}) map[string]interface{} {
+		for _, event := range events {
+			if event["type"] == "llm_stream_complete" {
+				return event
+			}
+		}
+		return nil
+	}
+
+	t.Run("redacted", func(t *testing.T) {
+		config := newTestConfig(t.TempDir())
+		config.MaxBodySize = 64 * 1024
+
+		events := run(t, config)
+		stream := findStream(events)
+		if stream == nil {
+			t.Fatalf("Expected llm_stream_complete, got %v", events)
+		}
+		if stream["text"] != "your key is "+redactedValue {
+			t.Errorf("Expected the minted token to be redacted, got %v", stream["text"])
+		}
+		if stream["request_id"] == nil || stream["request_id"] == "" {
+			t.Error("Expected llm_stream_complete to carry the request_id")
+		}
+		if _, ok := stream["truncated"]; ok {
+			t.Error("Expected a fully captured stream not to be marked truncated")
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		config := newTestConfig(t.TempDir())
+		config.MaxBodySize = int64(len(streamPayload) - len("data: [DONE]\n\n"))
+
+		stream := findStream(run(t, config))
+		if stream == nil || stream["truncated"] != true {
+			t.Errorf("Expected a stream rebuilt from a truncated body to be marked truncated, got %v", stream)
+		}
+	})
+
+	t.Run("body not stored", func(t *testing.T) {
+		config := newTestConfig(t.TempDir())
+		config.MaxBodySize = 64 * 1024
+		config.MaxSessionBytes = 1
+
+		if stream := findStream(run(t, config)); stream != nil {
+			t.Errorf("Expected no llm_stream_complete when the response body was not stored, got %v", stream)
+		}
+	})
+}
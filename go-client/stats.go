@@ -0,0 +1,109 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// statsDurationWindow is how many recent request durations feed the p95
+const statsDurationWindow = 1024
+
+// Stats is a point-in-time snapshot of the client's request statistics
+type Stats struct {
+	TotalRequests int64            `json:"total_requests"`
+	InFlight      int64            `json:"in_flight"`
+	Errors        int64            `json:"errors"`
+	BytesWritten  int64            `json:"bytes_written"`
+	EventsDropped int64            `json:"events_dropped"`
+	StatusClasses map[string]int64 `json:"status_classes"`
+	AvgDuration   time.Duration    `json:"avg_duration"`
+	P95Duration   time.Duration    `json:"p95_duration"`
+}
+
+// requestStats holds the counters behind Stats
+type requestStats struct {
+	mu            sync.Mutex
+	total         int64
+	inFlight      int64
+	errors        int64
+	completed     int64
+	totalDuration time.Duration
+	statusClasses map[string]int64
+
+	// Ring buffer of the most recent durations
+	durations []time.Duration
+	next      int
+}
+
+// requestStarted records a traced request entering the transport
+func (l *Logger) requestStarted() {
+	l.stats.mu.Lock()
+	l.stats.total++
+	l.stats.inFlight++
+	l.stats.mu.Unlock()
+}
+
+// requestFinished records the outcome of a traced request. statusCode is 0
+// when no response was received.
+func (l *Logger) requestFinished(statusCode int, err error, duration time.Duration) {
+	s := &l.stats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inFlight--
+	if err != nil {
+		s.errors++
+	}
+	if statusCode > 0 {
+		if s.statusClasses == nil {
+			s.statusClasses = make(map[string]int64)
+		}
+		s.statusClasses[statusClass(statusCode)]++
+	}
+
+	s.completed++
+	s.totalDuration += duration
+	if len(s.durations) < statsDurationWindow {
+		s.durations = append(s.durations, duration)
+	} else {
+		s.durations[s.next] = duration
+		s.next = (s.next + 1) % statsDurationWindow
+	}
+}
+
+// Stats returns a consistent snapshot of the request statistics
+func (l *Logger) Stats() Stats {
+	s := &l.stats
+	s.mu.Lock()
+	stats := Stats{
+		TotalRequests: s.total,
+		InFlight:      s.inFlight,
+		Errors:        s.errors,
+		StatusClasses: make(map[string]int64, len(s.statusClasses)),
+	}
+	for class, count := range s.statusClasses {
+		stats.StatusClasses[class] = count
+	}
+	if s.completed > 0 {
+		stats.AvgDuration = s.totalDuration / time.Duration(s.completed)
+	}
+	durations := append([]time.Duration(nil), s.durations...)
+	s.mu.Unlock()
+
+	if len(durations) > 0 {
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		stats.P95Duration = durations[(len(durations)*95+99)/100-1]
+	}
+
+	stats.BytesWritten = l.sessionBytes.Load()
+	if l.async != nil {
+		stats.EventsDropped = l.async.dropped.Load()
+	}
+	return stats
+}
+
+// statusClass returns the class of an HTTP status code, e.g. "2xx"
+func statusClass(statusCode int) string {
+	return string(rune('0'+statusCode/100)) + "xx"
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestClientStats(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-stats-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			time.Sleep(5 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+
+	client := NewTracingHTTPClientWithConfig("test-stats", newTestConfig(tempDir))
+	defer client.Close()
+
+	if stats := client.Stats(); stats.TotalRequests != 0 || len(stats.StatusClasses) != 0 {
+		t.Errorf("Expected empty stats before any request, got %+v", stats)
+	}
+
+	for _, path := range []string{"/ok", "/ok", "/ok", "/missing", "/broken"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	deadURL := server.URL
+	server.Close()
+	if resp, err := client.Get(deadURL + "/down"); err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected request to a closed server to fail")
+	}
+
+	stats := client.Stats()
+	if stats.TotalRequests != 6 {
+		t.Errorf("Expected 6 requests, got %d", stats.TotalRequests)
+	}
+	if stats.InFlight != 0 {
+		t.Errorf("Expected nothing in flight, got %d", stats.InFlight)
+	}
+	if stats.Errors != 1 {
+		t.Errorf("Expected 1 error, got %d", stats.Errors)
+	}
+	expectedClasses := map[string]int64{"2xx": 3, "4xx": 1, "5xx": 1}
+	for class, count := range expectedClasses {
+		if stats.StatusClasses[class] != count {
+			t.Errorf("Expected %d %s responses, got %d", count, class, stats.StatusClasses[class])
+		}
+	}
+	if stats.BytesWritten == 0 {
+		t.Error("Expected bytes written to be reported")
+	}
+	if stats.AvgDuration <= 0 || stats.P95Duration < 5*time.Millisecond {
+		t.Errorf("Unexpected durations: avg %v p95 %v", stats.AvgDuration, stats.P95Duration)
+	}
+
+	// Snapshots are copies
+	stats.StatusClasses["2xx"] = 100
+	if client.Stats().StatusClasses["2xx"] != 3 {
+		t.Error("Expected Stats to return an independent copy")
+	}
+}
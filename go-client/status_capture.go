@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StatusPatterns lists response statuses as exact codes ("401") or classes
+// ("5xx"). In a config file entries may be numbers or strings, as in
+// [401, 403, "5xx"].
+type StatusPatterns []string
+
+// UnmarshalJSON accepts numbers as well as strings
+func (p *StatusPatterns) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	patterns := make(StatusPatterns, 0, len(raw))
+	for _, entry := range raw {
+		var code int
+		if err := json.Unmarshal(entry, &code); err == nil {
+			patterns = append(patterns, strconv.Itoa(code))
+			continue
+		}
+
+		var pattern string
+		if err := json.Unmarshal(entry, &pattern); err != nil {
+			return fmt.Errorf("status pattern %s must be a number or a string", entry)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	*p = patterns
+	return nil
+}
+
+// parseStatusPatterns splits a comma-separated list such as "401,403,5xx"
+func parseStatusPatterns(value string) StatusPatterns {
+	var patterns StatusPatterns
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// validStatusPattern reports whether pattern is a status code or class
+func validStatusPattern(pattern string) bool {
+	pattern = strings.ToLower(pattern)
+	if len(pattern) != 3 || pattern[0] < '1' || pattern[0] > '5' {
+		return false
+	}
+	if pattern[1:] == "xx" {
+		return true
+	}
+	return isDigit(pattern[1]) && isDigit(pattern[2])
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// matches reports whether status matches any of the patterns
+func (p StatusPatterns) matches(status int) bool {
+	code := strconv.Itoa(status)
+	for _, pattern := range p {
+		pattern = strings.ToLower(pattern)
+		if pattern == code || (strings.HasSuffix(pattern, "xx") && len(pattern) == 3 && len(code) == 3 && pattern[0] == code[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// captureResponseBody decides whether the body of a response from rawURL with
+// the given status is captured: a matching BodyCaptureRules entry decides
+// first, then CaptureBodyStatusCodes, when set, overrides CaptureResponseBodies
+func (c *TracingConfig) captureResponseBody(rawURL string, status int) bool {
+	if rule := c.bodyCaptureRule(rawURL); rule != nil {
+		return rule.CaptureResponse
+	}
+	if len(c.CaptureBodyStatusCodes) == 0 {
+		return c.CaptureResponseBodies
+	}
+	return c.CaptureBodyStatusCodes.matches(status)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestCaptureBodyStatusCodes(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-status-capture-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/unauthorized":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("token expired"))
+		case "/broken":
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("upstream down"))
+		default:
+			w.Write([]byte("all good"))
+		}
+	}))
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.CaptureResponseBodies = false
+	config.CaptureBodyStatusCodes = StatusPatterns{"401", "5xx"}
+	client := NewTracingHTTPClientWithConfig("test-status-capture", config)
+
+	for _, path := range []string{"/ok", "/unauthorized", "/broken"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 3 {
+		t.Fatalf("Expected 3 response events, got %d", len(responses))
+	}
+	if body, ok := responses[0]["body"]; ok {
+		t.Errorf("Expected the 200 body to be skipped, got %v", body)
+	}
+	if responses[1]["body"] != "token expired" {
+		t.Errorf("Expected the 401 body to be captured, got %v", responses[1]["body"])
+	}
+	if responses[2]["body"] != "upstream down" {
+		t.Errorf("Expected the 502 body to be captured, got %v", responses[2]["body"])
+	}
+}
+
+func TestStatusPatterns(t *testing.T) {
+	var config TracingConfig
+	if err := json.Unmarshal([]byte(`{"capture_body_status_codes": [401, 403, "5xx"]}`), &config); err != nil {
+		t.Fatalf("Failed to parse mixed status patterns: %v", err)
+	}
+	if !reflect.DeepEqual(config.CaptureBodyStatusCodes, StatusPatterns{"401", "403", "5xx"}) {
+		t.Errorf("Unexpected patterns %v", config.CaptureBodyStatusCodes)
+	}
+
+	tests := []struct {
+		status   int
+		expected bool
+	}{
+		{200, false},
+		{401, true},
+		{403, true},
+		{404, false},
+		{500, true},
+		{503, true},
+	}
+	for _, tt := range tests {
+		if got := config.CaptureBodyStatusCodes.matches(tt.status); got != tt.expected {
+			t.Errorf("Status %d: expected %v, got %v", tt.status, tt.expected, got)
+		}
+	}
+
+	for pattern, valid := range map[string]bool{"401": true, "5xx": true, "5XX": true, "6xx": false, "40": false, "4+1": false, "abc": false} {
+		if got := validStatusPattern(pattern); got != valid {
+			t.Errorf("Pattern %q: expected valid=%v, got %v", pattern, valid, got)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"mime"
+	"strings"
+)
+
+// bodyNotCapturedStreaming is the reason recorded for responses matching
+// StreamingContentTypes
+const bodyNotCapturedStreaming = "streaming"
+
+// isUncapturedStream reports whether a response of contentType matches
+// StreamingContentTypes. Such bodies are handed to the caller untouched: the
+// tracer neither reads, tees nor hashes them, so a stream that never ends
+// cannot hang the request.
+func (c *TracingConfig) isUncapturedStream(contentType string) bool {
+	if len(c.StreamingContentTypes) == 0 || contentType == "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, _, _ = strings.Cut(contentType, ";")
+	}
+	for _, streaming := range c.StreamingContentTypes {
+		if strings.EqualFold(strings.TrimSpace(streaming), strings.TrimSpace(mediaType)) {
+			return true
+		}
+	}
+	return false
+}
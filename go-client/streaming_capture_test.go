@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingTransport counts the response body bytes read by anyone
+type countingTransport struct {
+	read atomic.Int64
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if resp != nil {
+		resp.Body = &countingBody{ReadCloser: resp.Body, read: &c.read}
+	}
+	return resp, err
+}
+
+type countingBody struct {
+	io.ReadCloser
+	read *atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read.Add(int64(n))
+	return n, err
+}
+
+func TestStreamingContentTypesLeaveBodyUnread(t *testing.T) {
+	// An SSE stream that never ends
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		flusher := w.(http.Flusher)
+		for {
+			if _, err := w.Write([]byte("data: tick\n\n")); err != nil {
+				return
+			}
+			flusher.Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-streaming-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := newTestConfig(tempDir)
+	config.HashBodies = true
+	config.StreamingContentTypes = []string{"text/event-stream"}
+	logger := NewLogger(config, "test-streaming")
+	base := &countingTransport{}
+	client := &http.Client{Transport: NewTracingRoundTripper(base, logger, config, "test-streaming")}
+
+	done := make(chan *http.Response, 1)
+	go func() {
+		resp, err := client.Get(server.URL + "/stream")
+		if err != nil {
+			t.Errorf("Request failed: %v", err)
+		}
+		done <- resp
+	}()
+
+	var resp *http.Response
+	select {
+	case resp = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Request did not complete; the tracer is reading the stream")
+	}
+	if resp == nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if read := base.read.Load(); read != 0 {
+		t.Errorf("Expected the tracer to read none of the body, it read %d bytes", read)
+	}
+
+	// The caller still gets the stream from its start
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != "data: tick\n" {
+		t.Errorf("Expected the first event for the caller, got %q (%v)", line, err)
+	}
+
+	responses := eventsOfType(readSessionEvents(t, tempDir), "http_response")
+	if len(responses) != 1 {
+		t.Fatalf("Expected one response event, got %d", len(responses))
+	}
+	event := responses[0]
+	if event["body_captured"] != false || event["reason"] != "streaming" {
+		t.Errorf("Expected body_captured false with reason streaming, got %v and %v", event["body_captured"], event["reason"])
+	}
+	if event["body"] != nil {
+		t.Errorf("Expected no body, got %v", event["body"])
+	}
+	if !strings.HasPrefix(event["content_type"].(string), "text/event-stream") {
+		t.Errorf("Expected the content type to be recorded, got %v", event["content_type"])
+	}
+}
+
+func TestIsUncapturedStream(t *testing.T) {
+	config := &TracingConfig{StreamingContentTypes: []string{"text/event-stream", " application/x-ndjson"}}
+
+	tests := map[string]bool{
+		"text/event-stream":                true,
+		"Text/Event-Stream; charset=utf-8": true,
+		"application/x-ndjson":             true,
+		"application/json":                 false,
+		"":                                 false,
+	}
+	for contentType, expected := range tests {
+		if got := config.isUncapturedStream(contentType); got != expected {
+			t.Errorf("isUncapturedStream(%q) = %v, expected %v", contentType, got, expected)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"time"
+)
+
+// Summary returns statistics for the session so far
+func (l *Logger) Summary() SessionSummary {
+	summary := SessionSummary{
+		EventsWritten: l.eventsWritten.Load(),
+		BytesWritten:  l.sessionBytes.Load(),
+	}
+	if l.async != nil {
+		summary.EventsDropped = l.async.dropped.Load()
+	}
+	summary.RateLimitMinRemaining = l.rateLimitSummary()
+	summary.SlowestRequests = l.slowestRequests()
+	summary.RequestsByFingerprint = l.fingerprintSummary()
+	return summary
+}
+
+// writeSummary appends a session_summary event if the session produced any events
+func (l *Logger) writeSummary() error {
+	if !l.config.Enabled {
+		return nil
+	}
+
+	summary := l.Summary()
+	if summary.EventsWritten == 0 && summary.EventsDropped == 0 {
+		return nil
+	}
+
+	return l.persistEvent(SessionSummaryEvent{
+		Type:           "session_summary",
+		Timestamp:      time.Now().UnixMilli(),
+		SessionID:      l.sessionID,
+		SessionSummary: summary,
+	})
+}
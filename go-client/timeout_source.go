@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Connection error class of a request that ran out of time, and the
+// deadlines that may have ended it. A per-request timeout override counts
+// as the client timeout.
+const (
+	ConnectionErrorTimeout = "timeout"
+	DeadlineContext        = "context_deadline"
+	DeadlineClientTimeout  = "client_timeout"
+)
+
+// clientTimeoutSlack is how far a context deadline may sit from start plus
+// the client Timeout and still be taken for the deadline http.Client derived
+// from it; the client computes its deadline shortly before the round trip
+const clientTimeoutSlack = 100 * time.Millisecond
+
+// effectiveDeadline returns which deadline ended a request started at start,
+// or "" when the request did not fail on a deadline. http.Client enforces its
+// Timeout through the request context, so the context deadline is compared
+// with the one the client would have set.
+func (t *TracingRoundTripper) effectiveDeadline(req *http.Request, start time.Time) string {
+	ctx := req.Context()
+	if ctx.Err() != context.DeadlineExceeded {
+		return ""
+	}
+
+	// A per-request override replaces the client Timeout
+	timeout := t.clientTimeout()
+	if override, ok := requestTimeoutFromContext(ctx); ok {
+		timeout = override
+	}
+
+	deadline, ok := ctx.Deadline()
+	if timeout <= 0 || !ok {
+		return DeadlineContext
+	}
+
+	clientDeadline := start.Add(timeout)
+	if deadline.Before(clientDeadline.Add(-clientTimeoutSlack)) || deadline.After(clientDeadline.Add(clientTimeoutSlack)) {
+		return DeadlineContext
+	}
+	return DeadlineClientTimeout
+}
+
+// clientTimeout returns the Timeout of the http.Client sending requests
+// through t: the client WrapClient built, which keeps the Timeout of the
+// client it wrapped, or config.Timeout for a bare transport
+func (t *TracingRoundTripper) clientTimeout() time.Duration {
+	if t.client != nil {
+		return t.client.Timeout
+	}
+	return t.config.Timeout
+}
+
+// LogTimeoutError logs a request that failed on a deadline, naming the
+// deadline that was effective
+func (l *Logger) LogTimeoutError(requestID string, err error, deadline string) error {
+	if !l.config.Enabled {
+		return nil
+	}
+
+	errorEvent := map[string]interface{}{
+		"type":       "error",
+		"timestamp":  time.Now().UnixMilli(),
+		"session_id": l.sessionID,
+		"request_id": requestID,
+		"error": map[string]string{
+			"message":            l.redactError(err),
+			"context":            "connection error",
+			"class":              ConnectionErrorTimeout,
+			"effective_deadline": deadline,
+		},
+	}
+
+	return l.writeEvent(errorEvent)
+}
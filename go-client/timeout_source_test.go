@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestTimeoutSourceClassified(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		ctxTimeout time.Duration
+		expected   string
+	}{
+		{"context deadline", 5 * time.Second, 50 * time.Millisecond, DeadlineContext},
+		{"client timeout", 50 * time.Millisecond, 5 * time.Second, DeadlineClientTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir, err := os.MkdirTemp("", "trace-timeout-source-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tempDir)
+
+			config := newTestConfig(tempDir)
+			config.Timeout = tt.timeout
+			client := NewTracingHTTPClientWithConfig("test-timeout-source", config)
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.ctxTimeout)
+			defer cancel()
+			if resp, err := client.GetWithContext(ctx, server.URL); err == nil {
+				resp.Body.Close()
+				t.Fatal("Expected the request to time out")
+			}
+
+			errors := eventsOfType(readSessionEvents(t, tempDir), "error")
+			if len(errors) != 1 {
+				t.Fatalf("Expected 1 error event, got %d", len(errors))
+			}
+			detail, _ := errors[0]["error"].(map[string]interface{})
+			if detail["class"] != ConnectionErrorTimeout {
+				t.Errorf("Expected class %q, got %v", ConnectionErrorTimeout, detail["class"])
+			}
+			if detail["effective_deadline"] != tt.expected {
+				t.Errorf("Expected effective_deadline %q, got %v", tt.expected, detail["effective_deadline"])
+			}
+		})
+	}
+}
+
+func TestWrappedClientTimeoutRecorded(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "trace-timeout-wrapped-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// The wrapped client's Timeout applies, not the configured one
+	config := newTestConfig(tempDir)
+	config.Timeout = 5 * time.Second
+	logger := NewLogger(config, "test-timeout-wrapped")
+	client := WrapClient(&http.Client{Timeout: 50 * time.Millisecond}, logger, config, "test-timeout-wrapped")
+
+	if resp, err := client.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected the request to time out")
+	}
+
+	events := readSessionEvents(t, tempDir)
+	requests := eventsOfType(events, "http_request")
+	if len(requests) != 1 || requests[0]["timeout_ms"] != float64(50) {
+		t.Errorf("Expected timeout_ms 50 from the wrapped client, got %v", requests)
+	}
+	errors := eventsOfType(events, "error")
+	if len(errors) != 1 {
+		t.Fatalf("Expected 1 error event, got %d", len(errors))
+	}
+	detail, _ := errors[0]["error"].(map[string]interface{})
+	if detail["effective_deadline"] != DeadlineClientTimeout {
+		t.Errorf("Expected effective_deadline %q, got %v", DeadlineClientTimeout, detail["effective_deadline"])
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import "time"
+
+// timeLocation resolves TimeZone, falling back to UTC when it is unset or
+// invalid; Validate reports invalid names
+func (c *TracingConfig) timeLocation() *time.Location {
+	if c.TimeZone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// formatTimestamp renders an epoch-millisecond event timestamp as RFC 3339
+// in location, or in UTC when location is nil
+func formatTimestamp(millis int64, location *time.Location) string {
+	if location == nil {
+		location = time.UTC
+	}
+	return time.UnixMilli(millis).In(location).Format(time.RFC3339Nano)
+}
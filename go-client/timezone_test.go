@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTimeZoneFormatting(t *testing.T) {
+	config := &TracingConfig{TimeZone: "America/New_York"}
+	location := config.timeLocation()
+
+	tests := []struct {
+		millis   int64
+		expected string
+	}{
+		// Standard time, UTC-5
+		{1700000000123, "2023-11-14T17:13:20.123-05:00"},
+		// Daylight saving time, UTC-4
+		{1688169600000, "2023-06-30T20:00:00-04:00"},
+	}
+	for _, test := range tests {
+		if got := formatTimestamp(test.millis, location); got != test.expected {
+			t.Errorf("formatTimestamp(%d) = %s, expected %s", test.millis, got, test.expected)
+		}
+	}
+
+	if got := formatTimestamp(1700000000123, (&TracingConfig{}).timeLocation()); got != "2023-11-14T22:13:20.123Z" {
+		t.Errorf("Expected UTC by default, got %s", got)
+	}
+}
+
+func TestTimeZoneAppliedToCloudEvents(t *testing.T) {
+	logger := NewLogger(&TracingConfig{TimeZone: "America/New_York"}, "session")
+	wrapped, err := logger.wrapCloudEvent([]byte(`{"type":"http_request","timestamp":1700000000123}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var envelope map[string]interface{}
+	if err := json.Unmarshal(wrapped, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope["time"] != "2023-11-14T17:13:20.123-05:00" {
+		t.Errorf("Expected the time in America/New_York, got %v", envelope["time"])
+	}
+}
+
+func TestValidateTimeZone(t *testing.T) {
+	config := &TracingConfig{TimeZone: "America/New_York"}
+	if err := config.Validate(); err != nil && strings.Contains(err.Error(), "time_zone") {
+		t.Errorf("Expected a valid time zone to pass, got %v", err)
+	}
+
+	config.TimeZone = "Mars/Olympus_Mons"
+	err := config.Validate()
+	if err == nil || !strings.Contains(err.Error(), "time_zone") {
+		t.Errorf("Expected an invalid time zone to fail validation, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Supported values for TracingConfig.MinTLSVersion
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Connection error classes reported on error events
+const (
+	ConnectionErrorTLS = "tls"
+)
+
+// minTLSVersion returns the configured minimum TLS version, or 0 when none
+// or an unsupported value is set
+func minTLSVersion(config *TracingConfig) uint16 {
+	return tlsVersions[config.MinTLSVersion]
+}
+
+// enforceMinTLSVersion returns a transport that refuses to negotiate below
+// the minimum version. Only *http.Transport can be configured; any other
+// round tripper is returned unchanged and violations are logged instead.
+func enforceMinTLSVersion(transport http.RoundTripper, minVersion uint16) http.RoundTripper {
+	base, ok := transport.(*http.Transport)
+	if !ok || minVersion == 0 {
+		return transport
+	}
+
+	enforced := base.Clone()
+	if enforced.TLSClientConfig == nil {
+		enforced.TLSClientConfig = &tls.Config{}
+	}
+	if enforced.TLSClientConfig.MinVersion < minVersion {
+		enforced.TLSClientConfig.MinVersion = minVersion
+	}
+	return enforced
+}
+
+// checkTLSPolicy logs a tls_policy_violation when the response came over a
+// connection that negotiated less than the minimum version
+func (t *TracingRoundTripper) checkTLSPolicy(requestID string, req *http.Request, resp *http.Response) {
+	minVersion := minTLSVersion(t.config)
+	if minVersion == 0 || resp == nil || resp.TLS == nil || resp.TLS.Version >= minVersion {
+		return
+	}
+
+	if err := t.logger.LogTLSPolicyViolation(requestID, req.URL.Host, resp.TLS.Version, minVersion); err != nil {
+		t.logger.LogError(err, "failed to log TLS policy violation")
+	}
+}
+
+// LogTLSPolicyViolation logs a connection that negotiated a TLS version below the configured minimum
+func (l *Logger) LogTLSPolicyViolation(requestID, host string, negotiated, minimum uint16) error {
+	if !l.config.Enabled {
+		return nil
+	}
+
+	event := TLSPolicyViolationEvent{
+		Type:              "tls_policy_violation",
+		Timestamp:         time.Now().UnixMilli(),
+		SessionID:         l.sessionID,
+		RequestID:         requestID,
+		Host:              host,
+		NegotiatedVersion: tls.VersionName(negotiated),
+		MinVersion:        tls.VersionName(minimum),
+	}
+
+	return l.writeEvent(event)
+}
+
+// classifyConnectionError returns the class of a transport error, or "" when
+// it is not one the tracer recognizes
+func classifyConnectionError(err error) string {
+	var (
+		recordErr    tls.RecordHeaderError
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+		opErr        *net.OpError
+	)
+
+	switch {
+	case errors.As(err, &recordErr), errors.As(err, &verifyErr),
+		errors.As(err, &authorityErr), errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return ConnectionErrorTLS
+	case errors.As(err, &opErr) && (opErr.Op == "remote error" || opErr.Op == "local error"):
+		// TLS alerts sent or received during the handshake
+		return ConnectionErrorTLS
+	case strings.Contains(err.Error(), "tls: "):
+		return ConnectionErrorTLS
+	}
+	return ""
+}
+
+// LogConnectionError logs a failed request whose error was classified, such as a TLS handshake failure
+func (l *Logger) LogConnectionError(requestID string, err error, class string) error {
+	if !l.config.Enabled {
+		return nil
+	}
+
+	errorEvent := map[string]interface{}{
+		"type":       "error",
+		"timestamp":  time.Now().UnixMilli(),
+		"session_id": l.sessionID,
+		"request_id": requestID,
+		"error": map[string]string{
+			"message": l.redactError(err),
+			"context": "connection error",
+			"class":   class,
+		},
+	}
+
+	return l.writeEvent(errorEvent)
+}
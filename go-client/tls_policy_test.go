@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// newTLS12Server starts a TLS server that refuses anything above TLS 1.2
+func newTLS12Server() *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	return server
+}
+
+func TestMinTLSVersionEnforced(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-tls-policy-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := newTLS12Server()
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.MinTLSVersion = "1.3"
+	client := WrapClient(server.Client(), NewLogger(config, "test-tls-policy"), config, "test-tls-policy")
+
+	resp, err := client.Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected the handshake to fail below the minimum TLS version")
+	}
+
+	errors := eventsOfType(readSessionEvents(t, tempDir), "error")
+	if len(errors) != 1 {
+		t.Fatalf("Expected 1 error event, got %d", len(errors))
+	}
+	detail, _ := errors[0]["error"].(map[string]interface{})
+	if detail["context"] != "connection error" || detail["class"] != ConnectionErrorTLS {
+		t.Errorf("Expected a TLS connection error, got %v", detail)
+	}
+}
+
+// passthroughTransport hides the underlying *http.Transport from the tracer
+type passthroughTransport struct {
+	http.RoundTripper
+}
+
+func TestTLSPolicyViolationWithCustomTransport(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-tls-policy-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := newTLS12Server()
+	defer server.Close()
+
+	config := newTestConfig(tempDir)
+	config.MinTLSVersion = "1.3"
+	base := &http.Client{Transport: passthroughTransport{server.Client().Transport}}
+	client := WrapClient(base, NewLogger(config, "test-tls-policy"), config, "test-tls-policy")
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	violations := eventsOfType(readSessionEvents(t, tempDir), "tls_policy_violation")
+	if len(violations) != 1 {
+		t.Fatalf("Expected 1 tls_policy_violation event, got %d", len(violations))
+	}
+	if violations[0]["negotiated_version"] != "TLS 1.2" || violations[0]["min_version"] != "TLS 1.3" {
+		t.Errorf("Unexpected violation: %v", violations[0])
+	}
+}
@@ -9,12 +9,77 @@ type HTTPRequestEvent struct {
 	Type        string            `json:"type"`
 	Timestamp   int64             `json:"timestamp"`
 	SessionID   string            `json:"session_id"`
+	RequestID   string            `json:"request_id,omitempty"`
 	Method      string            `json:"method"`
 	URL         string            `json:"url"`
 	Headers     map[string]string `json:"headers"`
 	Body        string            `json:"body,omitempty"`
 	ContentType string            `json:"content_type,omitempty"`
 	UserAgent   string            `json:"user_agent,omitempty"`
+	Timeout     int64             `json:"timeout_ms,omitempty"`
+
+	// Content-Length of the body sent, when the request declared one
+	RequestSize int64 `json:"request_size,omitempty"`
+
+	// Host sent on the wire, when it differs from the URL host
+	Host string `json:"host,omitempty"`
+
+	// Socket an http+unix request was sent over; the URL also holds the HTTP path
+	UnixSocket string `json:"unix_socket,omitempty"`
+
+	// Method requested by a method override header, when it differs from Method
+	EffectiveMethod string `json:"effective_method,omitempty"`
+
+	// Whether capture happened after other middleware ("innermost") or
+	// before it ("outermost"); unset when the placement was not declared
+	ChainPosition string `json:"chain_position,omitempty"`
+
+	// Set on attempts made by DoWithRetry: the ID shared by its attempts and
+	// the attempt number, counting from 0
+	RetryID      string `json:"retry_id,omitempty"`
+	RetryAttempt *int   `json:"retry_attempt,omitempty"`
+
+	// Copies of the headers named in PromoteHeaders, keyed by lower-cased name
+	PromotedHeaders map[string]string `json:"promoted_headers,omitempty"`
+
+	// Every value of every header, with PreserveHeaderMultiValues
+	HeadersMulti map[string][]string `json:"headers_multi,omitempty"`
+
+	// Reconstructed request line, e.g. "GET /path?x=1 HTTP/1.1"
+	RawRequestLine string `json:"raw_request_line,omitempty"`
+
+	// Time since the previous request in the session started, 0 for the first
+	GapSincePrev *int64 `json:"gap_since_prev_ms,omitempty"`
+
+	// Operation of a GraphQL request
+	GraphQL *GraphQLInfo `json:"graphql,omitempty"`
+
+	// Parsed Accept, Accept-Encoding and Accept-Language with their q-factors
+	ContentNegotiation *ContentNegotiation `json:"content_negotiation,omitempty"`
+
+	// Hash of the request's structure, shared by requests differing only in values
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	// Comparison with the baseline session, when one is configured
+	MatchesBaseline *bool    `json:"matches_baseline,omitempty"`
+	BaselineDiff    []string `json:"baseline_diff,omitempty"`
+
+	BodyEncoding         string `json:"body_encoding,omitempty"`
+	BodyOriginalSize     int64  `json:"body_original_size,omitempty"`
+	BodySuppressedBudget bool   `json:"body_suppressed_budget,omitempty"`
+
+	// Set when a body cut at MaxBodySize was dropped because it could not be redacted
+	BodyRedactionFailed bool `json:"body_redaction_failed,omitempty"`
+
+	// SHA-256 and length of the stored body, when an IntegrityKey is set
+	BodySHA256 string `json:"body_sha256,omitempty"`
+	BodyLength int    `json:"body_length,omitempty"`
+
+	// Original charset of a body stored transcoded to UTF-8
+	Charset string `json:"charset,omitempty"`
+
+	// Fields from the EventEnricher, merged into the top level when marshaled
+	Extra map[string]interface{} `json:"-"`
 }
 
 // HTTPResponseEvent represents an HTTP response event
@@ -22,6 +87,7 @@ type HTTPResponseEvent struct {
 	Type         string            `json:"type"`
 	Timestamp    int64             `json:"timestamp"`
 	SessionID    string            `json:"session_id"`
+	RequestID    string            `json:"request_id,omitempty"`
 	StatusCode   int               `json:"status_code"`
 	Status       string            `json:"status"`
 	Headers      map[string]string `json:"headers"`
@@ -30,22 +96,445 @@ type HTTPResponseEvent struct {
 	ResponseSize int64             `json:"response_size"`
 	Duration     int64             `json:"duration_ms"`
 	Success      bool              `json:"success"`
+
+	// Reconstructed status line, e.g. "HTTP/1.1 200 OK"
+	RawStatusLine string `json:"raw_status_line,omitempty"`
+
+	// Copies of the headers named in PromoteHeaders, keyed by lower-cased name
+	PromotedHeaders map[string]string `json:"promoted_headers,omitempty"`
+
+	// Every value of every header, with PreserveHeaderMultiValues
+	HeadersMulti map[string][]string `json:"headers_multi,omitempty"`
+
+	// The DoWithRetry attempt this response answers, as on the request event
+	RetryID      string `json:"retry_id,omitempty"`
+	RetryAttempt *int   `json:"retry_attempt,omitempty"`
+
+	// Set when the response took longer than SlowRequestThreshold
+	Slow bool `json:"slow,omitempty"`
+
+	// False, with the reason, when the body was deliberately left unread
+	BodyCaptured *bool  `json:"body_captured,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+
+	BodyEncoding         string `json:"body_encoding,omitempty"`
+	BodyOriginalSize     int64  `json:"body_original_size,omitempty"`
+	BodySuppressedBudget bool   `json:"body_suppressed_budget,omitempty"`
+
+	// Set when a body cut at MaxBodySize was dropped because it could not be redacted
+	BodyRedactionFailed bool `json:"body_redaction_failed,omitempty"`
+
+	// SHA-256 and length of the stored body, when an IntegrityKey is set
+	BodySHA256 string `json:"body_sha256,omitempty"`
+	BodyLength int    `json:"body_length,omitempty"`
+
+	// Original charset of a body stored transcoded to UTF-8
+	Charset string `json:"charset,omitempty"`
+
+	// Fields from the EventEnricher, merged into the top level when marshaled
+	Extra map[string]interface{} `json:"-"`
+
+	// Time spent waiting for 100 Continue on Expect: 100-continue requests
+	Wait100Continue *int64 `json:"wait_100_continue_ms,omitempty"`
+
+	// Time to first response byte, and for streaming responses the time from
+	// the first byte until the body was closed
+	TTFB           *int64 `json:"ttfb_ms,omitempty"`
+	StreamDuration *int64 `json:"stream_duration_ms,omitempty"`
+
+	// Connections the transport asked for; above 1 when it retried on a dead connection
+	ConnectionAttempts int `json:"connection_attempts,omitempty"`
+
+	// Whether the request went out on a reused connection, and how many traced
+	// requests, this one included, were in flight on it when it was sent
+	ConnectionReused   bool `json:"connection_reused,omitempty"`
+	ConnectionInFlight int  `json:"connection_in_flight,omitempty"`
+
+	RateLimit *RateLimitInfo `json:"rate_limit,omitempty"`
+
+	// CDN or proxy cache outcome from Age, X-Cache, CF-Cache-Status and Cache-Control
+	Cache *CacheInfo `json:"cache,omitempty"`
+
+	// Whether the request carried If-None-Match or If-Modified-Since, whether
+	// the server answered 304 Not Modified, and whether a caching RoundTripper
+	// beneath the tracer served the response
+	ConditionalRequest bool `json:"conditional_request,omitempty"`
+	Revalidated        bool `json:"revalidated,omitempty"`
+	FromCache          bool `json:"from_cache,omitempty"`
+
+	// Addresses the hostname resolved to, with EnableHTTPTrace
+	DNS *DNSInfo `json:"dns,omitempty"`
+
+	// Rough network round-trip time, with EnableHTTPTrace, when the request
+	// opened a new connection
+	EstimatedRTT *int64 `json:"estimated_rtt_ms,omitempty"`
+
+	// Bytes read from the body after transfer decoding, and the content encoding
+	DecodedSize int64  `json:"decoded_size,omitempty"`
+	Compression string `json:"compression,omitempty"`
+
+	// Set when the stored body was decoded from the Compression the caller received
+	BodyDecoded bool `json:"body_decoded,omitempty"`
+
+	// Compressed and uncompressed body sizes and their ratio, when both are known
+	CompressedSize   int64   `json:"compressed_size,omitempty"`
+	UncompressedSize int64   `json:"uncompressed_size,omitempty"`
+	CompressionRatio float64 `json:"compression_ratio,omitempty"`
+
+	// Media type sniffed from the body when it disagrees with Content-Type
+	DetectedContentType string `json:"detected_content_type,omitempty"`
+
+	// HTTP versions of the request and response, and for TLS connections the
+	// TLS version and ALPN protocol, with CaptureTLSInfo
+	RequestProto       string `json:"request_proto,omitempty"`
+	Proto              string `json:"proto,omitempty"`
+	TLSVersion         string `json:"tls_version,omitempty"`
+	NegotiatedProtocol string `json:"negotiated_protocol,omitempty"`
+}
+
+// TracerInternalErrorEvent records a panic recovered in the tracer's capture
+// or logging code. The traced request itself is unaffected.
+type TracerInternalErrorEvent struct {
+	Type      string `json:"type"`
+	Timestamp int64  `json:"timestamp"`
+	SessionID string `json:"session_id"`
+	RequestID string `json:"request_id,omitempty"`
+	Stage     string `json:"stage"`
+	Panic     string `json:"panic"`
+	Stack     string `json:"stack"`
+}
+
+// ProxyConnectEvent records the CONNECT tunnel set up through a proxy for an
+// HTTPS request. Duration runs from dialing the proxy until the tunnel is up.
+type ProxyConnectEvent struct {
+	Type      string `json:"type"`
+	Timestamp int64  `json:"timestamp"`
+	SessionID string `json:"session_id"`
+	RequestID string `json:"request_id,omitempty"`
+	Proxy     string `json:"proxy"`
+	Target    string `json:"target"`
+	Duration  int64  `json:"duration_ms"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
+// TLSPolicyViolationEvent records a connection that negotiated a TLS version
+// below MinTLSVersion, which only happens with transports the tracer cannot configure
+type TLSPolicyViolationEvent struct {
+	Type              string `json:"type"`
+	Timestamp         int64  `json:"timestamp"`
+	SessionID         string `json:"session_id"`
+	RequestID         string `json:"request_id,omitempty"`
+	Host              string `json:"host"`
+	NegotiatedVersion string `json:"negotiated_version"`
+	MinVersion        string `json:"min_version"`
+}
+
+// RedirectLoopEvent records a redirect chain that came back to a URL it had
+// already visited. Cycle runs from the first visit to the repeat, inclusive;
+// Hop counts the redirects before the repeated request.
+type RedirectLoopEvent struct {
+	Type      string   `json:"type"`
+	Timestamp int64    `json:"timestamp"`
+	SessionID string   `json:"session_id"`
+	RequestID string   `json:"request_id,omitempty"`
+	Cycle     []string `json:"cycle"`
+	Hop       int      `json:"hop"`
+}
+
+// HTTPInformationalEvent represents an interim 1xx response received before the final response
+type HTTPInformationalEvent struct {
+	Type       string            `json:"type"`
+	Timestamp  int64             `json:"timestamp"`
+	SessionID  string            `json:"session_id"`
+	RequestID  string            `json:"request_id,omitempty"`
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers"`
+}
+
+// HTTPEffectiveRequestEvent represents the request as written to the wire for one hop,
+// after redirects, cookie-jar application and transport defaults
+type HTTPEffectiveRequestEvent struct {
+	Type           string            `json:"type"`
+	Timestamp      int64             `json:"timestamp"`
+	SessionID      string            `json:"session_id"`
+	RequestID      string            `json:"request_id,omitempty"`
+	Hop            int               `json:"hop"`
+	Method         string            `json:"method"`
+	URL            string            `json:"url"`
+	Headers        map[string]string `json:"headers"`
+	RedirectedFrom string            `json:"redirected_from,omitempty"`
+}
+
+// LLMStreamCompleteEvent represents the reconstructed output of a streamed LLM completion
+type LLMStreamCompleteEvent struct {
+	Type             string `json:"type"`
+	Timestamp        int64  `json:"timestamp"`
+	SessionID        string `json:"session_id"`
+	RequestID        string `json:"request_id,omitempty"`
+	Provider         string `json:"provider"`
+	Model            string `json:"model,omitempty"`
+	Text             string `json:"text"`
+	TokenCount       int    `json:"token_count"`
+	TokenCountSource string `json:"token_count_source"`
+	FrameCount       int    `json:"frame_count"`
+	FinishReason     string `json:"finish_reason,omitempty"`
+	Truncated        bool   `json:"truncated,omitempty"`
+}
+
+// RetryAttempt describes a single attempt made by DoWithRetry
+type RetryAttempt struct {
+	Attempt    int    `json:"attempt"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Duration   int64  `json:"duration_ms"`
+	Backoff    int64  `json:"backoff_ms"`
+}
+
+// HTTPRequestWithRetriesEvent consolidates every attempt of a retried request
+type HTTPRequestWithRetriesEvent struct {
+	Type          string             `json:"type"`
+	Timestamp     int64              `json:"timestamp"`
+	SessionID     string             `json:"session_id"`
+	RequestID     string             `json:"request_id"`
+	Method        string             `json:"method"`
+	URL           string             `json:"url"`
+	Attempts      []RetryAttempt     `json:"attempts"`
+	Outcome       string             `json:"outcome"`
+	FinalRequest  *HTTPRequestEvent  `json:"final_request,omitempty"`
+	FinalResponse *HTTPResponseEvent `json:"final_response,omitempty"`
+	FinalError    string             `json:"final_error,omitempty"`
+	TotalDuration int64              `json:"total_duration_ms"`
+}
+
+// RetriesExhaustedEvent is written when DoWithRetry gives up after its last
+// attempt failed. RetryID matches the attempts' request and response events.
+type RetriesExhaustedEvent struct {
+	Type            string `json:"type"`
+	Timestamp       int64  `json:"timestamp"`
+	SessionID       string `json:"session_id"`
+	RetryID         string `json:"retry_id"`
+	Method          string `json:"method"`
+	URL             string `json:"url"`
+	Attempts        int    `json:"attempts"`
+	FinalStatusCode int    `json:"final_status_code,omitempty"`
+	FinalError      string `json:"final_error,omitempty"`
+}
+
+// RetryBudgetExhaustedEvent is written when DoWithRetry first refuses a retry
+// because the session's RetryBudget is spent
+type RetryBudgetExhaustedEvent struct {
+	Type       string `json:"type"`
+	Timestamp  int64  `json:"timestamp"`
+	SessionID  string `json:"session_id"`
+	RetryID    string `json:"retry_id"`
+	Method     string `json:"method"`
+	URL        string `json:"url"`
+	Budget     int    `json:"budget"`
+	WindowMs   int64  `json:"window_ms,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// HTTPCanceledEvent is written instead of an error event when the caller
+// canceled a request's context before it completed
+type HTTPCanceledEvent struct {
+	Type      string `json:"type"`
+	Timestamp int64  `json:"timestamp"`
+	SessionID string `json:"session_id"`
+	RequestID string `json:"request_id"`
+	Method    string `json:"method"`
+	URL       string `json:"url"`
+	Duration  int64  `json:"duration_ms"`
+	Error     string `json:"error"`
+}
+
+// StartupHealthEvent records the connectivity probe made on client creation
+type StartupHealthEvent struct {
+	Type       string `json:"type"`
+	Timestamp  int64  `json:"timestamp"`
+	SessionID  string `json:"session_id"`
+	URL        string `json:"url"`
+	Reachable  bool   `json:"reachable"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Latency    int64  `json:"latency_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// BodyHashEvent records SHA-256 digests of the bodies of one request
+type BodyHashEvent struct {
+	Type               string `json:"type"`
+	Timestamp          int64  `json:"timestamp"`
+	SessionID          string `json:"session_id"`
+	RequestID          string `json:"request_id"`
+	RequestBodySHA256  string `json:"request_body_sha256,omitempty"`
+	RequestBodySize    int64  `json:"request_body_size,omitempty"`
+	ResponseBodySHA256 string `json:"response_body_sha256,omitempty"`
+	ResponseBodySize   int64  `json:"response_body_size,omitempty"`
+}
+
+// SessionSummary aggregates statistics for a session
+type SessionSummary struct {
+	EventsWritten int64 `json:"events_written"`
+	EventsDropped int64 `json:"events_dropped"`
+	BytesWritten  int64 `json:"bytes_written"`
+
+	// Lowest remaining quota observed, by provider and limit kind (requests, tokens, ...)
+	RateLimitMinRemaining map[string]map[string]int64 `json:"rate_limit_min_remaining,omitempty"`
+
+	// Slowest requests, slowest first, when SlowRequestThreshold is set
+	SlowestRequests []SlowRequest `json:"slowest_requests,omitempty"`
+
+	// Requests logged per request fingerprint
+	RequestsByFingerprint map[string]int64 `json:"requests_by_fingerprint,omitempty"`
+}
+
+// SessionSummaryEvent is written when the logger is closed
+type SessionSummaryEvent struct {
+	Type      string `json:"type"`
+	Timestamp int64  `json:"timestamp"`
+	SessionID string `json:"session_id"`
+	SessionSummary
 }
 
 // TracingConfig holds configuration for the tracing client
 type TracingConfig struct {
 	Enabled              bool          `json:"enabled"`
 	OutputDir            string        `json:"output_dir"`
+	CreateOutputDir      bool          `json:"create_output_dir"`
 	MaxBodySize          int64         `json:"max_body_size"`
 	CaptureRequestBodies bool          `json:"capture_request_bodies"`
 	CaptureResponseBodies bool         `json:"capture_response_bodies"`
 	SensitiveHeaders     []string      `json:"sensitive_headers"`
 	Timeout              time.Duration `json:"timeout"`
 	MaxRetries           int           `json:"max_retries"`
+	RetryLoggingMode     string        `json:"retry_logging_mode"`
+	EnableHTTPTrace      bool          `json:"enable_httptrace"`
+	CaptureRawLines      bool          `json:"capture_raw_lines"`
+	AppendUserAgent      bool          `json:"append_user_agent"`
+	FsyncOnWrite         bool          `json:"fsync_on_write"`
+	HashBodies           bool          `json:"hash_bodies"`
+	MinFreeDiskBytes     int64         `json:"min_free_disk_bytes"`
+	AsyncWrite           bool          `json:"async_write"`
+	AsyncQueueSize       int           `json:"async_queue_size"`
+	AsyncOverflowPolicy  string        `json:"async_overflow_policy"`
+	MaxSessionBytes      int64         `json:"max_session_bytes"`
+	MaxSessionBytesHard  int64         `json:"max_session_bytes_hard"`
+	MaxRequests          int           `json:"max_requests"`
+
+	// Inline compression of large bodies
+	BodyCompression          string `json:"body_compression"`
+	BodyCompressionThreshold int64  `json:"body_compression_threshold"`
+
+	// Body sampling for responses larger than MaxBodySize
+	BodySampleMode      string `json:"body_sample_mode"`
+	BodySampleTailBytes int64  `json:"body_sample_tail_bytes"`
+
+	// JSONPath expressions (e.g. $.data.secret) redacted from JSON response bodies
+	RedactResponseJSONPaths []string `json:"redact_response_json_paths"`
+
+	// Log the request each hop actually sent as http_request_effective
+	CaptureEffectiveRequests bool `json:"capture_effective_requests"`
+
+	// One-time connectivity probe on client creation
+	HealthCheckURL     string        `json:"health_check_url"`
+	HealthCheckTimeout time.Duration `json:"health_check_timeout"`
+
+	// Headers added to requests for a host ("api.example.com" or "host:port")
+	// unless the caller already set them
+	DefaultHeadersByHost map[string]map[string]string `json:"default_headers_by_host"`
+
+	// Session file or directory that requests are compared against
+	BaselineSession string `json:"baseline_session"`
+
+	// Lowest TLS version the transport may negotiate: "1.0", "1.1", "1.2" or "1.3"
+	MinTLSVersion string `json:"min_tls_version"`
+
+	// Longest JSONL line written; longer events lose body content first
+	MaxLineBytes int `json:"max_line_bytes"`
+
+	// Keep GraphQL variable values in request bodies instead of redacting them
+	CaptureGraphQLVariables bool `json:"capture_graphql_variables"`
+
+	// Subdirectory of OutputDir that holds sessions/, for tools sharing a directory
+	OutputNamespace string `json:"output_namespace"`
+
+	// Fail requests whose request event cannot be written instead of sending them untraced
+	RequireTracing bool `json:"require_tracing"`
+
+	// Headers (case-insensitive) also copied into promoted_headers on request and response events
+	PromoteHeaders []string `json:"promote_headers"`
+
+	// Responses slower than this are flagged slow; 0 disables slow-request tracking
+	SlowRequestThreshold time.Duration `json:"slow_request_threshold"`
+
+	// Report notable events, such as slow requests, on stderr
+	Verbose bool `json:"verbose"`
+
+	// Response statuses whose bodies are captured, e.g. [401, "5xx"]; when
+	// set, overrides CaptureResponseBodies
+	CaptureBodyStatusCodes StatusPatterns `json:"capture_body_status_codes"`
+
+	// Record every header value in headers_multi, not just the first in headers
+	PreserveHeaderMultiValues bool `json:"preserve_header_multi_values"`
+
+	// Base64-encoded key for the hmac added to every event, and body digests
+	// on request and response events; integrity fields are off when empty
+	IntegrityKey string `json:"integrity_key"`
+
+	// How header values that are not valid UTF-8 are stored: "replace" or "base64"
+	NonUTF8HeaderMode string `json:"non_utf8_header_mode"`
+
+	// One NDJSON file all sessions append to, with an index sidecar of session offsets
+	AggregateFile string `json:"aggregate_file"`
+
+	// Regular expressions; DoWithRetry retries a response whose body matches one
+	RetryOnBodyPatterns []string `json:"retry_on_body_patterns"`
+
+	// URL path segments replaced with [MASKED] in events, by 0-based position or by regex
+	MaskPathSegments []int    `json:"mask_path_segments"`
+	PathMaskPatterns []string `json:"path_mask_patterns"`
+
+	// Record HTTP versions and the negotiated TLS version and ALPN protocol on response events
+	CaptureTLSInfo bool `json:"capture_tls_info"`
+
+	// How events are written: "raw", or "cloudevents" to wrap each in a CloudEvents 1.0 envelope
+	EventFormat string `json:"event_format"`
+
+	// IANA time zone RFC 3339 timestamps are rendered in, UTC when empty
+	TimeZone string `json:"time_zone"`
+
+	// Response media types, e.g. text/event-stream, logged without reading their bodies
+	StreamingContentTypes []string `json:"streaming_content_types"`
+
+	// Where sessions are written, local files under OutputDir when nil
+	SessionStore SessionStore `json:"-"`
+
+	// Retries DoWithRetry may make across the session, unlimited when 0; with a
+	// window the budget refills at that many retries per window
+	RetryBudget       int           `json:"retry_budget"`
+	RetryBudgetWindow time.Duration `json:"retry_budget_window"`
+
+	// Response headers recorded in events, all when empty; an entry ending in * is a prefix
+	ResponseHeaderAllowList []string `json:"response_header_allow_list"`
+
+	// Browser origins, besides the feed's own host, allowed to open the ServeLive feed
+	LiveAllowedOrigins []string `json:"live_allowed_origins"`
+
+	// Per-URL body capture, first match wins; URLs matching no rule use the global flags
+	BodyCaptureRules []BodyCaptureRule `json:"body_capture_rules"`
+
+	// Fraction of operations traced, between 0 and 1; a request, its redirects
+	// and its retries are kept or left out together. 0 traces everything.
+	SampleRate float64 `json:"sample_rate"`
+
+	// Add the hostname and pid of the traced process to every event
+	IncludeHostInfo bool `json:"include_host_info"`
 }
 
 // RequestCapture holds captured request data
 type RequestCapture struct {
+	RequestID   string
 	StartTime   time.Time
 	Method      string
 	URL         string
@@ -53,10 +542,31 @@ type RequestCapture struct {
 	Body        []byte
 	ContentType string
 	UserAgent   string
+	Timeout     time.Duration
+	Host        string
+	UnixSocket  string
+
+	// Body holds only the first MaxBodySize bytes of a longer body
+	BodyTruncated bool
+
+	// Declared Content-Length of the body, 0 when unknown
+	RequestSize int64
+
+	EffectiveMethod string
+	MultiHeaders    map[string][]string
+	ChainPosition   string
+
+	Retry *retryAttemptInfo
+
+	RawRequestLine string
+
+	Extra map[string]interface{}
 }
 
 // ResponseCapture holds captured response data
 type ResponseCapture struct {
+	RequestID    string
+	URL          string
 	EndTime      time.Time
 	StatusCode   int
 	Status       string
@@ -66,4 +576,61 @@ type ResponseCapture struct {
 	ResponseSize int64
 	Duration     time.Duration
 	Success      bool
+
+	Waited100Continue bool
+	Wait100Continue   time.Duration
+
+	HasTTFB        bool
+	TTFB           time.Duration
+	BodyClosed     time.Time
+	Streamed       bool
+	StreamDuration time.Duration
+
+	DecodedSize int64
+	Compression string
+	BodyDecoded bool
+
+	// Body holds only the first MaxBodySize bytes of a longer body
+	BodyTruncated bool
+
+	// With head/tail sampling, the end of the body and how many bytes lie
+	// between it and Body; sampledBody fills them in once the body is read
+	BodyTail    []byte
+	BodyOmitted int64
+	sampledBody *sampledBody
+
+	// Why the body was left unread, e.g. bodyNotCapturedStreaming
+	BodyNotCapturedReason string
+
+	ConditionalRequest bool
+	Revalidated        bool
+	FromCache          bool
+
+	// Body sizes before and after decompression, set only when the whole body was read
+	CompressedSize   int64
+	UncompressedSize int64
+
+	MultiHeaders map[string][]string
+
+	ConnectionAttempts int
+
+	ConnectionReused   bool
+	ConnectionInFlight int
+
+	Retry *retryAttemptInfo
+
+	DNS *DNSInfo
+
+	EstimatedRTT *int64
+
+	RequestProto       string
+	Proto              string
+	TLSVersion         string
+	NegotiatedProtocol string
+
+	DetectedContentType string
+
+	RawStatusLine string
+
+	Extra map[string]interface{}
 }
\ No newline at end of file
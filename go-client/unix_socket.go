@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UnixSocketScheme is the URL scheme of requests sent over a Unix socket. The
+// path names the socket, then after a colon the HTTP path, as in
+// http+unix:///var/run/daemon.sock:/v1/status?verbose=1. Socket paths may not
+// contain a colon.
+const UnixSocketScheme = "http+unix"
+
+// UnixSocketDialer connects to the Unix socket at socketPath
+type UnixSocketDialer func(ctx context.Context, socketPath string) (net.Conn, error)
+
+// dialUnixSocket is the default UnixSocketDialer
+func dialUnixSocket(ctx context.Context, socketPath string) (net.Conn, error) {
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "unix", socketPath)
+}
+
+// splitUnixSocketURL returns the socket path and the escaped HTTP path of an
+// http+unix URL
+func splitUnixSocketURL(u *url.URL) (socketPath, httpPath string, ok bool) {
+	if u == nil || u.Scheme != UnixSocketScheme {
+		return "", "", false
+	}
+
+	escapedSocket, httpPath, found := strings.Cut(u.EscapedPath(), ":")
+	socketPath, err := url.PathUnescape(escapedSocket)
+	if !found || err != nil || socketPath == "" {
+		return "", "", false
+	}
+	if httpPath == "" {
+		httpPath = "/"
+	}
+	return socketPath, httpPath, true
+}
+
+// unixSocketTransport sends http+unix requests as plain HTTP over their
+// socket, with one pooled transport per socket
+type unixSocketTransport struct {
+	mu         sync.Mutex
+	dial       UnixSocketDialer
+	transports map[string]*http.Transport
+}
+
+// newClientTransport returns the transport TracingHTTPClient sends through:
+// a copy of http.DefaultTransport that also handles http+unix URLs
+func newClientTransport(unixSockets *unixSocketTransport) http.RoundTripper {
+	base, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return http.DefaultTransport
+	}
+
+	transport := base.Clone()
+	transport.RegisterProtocol(UnixSocketScheme, unixSockets)
+	return transport
+}
+
+// RoundTrip implements http.RoundTripper for http+unix requests
+func (u *unixSocketTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	socketPath, httpPath, ok := splitUnixSocketURL(req.URL)
+	if !ok {
+		return nil, fmt.Errorf("%s URL %q names no socket, expected %s:///path/to.sock:/http/path", UnixSocketScheme, req.URL, UnixSocketScheme)
+	}
+
+	path, err := url.PathUnescape(httpPath)
+	if err != nil {
+		return nil, err
+	}
+
+	outgoing := req.Clone(req.Context())
+	outgoing.URL.Scheme = "http"
+	outgoing.URL.Host = "localhost"
+	outgoing.URL.Path = path
+	outgoing.URL.RawPath = httpPath
+	if outgoing.Host == "" {
+		outgoing.Host = "localhost"
+	}
+
+	resp, err := u.transport(socketPath).RoundTrip(outgoing)
+	if resp != nil {
+		// Report the URL the caller asked for, socket included
+		resp.Request = req
+	}
+	return resp, err
+}
+
+// transport returns the pooled transport dialing socketPath
+func (u *unixSocketTransport) transport(socketPath string) *http.Transport {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if transport, ok := u.transports[socketPath]; ok {
+		return transport
+	}
+
+	dial := u.dial
+	if dial == nil {
+		dial = dialUnixSocket
+	}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dial(ctx, socketPath)
+		},
+		MaxIdleConns:    10,
+		IdleConnTimeout: 90 * time.Second,
+	}
+	if u.transports == nil {
+		u.transports = make(map[string]*http.Transport)
+	}
+	u.transports[socketPath] = transport
+	return transport
+}
+
+// setDialer replaces the dialer; pooled connections of the old one are closed
+func (u *unixSocketTransport) setDialer(dial UnixSocketDialer) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.dial = dial
+	for _, transport := range u.transports {
+		transport.CloseIdleConnections()
+	}
+	u.transports = nil
+}
+
+// WithUnixSocketDialer sets how http+unix requests connect to their socket
+// and returns the client
+func (t *TracingHTTPClient) WithUnixSocketDialer(dial UnixSocketDialer) *TracingHTTPClient {
+	t.unixSockets.setDialer(dial)
+	return t
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// serveUnixSocket serves HTTP on a socket in dir, answering with the request
+// path and query
+func serveUnixSocket(t *testing.T, dir string) string {
+	t.Helper()
+
+	socketPath := filepath.Join(dir, "daemon.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("Unix sockets unavailable: %v", err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.RequestURI()))
+	})}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return socketPath
+}
+
+func TestUnixSocketRequestTraced(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-unix-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socketPath := serveUnixSocket(t, tempDir)
+	client := NewTracingHTTPClientWithConfig("test-unix", newTestConfig(tempDir))
+
+	target := UnixSocketScheme + "://" + socketPath + ":/v1/status?verbose=1"
+	resp, err := client.Get(target)
+	if err != nil {
+		t.Fatalf("Request over the socket failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	client.Close()
+
+	if string(body) != "/v1/status?verbose=1" {
+		t.Errorf("Expected the server to see /v1/status?verbose=1, got %q", body)
+	}
+
+	events := readSessionEvents(t, tempDir)
+	requests := eventsOfType(events, "http_request")
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request event, got %d", len(requests))
+	}
+	if requests[0]["url"] != target {
+		t.Errorf("Expected url %s, got %v", target, requests[0]["url"])
+	}
+	if requests[0]["unix_socket"] != socketPath {
+		t.Errorf("Expected unix_socket %s, got %v", socketPath, requests[0]["unix_socket"])
+	}
+
+	responses := eventsOfType(events, "http_response")
+	if len(responses) != 1 || responses[0]["status_code"] != float64(http.StatusOK) {
+		t.Errorf("Expected a 200 response event, got %v", responses)
+	}
+}
+
+func TestUnixSocketDialer(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-unix-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socketPath := serveUnixSocket(t, tempDir)
+	client := NewTracingHTTPClientWithConfig("test-unix-dialer", newTestConfig(tempDir))
+	defer client.Close()
+
+	// The dialer chooses the socket, whatever the URL names
+	var dials atomic.Int32
+	client.WithUnixSocketDialer(func(ctx context.Context, path string) (net.Conn, error) {
+		dials.Add(1)
+		return dialUnixSocket(ctx, socketPath)
+	})
+
+	resp, err := client.Get(UnixSocketScheme + "://" + filepath.Join(tempDir, "alias.sock") + ":/ping")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if dials.Load() != 1 {
+		t.Errorf("Expected the custom dialer to be used once, got %d", dials.Load())
+	}
+}
+
+func TestUnixSocketURLWithoutSocket(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-unix-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	client := NewTracingHTTPClientWithConfig("test-unix-invalid", newTestConfig(tempDir))
+	defer client.Close()
+
+	if _, err := client.Get(UnixSocketScheme + ":///v1/status"); err == nil {
+		t.Error("Expected an error for an http+unix URL naming no socket")
+	}
+}
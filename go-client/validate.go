@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Validate reports configuration values that are out of range or not recognized
+func (c *TracingConfig) Validate() error {
+	var errs []error
+
+	nonNegative := map[string]int64{
+		"max_body_size":              c.MaxBodySize,
+		"timeout":                    int64(c.Timeout),
+		"max_retries":                int64(c.MaxRetries),
+		"min_free_disk_bytes":        c.MinFreeDiskBytes,
+		"async_queue_size":           int64(c.AsyncQueueSize),
+		"max_session_bytes":          c.MaxSessionBytes,
+		"max_session_bytes_hard":     c.MaxSessionBytesHard,
+		"max_requests":               int64(c.MaxRequests),
+		"body_compression_threshold": c.BodyCompressionThreshold,
+		"body_sample_tail_bytes":     c.BodySampleTailBytes,
+		"health_check_timeout":       int64(c.HealthCheckTimeout),
+		"slow_request_threshold":     int64(c.SlowRequestThreshold),
+		"max_line_bytes":             int64(c.MaxLineBytes),
+		"retry_budget":               int64(c.RetryBudget),
+		"retry_budget_window":        int64(c.RetryBudgetWindow),
+	}
+	names := make([]string, 0, len(nonNegative))
+	for name := range nonNegative {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if nonNegative[name] < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", name))
+		}
+	}
+
+	if c.MaxLineBytes > 0 && c.MaxLineBytes < minLineBytes {
+		errs = append(errs, fmt.Errorf("max_line_bytes must be 0 (unlimited) or at least %d", minLineBytes))
+	}
+
+	if _, err := c.integrityKey(); err != nil {
+		errs = append(errs, err)
+	}
+
+	errs = append(errs, c.choiceErrors()...)
+
+	// Sensitive headers are matched as substrings, not regular expressions
+	for _, header := range c.SensitiveHeaders {
+		if strings.TrimSpace(header) == "" {
+			errs = append(errs, errors.New("sensitive_headers contains an empty entry, which would redact every header"))
+		} else if strings.ContainsAny(header, `^$*+?()[]{}|\`) {
+			errs = append(errs, fmt.Errorf("sensitive_headers entry %q looks like a regular expression; entries are matched as plain substrings", header))
+		}
+	}
+
+	// The namespace must stay inside the output directory
+	if c.OutputNamespace != "" && !filepath.IsLocal(c.OutputNamespace) {
+		errs = append(errs, fmt.Errorf("output_namespace %q must be a relative path inside output_dir", c.OutputNamespace))
+	}
+
+	for _, pattern := range c.CaptureBodyStatusCodes {
+		if !validStatusPattern(pattern) {
+			errs = append(errs, fmt.Errorf("capture_body_status_codes entry %q is not a status code or class such as 5xx", pattern))
+		}
+	}
+
+	for _, position := range c.MaskPathSegments {
+		if position < 0 {
+			errs = append(errs, fmt.Errorf("mask_path_segments entry %d must not be negative", position))
+		}
+	}
+
+	for _, pattern := range c.PathMaskPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("path_mask_patterns entry %q is not a valid regular expression: %v", pattern, err))
+		}
+	}
+
+	if c.TimeZone != "" {
+		if _, err := time.LoadLocation(c.TimeZone); err != nil {
+			errs = append(errs, fmt.Errorf("time_zone %q is not a valid IANA time zone: %v", c.TimeZone, err))
+		}
+	}
+
+	if c.SampleRate < 0 || c.SampleRate > 1 {
+		errs = append(errs, fmt.Errorf("sample_rate %v must be between 0 and 1", c.SampleRate))
+	}
+
+	for _, rule := range c.BodyCaptureRules {
+		if _, err := regexp.Compile(rule.URLPattern); err != nil {
+			errs = append(errs, fmt.Errorf("body_capture_rules url_pattern %q is not a valid regular expression: %v", rule.URLPattern, err))
+		}
+	}
+
+	for _, pattern := range c.RetryOnBodyPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("retry_on_body_patterns entry %q is not a valid regular expression: %v", pattern, err))
+		}
+	}
+
+	for _, path := range c.RedactResponseJSONPaths {
+		if _, ok := parseJSONPath(path); !ok {
+			errs = append(errs, fmt.Errorf("redact_response_json_paths entry %q is not a supported JSONPath", path))
+		}
+	}
+
+	errs = append(errs, c.sessionStoreConflicts()...)
+
+	return errors.Join(errs...)
+}
+
+// ValidateConfigFile checks a trace config file for unknown keys, values of
+// the wrong type and invalid settings. It returns nil when the file is valid.
+func ValidateConfigFile(path string) []error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{fmt.Errorf("failed to read config file: %w", err)}
+	}
+
+	var errs []error
+	var config TracingConfig
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		if !strings.Contains(err.Error(), "unknown field") {
+			return []error{fmt.Errorf("invalid config file: %w", err)}
+		}
+
+		// Report every unknown key, then validate the rest of the file
+		unknown, err := unknownConfigKeys(data)
+		if err != nil {
+			return []error{fmt.Errorf("invalid config file: %w", err)}
+		}
+		for _, key := range unknown {
+			errs = append(errs, fmt.Errorf("unknown key %q", key))
+		}
+
+		config = TracingConfig{}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return append(errs, fmt.Errorf("invalid config file: %w", err))
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			errs = append(errs, joined.Unwrap()...)
+		} else {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// unknownConfigKeys returns the top-level keys of a config file that do not
+// match any TracingConfig field
+func unknownConfigKeys(data []byte) ([]string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	known := jsonFieldNames(reflect.TypeOf(TracingConfig{}))
+
+	var unknown []string
+	for key := range raw {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// choiceErrors reports the settings whose value is not one of the values
+// they accept. Each falls back to its default, so the logger also warns about
+// them when it starts.
+func (c *TracingConfig) choiceErrors() []error {
+	choices := []struct {
+		name    string
+		value   string
+		allowed []string
+	}{
+		{"retry_logging_mode", c.RetryLoggingMode, []string{RetryLoggingFlat, RetryLoggingNested}},
+		{"body_sample_mode", c.BodySampleMode, []string{BodySamplePrefix, BodySampleHeadTail}},
+		{"async_overflow_policy", c.AsyncOverflowPolicy, []string{OverflowBlock, OverflowDropOldest, OverflowDropNewest}},
+		{"body_compression", c.BodyCompression, []string{BodyCompressionNone, BodyCompressionGzip}},
+		{"min_tls_version", c.MinTLSVersion, []string{"1.0", "1.1", "1.2", "1.3"}},
+		{"non_utf8_header_mode", c.NonUTF8HeaderMode, []string{NonUTF8HeaderReplace, NonUTF8HeaderBase64}},
+		{"event_format", c.EventFormat, []string{EventFormatRaw, EventFormatCloudEvents}},
+	}
+
+	var errs []error
+	for _, choice := range choices {
+		if choice.value == "" {
+			continue
+		}
+		if !containsString(choice.allowed, choice.value) {
+			errs = append(errs, fmt.Errorf("%s %q is not one of %s", choice.name, choice.value, strings.Join(choice.allowed, ", ")))
+		}
+	}
+	return errs
+}
+
+// sessionStoreConflicts reports the settings that only work with the default
+// file store. The aggregate index is a local file beside the aggregate file,
+// and the free space check looks at the local output directory.
+func (c *TracingConfig) sessionStoreConflicts() []error {
+	if c.SessionStore == nil {
+		return nil
+	}
+
+	var errs []error
+	if c.AggregateFile != "" {
+		errs = append(errs, errors.New("aggregate_file needs the default file store and cannot be used with a custom session store"))
+	}
+	if c.MinFreeDiskBytes > 0 {
+		errs = append(errs, errors.New("min_free_disk_bytes checks the local output_dir and cannot be used with a custom session store"))
+	}
+	return errs
+}
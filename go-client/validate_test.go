@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfigFile writes a config file into dir and returns its path
+func writeConfigFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidateConfigFile(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-validate-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name: "valid",
+			content: `{
+  "enabled": true,
+  "output_dir": "./custom-trace",
+  "max_body_size": 2048,
+  "max_retries": 2,
+  "retry_logging_mode": "nested",
+  "sensitive_headers": ["authorization", "x-api-key"],
+  "redact_response_json_paths": ["$.data.secret", "$.items[*].token"]
+}`,
+		},
+		{
+			name:     "unknown keys",
+			content:  `{"enabled": true, "out_dir": "./trace", "max_body_sise": 10}`,
+			expected: []string{`unknown key "max_body_sise"`, `unknown key "out_dir"`},
+		},
+		{
+			name:    "bad patterns",
+			content: `{"sensitive_headers": ["x-api-key", "x-(secret|token"], "redact_response_json_paths": ["data.secret"]}`,
+			expected: []string{
+				`sensitive_headers entry "x-(secret|token" looks like a regular expression`,
+				`redact_response_json_paths entry "data.secret" is not a supported JSONPath`,
+			},
+		},
+		{
+			name:     "bad values",
+			content:  `{"max_retries": -1, "body_compression": "zstd"}`,
+			expected: []string{"max_retries must not be negative", `body_compression "zstd" is not one of none, gzip`},
+		},
+		{
+			name:     "namespace outside output dir",
+			content:  `{"output_namespace": "../other-tool"}`,
+			expected: []string{`output_namespace "../other-tool" must be a relative path inside output_dir`},
+		},
+		{
+			name:     "wrong type",
+			content:  `{"timeout": "10s"}`,
+			expected: []string{"invalid config file"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, tempDir, strings.ReplaceAll(tt.name, " ", "-")+".json", tt.content)
+			errs := ValidateConfigFile(path)
+
+			if len(errs) != len(tt.expected) {
+				t.Fatalf("Expected %d errors, got %d: %v", len(tt.expected), len(errs), errs)
+			}
+			for i, expected := range tt.expected {
+				if !strings.Contains(errs[i].Error(), expected) {
+					t.Errorf("Error %d: expected %q, got %q", i, expected, errs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateCommand(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-validate-cmd-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	valid := writeConfigFile(t, tempDir, "valid.json", `{"enabled": true}`)
+	invalid := writeConfigFile(t, tempDir, "invalid.json", `{"out_dir": "x"}`)
+
+	var stdout, stderr bytes.Buffer
+	if code := runCommand([]string{"validate", valid}, &stdout, &stderr); code != 0 {
+		t.Errorf("Expected exit 0 for a valid file, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "ok") {
+		t.Errorf("Expected ok output, got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := runCommand([]string{"validate", invalid}, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected exit 1 for an invalid file, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), `unknown key "out_dir"`) {
+		t.Errorf("Expected the unknown key to be reported, got %q", stderr.String())
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Warning types
+const (
+	WarningBodyTruncated    = "body_truncated"
+	WarningLineTruncated    = "line_truncated"
+	WarningDecompression    = "decompression_failed"
+	WarningInvalidRedaction = "invalid_redaction_path"
+	WarningBodyRedaction    = "body_redaction_failed"
+	WarningEventDropped     = "event_dropped"
+	WarningSlowRequest      = "slow_request"
+	WarningInvalidConfig    = "invalid_config"
+)
+
+// warningBufferSize is how many warnings wait for a reader before new ones
+// are counted but not delivered
+const warningBufferSize = 100
+
+// Warning is a non-fatal tracer issue: the request went through, but its
+// trace is incomplete or altered
+type Warning struct {
+	Type      string    `json:"type"`
+	Message   string    `json:"message"`
+	RequestID string    `json:"request_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Warnings returns the channel warnings are delivered on. The tracer never
+// blocks on it: warnings arriving while the buffer is full are only counted.
+// The channel is never closed.
+func (l *Logger) Warnings() <-chan Warning {
+	return l.warnings
+}
+
+// WarningCount returns how many warnings have been raised, delivered or not
+func (l *Logger) WarningCount() int64 {
+	return l.warningCount.Load()
+}
+
+// warn raises a warning, echoing it to stderr in verbose mode. It is a
+// no-op on a nil logger, as used by bare transports in tests.
+func (l *Logger) warn(kind, requestID, format string, args ...interface{}) {
+	if l == nil {
+		return
+	}
+
+	warning := Warning{
+		Type:      kind,
+		Message:   fmt.Sprintf(format, args...),
+		RequestID: requestID,
+		Timestamp: time.Now(),
+	}
+	l.warningCount.Add(1)
+
+	if l.config.Verbose {
+		fmt.Fprintf(os.Stderr, "opencode-trace: %s: %s\n", kind, warning.Message)
+	}
+
+	select {
+	case l.warnings <- warning:
+	default:
+	}
+}
+
+// Warnings returns the channel the client's tracer warnings are delivered on
+func (t *TracingHTTPClient) Warnings() <-chan Warning {
+	return t.logger.Warnings()
+}
+
+// WarningCount returns how many warnings the client's tracer has raised
+func (t *TracingHTTPClient) WarningCount() int64 {
+	return t.logger.WarningCount()
+}
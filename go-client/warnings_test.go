@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDecompressionFailureRaisesWarning(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "trace-warnings-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write([]byte("this is not gzip"))
+	}))
+	defer server.Close()
+
+	client := NewTracingHTTPClientWithConfig("test-warnings", newTestConfig(tempDir))
+	if resp, err := client.Get(server.URL); err == nil {
+		resp.Body.Close()
+	}
+
+	select {
+	case warning := <-client.Warnings():
+		if warning.Type != WarningDecompression {
+			t.Errorf("Expected a %s warning, got %s: %s", WarningDecompression, warning.Type, warning.Message)
+		}
+		if warning.Message == "" || warning.Timestamp.IsZero() {
+			t.Errorf("Expected a message and timestamp, got %+v", warning)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a warning for the undecodable body")
+	}
+
+	if count := client.WarningCount(); count != 1 {
+		t.Errorf("Expected 1 warning, got %d", count)
+	}
+}
+
+func TestWarningsNeverBlock(t *testing.T) {
+	logger := NewLogger(newTestConfig(os.TempDir()), "test-warnings-full")
+
+	for i := 0; i < warningBufferSize+10; i++ {
+		logger.warn(WarningBodyTruncated, "", "warning %d", i)
+	}
+
+	if count := logger.WarningCount(); count != warningBufferSize+10 {
+		t.Errorf("Expected every warning to be counted, got %d", count)
+	}
+	if queued := len(logger.Warnings()); queued != warningBufferSize {
+		t.Errorf("Expected %d buffered warnings, got %d", warningBufferSize, queued)
+	}
+}
@@ -117,10 +117,15 @@ export class CleanupManager {
   }
 
 
-  async cleanupOldSessions(traceDir: string, olderThanDays: number = 30): Promise<void> {
+  async cleanupOldSessions(
+    traceDir: string,
+    olderThanDays: number = 30,
+    namespace: string = process.env.OPENCODE_TRACE_NAMESPACE || ''
+  ): Promise<void> {
     console.log(chalk.blue(`🗑️  Cleaning up sessions older than ${olderThanDays} days...`));
     
-    const sessionsDir = join(traceDir, 'sessions');
+    // Sessions live under <traceDir>/<namespace>/sessions when a namespace is set
+    const sessionsDir = join(traceDir, namespace, 'sessions');
     
     if (!existsSync(sessionsDir)) {
       return;
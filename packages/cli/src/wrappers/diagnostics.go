@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Diagnostic output modes
+const (
+	DiagnosticsText  = "text"
+	DiagnosticsJSON  = "json"
+	DiagnosticsQuiet = "quiet"
+)
+
+// Diagnostics writes the wrapper's own status and error messages, keeping
+// them out of opencode's output when it is scripted or piped. In text mode
+// status lines go to stdout and problems to stderr, as they always have; json
+// mode writes one JSON object per message to stderr; quiet mode drops them.
+// A nil *Diagnostics behaves like text mode on the process's stdout and stderr.
+type Diagnostics struct {
+	mode   string
+	stdout io.Writer
+	stderr io.Writer
+
+	mu sync.Mutex
+}
+
+// NewDiagnostics creates a diagnostic writer in the given mode
+func NewDiagnostics(mode string, stdout, stderr io.Writer) *Diagnostics {
+	return &Diagnostics{mode: mode, stdout: stdout, stderr: stderr}
+}
+
+// diagnosticsFromEnv builds the diagnostic writer from OPENCODE_TRACE_QUIET,
+// OPENCODE_TRACE_DIAGNOSTICS (text, json or quiet) and
+// OPENCODE_TRACE_DIAGNOSTICS_FILE, which sends every message to a log file.
+// The returned function closes the log file.
+func diagnosticsFromEnv() (*Diagnostics, func(), error) {
+	mode := DiagnosticsText
+	switch strings.ToLower(os.Getenv("OPENCODE_TRACE_DIAGNOSTICS")) {
+	case DiagnosticsJSON:
+		mode = DiagnosticsJSON
+	case DiagnosticsQuiet:
+		mode = DiagnosticsQuiet
+	}
+	if quiet := os.Getenv("OPENCODE_TRACE_QUIET"); quiet == "true" || quiet == "1" {
+		mode = DiagnosticsQuiet
+	}
+
+	path := os.Getenv("OPENCODE_TRACE_DIAGNOSTICS_FILE")
+	if path == "" || mode == DiagnosticsQuiet {
+		return NewDiagnostics(mode, os.Stdout, os.Stderr), func() {}, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return NewDiagnostics(mode, os.Stdout, os.Stderr), func() {}, fmt.Errorf("failed to open diagnostics file: %v", err)
+	}
+	return NewDiagnostics(mode, file, file), func() { file.Close() }, nil
+}
+
+// Infof reports progress, prefixed with symbol in text mode
+func (d *Diagnostics) Infof(symbol, format string, args ...interface{}) {
+	d.write("info", symbol, format, args...)
+}
+
+// Warnf reports a problem the wrapper works around
+func (d *Diagnostics) Warnf(format string, args ...interface{}) {
+	d.write("warn", "⚠️ ", format, args...)
+}
+
+// Errorf reports a failure
+func (d *Diagnostics) Errorf(format string, args ...interface{}) {
+	d.write("error", "❌", format, args...)
+}
+
+// Debugf reports detail that callers only emit with Debug set
+func (d *Diagnostics) Debugf(format string, args ...interface{}) {
+	d.write("debug", "", format, args...)
+}
+
+// write formats one message for the configured mode
+func (d *Diagnostics) write(level, symbol, format string, args ...interface{}) {
+	if d == nil {
+		d = &Diagnostics{mode: DiagnosticsText, stdout: os.Stdout, stderr: os.Stderr}
+	}
+	if d.mode == DiagnosticsQuiet {
+		return
+	}
+
+	message := fmt.Sprintf(format, args...)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.mode == DiagnosticsJSON {
+		data, err := json.Marshal(map[string]string{
+			"time":    time.Now().UTC().Format(time.RFC3339Nano),
+			"level":   level,
+			"message": message,
+		})
+		if err == nil {
+			fmt.Fprintf(d.stderr, "%s\n", data)
+		}
+		return
+	}
+
+	out := d.stderr
+	if level == "info" {
+		out = d.stdout
+	}
+	if symbol != "" {
+		message = symbol + " " + message
+	}
+	fmt.Fprintln(out, message)
+}
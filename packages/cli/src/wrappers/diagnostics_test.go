@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQuietDiagnosticsStillTraces(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tui-quiet-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Keep IPC messages inside the test directory, and undo the injector's env changes
+	t.Setenv("TMPDIR", tempDir)
+	t.Setenv("HTTP_CLIENT_TRACE", "")
+	t.Setenv("OPENCODE_HTTP_TRACE", "")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	sessionID := "quiet-test"
+	config := TracingConfig{
+		OutputDir:   tempDir,
+		Debug:       true,
+		Diagnostics: NewDiagnostics(DiagnosticsQuiet, &stdout, &stderr),
+	}
+
+	coordinator := NewSessionCoordinator(sessionID, config)
+	if err := coordinator.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	client, err := NewTracingHTTPClient(sessionID, config)
+	if err != nil {
+		t.Fatalf("NewTracingHTTPClient failed: %v", err)
+	}
+	if err := injectTracingClient(client); err != nil {
+		t.Fatalf("injectTracingClient failed: %v", err)
+	}
+
+	resp, err := client.client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+	if err := coordinator.Finalize(0, time.Second); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+
+	if stdout.Len() != 0 || stderr.Len() != 0 {
+		t.Errorf("Expected no diagnostic output in quiet mode, got stdout %q, stderr %q", stdout.String(), stderr.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "sessions", sessionID, "session.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"type":"http_request"`) {
+		t.Errorf("Expected the request to be traced, got %q", data)
+	}
+}
+
+func TestDiagnosticsModes(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	text := NewDiagnostics(DiagnosticsText, &stdout, &stderr)
+	text.Infof("🟩", "Initializing Go TUI tracing for session: %s", "abc")
+	text.Warnf("Failed to finalize session: %v", "disk full")
+	text.Errorf("Failed to execute opencode: %v", "not found")
+
+	if stdout.String() != "🟩 Initializing Go TUI tracing for session: abc\n" {
+		t.Errorf("Unexpected text stdout %q", stdout.String())
+	}
+	if stderr.String() != "⚠️  Failed to finalize session: disk full\n❌ Failed to execute opencode: not found\n" {
+		t.Errorf("Unexpected text stderr %q", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	structured := NewDiagnostics(DiagnosticsJSON, &stdout, &stderr)
+	structured.Infof("✅", "Go TUI tracing initialized successfully")
+
+	if stdout.Len() != 0 {
+		t.Errorf("Expected json mode to leave stdout alone, got %q", stdout.String())
+	}
+	var message map[string]string
+	if err := json.Unmarshal(stderr.Bytes(), &message); err != nil {
+		t.Fatalf("Expected a JSON line on stderr, got %q: %v", stderr.String(), err)
+	}
+	if message["level"] != "info" || message["message"] != "Go TUI tracing initialized successfully" || message["time"] == "" {
+		t.Errorf("Unexpected json message %v", message)
+	}
+}
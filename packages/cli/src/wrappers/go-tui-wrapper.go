@@ -2,56 +2,90 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 )
 
 // Main entry point for the Go TUI wrapper
 func main() {
+	// Route the wrapper's own messages as configured, away from opencode's output
+	diagnostics, closeDiagnostics, err := diagnosticsFromEnv()
+	if err != nil {
+		diagnostics.Warnf("%v", err)
+	}
+
 	// Check if tracing is enabled
 	if !isTracingEnabled() {
 		// If tracing is not enabled, just execute opencode normally
-		executeOpenCode(os.Args[1:])
+		executeOpenCode(os.Args[1:], diagnostics, closeDiagnostics)
 		return
 	}
 
 	// Initialize tracing
-	sessionID := getSessionID()
+	sessionID := NewEnvSessionIDResolver().ResolveSessionID()
 	config := getTraceConfig()
+	config.Diagnostics = diagnostics
+
+	// Clear out IPC files that earlier sessions left behind
+	if err := GCStaleIPC(config.IPCMaxAge); err != nil && config.Debug {
+		diagnostics.Debugf("Warning: Failed to remove stale IPC files: %v", err)
+	}
 	
-	fmt.Printf("🟩 Initializing Go TUI tracing for session: %s\n", sessionID)
+	diagnostics.Infof("🟩", "Initializing Go TUI tracing for session: %s", sessionID)
 	
 	// Create session coordinator
 	coordinator := NewSessionCoordinator(sessionID, config)
 	if err := coordinator.Initialize(); err != nil {
-		fmt.Fprintf(os.Stderr, "⚠️  Failed to initialize session coordinator: %v\n", err)
+		diagnostics.Warnf("Failed to initialize session coordinator: %v", err)
 	}
-	defer coordinator.Finalize()
 	
 	// Create tracing client using Plan v1 component
 	tracingClient, err := NewTracingHTTPClient(sessionID, config)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Failed to create tracing client: %v\n", err)
+		diagnostics.Errorf("Failed to create tracing client: %v", err)
 		// Continue without tracing
-		executeOpenCode(os.Args[1:])
-		return
-	}
-	defer tracingClient.Close()
-	
-	// Set up HTTP client injection
-	if err := injectTracingClient(tracingClient); err != nil {
-		fmt.Fprintf(os.Stderr, "⚠️  Failed to inject tracing client: %v\n", err)
+	} else if err := injectTracingClient(tracingClient); err != nil {
+		// Set up HTTP client injection
+		diagnostics.Warnf("Failed to inject tracing client: %v", err)
 		// Continue without tracing
 	} else {
-		fmt.Println("✅ Go TUI tracing initialized successfully")
+		diagnostics.Infof("✅", "Go TUI tracing initialized successfully")
 	}
 	
-	// Execute opencode with tracing
-	executeOpenCode(os.Args[1:])
+	// Copy the child's own logs into the session alongside its HTTP traffic
+	output, err := openProcessOutputLog(sessionID, config)
+	if err != nil {
+		diagnostics.Warnf("Failed to open process output log: %v", err)
+	}
+
+	// Execute opencode with tracing, keeping its exit status for the session
+	startTime := time.Now()
+	exitCode, err := runOpenCode(os.Args[1:], output)
+	if err != nil {
+		diagnostics.Errorf("Failed to execute opencode: %v", err)
+	}
+	elapsed := time.Since(startTime)
+	if output != nil {
+		output.Close()
+	}
+	
+	// os.Exit skips deferred calls, so finish the session explicitly. The
+	// trace is closed first so the manifest sees its final contents.
+	if tracingClient != nil {
+		tracingClient.Close()
+	}
+	if err := coordinator.Finalize(exitCode, elapsed); err != nil {
+		diagnostics.Warnf("Failed to finalize session: %v", err)
+	}
+	closeDiagnostics()
+	
+	os.Exit(exitCode)
 }
 
 // Check if tracing is enabled via environment variables
@@ -60,20 +94,12 @@ func isTracingEnabled() bool {
 		   os.Getenv("OPENCODE_TRACE_MODE") == "tui"
 }
 
-// Get session ID from environment
-func getSessionID() string {
-	sessionID := os.Getenv("OPENCODE_TRACE_SESSION_ID")
-	if sessionID == "" {
-		sessionID = "default"
-	}
-	return sessionID
-}
-
 // Build trace configuration from environment variables
 func getTraceConfig() TracingConfig {
 	config := TracingConfig{
 		Enabled:               true,
 		OutputDir:             getEnvWithDefault("OPENCODE_TRACE_DIR", ".opencode-trace"),
+		OutputNamespace:       os.Getenv("OPENCODE_TRACE_NAMESPACE"),
 		MaxBodySize:           1048576, // 1MB default
 		CaptureRequestBodies:  true,
 		CaptureResponseBodies: true,
@@ -83,6 +109,15 @@ func getTraceConfig() TracingConfig {
 		Timeout:               30 * time.Second,
 		MaxRetries:            3,
 		SensitiveHeaders:      []string{"authorization", "x-api-key", "x-auth-token"},
+		CaptureCommandLine:    os.Getenv("OPENCODE_TRACE_CAPTURE_COMMAND_LINE") != "false",
+		IPCMaxAge:             24 * time.Hour,
+	}
+
+	// Parse the age after which IPC files of other sessions are removed
+	if maxAgeStr := os.Getenv("OPENCODE_TRACE_IPC_MAX_AGE"); maxAgeStr != "" {
+		if maxAge, err := time.ParseDuration(maxAgeStr); err == nil && maxAge > 0 {
+			config.IPCMaxAge = maxAge
+		}
 	}
 	
 	// Parse max body size
@@ -91,10 +126,52 @@ func getTraceConfig() TracingConfig {
 			config.MaxBodySize = maxBody
 		}
 	}
+
+	// Parse env var allow/deny lists (comma-separated names)
+	if allowList := os.Getenv("OPENCODE_TRACE_ENV_ALLOWLIST"); allowList != "" {
+		config.EnvVarAllowList = splitList(allowList)
+	}
+	if denyList := os.Getenv("OPENCODE_TRACE_ENV_DENYLIST"); denyList != "" {
+		config.EnvVarDenyList = splitList(denyList)
+	}
+
+	// Parse which child output streams are copied into the session
+	switch captureOutput := os.Getenv("OPENCODE_TRACE_CAPTURE_OUTPUT"); captureOutput {
+	case "true", "1", CaptureOutputStderr:
+		config.CaptureOutput = CaptureOutputStderr
+	case CaptureOutputAll:
+		config.CaptureOutput = CaptureOutputAll
+	}
+
+	// Parse session tags (comma-separated)
+	if tags := os.Getenv("OPENCODE_TRACE_SESSION_TAGS"); tags != "" {
+		config.SessionTags = splitList(tags)
+	}
+
+	// Parse user-supplied build info (key=value,key=value)
+	if buildInfoStr := os.Getenv("OPENCODE_TRACE_BUILD_INFO"); buildInfoStr != "" {
+		config.BuildInfo = make(map[string]string)
+		for _, pair := range strings.Split(buildInfoStr, ",") {
+			if key, value, ok := strings.Cut(pair, "="); ok && strings.TrimSpace(key) != "" {
+				config.BuildInfo[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+	}
 	
 	return config
 }
 
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // Inject the tracing HTTP client into the opencode process
 func injectTracingClient(client *TracingHTTPClient) error {
 	// This is where we would integrate with opencode's HTTP client
@@ -112,38 +189,56 @@ func injectTracingClient(client *TracingHTTPClient) error {
 	// 3. LD_PRELOAD library injection (Linux/macOS)
 	// 4. DLL injection (Windows)
 	
-	fmt.Println("🔌 HTTP client injection configured")
+	client.config.Diagnostics.Infof("🔌", "HTTP client injection configured")
 	return nil
 }
 
-// Execute opencode with the provided arguments
-func executeOpenCode(args []string) {
+// Execute opencode with the provided arguments and exit with its status
+func executeOpenCode(args []string, diagnostics *Diagnostics, closeDiagnostics func()) {
+	exitCode, err := runOpenCode(args, nil)
+	if err != nil {
+		diagnostics.Errorf("Failed to execute opencode: %v", err)
+	}
+	closeDiagnostics()
+	os.Exit(exitCode)
+}
+
+// Run opencode with the provided arguments and return its exit code. When
+// output is set, the streams it captures are also copied into it.
+func runOpenCode(args []string, output *processOutputLog) (int, error) {
 	// Find opencode binary
 	opencodeCmd, err := findOpenCodeBinary()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Failed to find opencode binary: %v\n", err)
-		os.Exit(1)
+		return 1, err
 	}
 	
+	stdout, stderr := output.tee(os.Stdout, os.Stderr)
+	return runChild(opencodeCmd, args, stdout, stderr)
+}
+
+// Run a child process reading this process's stdin and writing to stdout and
+// stderr, and return its exit code. Errors are returned only when the child
+// could not be run at all.
+func runChild(name string, args []string, stdout, stderr io.Writer) (int, error) {
 	// Prepare command
-	cmd := exec.Command(opencodeCmd, args...)
+	cmd := exec.Command(name, args...)
 	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
 	
 	// Copy environment variables
 	cmd.Env = os.Environ()
 	
-	// Execute and replace current process
 	if err := cmd.Run(); err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
 			if status, ok := exitError.Sys().(syscall.WaitStatus); ok {
-				os.Exit(status.ExitStatus())
+				return status.ExitStatus(), nil
 			}
 		}
-		fmt.Fprintf(os.Stderr, "❌ Failed to execute opencode: %v\n", err)
-		os.Exit(1)
+		return 1, err
 	}
+	
+	return 0, nil
 }
 
 // Find the opencode binary in common locations
@@ -16,6 +16,7 @@ import (
 type TracingConfig struct {
 	Enabled               bool          `json:"enabled"`
 	OutputDir             string        `json:"output_dir"`
+	OutputNamespace       string        `json:"output_namespace,omitempty"`
 	MaxBodySize           int64         `json:"max_body_size"`
 	CaptureRequestBodies  bool          `json:"capture_request_bodies"`
 	CaptureResponseBodies bool          `json:"capture_response_bodies"`
@@ -25,6 +26,32 @@ type TracingConfig struct {
 	IncludeAllRequests    bool          `json:"include_all_requests"`
 	Debug                 bool          `json:"debug"`
 	Verbose               bool          `json:"verbose"`
+
+	// User-supplied build info recorded in session metadata
+	BuildInfo map[string]string `json:"build_info,omitempty"`
+
+	// Environment captured in session metadata
+	CaptureCommandLine bool     `json:"capture_command_line"`
+	EnvVarAllowList    []string `json:"env_var_allow_list,omitempty"`
+	EnvVarDenyList     []string `json:"env_var_deny_list,omitempty"`
+
+	// IPC files of other sessions older than this are removed at startup
+	IPCMaxAge time.Duration `json:"ipc_max_age"`
+
+	// Tags recorded in session metadata, used to find sessions with IndexSessions
+	SessionTags []string `json:"session_tags,omitempty"`
+
+	// Child process output copied to process_output.log: "stderr" or "all"
+	CaptureOutput string `json:"capture_output,omitempty"`
+
+	// Where the wrapper's own messages go; nil prints them to stdout and stderr
+	Diagnostics *Diagnostics `json:"-"`
+}
+
+// sessionsDir returns the directory holding session directories,
+// OutputDir/<namespace>/sessions when an output namespace is set
+func (c TracingConfig) sessionsDir() string {
+	return filepath.Join(c.OutputDir, c.OutputNamespace, "sessions")
 }
 
 // HTTPRequestEvent represents an HTTP request event (from Plan v1)
@@ -66,7 +93,7 @@ type TracingHTTPClient struct {
 // NewTracingHTTPClient creates a new tracing HTTP client
 func NewTracingHTTPClient(sessionID string, config TracingConfig) (*TracingHTTPClient, error) {
 	// Create session directory
-	sessionDir := filepath.Join(config.OutputDir, "sessions", sessionID)
+	sessionDir := filepath.Join(config.sessionsDir(), sessionID)
 	if err := os.MkdirAll(sessionDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create session directory: %v", err)
 	}
@@ -244,7 +271,7 @@ func (t *TracingTransport) logEvent(event interface{}) {
 	jsonData, err := json.Marshal(event)
 	if err != nil {
 		if t.tracingClient.config.Debug {
-			fmt.Fprintf(os.Stderr, "Failed to marshal event: %v\n", err)
+			t.tracingClient.config.Diagnostics.Debugf("Failed to marshal event: %v", err)
 		}
 		return
 	}
@@ -252,7 +279,7 @@ func (t *TracingTransport) logEvent(event interface{}) {
 	// Write to log file
 	if _, err := t.tracingClient.logFile.Write(append(jsonData, '\n')); err != nil {
 		if t.tracingClient.config.Debug {
-			fmt.Fprintf(os.Stderr, "Failed to write event: %v\n", err)
+			t.tracingClient.config.Diagnostics.Debugf("Failed to write event: %v", err)
 		}
 	}
 }
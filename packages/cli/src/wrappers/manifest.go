@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// manifestFileName is the manifest written into the session directory
+const manifestFileName = "manifest.json"
+
+// SessionManifest lists the files a session produced, for CI steps that
+// archive the session and verify what they uploaded
+type SessionManifest struct {
+	SessionID string         `json:"session_id"`
+	CreatedAt int64          `json:"created_at"`
+	Files     []ManifestFile `json:"files"`
+}
+
+// ManifestFile is one produced file. Path is relative to the sessions
+// directory and uses forward slashes.
+type ManifestFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ManifestPath returns where Finalize writes the session manifest
+func (sc *SessionCoordinator) ManifestPath() string {
+	return filepath.Join(sc.config.sessionsDir(), sc.sessionID, manifestFileName)
+}
+
+// writeManifest lists every file of the session with its size and sha256:
+// everything in the session directory, such as the metadata, the injector's
+// session.jsonl and the process output, plus the go-client session files
+// named <timestamp>_session-<id>.* beside it
+func (sc *SessionCoordinator) writeManifest() error {
+	sessionsDir := sc.config.sessionsDir()
+	sessionDir := filepath.Join(sessionsDir, sc.sessionID)
+	manifestPath := sc.ManifestPath()
+
+	var paths []string
+	err := filepath.WalkDir(sessionDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() && path != manifestPath {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list session files: %v", err)
+	}
+
+	clientFiles, err := filepath.Glob(filepath.Join(sessionsDir, "*_session-"+sc.sessionID+".*"))
+	if err != nil {
+		return fmt.Errorf("failed to list session files: %v", err)
+	}
+	paths = append(paths, clientFiles...)
+
+	manifest := SessionManifest{
+		SessionID: sc.sessionID,
+		CreatedAt: time.Now().Unix(),
+		Files:     []ManifestFile{},
+	}
+	for _, path := range paths {
+		file, err := describeManifestFile(sessionsDir, path)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Path < manifest.Files[j].Path
+	})
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(manifestPath, data, 0644)
+}
+
+// describeManifestFile hashes a file and names it relative to root
+func describeManifestFile(root, path string) (ManifestFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ManifestFile{}, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return ManifestFile{}, fmt.Errorf("failed to hash %s: %v", path, err)
+	}
+
+	relative, err := filepath.Rel(root, path)
+	if err != nil {
+		relative = path
+	}
+	return ManifestFile{
+		Path:   filepath.ToSlash(relative),
+		Size:   size,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
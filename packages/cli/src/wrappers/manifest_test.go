@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFinalizeWritesManifest(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tui-manifest-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Keep IPC messages inside the test directory
+	t.Setenv("TMPDIR", tempDir)
+
+	sessionID := "manifest-test"
+	config := TracingConfig{OutputDir: tempDir}
+	coordinator := NewSessionCoordinator(sessionID, config)
+	if err := coordinator.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	sessionsDir := filepath.Join(tempDir, "sessions")
+	produced := map[string]string{
+		"manifest-test/session.jsonl":                            `{"type":"session_start"}` + "\n",
+		"manifest-test/process_output.log":                       "hello\n",
+		"manifest-test/bodies/abc.bin":                           "body",
+		"2024-01-01_00-00-00_session-manifest-test.jsonl":        `{"type":"http_request"}` + "\n",
+		"2024-01-01_00-00-00_session-manifest-test.jsonl.gz":     "compressed",
+		"2024-01-01_00-00-00_session-manifest-test.summary.json": "{}",
+		"2024-01-01_00-00-00_session-other-session.jsonl":        "not ours",
+	}
+	for name, content := range produced {
+		path := filepath.Join(sessionsDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := coordinator.Finalize(0, time.Second); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+
+	expectedPath := filepath.Join(sessionsDir, sessionID, "manifest.json")
+	if coordinator.ManifestPath() != expectedPath {
+		t.Errorf("Expected ManifestPath %s, got %s", expectedPath, coordinator.ManifestPath())
+	}
+	data, err := os.ReadFile(coordinator.ManifestPath())
+	if err != nil {
+		t.Fatalf("Expected manifest to be written: %v", err)
+	}
+	var manifest SessionManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.SessionID != sessionID {
+		t.Errorf("Expected session_id %s, got %s", sessionID, manifest.SessionID)
+	}
+
+	listed := make(map[string]ManifestFile)
+	for _, file := range manifest.Files {
+		listed[file.Path] = file
+	}
+	// metadata.json is rewritten by Finalize, so hash it as it is now
+	metadata, err := os.ReadFile(filepath.Join(sessionsDir, sessionID, "metadata.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	produced["manifest-test/metadata.json"] = string(metadata)
+	delete(produced, "2024-01-01_00-00-00_session-other-session.jsonl")
+
+	if len(listed) != len(produced) {
+		t.Errorf("Expected %d files in manifest, got %d: %v", len(produced), len(listed), manifest.Files)
+	}
+	for name, content := range produced {
+		file, ok := listed[name]
+		if !ok {
+			t.Errorf("Expected %s in manifest", name)
+			continue
+		}
+		sum := sha256.Sum256([]byte(content))
+		if file.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("Wrong sha256 for %s", name)
+		}
+		if file.Size != int64(len(content)) {
+			t.Errorf("Expected size %d for %s, got %d", len(content), name, file.Size)
+		}
+	}
+	if _, ok := listed["manifest-test/manifest.json"]; ok {
+		t.Error("Expected manifest not to list itself")
+	}
+}
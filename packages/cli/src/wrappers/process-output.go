@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Child output streams copied into the session by CaptureOutput
+const (
+	CaptureOutputStderr = "stderr"
+	CaptureOutputAll    = "all"
+)
+
+// processOutputLog copies the opencode child's output into the session
+// directory as process_output.log, one timestamped line per output line
+type processOutputLog struct {
+	file          *os.File
+	captureStdout bool
+
+	mu      sync.Mutex
+	streams []*timestampedWriter
+}
+
+// openProcessOutputLog opens the output log of a session, or returns nil
+// when CaptureOutput is not set
+func openProcessOutputLog(sessionID string, config TracingConfig) (*processOutputLog, error) {
+	if config.CaptureOutput != CaptureOutputStderr && config.CaptureOutput != CaptureOutputAll {
+		return nil, nil
+	}
+
+	sessionDir := filepath.Join(config.sessionsDir(), sessionID)
+	if err := os.MkdirAll(sessionDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %v", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(sessionDir, "process_output.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &processOutputLog{
+		file:          file,
+		captureStdout: config.CaptureOutput == CaptureOutputAll,
+	}, nil
+}
+
+// tee returns the writers the child should write to: stderr, and with
+// CaptureOutputAll stdout, also go to the log. Capturing stdout gives the
+// child a pipe instead of the terminal, which a TUI may not expect. A nil
+// log returns the writers unchanged.
+func (l *processOutputLog) tee(stdout, stderr io.Writer) (io.Writer, io.Writer) {
+	if l == nil {
+		return stdout, stderr
+	}
+
+	if l.captureStdout {
+		stdout = io.MultiWriter(stdout, l.stream("stdout"))
+	}
+	return stdout, io.MultiWriter(stderr, l.stream("stderr"))
+}
+
+// stream returns a writer that logs the lines of one output stream
+func (l *processOutputLog) stream(name string) io.Writer {
+	w := &timestampedWriter{log: l, name: name}
+	l.mu.Lock()
+	l.streams = append(l.streams, w)
+	l.mu.Unlock()
+	return w
+}
+
+// Close writes any unterminated final lines and closes the log
+func (l *processOutputLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, w := range l.streams {
+		if w.partial.Len() > 0 {
+			l.writeLine(w.name, w.partial.Bytes())
+			w.partial.Reset()
+		}
+	}
+	return l.file.Close()
+}
+
+// writeLine writes one line of a stream; the caller holds mu
+func (l *processOutputLog) writeLine(stream string, line []byte) {
+	fmt.Fprintf(l.file, "%s [%s] %s\n", time.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00"), stream, line)
+}
+
+// timestampedWriter splits one stream into lines for the output log,
+// holding back a trailing partial line until it is completed
+type timestampedWriter struct {
+	log     *processOutputLog
+	name    string
+	partial bytes.Buffer
+}
+
+func (w *timestampedWriter) Write(p []byte) (int, error) {
+	w.log.mu.Lock()
+	defer w.log.mu.Unlock()
+
+	w.partial.Write(p)
+	for {
+		line, err := w.partial.ReadBytes('\n')
+		if err != nil {
+			// No newline yet; keep the fragment for the next write
+			rest := append([]byte(nil), line...)
+			w.partial.Reset()
+			w.partial.Write(rest)
+			break
+		}
+		w.log.writeLine(w.name, bytes.TrimSuffix(line, []byte("\n")))
+	}
+	return len(p), nil
+}
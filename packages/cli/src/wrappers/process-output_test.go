@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestChildOutputHelperProcess is not a real test; it stands in for an
+// opencode child process that writes to stdout and stderr
+func TestChildOutputHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_CHILD_OUTPUT") != "1" {
+		return
+	}
+	fmt.Fprintln(os.Stdout, "rendering screen")
+	fmt.Fprintln(os.Stderr, "warn: model slow")
+	fmt.Fprint(os.Stderr, "no trailing newline")
+	os.Exit(0)
+}
+
+func TestProcessOutputCaptured(t *testing.T) {
+	tests := []struct {
+		mode          string
+		captureStdout bool
+	}{
+		{CaptureOutputStderr, false},
+		{CaptureOutputAll, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			tempDir, err := os.MkdirTemp("", "tui-output-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tempDir)
+
+			sessionID := "output-test"
+			output, err := openProcessOutputLog(sessionID, TracingConfig{OutputDir: tempDir, CaptureOutput: tt.mode})
+			if err != nil {
+				t.Fatalf("openProcessOutputLog failed: %v", err)
+			}
+
+			var displayedOut, displayedErr bytes.Buffer
+			stdout, stderr := output.tee(&displayedOut, &displayedErr)
+
+			t.Setenv("GO_WANT_CHILD_OUTPUT", "1")
+			exitCode, err := runChild(os.Args[0], []string{"-test.run=^TestChildOutputHelperProcess$"}, stdout, stderr)
+			if err != nil || exitCode != 0 {
+				t.Fatalf("runChild failed: exit %d, %v", exitCode, err)
+			}
+			if err := output.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// The output is still displayed as before
+			if !strings.Contains(displayedOut.String(), "rendering screen") {
+				t.Errorf("Expected stdout to be displayed, got %q", displayedOut.String())
+			}
+			if displayedErr.String() != "warn: model slow\nno trailing newline" {
+				t.Errorf("Expected stderr to be displayed, got %q", displayedErr.String())
+			}
+
+			data, err := os.ReadFile(filepath.Join(tempDir, "sessions", sessionID, "process_output.log"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			saved := string(data)
+
+			line := regexp.MustCompile(`(?m)^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{3}Z \[stderr\] warn: model slow$`)
+			if !line.MatchString(saved) {
+				t.Errorf("Expected a timestamped stderr line, got %q", saved)
+			}
+			if !strings.Contains(saved, "[stderr] no trailing newline\n") {
+				t.Errorf("Expected the unterminated line to be saved on close, got %q", saved)
+			}
+			if got := strings.Contains(saved, "[stdout] rendering screen"); got != tt.captureStdout {
+				t.Errorf("Expected stdout saved = %v, got %q", tt.captureStdout, saved)
+			}
+		})
+	}
+}
+
+func TestProcessOutputDisabled(t *testing.T) {
+	output, err := openProcessOutputLog("disabled", TracingConfig{OutputDir: t.TempDir()})
+	if err != nil || output != nil {
+		t.Fatalf("Expected no output log without CaptureOutput, got %v, %v", output, err)
+	}
+
+	stdout, stderr := output.tee(os.Stdout, os.Stderr)
+	if stdout != os.Stdout || stderr != os.Stderr {
+		t.Error("Expected a nil log to leave the writers unchanged")
+	}
+}
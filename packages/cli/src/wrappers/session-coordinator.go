@@ -5,10 +5,22 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime/debug"
+	"strings"
 	"time"
 )
 
+// readBuildInfo returns the build information embedded in the running binary
+var readBuildInfo = debug.ReadBuildInfo
+
+// ipcConsumeWait is how long Finalize gives a CLI wrapper to read its last
+// messages, longer than the 500ms the wrapper waits between polls of the IPC
+// directory
+var ipcConsumeWait = 750 * time.Millisecond
+
 // SessionCoordinator manages the integration between the Go TUI wrapper and the CLI wrapper
+// Its session files are always written under the local output directory; it
+// does not go through the go-client SessionStore
 type SessionCoordinator struct {
 	sessionID string
 	config    TracingConfig
@@ -25,7 +37,7 @@ func NewSessionCoordinator(sessionID string, config TracingConfig) *SessionCoord
 // Initialize sets up the session coordination
 func (sc *SessionCoordinator) Initialize() error {
 	// Create session directory structure
-	sessionDir := filepath.Join(sc.config.OutputDir, "sessions", sc.sessionID)
+	sessionDir := filepath.Join(sc.config.sessionsDir(), sc.sessionID)
 	if err := os.MkdirAll(sessionDir, 0755); err != nil {
 		return fmt.Errorf("failed to create session directory: %v", err)
 	}
@@ -43,33 +55,55 @@ func (sc *SessionCoordinator) Initialize() error {
 	}); err != nil {
 		// Don't fail if IPC is not available
 		if sc.config.Debug {
-			fmt.Fprintf(os.Stderr, "Warning: Failed to send IPC message: %v\n", err)
+			sc.config.Diagnostics.Debugf("Warning: Failed to send IPC message: %v", err)
 		}
 	}
 
 	return nil
 }
 
-// Finalize cleans up the session coordination
-func (sc *SessionCoordinator) Finalize() error {
-	// Send session end event to CLI wrapper via IPC
+// Finalize cleans up the session coordination, recording the exit code and
+// runtime of the opencode child process
+func (sc *SessionCoordinator) Finalize(exitCode int, runtime time.Duration) error {
 	if err := sc.sendIPCMessage("session_end", map[string]interface{}{
-		"mode":      "go_tui",
-		"pid":       os.Getpid(),
-		"timestamp": time.Now().UnixMilli(),
+		"mode":       "go_tui",
+		"pid":        os.Getpid(),
+		"timestamp":  time.Now().UnixMilli(),
+		"exit_code":  exitCode,
+		"runtime_ms": runtime.Milliseconds(),
 	}); err != nil {
 		// Don't fail if IPC is not available
 		if sc.config.Debug {
-			fmt.Fprintf(os.Stderr, "Warning: Failed to send IPC message: %v\n", err)
+			sc.config.Diagnostics.Debugf("Warning: Failed to send IPC message: %v", err)
 		}
 	}
 
+	// After a clean exit the IPC directory is removed once the CLI wrapper has
+	// read session_end from it
+	if exitCode == 0 {
+		sc.removeReadIPCDir()
+	}
+
+	// Record the outcome next to the metadata written at start
+	if err := sc.updateSessionMetadata(map[string]interface{}{
+		"end_time":   time.Now().Unix(),
+		"exit_code":  exitCode,
+		"runtime_ms": runtime.Milliseconds(),
+	}); err != nil {
+		return fmt.Errorf("failed to update session metadata: %v", err)
+	}
+
+	// Listed last, so the hashes cover the files in their final state
+	if err := sc.writeManifest(); err != nil {
+		return fmt.Errorf("failed to write session manifest: %v", err)
+	}
+
 	return nil
 }
 
 // writeSessionMetadata writes session metadata to a file
 func (sc *SessionCoordinator) writeSessionMetadata() error {
-	sessionDir := filepath.Join(sc.config.OutputDir, "sessions", sc.sessionID)
+	sessionDir := filepath.Join(sc.config.sessionsDir(), sc.sessionID)
 	metadataPath := filepath.Join(sessionDir, "metadata.json")
 
 	metadata := map[string]interface{}{
@@ -78,7 +112,11 @@ func (sc *SessionCoordinator) writeSessionMetadata() error {
 		"mode":        "go_tui",
 		"pid":         os.Getpid(),
 		"config":      sc.config,
-		"environment": getEnvironmentInfo(),
+		"environment": sc.getEnvironmentInfo(),
+		"build_info":  sc.getBuildInfo(),
+	}
+	if len(sc.config.SessionTags) > 0 {
+		metadata["tags"] = sc.config.SessionTags
 	}
 
 	data, err := json.MarshalIndent(metadata, "", "  ")
@@ -89,6 +127,31 @@ func (sc *SessionCoordinator) writeSessionMetadata() error {
 	return os.WriteFile(metadataPath, data, 0644)
 }
 
+// updateSessionMetadata merges fields into the session metadata file
+func (sc *SessionCoordinator) updateSessionMetadata(fields map[string]interface{}) error {
+	metadataPath := filepath.Join(sc.config.sessionsDir(), sc.sessionID, "metadata.json")
+
+	data, err := os.ReadFile(metadataPath)
+	if err != nil {
+		return err
+	}
+
+	metadata := make(map[string]interface{})
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return err
+	}
+	for key, value := range fields {
+		metadata[key] = value
+	}
+
+	data, err = json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(metadataPath, data, 0644)
+}
+
 // sendIPCMessage sends a message to the CLI wrapper via IPC
 func (sc *SessionCoordinator) sendIPCMessage(messageType string, data map[string]interface{}) error {
 	// Create IPC message structure (compatible with CLI wrapper)
@@ -101,8 +164,7 @@ func (sc *SessionCoordinator) sendIPCMessage(messageType string, data map[string
 	}
 
 	// Write to temporary IPC file that the CLI wrapper will pick up
-	tmpDir := os.TempDir()
-	ipcDir := filepath.Join(tmpDir, "opencode-trace", sc.sessionID)
+	ipcDir := sc.ipcDir()
 
 	// Create IPC directory if it doesn't exist
 	if err := os.MkdirAll(ipcDir, 0755); err != nil {
@@ -121,39 +183,237 @@ func (sc *SessionCoordinator) sendIPCMessage(messageType string, data map[string
 	return os.WriteFile(messageFile, messageData, 0644)
 }
 
+// ipcDir returns the directory this session's IPC messages are written to
+func (sc *SessionCoordinator) ipcDir() string {
+	return filepath.Join(ipcRootDir(), sc.sessionID)
+}
+
+// removeReadIPCDir removes this session's IPC directory once it is empty. The
+// CLI wrapper deletes each message it reads, so it gets up to ipcConsumeWait
+// to poll first. Messages nobody read are never deleted here; the directory
+// is then left for GCStaleIPC.
+func (sc *SessionCoordinator) removeReadIPCDir() {
+	deadline := time.Now().Add(ipcConsumeWait)
+	for {
+		err := os.Remove(sc.ipcDir())
+		if err == nil || os.IsNotExist(err) {
+			return
+		}
+		if time.Now().After(deadline) {
+			if sc.config.Debug {
+				sc.config.Diagnostics.Debugf("IPC directory left for GCStaleIPC: %v", err)
+			}
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// ipcRootDir returns the directory holding the IPC directories of all sessions
+func ipcRootDir() string {
+	return filepath.Join(os.TempDir(), "opencode-trace")
+}
+
+// GCStaleIPC removes IPC directories and files left behind by sessions that
+// ended more than maxAge ago, for example because no CLI wrapper ever read
+// them. A directory counts as stale only when it and every file in it are
+// older than maxAge, so sessions still exchanging messages are kept.
+func GCStaleIPC(maxAge time.Duration) error {
+	root := ipcRootDir()
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	var firstErr error
+	for _, entry := range entries {
+		path := filepath.Join(root, entry.Name())
+		modified, err := newestModTime(path)
+		if err != nil || !modified.Before(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// newestModTime returns the latest modification time of path and, for a
+// directory, of the entries directly inside it
+func newestModTime(path string) (time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	newest := info.ModTime()
+	if !info.IsDir() {
+		return newest, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, entry := range entries {
+		if entryInfo, err := entry.Info(); err == nil && entryInfo.ModTime().After(newest) {
+			newest = entryInfo.ModTime()
+		}
+	}
+	return newest, nil
+}
+
+// getBuildInfo collects the module version and VCS stamp of the running binary
+// merged with any user-supplied build info from config. Binaries built without
+// stamping (e.g. `go run`) report only what is available.
+func (sc *SessionCoordinator) getBuildInfo() map[string]string {
+	info := make(map[string]string)
+
+	if buildInfo, ok := readBuildInfo(); ok {
+		info["go_version"] = buildInfo.GoVersion
+		info["module_path"] = buildInfo.Main.Path
+		if buildInfo.Main.Version != "" {
+			info["module_version"] = buildInfo.Main.Version
+		}
+
+		for _, setting := range buildInfo.Settings {
+			switch setting.Key {
+			case "vcs", "vcs.revision", "vcs.time", "vcs.modified":
+				info[setting.Key] = setting.Value
+			}
+		}
+	} else {
+		info["unavailable"] = "true"
+	}
+
+	for key, value := range sc.config.BuildInfo {
+		info[key] = value
+	}
+
+	return info
+}
+
+// defaultEnvVars are the environment variables captured when no allowlist is configured
+var defaultEnvVars = []string{
+	"OPENCODE_TRACE",
+	"OPENCODE_TRACE_MODE",
+	"OPENCODE_TRACE_SESSION_ID",
+	"OPENCODE_TRACE_DIR",
+	"OPENCODE_TRACE_NAMESPACE",
+	"OPENCODE_TRACE_DEBUG",
+	"OPENCODE_TRACE_VERBOSE",
+	"OPENCODE_TRACE_INCLUDE_ALL",
+	"OPENCODE_TRACE_MAX_BODY_SIZE",
+	"NODE_ENV",
+	"GO_VERSION",
+	"GOOS",
+	"GOARCH",
+}
+
 // getEnvironmentInfo collects relevant environment information
-func getEnvironmentInfo() map[string]interface{} {
+func (sc *SessionCoordinator) getEnvironmentInfo() map[string]interface{} {
 	env := make(map[string]interface{})
 
 	// Collect relevant environment variables
-	relevantVars := []string{
-		"OPENCODE_TRACE",
-		"OPENCODE_TRACE_MODE",
-		"OPENCODE_TRACE_SESSION_ID",
-		"OPENCODE_TRACE_DIR",
-		"OPENCODE_TRACE_DEBUG",
-		"OPENCODE_TRACE_VERBOSE",
-		"OPENCODE_TRACE_INCLUDE_ALL",
-		"OPENCODE_TRACE_MAX_BODY_SIZE",
-		"NODE_ENV",
-		"GO_VERSION",
-		"GOOS",
-		"GOARCH",
+	relevantVars := defaultEnvVars
+	if len(sc.config.EnvVarAllowList) > 0 {
+		relevantVars = sc.config.EnvVarAllowList
 	}
 
 	for _, varName := range relevantVars {
+		if sc.isDeniedEnvVar(varName) {
+			continue
+		}
 		if value := os.Getenv(varName); value != "" {
+			if sc.isSensitiveName(varName) {
+				value = "[REDACTED]"
+			}
 			env[varName] = value
 		}
 	}
 
 	// Add system information
 	env["working_directory"] = getCurrentWorkingDirectory()
-	env["command_line"] = os.Args
+	if sc.config.CaptureCommandLine {
+		env["command_line"] = sc.redactArgs(os.Args)
+	}
 
 	return env
 }
 
+// isDeniedEnvVar reports whether an env var is excluded by the denylist
+func (sc *SessionCoordinator) isDeniedEnvVar(name string) bool {
+	for _, denied := range sc.config.EnvVarDenyList {
+		if strings.EqualFold(name, denied) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSensitiveName reports whether an env var or flag name looks like it holds a secret
+func (sc *SessionCoordinator) isSensitiveName(name string) bool {
+	normalized := strings.ToLower(strings.TrimLeft(name, "-"))
+	normalized = strings.ReplaceAll(normalized, "_", "-")
+
+	for _, pattern := range []string{"token", "key", "secret", "password", "auth"} {
+		if strings.Contains(normalized, pattern) {
+			return true
+		}
+	}
+	for _, sensitive := range sc.config.SensitiveHeaders {
+		if strings.Contains(normalized, strings.ToLower(sensitive)) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactArgs returns a copy of args with the values of secret-looking flags
+// replaced, for both --flag=value and --flag value forms
+func (sc *SessionCoordinator) redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+
+	for i := 1; i < len(redacted); i++ {
+		arg := redacted[i]
+		if !strings.HasPrefix(arg, "-") {
+			if looksLikeSecret(arg) {
+				redacted[i] = "[REDACTED]"
+			}
+			continue
+		}
+
+		name, _, hasValue := strings.Cut(arg, "=")
+		if !sc.isSensitiveName(name) {
+			continue
+		}
+
+		if hasValue {
+			redacted[i] = name + "=[REDACTED]"
+		} else if i+1 < len(redacted) && !strings.HasPrefix(redacted[i+1], "-") {
+			redacted[i+1] = "[REDACTED]"
+			i++
+		}
+	}
+
+	return redacted
+}
+
+// looksLikeSecret detects common API key and token formats passed as bare values
+func looksLikeSecret(value string) bool {
+	for _, prefix := range []string{"sk-", "sk_", "Bearer ", "ghp_", "xoxb-", "AKIA"} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // getCurrentWorkingDirectory safely gets the current working directory
 func getCurrentWorkingDirectory() string {
 	if cwd, err := os.Getwd(); err == nil {
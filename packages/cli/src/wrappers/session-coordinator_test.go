@@ -0,0 +1,364 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSessionMetadataIncludesBuildInfo(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tui-metadata-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Simulate a binary built with VCS stamping
+	original := readBuildInfo
+	readBuildInfo = func() (*debug.BuildInfo, bool) {
+		return &debug.BuildInfo{
+			GoVersion: "go1.21.0",
+			Main:      debug.Module{Path: "github.com/opencode-trace/wrapper", Version: "v1.2.3"},
+			Settings: []debug.BuildSetting{
+				{Key: "vcs", Value: "git"},
+				{Key: "vcs.revision", Value: "0123456789abcdef"},
+				{Key: "vcs.modified", Value: "true"},
+				{Key: "-ldflags", Value: "-s -w"},
+			},
+		}, true
+	}
+	defer func() { readBuildInfo = original }()
+
+	sessionID := "metadata-test"
+	config := TracingConfig{
+		OutputDir: tempDir,
+		BuildInfo: map[string]string{"release_channel": "beta"},
+	}
+	if err := os.MkdirAll(filepath.Join(tempDir, "sessions", sessionID), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	coordinator := NewSessionCoordinator(sessionID, config)
+	if err := coordinator.writeSessionMetadata(); err != nil {
+		t.Fatalf("writeSessionMetadata failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "sessions", sessionID, "metadata.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var metadata struct {
+		BuildInfo map[string]string `json:"build_info"`
+	}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"vcs.revision":    "0123456789abcdef",
+		"vcs.modified":    "true",
+		"module_version":  "v1.2.3",
+		"release_channel": "beta",
+	}
+	for key, value := range expected {
+		if metadata.BuildInfo[key] != value {
+			t.Errorf("Expected build_info[%q] = %q, got %q", key, value, metadata.BuildInfo[key])
+		}
+	}
+	if _, ok := metadata.BuildInfo["-ldflags"]; ok {
+		t.Error("Expected unrelated build settings to be omitted")
+	}
+}
+
+func TestBuildInfoUnavailable(t *testing.T) {
+	original := readBuildInfo
+	readBuildInfo = func() (*debug.BuildInfo, bool) { return nil, false }
+	defer func() { readBuildInfo = original }()
+
+	info := NewSessionCoordinator("no-build-info", TracingConfig{}).getBuildInfo()
+	if info["unavailable"] != "true" {
+		t.Errorf("Expected build info to be marked unavailable, got %v", info)
+	}
+}
+
+func TestEnvironmentInfoRedaction(t *testing.T) {
+	os.Setenv("OPENCODE_TRACE_DEBUG", "true")
+	os.Setenv("NODE_ENV", "production")
+	os.Setenv("ANTHROPIC_API_KEY", "sk-ant-secret")
+	defer func() {
+		os.Unsetenv("OPENCODE_TRACE_DEBUG")
+		os.Unsetenv("NODE_ENV")
+		os.Unsetenv("ANTHROPIC_API_KEY")
+	}()
+
+	originalArgs := os.Args
+	os.Args = []string{"opencode", "--model", "claude", "--api-key=sk-live-123", "--token", "abc", "sk-proj-456"}
+	defer func() { os.Args = originalArgs }()
+
+	config := TracingConfig{
+		CaptureCommandLine: true,
+		SensitiveHeaders:   []string{"authorization"},
+		EnvVarAllowList:    []string{"OPENCODE_TRACE_DEBUG", "NODE_ENV", "ANTHROPIC_API_KEY"},
+		EnvVarDenyList:     []string{"NODE_ENV"},
+	}
+	env := NewSessionCoordinator("env-test", config).getEnvironmentInfo()
+
+	if env["OPENCODE_TRACE_DEBUG"] != "true" {
+		t.Errorf("Expected allowed env var to be captured, got %v", env["OPENCODE_TRACE_DEBUG"])
+	}
+	if _, ok := env["NODE_ENV"]; ok {
+		t.Error("Expected denied env var to be excluded")
+	}
+	if env["ANTHROPIC_API_KEY"] != "[REDACTED]" {
+		t.Errorf("Expected secret env var to be redacted, got %v", env["ANTHROPIC_API_KEY"])
+	}
+
+	args, _ := env["command_line"].([]string)
+	expected := []string{"opencode", "--model", "claude", "--api-key=[REDACTED]", "--token", "[REDACTED]", "[REDACTED]"}
+	if len(args) != len(expected) {
+		t.Fatalf("Expected args %v, got %v", expected, args)
+	}
+	for i := range expected {
+		if args[i] != expected[i] {
+			t.Errorf("Arg %d: expected %q, got %q", i, expected[i], args[i])
+		}
+	}
+	if os.Args[3] != "--api-key=sk-live-123" {
+		t.Error("Expected os.Args to be left unmodified")
+	}
+}
+
+func TestEnvironmentInfoWithoutCommandLine(t *testing.T) {
+	env := NewSessionCoordinator("env-test", TracingConfig{}).getEnvironmentInfo()
+	if _, ok := env["command_line"]; ok {
+		t.Error("Expected command_line to be omitted when CaptureCommandLine is false")
+	}
+}
+
+// TestChildExitHelperProcess is not a real test; it stands in for an opencode
+// child process that exits with the code in GO_WANT_CHILD_EXIT
+func TestChildExitHelperProcess(t *testing.T) {
+	code := os.Getenv("GO_WANT_CHILD_EXIT")
+	if code == "" {
+		return
+	}
+	exitCode, _ := strconv.Atoi(code)
+	os.Exit(exitCode)
+}
+
+func TestFinalizeRecordsChildExit(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tui-exit-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Keep IPC messages inside the test directory
+	t.Setenv("TMPDIR", tempDir)
+
+	sessionID := "exit-test"
+	coordinator := NewSessionCoordinator(sessionID, TracingConfig{OutputDir: tempDir})
+	if err := coordinator.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	t.Setenv("GO_WANT_CHILD_EXIT", "3")
+	startTime := time.Now()
+	exitCode, err := runChild(os.Args[0], []string{"-test.run=^TestChildExitHelperProcess$"}, os.Stdout, os.Stderr)
+	if err != nil {
+		t.Fatalf("runChild failed: %v", err)
+	}
+	if exitCode != 3 {
+		t.Fatalf("Expected child exit code 3, got %d", exitCode)
+	}
+
+	if err := coordinator.Finalize(exitCode, time.Since(startTime)); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "sessions", sessionID, "metadata.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		t.Fatal(err)
+	}
+	if metadata["exit_code"] != float64(3) {
+		t.Errorf("Expected exit_code 3 in metadata, got %v", metadata["exit_code"])
+	}
+	if _, ok := metadata["runtime_ms"].(float64); !ok {
+		t.Errorf("Expected runtime_ms in metadata, got %v", metadata["runtime_ms"])
+	}
+	if metadata["session_id"] != sessionID {
+		t.Errorf("Expected existing metadata to be kept, got %v", metadata["session_id"])
+	}
+
+	// The session_end IPC message carries the exit code as well
+	messages, err := filepath.Glob(filepath.Join(tempDir, "opencode-trace", sessionID, "msg-*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, path := range messages {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var message struct {
+			Type string                 `json:"type"`
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(data, &message); err != nil {
+			t.Fatal(err)
+		}
+		if message.Type == "session_end" {
+			found = true
+			if message.Data["exit_code"] != float64(3) {
+				t.Errorf("Expected exit_code 3 in session_end, got %v", message.Data["exit_code"])
+			}
+		}
+	}
+	if !found {
+		t.Error("Expected a session_end message")
+	}
+}
+
+func TestGCStaleIPC(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tui-ipc-gc-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	t.Setenv("TMPDIR", tempDir)
+
+	root := filepath.Join(tempDir, "opencode-trace")
+	stale := time.Now().Add(-48 * time.Hour)
+	write := func(path string, modified time.Time) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(filepath.Join(root, "old-session", "msg-1.json"), stale)
+	os.Chtimes(filepath.Join(root, "old-session"), stale, stale)
+	write(filepath.Join(root, "stray-file.json"), stale)
+
+	// A session dir that is old itself but still receives messages survives
+	write(filepath.Join(root, "active-session", "msg-2.json"), time.Now())
+	os.Chtimes(filepath.Join(root, "active-session"), stale, stale)
+	write(filepath.Join(root, "new-session", "msg-3.json"), time.Now())
+
+	if err := GCStaleIPC(24 * time.Hour); err != nil {
+		t.Fatalf("GCStaleIPC failed: %v", err)
+	}
+
+	for _, name := range []string{"old-session", "stray-file.json"} {
+		if _, err := os.Stat(filepath.Join(root, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected stale %s to be removed, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"active-session", "new-session"} {
+		if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+			t.Errorf("Expected fresh %s to survive, got %v", name, err)
+		}
+	}
+}
+
+func TestFinalizeKeepsUnreadIPCMessages(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tui-ipc-finalize-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	t.Setenv("TMPDIR", tempDir)
+	defer func(wait time.Duration) { ipcConsumeWait = wait }(ipcConsumeWait)
+	ipcConsumeWait = 10 * time.Millisecond
+
+	coordinator := NewSessionCoordinator("ipc-finalize", TracingConfig{OutputDir: tempDir})
+	if err := coordinator.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	ipcDir := filepath.Join(tempDir, "opencode-trace", "ipc-finalize")
+	if _, err := os.Stat(ipcDir); err != nil {
+		t.Fatalf("Expected the IPC directory to exist, got %v", err)
+	}
+
+	// No CLI wrapper has polled yet, so its messages stay for it or GCStaleIPC
+	if err := coordinator.Finalize(0, time.Second); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	found := false
+	messages, _ := filepath.Glob(filepath.Join(ipcDir, "msg-*.json"))
+	for _, path := range messages {
+		var message map[string]interface{}
+		if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &message) == nil {
+			found = found || message["type"] == "session_end"
+		}
+	}
+	if !found {
+		t.Errorf("Expected session_end to be left unread in %s, got %v", ipcDir, messages)
+	}
+}
+
+func TestFinalizeSendsSessionEnd(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tui-ipc-finalize-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	t.Setenv("TMPDIR", tempDir)
+	defer func(wait time.Duration) { ipcConsumeWait = wait }(ipcConsumeWait)
+	ipcConsumeWait = 500 * time.Millisecond
+
+	coordinator := NewSessionCoordinator("ipc-session-end", TracingConfig{OutputDir: tempDir})
+	if err := coordinator.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	// A CLI wrapper that has not polled yet when the child exits
+	ipcDir := filepath.Join(tempDir, "opencode-trace", "ipc-session-end")
+	received := make(chan []string)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		var types []string
+		messages, _ := filepath.Glob(filepath.Join(ipcDir, "msg-*.json"))
+		for _, path := range messages {
+			var message map[string]interface{}
+			if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &message) == nil {
+				types = append(types, message["type"].(string))
+			}
+			os.Remove(path)
+		}
+		received <- types
+	}()
+
+	if err := coordinator.Finalize(0, time.Second); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+
+	types := <-received
+	found := false
+	for _, messageType := range types {
+		found = found || messageType == "session_end"
+	}
+	if !found {
+		t.Errorf("Expected the wrapper to receive session_end, got %v", types)
+	}
+
+	// Every message was read, so a clean exit removes the directory
+	if _, err := os.Stat(ipcDir); !os.IsNotExist(err) {
+		t.Errorf("Expected the IPC directory to be removed, got %v", err)
+	}
+}
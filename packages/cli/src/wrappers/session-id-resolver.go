@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"strconv"
+)
+
+const (
+	// sessionIDEnv carries the effective session ID to child processes
+	sessionIDEnv = "OPENCODE_TRACE_SESSION_ID"
+
+	// sessionOwnerEnv holds the PID of the process that resolved sessionIDEnv.
+	// A process that finds another PID here is a nested child of a traced process.
+	sessionOwnerEnv = "OPENCODE_TRACE_SESSION_OWNER_PID"
+
+	// sessionIDSeparator joins the segments of a hierarchical session ID
+	sessionIDSeparator = "."
+
+	defaultSessionID = "default"
+)
+
+// SessionIDResolver determines the session ID traces of this process are recorded under
+type SessionIDResolver interface {
+	ResolveSessionID() string
+}
+
+// EnvSessionIDResolver resolves hierarchical session IDs through the environment.
+// The process launched by the CLI uses OPENCODE_TRACE_SESSION_ID as-is; a traced
+// process started beneath it extends the inherited ID with its own segment, so
+// its traces stay attributable to the parent session (e.g. "abc123.4242").
+type EnvSessionIDResolver struct {
+	Getenv func(key string) string
+	Setenv func(key, value string) error
+
+	// Pid identifies this process; Segment names it within the parent session
+	Pid     int
+	Segment string
+}
+
+// NewEnvSessionIDResolver creates a resolver for the current process
+func NewEnvSessionIDResolver() *EnvSessionIDResolver {
+	pid := os.Getpid()
+	return &EnvSessionIDResolver{
+		Getenv:  os.Getenv,
+		Setenv:  os.Setenv,
+		Pid:     pid,
+		Segment: strconv.Itoa(pid),
+	}
+}
+
+// ResolveSessionID derives this process's session ID and writes it back to
+// the environment so that further children extend it in turn
+func (r *EnvSessionIDResolver) ResolveSessionID() string {
+	inherited := r.Getenv(sessionIDEnv)
+	owner := r.Getenv(sessionOwnerEnv)
+	pid := strconv.Itoa(r.Pid)
+
+	sessionID := inherited
+	switch {
+	case inherited == "":
+		sessionID = defaultSessionID
+	case owner != "" && owner != pid:
+		sessionID = inherited + sessionIDSeparator + r.Segment
+	}
+
+	r.Setenv(sessionIDEnv, sessionID)
+	r.Setenv(sessionOwnerEnv, pid)
+
+	return sessionID
+}
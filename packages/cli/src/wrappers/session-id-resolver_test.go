@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestSessionIDHelperProcess is not a real test; it runs as a traced child
+// process, prints its resolved session ID and optionally spawns another level.
+func TestSessionIDHelperProcess(t *testing.T) {
+	depth, err := strconv.Atoi(os.Getenv("GO_WANT_SESSION_HELPER_DEPTH"))
+	if err != nil || depth <= 0 {
+		return
+	}
+
+	fmt.Printf("%d %s\n", os.Getpid(), NewEnvSessionIDResolver().ResolveSessionID())
+
+	if depth > 1 {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestSessionIDHelperProcess$")
+		cmd.Env = append(os.Environ(), "GO_WANT_SESSION_HELPER_DEPTH="+strconv.Itoa(depth-1))
+		output, err := cmd.Output()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "child failed: %v\n", err)
+			os.Exit(1)
+		}
+		os.Stdout.Write(output)
+	}
+	os.Exit(0)
+}
+
+func TestSessionIDHierarchy(t *testing.T) {
+	// The CLI launches the top-level wrapper with a plain session ID
+	t.Setenv(sessionIDEnv, "abc123")
+	t.Setenv(sessionOwnerEnv, "")
+
+	rootID := NewEnvSessionIDResolver().ResolveSessionID()
+	if rootID != "abc123" {
+		t.Fatalf("Expected top-level process to keep the session ID, got %q", rootID)
+	}
+	if owner := os.Getenv(sessionOwnerEnv); owner != strconv.Itoa(os.Getpid()) {
+		t.Errorf("Expected owner PID to be exported, got %q", owner)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSessionIDHelperProcess$")
+	cmd.Env = append(os.Environ(), "GO_WANT_SESSION_HELPER_DEPTH=2")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Helper process failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected output from two child levels, got %q", output)
+	}
+
+	parentID := rootID
+	for i, line := range lines {
+		pid, sessionID, _ := strings.Cut(line, " ")
+		expected := parentID + sessionIDSeparator + pid
+		if sessionID != expected {
+			t.Errorf("Level %d: expected session ID %q, got %q", i+1, expected, sessionID)
+		}
+		parentID = sessionID
+	}
+}
+
+func TestSessionIDResolverDefaults(t *testing.T) {
+	env := map[string]string{}
+	resolver := &EnvSessionIDResolver{
+		Getenv:  func(key string) string { return env[key] },
+		Setenv:  func(key, value string) error { env[key] = value; return nil },
+		Pid:     100,
+		Segment: "worker",
+	}
+
+	if id := resolver.ResolveSessionID(); id != defaultSessionID {
+		t.Errorf("Expected %q without an inherited ID, got %q", defaultSessionID, id)
+	}
+
+	// Resolving again in the same process must not nest the ID
+	if id := resolver.ResolveSessionID(); id != defaultSessionID {
+		t.Errorf("Expected repeated resolution to be stable, got %q", id)
+	}
+
+	child := *resolver
+	child.Pid = 101
+	if id := child.ResolveSessionID(); id != "default.worker" {
+		t.Errorf("Expected child ID %q, got %q", "default.worker", id)
+	}
+	if env[sessionIDEnv] != "default.worker" || env[sessionOwnerEnv] != "101" {
+		t.Errorf("Expected child to export its ID and PID, got %v", env)
+	}
+}
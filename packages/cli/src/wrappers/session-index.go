@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// SessionInfo describes one session found by IndexSessions
+type SessionInfo struct {
+	ID           string    `json:"id"`
+	StartTime    time.Time `json:"start_time"`
+	Tags         []string  `json:"tags,omitempty"`
+	RequestCount int       `json:"request_count"`
+}
+
+// HasTag reports whether the session was tagged with tag
+func (s SessionInfo) HasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionsWithTag returns the sessions tagged with tag, keeping their order
+func SessionsWithTag(sessions []SessionInfo, tag string) []SessionInfo {
+	var matched []SessionInfo
+	for _, session := range sessions {
+		if session.HasTag(tag) {
+			matched = append(matched, session)
+		}
+	}
+	return matched
+}
+
+// IndexSessions scans the session directories under dir (a sessions
+// directory as returned by TracingConfig.sessionsDir) and returns every
+// session with a readable metadata.json, oldest first
+func IndexSessions(dir string) ([]SessionInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []SessionInfo
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		sessionDir := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(filepath.Join(sessionDir, "metadata.json"))
+		if err != nil {
+			// Not a session directory, or one still being created
+			continue
+		}
+
+		var metadata struct {
+			SessionID string   `json:"session_id"`
+			StartTime int64    `json:"start_time"`
+			Tags      []string `json:"tags"`
+		}
+		if err := json.Unmarshal(data, &metadata); err != nil {
+			continue
+		}
+		if metadata.SessionID == "" {
+			metadata.SessionID = entry.Name()
+		}
+
+		sessions = append(sessions, SessionInfo{
+			ID:           metadata.SessionID,
+			StartTime:    time.Unix(metadata.StartTime, 0),
+			Tags:         metadata.Tags,
+			RequestCount: countRequests(filepath.Join(sessionDir, "session.jsonl")),
+		})
+	}
+
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].StartTime.Before(sessions[j].StartTime)
+	})
+	return sessions, nil
+}
+
+// countRequests counts the http_request events in a session log; a missing
+// log counts as no requests
+func countRequests(logPath string) int {
+	file, err := os.Open(logPath)
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var event struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &event) == nil && event.Type == "http_request" {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndexSessionsByTag(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tui-index-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	sessions := []struct {
+		id       string
+		tags     []string
+		requests int
+	}{
+		{"checkout-1", []string{"checkout", "ci"}, 2},
+		{"search-1", []string{"search"}, 1},
+		{"checkout-2", []string{"checkout"}, 0},
+		{"untagged", nil, 3},
+	}
+
+	for _, s := range sessions {
+		config := TracingConfig{OutputDir: tempDir, SessionTags: s.tags}
+		if err := os.MkdirAll(filepath.Join(tempDir, "sessions", s.id), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := NewSessionCoordinator(s.id, config).writeSessionMetadata(); err != nil {
+			t.Fatalf("writeSessionMetadata %s failed: %v", s.id, err)
+		}
+
+		log := strings.Repeat(`{"type":"http_request"}`+"\n"+`{"type":"http_response"}`+"\n", s.requests)
+		if err := os.WriteFile(filepath.Join(tempDir, "sessions", s.id, "session.jsonl"), []byte(log), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A directory without metadata is not a session
+	if err := os.MkdirAll(filepath.Join(tempDir, "sessions", "stray"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := IndexSessions(filepath.Join(tempDir, "sessions"))
+	if err != nil {
+		t.Fatalf("IndexSessions failed: %v", err)
+	}
+	if len(index) != len(sessions) {
+		t.Fatalf("Expected %d sessions, got %d: %+v", len(sessions), len(index), index)
+	}
+
+	counts := make(map[string]int)
+	for _, info := range index {
+		counts[info.ID] = info.RequestCount
+		if info.StartTime.IsZero() {
+			t.Errorf("Expected a start time for %s", info.ID)
+		}
+	}
+	for _, s := range sessions {
+		if counts[s.id] != s.requests {
+			t.Errorf("Expected %d requests for %s, got %d", s.requests, s.id, counts[s.id])
+		}
+	}
+
+	checkout := SessionsWithTag(index, "checkout")
+	if len(checkout) != 2 {
+		t.Fatalf("Expected 2 checkout sessions, got %+v", checkout)
+	}
+	for _, info := range checkout {
+		if !strings.HasPrefix(info.ID, "checkout-") {
+			t.Errorf("Unexpected session %s tagged checkout", info.ID)
+		}
+	}
+
+	if got := SessionsWithTag(index, "missing"); len(got) != 0 {
+		t.Errorf("Expected no sessions for an unknown tag, got %+v", got)
+	}
+}